@@ -24,6 +24,7 @@ import (
 
 	"github.com/apache/trafficcontrol/grove/cachedata"
 	"github.com/apache/trafficcontrol/grove/plugin"
+	"github.com/apache/trafficcontrol/grove/purge"
 
 	"github.com/apache/trafficcontrol/grove/remap"
 	"github.com/apache/trafficcontrol/grove/stat"
@@ -69,7 +70,13 @@ type Handler struct {
 	httpConns       *web.ConnMap
 	httpsConns      *web.ConnMap
 	interfaceName   string
-	requestID       uint64 // Atomic - DO NOT access or modify without atomic operations
+	// collapseTimeout is the maximum time a request waits for a concurrent parent request for the same cache key, for rules without their own. If 0, requests wait for the concurrent request to complete.
+	collapseTimeout time.Duration
+	// streamChunkBytes is the size of the chunks to stream parent response bodies in, or 0 to read them in full before responding.
+	streamChunkBytes int
+	requestID        uint64 // Atomic - DO NOT access or modify without atomic operations
+	// invalidations are the soft invalidations of cached objects, which must be revalidated before they're served. They're shared by all Handlers, so they persist across config reloads.
+	invalidations *purge.Invalidations
 	// keyThrottlers     Throttlers
 	// nocacheThrottlers Throttlers
 }
@@ -112,6 +119,9 @@ func NewHandler(
 	httpConns *web.ConnMap,
 	httpsConns *web.ConnMap,
 	interfaceName string,
+	streamChunkBytes int,
+	collapseTimeout time.Duration,
+	invalidations *purge.Invalidations,
 ) *Handler {
 	hostname, err := os.Hostname()
 	if err != nil {
@@ -119,21 +129,24 @@ func NewHandler(
 	}
 
 	return &Handler{
-		remapper:        remapper,
-		getter:          thread.NewGetter(),
-		ruleThrottlers:  makeRuleThrottlers(remapper, ruleLimit),
-		strictRFC:       strictRFC,
-		scheme:          scheme,
-		port:            port,
-		hostname:        hostname,
-		stats:           stats,
-		conns:           conns,
-		connectionClose: connectionClose,
-		plugins:         plugins,
-		pluginContext:   pluginContext,
-		httpConns:       httpConns,
-		httpsConns:      httpsConns,
-		interfaceName:   interfaceName,
+		remapper:         remapper,
+		getter:           thread.NewGetter(),
+		ruleThrottlers:   makeRuleThrottlers(remapper, ruleLimit),
+		strictRFC:        strictRFC,
+		scheme:           scheme,
+		port:             port,
+		hostname:         hostname,
+		stats:            stats,
+		conns:            conns,
+		connectionClose:  connectionClose,
+		plugins:          plugins,
+		pluginContext:    pluginContext,
+		httpConns:        httpConns,
+		httpsConns:       httpsConns,
+		interfaceName:    interfaceName,
+		streamChunkBytes: streamChunkBytes,
+		collapseTimeout:  collapseTimeout,
+		invalidations:    invalidations,
 		// keyThrottlers:     NewThrottlers(keyLimit),
 		// nocacheThrottlers: NewThrottlers(nocacheLimit),
 	}
@@ -166,7 +179,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	reqID := atomic.AddUint64(&h.requestID, 1)
 	pluginContext := copyPluginContext(h.pluginContext) // must give each request a copy, because they can modify in parallel
 	srvrData := cachedata.SrvrData{Hostname: h.hostname, Port: h.port, Scheme: h.scheme}
-	onReqData := plugin.OnRequestData{W: w, R: r, Stats: h.stats, StatRules: h.remapper.StatRules(), HTTPConns: h.httpConns, HTTPSConns: h.httpsConns, InterfaceName: h.interfaceName, SrvrData: srvrData, RequestID: reqID}
+	onReqData := plugin.OnRequestData{W: w, R: r, Stats: h.stats, StatRules: h.remapper.StatRules(), HTTPConns: h.httpConns, HTTPSConns: h.httpsConns, InterfaceName: h.interfaceName, SrvrData: srvrData, RequestID: reqID, Invalidations: h.invalidations}
 	stop := h.plugins.OnRequest(h.remapper.PluginCfg(), pluginContext, onReqData)
 	if stop {
 		return
@@ -232,7 +245,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	cache := remappingProducer.Cache()
 
 	var reqHost *string
-	cacheObj, ok := cache.Get(cacheKey)
+	cacheObj, ok := getVariant(cache, cacheKey, reqHeader)
 	if !ok {
 		log.Debugf("cache.Handler.ServeHTTP: '%v' not in cache (reqid %v)\n", cacheKey, reqID)
 		beforeParentRequestData := plugin.BeforeParentRequestData{Req: r, RemapRule: remappingProducer.Name()}
@@ -251,14 +264,14 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		responder.OriginCode = cacheObj.OriginCode
 		// create new pointers, so plugins don't modify the cacheObj
-		codePtr, hdrsPtr, bodyPtr := cacheObj.Code, cacheObj.RespHeaders, cacheObj.Body
-		responder.SetResponse(&codePtr, &hdrsPtr, &bodyPtr, connectionClose)
+		codePtr, hdrsPtr, bodyPtr, chunkedPtr := cacheObj.Code, cacheObj.RespHeaders, cacheObj.Body, cacheObj.Chunked
+		responder.SetResponse(&codePtr, &hdrsPtr, &bodyPtr, &chunkedPtr, connectionClose)
 		responder.OriginReqSuccess = true
 		responder.ProxyStr = cacheObj.ProxyURL
 		if reqHost != nil {
 			responder.ToFQDN = *reqHost
 		}
-		beforeRespData := plugin.BeforeRespondData{Req: r, CacheObj: cacheObj, Code: &codePtr, Hdr: &hdrsPtr, Body: &bodyPtr, RemapRule: remappingProducer.Name()}
+		beforeRespData := plugin.BeforeRespondData{Req: r, CacheObj: cacheObj, Code: &codePtr, Hdr: &hdrsPtr, Body: &bodyPtr, BodyChunks: &chunkedPtr, RemapRule: remappingProducer.Name()}
 		h.plugins.OnBeforeRespond(remappingProducer.PluginCfg(), pluginContext, beforeRespData)
 		responder.Do()
 		return
@@ -266,6 +279,10 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	reqHeaders := r.Header
 	canReuseStored := rfc.CanReuseStored(reqHeaders, cacheObj.RespHeaders, reqCacheControl, cacheObj.RespCacheControl, cacheObj.ReqHeaders, cacheObj.ReqRespTime, cacheObj.RespRespTime, h.strictRFC)
+	if canReuseStored != rfc.ReuseCannot && h.invalidations.Invalidated(cacheKey, cacheObj.ReqRespTime) {
+		log.Debugf("cache.Handler.ServeHTTP: '%v' invalidated, must revalidate (reqid %v)\n", cacheKey, reqID)
+		canReuseStored = rfc.ReuseMustRevalidate
+	}
 
 	if canReuseStored != rfc.ReuseCan { // run the BeforeParentRequest hook for revalidations / ReuseCannot
 		beforeParentRequestData := plugin.BeforeParentRequestData{Req: r, RemapRule: remappingProducer.Name()}
@@ -293,18 +310,30 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	case rfc.ReuseMustRevalidateCanStale:
 		log.Debugf("cache.Handler.ServeHTTP: '%v' must revalidate (but allowed stale) (reqid %v)\n", cacheKey, reqID)
+		staleness := rfc.Staleness(cacheObj.RespHeaders, cacheObj.RespCacheControl, cacheObj.ReqRespTime, cacheObj.RespRespTime)
+		if window, ok := staleWhileRevalidate(remappingProducer, reqCacheControl, h.strictRFC, cacheObj); ok && staleness < window {
+			log.Debugf("cache.Handler.ServeHTTP: '%v' stale for %v within stale-while-revalidate %v, serving stale and revalidating in the background (reqid %v)\n", cacheKey, staleness, window, reqID)
+			revalidateInBackground(retrier, r, cacheObj, cacheKey, reqID)
+			canReuseStored = rfc.ReuseCan // the stale object is served without waiting for the parent, so it's a hit
+			break
+		}
 		oldCacheObj := cacheObj
 		cacheObj, reqHost, err = retrier.Get(r, cacheObj)
 		if err != nil {
 			log.Errorf("retrying get error - serving stale as allowed: %v (reqid %v)\n", err, reqID)
 			cacheObj = oldCacheObj
+		} else if isParentError(cacheObj) {
+			if window, ok := staleIfError(remappingProducer, reqCacheControl, oldCacheObj); ok && staleness < window {
+				log.Errorf("revalidating '%v' parent error code %v - serving stale for %v within stale-if-error %v (reqid %v)\n", cacheKey, cacheObj.Code, staleness, window, reqID)
+				cacheObj = oldCacheObj
+			}
 		}
 	}
 	log.Debugf("cache.Handler.ServeHTTP: '%v' responding with %v (reqid %v)\n", cacheKey, cacheObj.Code, reqID)
 
 	// create new pointers, so plugins don't modify the cacheObj
-	codePtr, hdrsPtr, bodyPtr := cacheObj.Code, cacheObj.RespHeaders, cacheObj.Body
-	responder.SetResponse(&codePtr, &hdrsPtr, &bodyPtr, connectionClose)
+	codePtr, hdrsPtr, bodyPtr, chunkedPtr := cacheObj.Code, cacheObj.RespHeaders, cacheObj.Body, cacheObj.Chunked
+	responder.SetResponse(&codePtr, &hdrsPtr, &bodyPtr, &chunkedPtr, connectionClose)
 	responder.OriginReqSuccess = true
 	responder.Reuse = canReuseStored
 	responder.OriginCode = cacheObj.OriginCode
@@ -313,7 +342,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if reqHost != nil {
 		responder.ToFQDN = *reqHost
 	}
-	beforeRespData := plugin.BeforeRespondData{Req: r, CacheObj: cacheObj, Code: &codePtr, Hdr: &hdrsPtr, Body: &bodyPtr, RemapRule: remappingProducer.Name()}
+	beforeRespData := plugin.BeforeRespondData{Req: r, CacheObj: cacheObj, Code: &codePtr, Hdr: &hdrsPtr, Body: &bodyPtr, BodyChunks: &chunkedPtr, RemapRule: remappingProducer.Name()}
 	h.plugins.OnBeforeRespond(remappingProducer.PluginCfg(), pluginContext, beforeRespData)
 	responder.Do()
 }
@@ -18,6 +18,7 @@ import (
 	"net/http"
 
 	"github.com/apache/trafficcontrol/grove/cachedata"
+	"github.com/apache/trafficcontrol/grove/cacheobj"
 	"github.com/apache/trafficcontrol/grove/plugin"
 	"github.com/apache/trafficcontrol/grove/stat"
 	"github.com/apache/trafficcontrol/grove/web"
@@ -78,11 +79,16 @@ func NewResponder(w http.ResponseWriter, pluginCfg map[string]interface{}, plugi
 }
 
 // SetResponse is a helper which sets the RespondFunc of r to `web.Respond` with the given code, headers, body, and connectionClose. Note it takes a pointer to the headers and body, which may be modified after calling this but before the Do() sends the response.
-func (r *Responder) SetResponse(code *int, hdrs *http.Header, body *[]byte, connectionClose bool) {
+// If the chunked body is non-nil when the response is sent, it's streamed to the client with `web.RespondReader` instead of the body.
+func (r *Responder) SetResponse(code *int, hdrs *http.Header, body *[]byte, chunked **cacheobj.ChunkedBody, connectionClose bool) {
 	r.ResponseCode = code
 	r.F = func() (uint64, error) {
 		if r.Req.Method == http.MethodHead {
 			*body = nil
+			*chunked = nil
+		}
+		if *chunked != nil {
+			return web.RespondReader(r.W, *code, *hdrs, (*chunked).NewReader(), connectionClose)
 		}
 		return web.Respond(r.W, *code, *hdrs, *body, connectionClose)
 	}
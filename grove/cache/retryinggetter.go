@@ -59,10 +59,24 @@ func (r *Retrier) Get(req *http.Request, obj *cacheobj.CacheObj) (*cacheobj.Cach
 		canReuse := func(cacheObj *cacheobj.CacheObj) bool {
 			return cacheobj.CanReuse(r.ReqHdr, r.ReqCacheControl, cacheObj, r.H.strictRFC, true)
 		}
+		keepStaleOnError := obj != nil && withinStaleIfError(r.RemappingProducer, r.ReqCacheControl, obj)
 		getAndCache := func() *cacheobj.CacheObj {
-			return GetAndCache(remapping.Request, remapping.ProxyURL, remapping.CacheKey, remapping.Name, remapping.Request.Header, r.ReqTime, r.H.strictRFC, remapping.Cache, r.H.ruleThrottlers[remapping.Name], obj, remapping.Timeout, retryFailures, remapping.RetryNum, remapping.RetryCodes, remapping.Transport, r.ReqID)
+			return GetAndCache(remapping.Request, remapping.ProxyURL, remapping.CacheKey, remapping.Name, remapping.Request.Header, r.ReqTime, r.H.strictRFC, remapping.Cache, r.H.ruleThrottlers[remapping.Name], obj, keepStaleOnError, remapping.Timeout, retryFailures, remapping.RetryNum, remapping.RetryCodes, remapping.Transport, r.H.streamChunkBytes, r.ReqID)
+		}
+		collapseTimeout := r.RemappingProducer.CollapseTimeout()
+		if collapseTimeout == 0 {
+			collapseTimeout = r.H.collapseTimeout
+		}
+		gotObj, getReqID, collapse := r.H.getter.Get(remapping.CacheKey, getAndCache, canReuse, collapseTimeout, r.ReqID)
+		switch collapse {
+		case thread.CollapseWaited:
+			r.H.stats.AddCollapsedRequest()
+		case thread.CollapseTimedOut:
+			log.Debugf("Retrier.Get timed out after %v waiting for concurrent request for %v, requesting (reqid %v)\n", collapseTimeout, remapping.CacheKey, r.ReqID)
+			r.H.stats.AddCollapseTimeout()
+		case thread.CollapseUnusable:
+			r.H.stats.AddCollapseUnusable()
 		}
-		gotObj, getReqID := r.H.getter.Get(remapping.CacheKey, getAndCache, canReuse, r.ReqID)
 
 		req := remapping.Request
 		log.Debugf("Retrier.Get Y URI %v %v %v remapping.CacheKey %v rule %v parent %v code %v headers %+v len(body) %v getterid %v (reqid %v)\n", req.URL.Scheme, req.URL.Host, req.URL.EscapedPath(), remapping.CacheKey, remapping.Name, remapping.ProxyURL, gotObj.Code, gotObj.RespHeaders, len(gotObj.Body), getReqID, r.ReqID)
@@ -104,6 +118,8 @@ const ModifiedSinceHdr = "If-Modified-Since"
 
 // GetAndCache makes a client request for the given `http.Request` and caches it if `CanCache`.
 // THe `ruleThrottler` may be nil, in which case the request will be unthrottled.
+// If `keepStaleOnError`, a parent error revalidating `revalidateObj` doesn't replace it in the cache, so it may still be served stale-if-error.
+// If `streamChunkBytes` is positive, the body is streamed from the parent in chunks of that size, and the object is returned and cached as soon as the response headers are received. Other requests for the object tail its body as it's read. The `ruleThrottler` then only throttles requests until their headers are received.
 func GetAndCache(
 	req *http.Request,
 	proxyURL *url.URL,
@@ -115,11 +131,13 @@ func GetAndCache(
 	cache icache.Cache,
 	ruleThrottler thread.Throttler,
 	revalidateObj *cacheobj.CacheObj,
+	keepStaleOnError bool,
 	timeout time.Duration,
 	cacheFailure bool,
 	retryNum int,
 	retryCodes map[int]struct{},
 	transport *http.Transport,
+	streamChunkBytes int,
 	reqID uint64,
 ) *cacheobj.CacheObj {
 	// TODO this is awkward, with 'revalidateObj' indicating whether the request is a Revalidate. Should Getting and Caching be split up? How?
@@ -136,7 +154,7 @@ func GetAndCache(
 		} else {
 			req.Header.Del(ModifiedSinceHdr)
 		}
-		respCode, respHeader, respBody, reqTime, reqRespTime, err := web.Request(transport, req)
+		respCode, respHeader, respBody, respChunked, reqTime, reqRespTime, err := request(transport, req, streamChunkBytes, timeout)
 		log.Debugf("GetAndCache web.Request URI %v %v %v cacheKey %v rule %v parent %v error %v reval %v code %v len(body) %v (reqid %v)\n", req.URL.Scheme, req.URL.Host, req.URL.EscapedPath(), cacheKey, remapName, proxyURLStr, err, revalidateObj != nil, respCode, len(respBody), reqID)
 
 		if err != nil {
@@ -145,8 +163,14 @@ func GetAndCache(
 			body := []byte(http.StatusText(code))
 			return cacheobj.New(reqHeader, body, code, code, proxyURLStr, respHeader, reqTime, reqRespTime, reqRespTime, time.Time{})
 		}
+		newObj := func(respRespTime time.Time, lastModified time.Time) *cacheobj.CacheObj {
+			if respChunked != nil {
+				return cacheobj.NewChunked(reqHeader, respChunked, respCode, respCode, proxyURLStr, respHeader, reqTime, reqRespTime, respRespTime, lastModified)
+			}
+			return cacheobj.New(reqHeader, respBody, respCode, respCode, proxyURLStr, respHeader, reqTime, reqRespTime, respRespTime, lastModified)
+		}
 		if _, ok := retryCodes[respCode]; ok && !cacheFailure {
-			return cacheobj.New(reqHeader, respBody, respCode, respCode, proxyURLStr, respHeader, reqTime, reqRespTime, reqRespTime, time.Time{})
+			return newObj(reqRespTime, time.Time{})
 		}
 
 		log.Debugf("GetAndCache request returned %v headers %+v (reqid %v)\n", respCode, respHeader, reqID)
@@ -166,10 +190,26 @@ func GetAndCache(
 		log.Debugf("GetAndCache respCode %v (reqid %v)\n", respCode, reqID)
 		if revalidateObj == nil || respCode != http.StatusNotModified {
 			log.Debugf("GetAndCache new %v (reqid %v)\n", cacheKey, reqID)
-			obj = cacheobj.New(reqHeader, respBody, respCode, respCode, proxyURLStr, respHeader, reqTime, reqRespTime, respRespTime, lastModified)
+			obj = newObj(respRespTime, lastModified)
 			if !rfc.CanCache(req.Method, reqHeader, respCode, respHeader, strictRFC) {
 				return obj // return without caching
 			}
+			if revalidateObj != nil && keepStaleOnError && isParentError(obj) {
+				return obj // return without replacing the stored object, so it may still be served stale if-error
+			}
+			if respChunked != nil {
+				// cache the object now, so other requests tail its body, and again once the body is complete, so its size is correct and caches which store complete objects store it.
+				go func() {
+					if err := respChunked.Wait(); err != nil {
+						log.Errorf("reading streamed body for URI %v %v %v cacheKey %v rule %v parent %v error %v (reqid %v)\n", req.URL.Scheme, req.URL.Host, req.URL.EscapedPath(), cacheKey, remapName, proxyURLStr, err, reqID)
+						return
+					}
+					if cached, ok := peekVariant(cache, cacheKey, reqHeader); ok && cached.ReqRespTime.After(obj.ReqRespTime) {
+						return // replaced by a newer response while the body was read
+					}
+					addVariant(cache, cacheKey, reqHeader, newObj(respRespTime, lastModified))
+				}()
+			}
 		} else {
 			log.Debugf("GetAndCache revalidating %v len(revalidateObj.Body) %v (reqid %v)\n", cacheKey, len(revalidateObj.Body), reqID)
 			// must copy, because this cache object may be concurrently read by other goroutines
@@ -177,6 +217,7 @@ func GetAndCache(
 			newRespHeader.Set("Date", respHeader.Get("Date"))
 			obj = &cacheobj.CacheObj{
 				Body:             revalidateObj.Body,
+				Chunked:          revalidateObj.Chunked,
 				ReqHeaders:       revalidateObj.ReqHeaders,
 				RespHeaders:      newRespHeader,
 				RespCacheControl: revalidateObj.RespCacheControl,
@@ -189,9 +230,10 @@ func GetAndCache(
 				LastModified:     revalidateObj.LastModified,
 				Size:             revalidateObj.Size,
 				HitCount:         revalidateObj.HitCount, // no need to +1 here, the cache Get did that
+				Vary:             revalidateObj.Vary,
 			}
 		}
-		cache.Add(cacheKey, obj) // TODO store pointer?
+		addVariant(cache, cacheKey, reqHeader, obj) // TODO store pointer?
 		return obj
 	}
 
@@ -203,3 +245,22 @@ func GetAndCache(
 	ruleThrottler.Throttle(func() { c = get() })
 	return c
 }
+
+// request makes the given request to the parent. If streamChunkBytes is positive, the returned object body is nil, and the body is instead returned as a ChunkedBody which is read from the parent in the background. Otherwise, the returned ChunkedBody is nil.
+// A streamed body fails if the parent sends nothing for the timeout, so readers tailing it don't hang on a stalled parent.
+func request(transport *http.Transport, req *http.Request, streamChunkBytes int, timeout time.Duration) (int, http.Header, []byte, *cacheobj.ChunkedBody, time.Time, time.Time, error) {
+	if streamChunkBytes <= 0 {
+		respCode, respHeader, respBody, reqTime, respTime, err := web.Request(transport, req)
+		return respCode, respHeader, respBody, nil, reqTime, respTime, err
+	}
+	respCode, respHeader, respBodyReader, reqTime, respTime, err := web.RequestStream(transport, req, timeout)
+	if err != nil {
+		return respCode, respHeader, nil, nil, reqTime, respTime, err
+	}
+	respChunked := cacheobj.NewChunkedBody(streamChunkBytes)
+	go func() {
+		defer respBodyReader.Close()
+		respChunked.ReadFrom(respBodyReader) // errors are returned to readers by the ChunkedBody
+	}()
+	return respCode, respHeader, nil, respChunked, reqTime, respTime, nil
+}
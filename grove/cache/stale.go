@@ -0,0 +1,88 @@
+package cache
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/apache/trafficcontrol/grove/cacheobj"
+	"github.com/apache/trafficcontrol/grove/remap"
+	"github.com/apache/trafficcontrol/grove/web"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-rfc"
+)
+
+// staleWhileRevalidate returns the RFC5861 stale-while-revalidate window of the given stored object, within which it may be served stale while it's revalidated in the background: the remap rule's window if it has one, else the object's. Returns false if it has none, or it must never be served stale, or if strictRFC and the request doesn't allow it to be served stale.
+func staleWhileRevalidate(remappingProducer *remap.RemappingProducer, reqCacheControl rfc.CacheControlMap, strictRFC bool, obj *cacheobj.CacheObj) (time.Duration, bool) {
+	if !rfc.CanServeStale(obj.RespCacheControl) {
+		return 0, false
+	}
+	if strictRFC && !rfc.RequestAllowsStale(reqCacheControl, obj.RespHeaders, obj.RespCacheControl, obj.ReqRespTime, obj.RespRespTime) {
+		return 0, false
+	}
+	if window, ok := remappingProducer.StaleWhileRevalidate(); ok {
+		return window, true
+	}
+	return rfc.StaleWhileRevalidate(obj.RespCacheControl)
+}
+
+// staleIfError returns the RFC5861 stale-if-error window of the given stored object, within which it may be served stale if revalidating it fails: the remap rule's window if it has one, else that of the request and the object. Returns false if it has none, or it must never be served stale.
+func staleIfError(remappingProducer *remap.RemappingProducer, reqCacheControl rfc.CacheControlMap, obj *cacheobj.CacheObj) (time.Duration, bool) {
+	if !rfc.CanServeStale(obj.RespCacheControl) {
+		return 0, false
+	}
+	if window, ok := remappingProducer.StaleIfError(); ok {
+		return window, true
+	}
+	return rfc.StaleIfError(reqCacheControl, obj.RespCacheControl)
+}
+
+// withinStaleIfError returns whether the given stored object has been stale for less than its stale-if-error window, so it may be served stale if revalidating it fails.
+func withinStaleIfError(remappingProducer *remap.RemappingProducer, reqCacheControl rfc.CacheControlMap, obj *cacheobj.CacheObj) bool {
+	window, ok := staleIfError(remappingProducer, reqCacheControl, obj)
+	return ok && rfc.Staleness(obj.RespHeaders, obj.RespCacheControl, obj.ReqRespTime, obj.RespRespTime) < window
+}
+
+// isParentError returns whether the given object is an error for which a stale object may be served instead, per RFC5861§4. This is any 5xx response, including the CodeConnectFailure of a parent which couldn't be reached or timed out.
+func isParentError(obj *cacheobj.CacheObj) bool {
+	return obj.Code >= http.StatusInternalServerError
+}
+
+// revalidateInBackground revalidates the given stale object with the parent, caching the result, without blocking. The request and retrier are copied, because the background request may outlive the request, and the request's headers may be modified while it's served.
+func revalidateInBackground(retrier *Retrier, r *http.Request, obj *cacheobj.CacheObj, cacheKey string, reqID uint64) {
+	bgReq := r.Clone(context.Background())
+	bgRetrier := *retrier
+	bgRetrier.ReqHdr = web.CopyHeader(retrier.ReqHdr)
+	bgRetrier.ReqCacheControl = copyCacheControl(retrier.ReqCacheControl)
+	go func() {
+		newObj, _, err := bgRetrier.Get(bgReq, obj)
+		if err != nil {
+			log.Errorf("background revalidation of '%v' error: %v (reqid %v)\n", cacheKey, err, reqID)
+			return
+		}
+		log.Debugf("background revalidation of '%v' got %v (reqid %v)\n", cacheKey, newObj.Code, reqID)
+	}()
+}
+
+func copyCacheControl(cc rfc.CacheControlMap) rfc.CacheControlMap {
+	newCC := make(rfc.CacheControlMap, len(cc))
+	for k, v := range cc {
+		newCC[k] = v
+	}
+	return newCC
+}
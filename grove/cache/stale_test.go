@@ -0,0 +1,186 @@
+package cache
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/grove/icache"
+	"github.com/apache/trafficcontrol/grove/memcache"
+	"github.com/apache/trafficcontrol/grove/plugin"
+	"github.com/apache/trafficcontrol/grove/purge"
+	"github.com/apache/trafficcontrol/grove/remap"
+	"github.com/apache/trafficcontrol/grove/stat"
+	"github.com/apache/trafficcontrol/grove/web"
+)
+
+// staleTestParent is a parent which responds with the given code and Cache-Control, and a body of the number of requests it has received.
+type staleTestParent struct {
+	m            sync.Mutex
+	code         int
+	cacheControl string
+	requests     int
+}
+
+func (p *staleTestParent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.m.Lock()
+	p.requests++
+	code, cacheControl, body := p.code, p.cacheControl, strconv.Itoa(p.requests)
+	p.m.Unlock()
+	w.Header().Set("Cache-Control", cacheControl)
+	w.WriteHeader(code)
+	w.Write([]byte(body))
+}
+
+func (p *staleTestParent) set(code int, cacheControl string) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.code, p.cacheControl = code, cacheControl
+}
+
+func (p *staleTestParent) numRequests() int {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.requests
+}
+
+// newStaleTestHandler returns a Handler with a single remap rule from http://grove.test to the given parent, without retries, and the rule's cache.
+func newStaleTestHandler(t *testing.T, parentURL string, strictRFC bool) (*Handler, icache.Cache) {
+	dir, err := ioutil.TempDir("", "grove-cache")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	rulesFile := filepath.Join(dir, "remap.json")
+	rules := `{"parent_selection": "consistent-hash", "retry_num": 0, "retry_codes": [], "timeout_ms": 5000, "rules": [
+		{"name": "test", "from": "http://grove.test", "to": [{"url": "` + parentURL + `"}]}
+	]}`
+	if err := ioutil.WriteFile(rulesFile, []byte(rules), 0644); err != nil {
+		t.Fatalf("writing remap rules: %v", err)
+	}
+
+	cache := memcache.New(1024 * 1024)
+	caches := map[string]icache.Cache{"": cache}
+	remapper, err := remap.LoadRemapper(rulesFile, nil, caches, &http.Transport{})
+	if err != nil {
+		t.Fatalf("loading remap rules: %v", err)
+	}
+	httpConns := web.NewConnMap()
+	stats := stat.New(remapper.Rules(), caches, 1024*1024, httpConns, nil, "test")
+	h := NewHandler(remapper, 0, stats, "http", "80", httpConns, strictRFC, false, plugin.Get(nil), map[string]*interface{}{}, httpConns, nil, "", 0, 0, purge.NewInvalidations())
+	return h, cache
+}
+
+// serveStaleTest makes a request to the handler with the given Cache-Control, and returns the response code and body.
+func serveStaleTest(h *Handler, cacheControl string) (int, string) {
+	r := httptest.NewRequest(http.MethodGet, "http://grove.test/foo", nil)
+	r.RequestURI = "/foo" // as received from clients, which the remapper appends to the scheme and host
+	if cacheControl != "" {
+		r.Header.Set("Cache-Control", cacheControl)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code, w.Body.String()
+}
+
+// waitForCachedBody waits for the cached object of the test request to have the given body.
+func waitForCachedBody(t *testing.T, cache icache.Cache, key string, body string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if obj, ok := cache.Peek(key); ok && string(obj.Body) == body {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	obj, _ := cache.Peek(key)
+	t.Fatalf("cached object expected body '%v' after background revalidation, actual %+v", body, obj)
+}
+
+func TestServeStaleWhileRevalidate(t *testing.T) {
+	parent := &staleTestParent{code: http.StatusOK, cacheControl: "max-age=0, stale-while-revalidate=60"}
+	parentSrv := httptest.NewServer(parent)
+	defer parentSrv.Close()
+	key := "GET:" + parentSrv.URL + "/foo"
+
+	h, cache := newStaleTestHandler(t, parentSrv.URL, false)
+	if code, body := serveStaleTest(h, ""); code != http.StatusOK || body != "1" {
+		t.Fatalf("uncached request expected 200 '1', actual %v '%v'", code, body)
+	}
+	if code, body := serveStaleTest(h, ""); code != http.StatusOK || body != "1" {
+		t.Errorf("stale-while-revalidate request expected stale 200 '1', actual %v '%v'", code, body)
+	}
+	waitForCachedBody(t, cache, key, "2")
+	if code, body := serveStaleTest(h, ""); code != http.StatusOK || body != "2" {
+		t.Errorf("request after background revalidation expected 200 '2', actual %v '%v'", code, body)
+	}
+	waitForCachedBody(t, cache, key, "3")
+
+	strictH, strictCache := newStaleTestHandler(t, parentSrv.URL, true)
+	if code, body := serveStaleTest(strictH, ""); code != http.StatusOK || body != "4" {
+		t.Fatalf("uncached strict request expected 200 '4', actual %v '%v'", code, body)
+	}
+	if code, body := serveStaleTest(strictH, "max-age=0"); code != http.StatusOK || body != "5" {
+		t.Errorf("strict request with max-age=0 expected revalidated 200 '5', actual %v '%v'", code, body)
+	}
+	if code, body := serveStaleTest(strictH, ""); code != http.StatusOK || body != "5" {
+		t.Errorf("strict request without max-age expected stale 200 '5', actual %v '%v'", code, body)
+	}
+	waitForCachedBody(t, strictCache, key, "6")
+}
+
+func TestServeStaleIfError(t *testing.T) {
+	parent := &staleTestParent{code: http.StatusOK, cacheControl: "max-age=0, stale-if-error=60"}
+	parentSrv := httptest.NewServer(parent)
+	defer parentSrv.Close()
+	key := "GET:" + parentSrv.URL + "/foo"
+
+	h, cache := newStaleTestHandler(t, parentSrv.URL, false)
+	if code, body := serveStaleTest(h, ""); code != http.StatusOK || body != "1" {
+		t.Fatalf("uncached request expected 200 '1', actual %v '%v'", code, body)
+	}
+	parent.set(http.StatusInternalServerError, "max-age=60")
+	if code, body := serveStaleTest(h, ""); code != http.StatusOK || body != "1" {
+		t.Errorf("stale-if-error request with parent error expected stale 200 '1', actual %v '%v'", code, body)
+	}
+	if obj, ok := cache.Peek(key); !ok || obj.Code != http.StatusOK {
+		t.Errorf("parent error within stale-if-error expected to keep cached 200, actual %+v", obj)
+	}
+
+	// without a stale-if-error window, the parent error is served, and replaces the stored object.
+	parent.set(http.StatusOK, "max-age=0")
+	cache.Remove(key)
+	if code, body := serveStaleTest(h, ""); code != http.StatusOK || body != "3" {
+		t.Fatalf("uncached request expected 200 '3', actual %v '%v'", code, body)
+	}
+	parent.set(http.StatusInternalServerError, "max-age=60")
+	if code, body := serveStaleTest(h, ""); code != http.StatusInternalServerError || body != "4" {
+		t.Errorf("request with parent error without stale-if-error expected 500 '4', actual %v '%v'", code, body)
+	}
+	if obj, ok := cache.Peek(key); !ok || obj.Code != http.StatusInternalServerError {
+		t.Errorf("parent error without stale-if-error expected to replace cached object, actual %+v", obj)
+	}
+	if requests := parent.numRequests(); requests != 4 {
+		t.Errorf("parent requests expected 4, actual %v", requests)
+	}
+}
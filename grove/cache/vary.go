@@ -0,0 +1,57 @@
+package cache
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"net/http"
+
+	"github.com/apache/trafficcontrol/grove/cacheobj"
+	"github.com/apache/trafficcontrol/grove/icache"
+)
+
+// getVariant gets the object cached under the given key. If the responses for the key have a Vary, the key holds their index, and the variant selected by the given request headers is returned instead.
+// Each variant is a separate cache entry, so variants are evicted individually.
+// An object whose streamed body failed to be read from the parent is returned as a miss, so it's fetched again.
+func getVariant(cache icache.Cache, key string, reqHeader http.Header) (*cacheobj.CacheObj, bool) {
+	obj, ok := cache.Get(key)
+	if ok && obj.VaryIndex {
+		obj, ok = cache.Get(cacheobj.VariantKey(key, obj.Vary, reqHeader))
+	}
+	if ok && obj.Chunked != nil && obj.Chunked.Err() != nil {
+		return nil, false
+	}
+	return obj, ok
+}
+
+// peekVariant is like getVariant, but peeks rather than gets, so the object isn't counted as a hit, and returns objects whose streamed body failed.
+func peekVariant(cache icache.Cache, key string, reqHeader http.Header) (*cacheobj.CacheObj, bool) {
+	obj, ok := cache.Peek(key)
+	if !ok || !obj.VaryIndex {
+		return obj, ok
+	}
+	return cache.Peek(cacheobj.VariantKey(key, obj.Vary, reqHeader))
+}
+
+// addVariant caches the given object under the given key. If the object has a Vary, it's cached under the key of its variant selected by the given request headers, and the key holds the index of its variants.
+func addVariant(cache icache.Cache, key string, reqHeader http.Header, obj *cacheobj.CacheObj) {
+	if len(obj.Vary) == 0 {
+		cache.Add(key, obj)
+		return
+	}
+	cache.Add(cacheobj.VariantKey(key, obj.Vary, reqHeader), obj)
+	if index, ok := cache.Peek(key); !ok || !index.VaryIndex || !cacheobj.SameVary(index.Vary, obj.Vary) {
+		cache.Add(key, cacheobj.NewVaryIndex(obj.Vary))
+	}
+}
@@ -0,0 +1,64 @@
+package cache
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/grove/cacheobj"
+	"github.com/apache/trafficcontrol/grove/memcache"
+)
+
+func newVaryTestObj(body string, vary string) *cacheobj.CacheObj {
+	respHeader := http.Header{"Cache-Control": {"max-age=60"}}
+	if vary != "" {
+		respHeader.Set("Vary", vary)
+	}
+	now := time.Now()
+	return cacheobj.New(http.Header{}, []byte(body), http.StatusOK, http.StatusOK, "", respHeader, now, now, now, now)
+}
+
+func TestVariants(t *testing.T) {
+	cache := memcache.New(1024 * 1024)
+	key := "GET:http://origin.example.net/foo"
+	gzip := http.Header{"Accept-Encoding": {"gzip"}}
+	br := http.Header{"Accept-Encoding": {"br"}}
+
+	addVariant(cache, key, gzip, newVaryTestObj("gzip body", "accept-encoding"))
+	addVariant(cache, key, br, newVaryTestObj("br body", "Accept-Encoding"))
+
+	if index, ok := cache.Peek(key); !ok || !index.VaryIndex || len(index.Vary) != 1 || index.Vary[0] != "Accept-Encoding" {
+		t.Fatalf("variant index - expected: index varying on Accept-Encoding, actual: %+v", index)
+	}
+	if obj, ok := getVariant(cache, key, gzip); !ok || string(obj.Body) != "gzip body" {
+		t.Errorf("gzip variant - expected: gzip body, actual: %+v", obj)
+	}
+	if obj, ok := getVariant(cache, key, http.Header{"Accept-Encoding": {" br "}}); !ok || string(obj.Body) != "br body" {
+		t.Errorf("br variant - expected: br body, actual: %+v", obj)
+	}
+	if obj, ok := getVariant(cache, key, http.Header{}); ok {
+		t.Errorf("variant without Accept-Encoding - expected: miss, actual: %+v", obj)
+	}
+	if keys := cache.Keys(); len(keys) != 3 {
+		t.Errorf("cache keys - expected: index and 2 variants, actual: %v", keys)
+	}
+
+	addVariant(cache, key, gzip, newVaryTestObj("identity body", ""))
+	if obj, ok := getVariant(cache, key, br); !ok || string(obj.Body) != "identity body" {
+		t.Errorf("response without Vary - expected: identity body for every request, actual: %+v", obj)
+	}
+}
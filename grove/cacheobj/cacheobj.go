@@ -34,12 +34,18 @@ type CacheObj struct {
 	RespRespTime     time.Time // the origin server's Date time when the object was sent
 	LastModified     time.Time // the origin LastModified if it exists, or Date if it doesn't
 	Size             uint64
-	HitCount         uint64 // the number of times this object was hit
+	HitCount         uint64       // the number of times this object was hit
+	Vary             []string     // the canonical names of the request headers in the origin Vary, which select this variant of the cache key
+	VaryIndex        bool         // whether this object isn't a response, but the index of the variants of a cache key, which are selected by its Vary
+	Chunked          *ChunkedBody // the body, if it's streamed in chunks rather than buffered in Body. Caches which serialize objects must store its chunks separately.
 }
 
 // ComputeSize computes the size of the given CacheObj. This computation is expensive, as the headers must be iterated over. Thus, the size should be computed once and stored, not computed on-the-fly for every new request for the cached object.
 func (c CacheObj) ComputeSize() uint64 {
 	// TODO include headers size
+	if c.Chunked != nil {
+		return c.Chunked.Size()
+	}
 	return uint64(len(c.Body))
 }
 
@@ -57,6 +63,7 @@ func New(reqHeader http.Header, bytes []byte, code int, originCode int, proxyURL
 		RespRespTime:     respRespTime,
 		LastModified:     lastModified,
 		HitCount:         1,
+		Vary:             rfc.ParseVary(respHeader),
 	}
 	// copyHeader(reqHeader, &obj.reqHeaders)
 	// copyHeader(respHeader, &obj.respHeaders)
@@ -64,6 +71,14 @@ func New(reqHeader http.Header, bytes []byte, code int, originCode int, proxyURL
 	return obj
 }
 
+// NewChunked creates a new CacheObj whose body is the given ChunkedBody, which may still be being read from the parent. Its Size is that of the body when it's created, so an object whose body is still being read must be created again once it's complete, for its Size to be correct.
+func NewChunked(reqHeader http.Header, body *ChunkedBody, code int, originCode int, proxyURL string, respHeader http.Header, reqTime time.Time, reqRespTime time.Time, respRespTime time.Time, lastModified time.Time) *CacheObj {
+	obj := New(reqHeader, nil, code, originCode, proxyURL, respHeader, reqTime, reqRespTime, respRespTime, lastModified)
+	obj.Chunked = body
+	obj.Size = obj.ComputeSize()
+	return obj
+}
+
 // CanReuse is a helper wrapping
 // github.com/apache/trafficcontrol/lib/go-rfc.CanReuseStored, returning a
 // boolean rather than an enumerated "Reuse" value, for when it's known whether
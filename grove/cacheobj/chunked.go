@@ -0,0 +1,213 @@
+package cacheobj
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ChunkedBody is a response body stored in fixed-size chunks, rather than a single contiguous slice, which may still be being read from the parent.
+// Readers of a body which is still being read from the parent tail it, receiving each chunk as it's read, so a body can be served to any number of clients while it's being fetched and cached.
+// It is safe for multiple goroutines.
+type ChunkedBody struct {
+	m         *sync.Mutex
+	cond      *sync.Cond
+	chunkSize int
+	chunks    [][]byte // every chunk is full, except the last
+	size      uint64
+	complete  bool
+	err       error
+}
+
+// ErrIncompleteBody is returned by operations on a ChunkedBody which require it to be complete.
+var ErrIncompleteBody = errors.New("body is still being read")
+
+// NewChunkedBody returns an empty ChunkedBody, to be read into with ReadFrom, whose chunks are the given size in bytes.
+func NewChunkedBody(chunkSize int) *ChunkedBody {
+	m := &sync.Mutex{}
+	return &ChunkedBody{m: m, cond: sync.NewCond(m), chunkSize: chunkSize}
+}
+
+// NewCompleteChunkedBody returns a complete ChunkedBody of the given chunks, which must all be of the given size, except the last. It's used to load a body which was stored in chunks.
+func NewCompleteChunkedBody(chunkSize int, chunks [][]byte) *ChunkedBody {
+	b := NewChunkedBody(chunkSize)
+	b.chunks = chunks
+	for _, chunk := range chunks {
+		b.size += uint64(len(chunk))
+	}
+	b.complete = true
+	return b
+}
+
+// ReadFrom reads the body from r until EOF or an error, making it available to readers as it's read, and then marks it complete. It implements io.ReaderFrom, and must only be called once.
+// If r returns an error other than EOF, the body is complete, but readers receive the error after the bytes read before it.
+func (b *ChunkedBody) ReadFrom(r io.Reader) (int64, error) {
+	total := int64(0)
+	for {
+		b.m.Lock()
+		if len(b.chunks) == 0 || len(b.chunks[len(b.chunks)-1]) == b.chunkSize {
+			b.chunks = append(b.chunks, make([]byte, 0, b.chunkSize))
+		}
+		last := b.chunks[len(b.chunks)-1]
+		b.m.Unlock()
+
+		// Readers only ever read the bytes of a chunk up to its length when they locked, so the capacity beyond it may be read into without locking.
+		n, err := r.Read(last[len(last):cap(last)])
+		total += int64(n)
+
+		b.m.Lock()
+		if n > 0 {
+			b.chunks[len(b.chunks)-1] = last[:len(last)+n]
+			b.size += uint64(n)
+		}
+		done := err != nil
+		if done {
+			if err == io.EOF {
+				err = nil
+			}
+			if len(last)+n == 0 {
+				b.chunks = b.chunks[:len(b.chunks)-1] // don't keep an empty chunk
+			}
+			b.complete = true
+			b.err = err
+		}
+		b.cond.Broadcast()
+		b.m.Unlock()
+		if done {
+			return total, err
+		}
+	}
+}
+
+// Complete returns whether the body has been completely read from the parent, successfully or not.
+func (b *ChunkedBody) Complete() bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.complete
+}
+
+// Err returns the error reading the body from the parent, if any. It returns nil if the body is still being read.
+func (b *ChunkedBody) Err() error {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.err
+}
+
+// Wait blocks until the body is complete, and returns the error reading it, if any.
+func (b *ChunkedBody) Wait() error {
+	b.m.Lock()
+	defer b.m.Unlock()
+	for !b.complete {
+		b.cond.Wait()
+	}
+	return b.err
+}
+
+// Size returns the number of bytes of the body read so far.
+func (b *ChunkedBody) Size() uint64 {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.size
+}
+
+// ChunkSize returns the size in bytes of the body's chunks.
+func (b *ChunkedBody) ChunkSize() int {
+	return b.chunkSize
+}
+
+// Chunks returns the chunks of the body. It returns ErrIncompleteBody if the body is still being read, or the error reading it, if any. The chunks must not be modified.
+func (b *ChunkedBody) Chunks() ([][]byte, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	if !b.complete {
+		return nil, ErrIncompleteBody
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.chunks[:len(b.chunks):len(b.chunks)], nil
+}
+
+// Bytes waits for the body to be complete, and returns it as a single contiguous slice. This copies the entire body, and should only be used by things which can't work with a stream, such as plugins which modify the body.
+func (b *ChunkedBody) Bytes() ([]byte, error) {
+	if err := b.Wait(); err != nil {
+		return nil, err
+	}
+	chunks, err := b.Chunks()
+	if err != nil {
+		return nil, err
+	}
+	body := make([]byte, 0, b.Size())
+	for _, chunk := range chunks {
+		body = append(body, chunk...)
+	}
+	return body, nil
+}
+
+// ErrChunkedBodyEncode is returned by attempts to gob encode or decode a ChunkedBody, whose chunks must be stored separately from the CacheObj.
+var ErrChunkedBodyEncode = errors.New("a chunked body can't be gob encoded, its chunks must be stored separately")
+
+// GobEncode implements gob.GobEncoder, by returning ErrChunkedBodyEncode. A CacheObj with a nil Chunked can be encoded.
+func (b *ChunkedBody) GobEncode() ([]byte, error) {
+	return nil, ErrChunkedBodyEncode
+}
+
+// GobDecode implements gob.GobDecoder, by returning ErrChunkedBodyEncode.
+func (b *ChunkedBody) GobDecode([]byte) error {
+	return ErrChunkedBodyEncode
+}
+
+// NewReader returns a reader of the body from its beginning. If the body is still being read, the reader tails it, blocking until more of it is read.
+func (b *ChunkedBody) NewReader() io.Reader {
+	return &chunkedBodyReader{body: b}
+}
+
+type chunkedBodyReader struct {
+	body  *ChunkedBody
+	chunk int
+	off   int
+}
+
+func (r *chunkedBodyReader) Read(p []byte) (int, error) {
+	b := r.body
+	b.m.Lock()
+	for {
+		if r.chunk < len(b.chunks) {
+			chunk := b.chunks[r.chunk]
+			if r.off < len(chunk) {
+				b.m.Unlock()
+				n := copy(p, chunk[r.off:])
+				r.off += n
+				return n, nil
+			}
+			if len(chunk) == b.chunkSize {
+				r.chunk++
+				r.off = 0
+				continue
+			}
+		}
+		if b.complete {
+			err := b.err
+			b.m.Unlock()
+			if err == nil {
+				err = io.EOF
+			}
+			return 0, err
+		}
+		b.cond.Wait()
+	}
+}
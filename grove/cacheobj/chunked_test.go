@@ -0,0 +1,121 @@
+package cacheobj
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestChunkedBodyTail(t *testing.T) {
+	pr, pw := io.Pipe()
+	body := NewChunkedBody(4)
+	go body.ReadFrom(pr)
+
+	reader := body.NewReader()
+	results := make(chan []byte)
+	go func() {
+		b, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Errorf("reading tailed body expected nil error, actual %v", err)
+		}
+		results <- b
+	}()
+
+	for _, write := range []string{"abc", "defgh", "", "ijklmnop", "q"} {
+		if _, err := pw.Write([]byte(write)); err != nil {
+			t.Fatalf("writing body: %v", err)
+		}
+	}
+	if body.Complete() {
+		t.Errorf("body complete before EOF")
+	}
+	if _, err := body.Chunks(); err != ErrIncompleteBody {
+		t.Errorf("Chunks of incomplete body expected ErrIncompleteBody, actual %v", err)
+	}
+	pw.Close()
+
+	expected := "abcdefghijklmnopq"
+	if actual := <-results; string(actual) != expected {
+		t.Errorf("tailed body expected '%v', actual '%v'", expected, string(actual))
+	}
+	if err := body.Wait(); err != nil {
+		t.Errorf("Wait expected nil error, actual %v", err)
+	}
+
+	chunks, err := body.Chunks()
+	if err != nil {
+		t.Fatalf("Chunks expected nil error, actual %v", err)
+	}
+	if len(chunks) != 5 {
+		t.Errorf("Chunks expected 5 chunks, actual %v", len(chunks))
+	}
+	for i, chunk := range chunks[:len(chunks)-1] {
+		if len(chunk) != 4 {
+			t.Errorf("chunk %v expected size 4, actual %v", i, len(chunk))
+		}
+	}
+	if body.Size() != uint64(len(expected)) {
+		t.Errorf("Size expected %v, actual %v", len(expected), body.Size())
+	}
+
+	// readers created after the body is complete read all of it
+	if b, err := ioutil.ReadAll(body.NewReader()); err != nil || string(b) != expected {
+		t.Errorf("reading complete body expected '%v' nil, actual '%v' %v", expected, string(b), err)
+	}
+	if b, err := body.Bytes(); err != nil || string(b) != expected {
+		t.Errorf("Bytes expected '%v' nil, actual '%v' %v", expected, string(b), err)
+	}
+}
+
+func TestChunkedBodyErr(t *testing.T) {
+	readErr := errors.New("connection reset")
+	body := NewChunkedBody(4)
+	body.ReadFrom(io.MultiReader(bytes.NewReader([]byte("abcdef")), &errReader{err: readErr}))
+
+	b, err := ioutil.ReadAll(body.NewReader())
+	if err != readErr {
+		t.Errorf("reading failed body expected error %v, actual %v", readErr, err)
+	}
+	if string(b) != "abcdef" {
+		t.Errorf("reading failed body expected 'abcdef' before the error, actual '%v'", string(b))
+	}
+	if body.Err() != readErr {
+		t.Errorf("Err expected %v, actual %v", readErr, body.Err())
+	}
+	if _, err := body.Bytes(); err != readErr {
+		t.Errorf("Bytes of failed body expected error %v, actual %v", readErr, err)
+	}
+}
+
+func TestCompleteChunkedBody(t *testing.T) {
+	body := NewCompleteChunkedBody(3, [][]byte{[]byte("abc"), []byte("def"), []byte("g")})
+	if !body.Complete() {
+		t.Errorf("complete chunked body expected complete")
+	}
+	if body.Size() != 7 {
+		t.Errorf("Size expected 7, actual %v", body.Size())
+	}
+	if b, err := ioutil.ReadAll(body.NewReader()); err != nil || string(b) != "abcdefg" {
+		t.Errorf("reading complete body expected 'abcdefg' nil, actual '%v' %v", string(b), err)
+	}
+}
+
+type errReader struct{ err error }
+
+func (r *errReader) Read(p []byte) (int, error) { return 0, r.err }
@@ -0,0 +1,63 @@
+package cacheobj
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/apache/trafficcontrol/lib/go-rfc"
+)
+
+// NewVaryIndex returns the object stored under a cache key whose responses have the given Vary, in place of a response. Each variant is stored under its VariantKey, selected by the request headers the Vary names.
+func NewVaryIndex(vary []string) *CacheObj {
+	return &CacheObj{Vary: vary, VaryIndex: true, HitCount: 1}
+}
+
+// VariantKey returns the key under which the variant of the given cache key, selected by the given request headers named in the given Vary, is stored.
+// Request header values are normalized per RFC7234§4.1, so requests whose headers differ only in whitespace select the same variant.
+func VariantKey(key string, vary []string, reqHeader http.Header) string {
+	b := strings.Builder{}
+	b.WriteString(key)
+	b.WriteString("#vary")
+	for _, name := range vary {
+		b.WriteString("\n")
+		b.WriteString(name)
+		b.WriteString(":")
+		b.WriteString(rfc.NormalizedHeaderValue(reqHeader, name))
+	}
+	return b.String()
+}
+
+// BaseKey returns the cache key of the given key, which may be a key returned by VariantKey.
+func BaseKey(key string) string {
+	if i := strings.Index(key, "#vary"); i >= 0 {
+		return key[:i]
+	}
+	return key
+}
+
+// SameVary returns whether the given Vary header names, as returned by rfc.ParseVary, are the same.
+func SameVary(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, name := range a {
+		if b[i] != name {
+			return false
+		}
+	}
+	return true
+}
@@ -61,6 +61,10 @@ type Config struct {
 	CacheFiles           map[string][]CacheFile `json:"cache_files"`
 	// FileMemBytes is the amount of memory to use as an LRU in front of each name in CacheFiles, that is, each named group of files. E.g. if there are 10 files, the amount of memory used will be 10*FileMemBytes+CacheSizeBytes.
 	FileMemBytes int `json:"file_mem_bytes"`
+	// StreamChunkBytes is the size of the chunks origin response bodies are streamed and stored in. If 0, bodies are not streamed, but read in full from the origin before responding to the client.
+	StreamChunkBytes int `json:"stream_chunk_bytes"`
+	// CollapseTimeoutMS is the maximum time in milliseconds a request waits for a concurrent parent request for the same cache key, before making its own. If 0, requests wait for the concurrent request to complete. Note this is overridden by any per-rule settings in the remap rules.
+	CollapseTimeoutMS int `json:"collapse_timeout_ms"`
 }
 
 type CacheFile struct {
@@ -16,6 +16,7 @@ package diskcache
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/gob"
 	"errors"
 	"sync/atomic"
@@ -38,6 +39,9 @@ type DiskCache struct {
 
 const BucketName = "b"
 
+// ChunkBucketName is the name of the bucket storing the chunks of objects whose bodies were streamed. Each chunk is stored under its object's key, followed by a NUL and the big-endian 32-bit index of the chunk, so an object's chunks are adjacent and in order.
+const ChunkBucketName = "c"
+
 func New(path string, cacheSizeBytes uint64) (*DiskCache, error) {
 	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
 	if err != nil {
@@ -48,6 +52,9 @@ func New(path string, cacheSizeBytes uint64) (*DiskCache, error) {
 		if _, err := tx.CreateBucketIfNotExists([]byte(BucketName)); err != nil {
 			return errors.New("creating bucket: " + err.Error())
 		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(ChunkBucketName)); err != nil {
+			return errors.New("creating chunk bucket: " + err.Error())
+		}
 		return nil
 	})
 	if err != nil {
@@ -67,11 +74,29 @@ func (c *DiskCache) ResetAfterRestart() {
 
 		cursor := b.Cursor()
 
+		sizes := map[string]uint64{}
 		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			c.lru.Add(string(k), uint64(len(v)))
+			sizes[string(k)] += uint64(len(v))
+			size += len(v)
+		}
+
+		chunkCursor := tx.Bucket([]byte(ChunkBucketName)).Cursor()
+		for k, v := chunkCursor.First(); k != nil; k, v = chunkCursor.Next() {
+			if len(k) < chunkKeySuffixLen {
+				continue // should never happen
+			}
+			key := string(k[:len(k)-chunkKeySuffixLen])
+			if _, ok := sizes[key]; !ok {
+				continue // orphaned chunk; it will be deleted if its key is ever added
+			}
+			sizes[key] += uint64(len(v))
 			size += len(v)
 		}
 
+		for key, keySize := range sizes {
+			c.lru.Add(key, keySize)
+		}
+
 		atomic.AddUint64(&c.sizeBytes, uint64(size))
 		log.Infof("Cache recovery from disk for %s done (%d bytes). ", c.db.Path(), c.sizeBytes)
 		return nil
@@ -82,23 +107,53 @@ func (c *DiskCache) ResetAfterRestart() {
 // The size is taken to fulfill the Cache interface, but the DiskCache doesn't use it.
 // Instead, we compute size from the serialized bytes stored to disk.
 //
+// Objects with a Chunked body are stored with their chunks in the chunk bucket. Objects whose Chunked body is still being read, or failed, aren't stored; they must be added again once their body is complete.
+//
 // Note DiskCache.Add does garbage collection in a goroutine, and thus it is not possible to determine eviction without impacting performance. This always returns false.
 func (c *DiskCache) Add(key string, val *cacheobj.CacheObj) bool {
 	log.Debugf("DiskCache Add CALLED key '%+v' size '%+v'\n", key, val.Size)
 	eviction := false
 
+	chunks := [][]byte(nil)
+	if val.Chunked != nil {
+		err := error(nil)
+		if chunks, err = val.Chunked.Chunks(); err != nil {
+			log.Debugf("DiskCache Add not storing key '%+v' with chunked body: %v\n", key, err)
+			return eviction
+		}
+		valCopy := *val
+		valCopy.Chunked = nil // the chunks are stored separately
+		val = &valCopy
+	}
+
 	buf := bytes.Buffer{}
 	if err := gob.NewEncoder(&buf).Encode(val); err != nil {
 		log.Errorln("DiskCache.Add encoding cache object: " + err.Error())
 		return eviction
 	}
 	valBytes := buf.Bytes()
+	sizeBytes := uint64(len(valBytes))
+	for _, chunk := range chunks {
+		sizeBytes += uint64(len(chunk))
+	}
 
 	err := c.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(BucketName))
 		if b == nil {
 			return errors.New("bucket does not exist")
 		}
+		chunkB := tx.Bucket([]byte(ChunkBucketName))
+		if chunkB == nil {
+			return errors.New("chunk bucket does not exist")
+		}
+		if err := deleteChunks(chunkB, key); err != nil {
+			return errors.New("deleting old chunks: " + err.Error())
+		}
+		for i, chunk := range chunks {
+			if err := chunkB.Put(chunkKey(key, i), chunk); err != nil {
+				return errors.New("inserting chunk: " + err.Error())
+			}
+		}
 		return b.Put([]byte(key), valBytes)
 	})
 	if err != nil {
@@ -106,14 +161,14 @@ func (c *DiskCache) Add(key string, val *cacheobj.CacheObj) bool {
 		return eviction
 	}
 
-	c.lru.Add(key, uint64(len(valBytes)))
+	oldSizeBytes := c.lru.Add(key, sizeBytes)
 
-	newSizeBytes := atomic.AddUint64(&c.sizeBytes, uint64(len(valBytes)))
+	newSizeBytes := atomic.AddUint64(&c.sizeBytes, sizeBytes-oldSizeBytes) // an overwritten object's old size is subtracted
 	if newSizeBytes > c.maxSizeBytes {
 		go c.gc(newSizeBytes)
 	}
 
-	log.Debugf("DiskCache Add SUCCESS key '%+v' size '%+v' valBytes '%+v' c.sizeBytes '%+v'\n", key, val.Size, len(valBytes), c.sizeBytes)
+	log.Debugf("DiskCache Add SUCCESS key '%+v' size '%+v' valBytes '%+v' chunks '%+v' c.sizeBytes '%+v'\n", key, val.Size, len(valBytes), len(chunks), c.sizeBytes)
 	return eviction
 }
 
@@ -137,6 +192,11 @@ func (c *DiskCache) gc(cacheSizeBytes uint64) {
 				return errors.New("bucket does not exist")
 			}
 			b.Delete([]byte(key))
+			if chunkB := tx.Bucket([]byte(ChunkBucketName)); chunkB != nil {
+				if err := deleteChunks(chunkB, key); err != nil {
+					return errors.New("deleting chunks: " + err.Error())
+				}
+			}
 
 			return b.Delete([]byte(key))
 		})
@@ -165,6 +225,7 @@ func (c *DiskCache) Get(key string) (*cacheobj.CacheObj, bool) {
 func (c *DiskCache) Peek(key string) (*cacheobj.CacheObj, bool) {
 	log.Debugln("DiskCache.Get key '" + key + "'")
 	valBytes := []byte(nil)
+	chunks := [][]byte(nil)
 
 	err := c.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(BucketName))
@@ -172,6 +233,16 @@ func (c *DiskCache) Peek(key string) (*cacheobj.CacheObj, bool) {
 			return errors.New("bucket does not exist")
 		}
 		valBytes = b.Get([]byte(key))
+		if valBytes == nil {
+			return nil
+		}
+		if chunkB := tx.Bucket([]byte(ChunkBucketName)); chunkB != nil {
+			prefix := chunkKeyPrefix(key)
+			cursor := chunkB.Cursor()
+			for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+				chunks = append(chunks, append([]byte(nil), v...)) // values are only valid for the life of the transaction
+			}
+		}
 		return nil
 	})
 	if err != nil {
@@ -191,10 +262,40 @@ func (c *DiskCache) Peek(key string) (*cacheobj.CacheObj, bool) {
 		return nil, false
 	}
 
+	if len(chunks) > 0 {
+		val.Chunked = cacheobj.NewCompleteChunkedBody(len(chunks[0]), chunks) // every chunk but the last is full, so the first is the chunk size
+	}
+
 	log.Debugln("DiskCache.Peek key '" + key + "' CACHE HIT")
 	return &val, true
 }
 
+// Remove removes the key, and its chunks, from the cache. Returns whether the key existed.
+func (c *DiskCache) Remove(key string) bool {
+	existed := false
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(BucketName))
+		if b == nil {
+			return errors.New("bucket does not exist")
+		}
+		existed = b.Get([]byte(key)) != nil
+		if chunkB := tx.Bucket([]byte(ChunkBucketName)); chunkB != nil {
+			if err := deleteChunks(chunkB, key); err != nil {
+				return errors.New("deleting chunks: " + err.Error())
+			}
+		}
+		return b.Delete([]byte(key))
+	})
+	if err != nil {
+		log.Errorln("DiskCache.Remove removing '" + key + "' from cache: " + err.Error())
+		return false
+	}
+	if sizeBytes, ok := c.lru.Remove(key); ok {
+		atomic.AddUint64(&c.sizeBytes, ^uint64(sizeBytes-1)) // subtract sizeBytes
+	}
+	return existed
+}
+
 func (c *DiskCache) Size() uint64 {
 	return atomic.LoadUint64(&c.sizeBytes)
 }
@@ -211,3 +312,30 @@ func (c *DiskCache) Keys() []string {
 func (c *DiskCache) Capacity() uint64 {
 	return c.maxSizeBytes
 }
+
+// chunkKeySuffixLen is the length of the suffix of a chunk key after its object's key: a NUL, and the 32-bit chunk index.
+const chunkKeySuffixLen = 5
+
+// chunkKeyPrefix returns the prefix of the keys of all chunks of the object with the given key.
+func chunkKeyPrefix(key string) []byte {
+	return append([]byte(key), 0)
+}
+
+// chunkKey returns the key of the given chunk of the object with the given key.
+func chunkKey(key string, i int) []byte {
+	k := make([]byte, 4)
+	binary.BigEndian.PutUint32(k, uint32(i))
+	return append(chunkKeyPrefix(key), k...)
+}
+
+// deleteChunks deletes all chunks of the object with the given key from the chunk bucket.
+func deleteChunks(chunkB *bolt.Bucket, key string) error {
+	prefix := chunkKeyPrefix(key)
+	cursor := chunkB.Cursor()
+	for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Seek(prefix) {
+		if err := cursor.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package diskcache
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/grove/cacheobj"
+)
+
+func TestChunkedObject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskcache")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := New(filepath.Join(dir, "cache.db"), 1024*1024)
+	if err != nil {
+		t.Fatalf("creating disk cache: %v", err)
+	}
+	defer c.Close()
+
+	newObj := func(body *cacheobj.ChunkedBody) *cacheobj.CacheObj {
+		now := time.Now()
+		return cacheobj.NewChunked(http.Header{}, body, http.StatusOK, http.StatusOK, "", http.Header{}, now, now, now, now)
+	}
+
+	incomplete := cacheobj.NewChunkedBody(3)
+	c.Add("foo", newObj(incomplete))
+	if _, ok := c.Peek("foo"); ok {
+		t.Errorf("Add of object with incomplete body expected not stored, actual stored")
+	}
+
+	c.Add("foo", newObj(cacheobj.NewCompleteChunkedBody(3, [][]byte{[]byte("abc"), []byte("def"), []byte("g")})))
+	c.Add("foobar", newObj(cacheobj.NewCompleteChunkedBody(3, [][]byte{[]byte("xyz")})))
+	sizeBefore := c.Size()
+
+	// overwriting must delete the old chunks
+	c.Add("foo", newObj(cacheobj.NewCompleteChunkedBody(3, [][]byte{[]byte("hij"), []byte("k")})))
+	if c.Size() >= sizeBefore {
+		t.Errorf("overwriting with a smaller object expected size less than %v, actual %v", sizeBefore, c.Size())
+	}
+
+	for key, expected := range map[string]string{"foo": "hijk", "foobar": "xyz"} {
+		obj, ok := c.Get(key)
+		if !ok {
+			t.Fatalf("Get '%v' expected found, actual not found", key)
+		}
+		if obj.Chunked == nil {
+			t.Fatalf("Get '%v' expected chunked body, actual nil", key)
+		}
+		b, err := obj.Chunked.Bytes()
+		if err != nil {
+			t.Fatalf("Get '%v' reading body: %v", key, err)
+		}
+		if string(b) != expected {
+			t.Errorf("Get '%v' body expected '%v', actual '%v'", key, expected, string(b))
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskcache")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := New(filepath.Join(dir, "cache.db"), 1024*1024)
+	if err != nil {
+		t.Fatalf("creating disk cache: %v", err)
+	}
+	defer c.Close()
+
+	now := time.Now()
+	c.Add("foo", cacheobj.NewChunked(http.Header{}, cacheobj.NewCompleteChunkedBody(3, [][]byte{[]byte("abc"), []byte("d")}), http.StatusOK, http.StatusOK, "", http.Header{}, now, now, now, now))
+	c.Add("foobar", cacheobj.New(http.Header{}, []byte("xyz"), http.StatusOK, http.StatusOK, "", http.Header{}, now, now, now, now))
+	sizeBefore := c.Size()
+
+	if !c.Remove("foo") {
+		t.Errorf("Remove existing key expected true, actual false")
+	}
+	if c.Remove("foo") {
+		t.Errorf("Remove removed key expected false, actual true")
+	}
+	if _, ok := c.Peek("foo"); ok {
+		t.Errorf("Peek removed key expected not found, actual found")
+	}
+	if c.Size() >= sizeBefore {
+		t.Errorf("Remove expected size less than %v, actual %v", sizeBefore, c.Size())
+	}
+	if keys := c.Keys(); len(keys) != 1 || keys[0] != "foobar" {
+		t.Errorf("Keys after Remove expected [foobar], actual %v", keys)
+	}
+
+	// the removed object's chunks must be deleted, so a new object with the key doesn't get them
+	c.Add("foo", cacheobj.NewChunked(http.Header{}, cacheobj.NewCompleteChunkedBody(3, [][]byte{[]byte("e")}), http.StatusOK, http.StatusOK, "", http.Header{}, now, now, now, now))
+	if _, ok := c.Peek("foo"); !ok {
+		t.Fatalf("Peek re-added key expected found, actual not found")
+	}
+	c.Remove("foo")
+	c.Add("foo", cacheobj.New(http.Header{}, []byte("fgh"), http.StatusOK, http.StatusOK, "", http.Header{}, now, now, now, now))
+	obj, ok := c.Peek("foo")
+	if !ok {
+		t.Fatalf("Peek re-added key expected found, actual not found")
+	}
+	if obj.Chunked != nil {
+		t.Errorf("Peek re-added key without chunks expected nil chunked body, actual chunks")
+	}
+	if string(obj.Body) != "fgh" {
+		t.Errorf("Peek re-added key body expected 'fgh', actual '%v'", string(obj.Body))
+	}
+}
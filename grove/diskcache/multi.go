@@ -66,6 +66,12 @@ func (c *MultiDiskCache) Peek(key string) (*cacheobj.CacheObj, bool) {
 	return (*c)[i].Peek(key)
 }
 
+func (c *MultiDiskCache) Remove(key string) bool {
+	i := c.keyIdx(key)
+	log.Debugf("MultiDiskCache.Remove key '%+v' mapped to %+v\n", key, i)
+	return (*c)[i].Remove(key)
+}
+
 func (c *MultiDiskCache) Size() uint64 {
 	sum := uint64(0)
 	for _, cache := range *c {
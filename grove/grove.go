@@ -42,6 +42,7 @@ import (
 	"github.com/apache/trafficcontrol/grove/icache"
 	"github.com/apache/trafficcontrol/grove/memcache"
 	"github.com/apache/trafficcontrol/grove/plugin"
+	"github.com/apache/trafficcontrol/grove/purge"
 	"github.com/apache/trafficcontrol/grove/remap"
 	"github.com/apache/trafficcontrol/grove/remapdata"
 	"github.com/apache/trafficcontrol/grove/stat"
@@ -133,6 +134,8 @@ func main() {
 	// TODO pass total size for all file groups?
 	stats := stat.New(remapper.Rules(), caches, uint64(cfg.CacheSizeBytes), httpConns, httpsConns, Version)
 
+	invalidations := purge.NewInvalidations() // not recreated on reload, so invalidations persist
+
 	buildHandler := func(scheme string, port string, conns *web.ConnMap, stats stat.Stats, pluginContext map[string]*interface{}) *cache.HandlerPointer {
 		return cache.NewHandlerPointer(cache.NewHandler(
 			remapper,
@@ -148,6 +151,9 @@ func main() {
 			httpConns,
 			httpsConns,
 			cfg.InterfaceName,
+			cfg.StreamChunkBytes,
+			time.Duration(cfg.CollapseTimeoutMS)*time.Millisecond,
+			invalidations,
 		))
 	}
 
@@ -235,6 +241,9 @@ func main() {
 			httpConns,
 			httpsConns,
 			cfg.InterfaceName,
+			cfg.StreamChunkBytes,
+			time.Duration(cfg.CollapseTimeoutMS)*time.Millisecond,
+			invalidations,
 		)
 		httpHandler.Set(httpCacheHandler)
 
@@ -252,6 +261,9 @@ func main() {
 			httpConns,
 			httpsConns,
 			cfg.InterfaceName,
+			cfg.StreamChunkBytes,
+			time.Duration(cfg.CollapseTimeoutMS)*time.Millisecond,
+			invalidations,
 		)
 		httpsHandler.Set(httpsCacheHandler)
 
@@ -26,6 +26,7 @@ type Cache interface {
 	Get(key string) (*cacheobj.CacheObj, bool)
 	Peek(key string) (*cacheobj.CacheObj, bool)
 	Keys() []string
+	Remove(key string) bool
 	Size() uint64
 	Close()
 }
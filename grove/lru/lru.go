@@ -74,3 +74,16 @@ func (c *LRU) Keys() []string {
 	}
 	return arr
 }
+
+// Remove removes the key from the LRU. Returns the size of the removed key, and true if it existed; else false.
+func (c *LRU) Remove(key string) (uint64, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	elem, ok := c.lElems[key]
+	if !ok {
+		return 0, false
+	}
+	c.l.Remove(elem)
+	delete(c.lElems, key)
+	return elem.Value.(*listObj).size, true
+}
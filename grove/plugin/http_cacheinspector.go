@@ -122,6 +122,8 @@ func cacheinspect(icfg interface{}, d OnRequestData) bool {
 			w.Write([]byte(fmt.Sprintf("  RespRespTime:                 %v\n", cacheObject.RespRespTime)))
 			w.Write([]byte(fmt.Sprintf("  LastModified:                 %v\n", cacheObject.LastModified)))
 			w.Write([]byte(fmt.Sprintf("  HitCount:                     %v\n", cacheObject.HitCount)))
+			w.Write([]byte(fmt.Sprintf("  Vary:                         %s\n", strings.Join(cacheObject.Vary, ","))))
+			w.Write([]byte(fmt.Sprintf("  VaryIndex:                    %v\n", cacheObject.VaryIndex)))
 		} else {
 			w.Write([]byte("Not Found"))
 		}
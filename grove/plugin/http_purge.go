@@ -0,0 +1,200 @@
+package plugin
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/grove/purge"
+	"github.com/apache/trafficcontrol/grove/web"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+)
+
+func init() {
+	AddPlugin(10000, Funcs{load: purgeLoad, onRequest: purgeRequest})
+}
+
+const PurgeEndpoint = "/_purge"
+
+// DefaultPurgeInvalidationTTL is how long a soft invalidation lasts, if the request doesn't give a TTL. Objects cached longer than this after an invalidation will be served without being revalidated.
+const DefaultPurgeInvalidationTTL = 24 * time.Hour
+
+// purgeMaxReqBytes is the maximum size of a purge request body.
+const purgeMaxReqBytes = 1024 * 1024
+
+type purgeConfig struct {
+	// Tokens are the bearer tokens which authorize purge requests. If there are none, all purge requests are refused.
+	Tokens []string `json:"tokens"`
+}
+
+// PurgeRequest is the body of a purge request. Objects whose cache key URL is any of the URLs, starts with any of the prefixes, or matches any of the regexes are purged.
+type PurgeRequest struct {
+	URLs     []string `json:"urls"`
+	Prefixes []string `json:"prefixes"`
+	Regexes  []string `json:"regexes"`
+	// Soft is whether to invalidate matching objects, so they're revalidated with the parent before they're served, rather than removing them.
+	Soft bool `json:"soft"`
+	// TTLSeconds is how long a soft invalidation lasts. If 0, DefaultPurgeInvalidationTTL is used.
+	TTLSeconds int `json:"ttl_seconds"`
+}
+
+// PurgeResponse is the body of a successful purge response.
+type PurgeResponse struct {
+	// Removed is the number of cached objects removed by a purge, including Vary variants. It's always 0 for a soft purge.
+	Removed int `json:"removed"`
+	// Invalidations is the number of soft invalidations in effect, after a soft purge. It's always 0 for a purge which isn't soft.
+	Invalidations int `json:"invalidations"`
+}
+
+func purgeLoad(b json.RawMessage) interface{} {
+	cfg := purgeConfig{}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		log.Errorln("http_purge loading config, unmarshalling JSON: " + err.Error())
+		return nil
+	}
+	log.Debugf("http_purge: load success: %v tokens\n", len(cfg.Tokens))
+	return &cfg
+}
+
+func purgeRequest(icfg interface{}, d OnRequestData) bool {
+	if !strings.HasPrefix(d.R.URL.Path, PurgeEndpoint) {
+		return false
+	}
+	reqTime := time.Now()
+
+	log.Debugf("plugin onrequest http_purge calling\n")
+
+	w := d.W
+	req := d.R
+
+	respCode, respBody := servePurge(icfg, d)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(respCode)
+	w.Write(respBody)
+
+	clientIP, _ := web.GetClientIPPort(req)
+
+	now := time.Now()
+	// log, so we know who purged what, and when. Purges make subsequent requests go to the parent, so they could become an accidental DDOS.
+	log.EventRaw(atsEventLogStr(now, clientIP, d.Hostname, req.Host, d.Port, "-", d.Scheme, req.URL.String(), req.Method, req.Proto, respCode, now.Sub(reqTime), uint64(len(respBody)), 0, 0, true, true, getCacheHitStr(true, false), "-", "-", req.UserAgent(), req.Header.Get("X-Money-Trace"), d.RequestID))
+
+	return true
+}
+
+// servePurge authorizes and executes the purge request, and returns the response code and body.
+func servePurge(icfg interface{}, d OnRequestData) (int, []byte) {
+	req := d.R
+
+	ip, err := web.GetIP(req)
+	if err != nil {
+		log.Errorln("http_purge failed to get IP: " + err.Error())
+		return purgeErrResp(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	if !d.StatRules.Allowed(ip) {
+		log.Debugln("http_purge IP " + ip.String() + " FORBIDDEN")
+		return purgeErrResp(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+	}
+
+	cfg, ok := icfg.(*purgeConfig)
+	if !ok || cfg == nil || len(cfg.Tokens) == 0 {
+		log.Errorln("http_purge request from " + ip.String() + " refused: no tokens are configured")
+		return purgeErrResp(http.StatusForbidden, "purging is not configured")
+	}
+	if !purgeAuthorized(cfg.Tokens, req.Header.Get("Authorization")) {
+		log.Errorln("http_purge request from " + ip.String() + " UNAUTHORIZED")
+		return purgeErrResp(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+	}
+
+	if req.Method != http.MethodPost {
+		return purgeErrResp(http.StatusMethodNotAllowed, "purge requests must be POST")
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, purgeMaxReqBytes+1))
+	if err != nil {
+		return purgeErrResp(http.StatusBadRequest, "reading request body: "+err.Error())
+	}
+	if len(body) > purgeMaxReqBytes {
+		return purgeErrResp(http.StatusRequestEntityTooLarge, http.StatusText(http.StatusRequestEntityTooLarge))
+	}
+	purgeReq := PurgeRequest{}
+	if err := json.Unmarshal(body, &purgeReq); err != nil {
+		return purgeErrResp(http.StatusBadRequest, "malformed JSON: "+err.Error())
+	}
+	if purgeReq.TTLSeconds < 0 {
+		return purgeErrResp(http.StatusBadRequest, "ttl_seconds must not be negative")
+	}
+	matcher, err := purge.NewMatcher(purgeReq.URLs, purgeReq.Prefixes, purgeReq.Regexes)
+	if err != nil {
+		return purgeErrResp(http.StatusBadRequest, err.Error())
+	}
+	if matcher.Empty() {
+		return purgeErrResp(http.StatusBadRequest, "at least one of urls, prefixes, or regexes is required")
+	}
+
+	resp := PurgeResponse{}
+	if purgeReq.Soft {
+		ttl := time.Duration(purgeReq.TTLSeconds) * time.Second
+		if ttl == 0 {
+			ttl = DefaultPurgeInvalidationTTL
+		}
+		d.Invalidations.Add(matcher, time.Now(), ttl)
+		resp.Invalidations = d.Invalidations.Len()
+		log.Infof("http_purge %v soft invalidated urls %v prefixes %v regexes %v for %v\n", ip, purgeReq.URLs, purgeReq.Prefixes, purgeReq.Regexes, ttl)
+	} else {
+		for _, cacheName := range d.Stats.CacheNames() {
+			for _, key := range d.Stats.CacheKeys(cacheName) {
+				if matcher.Match(key) && d.Stats.CacheRemove(key, cacheName) {
+					resp.Removed++
+				}
+			}
+		}
+		log.Infof("http_purge %v purged urls %v prefixes %v regexes %v: removed %v objects\n", ip, purgeReq.URLs, purgeReq.Prefixes, purgeReq.Regexes, resp.Removed)
+	}
+
+	respBody, err := json.Marshal(resp)
+	if err != nil {
+		log.Errorln("http_purge marshalling response: " + err.Error())
+		return purgeErrResp(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	return http.StatusOK, respBody
+}
+
+// purgeAuthorized returns whether the given Authorization header is a bearer token in tokens. Tokens are compared in constant time, so they can't be guessed by timing.
+func purgeAuthorized(tokens []string, authHdr string) bool {
+	const bearerPrefix = "Bearer "
+	if len(authHdr) < len(bearerPrefix) || !strings.EqualFold(authHdr[:len(bearerPrefix)], bearerPrefix) {
+		return false
+	}
+	token := []byte(strings.TrimSpace(authHdr[len(bearerPrefix):]))
+	authorized := false
+	for _, t := range tokens {
+		if t != "" && subtle.ConstantTimeCompare([]byte(t), token) == 1 {
+			authorized = true
+		}
+	}
+	return authorized
+}
+
+func purgeErrResp(code int, msg string) (int, []byte) {
+	b, _ := json.Marshal(map[string]string{"error": msg})
+	return code, b
+}
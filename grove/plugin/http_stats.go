@@ -124,6 +124,9 @@ func LoadRemapStats(stats stat.Stats, httpConns *web.ConnMap, httpsConns *web.Co
 	jsonStats["proxy.process.http.current_client_connections"] = httpConns.Len() + httpsConns.Len()
 	jsonStats["proxy.process.http.cache_hits"] = stats.CacheHits()
 	jsonStats["proxy.process.http.cache_misses"] = stats.CacheMisses()
+	jsonStats["proxy.process.http.collapsed_requests"] = stats.CollapsedRequests()
+	jsonStats["proxy.process.http.collapse_timeouts"] = stats.CollapseTimeouts()
+	jsonStats["proxy.process.http.collapse_unusable"] = stats.CollapseUnusable()
 	jsonStats["proxy.process.http.cache_capacity_bytes"] = stats.CacheCapacity()
 	jsonStats["proxy.process.http.cache_size_bytes"] = stats.CacheSize()
 
@@ -36,4 +36,7 @@ func ifModifiedSince(icfg interface{}, d BeforeRespondData) {
 		return
 	}
 	*d.Code, *d.Hdr, *d.Body = http.StatusNotModified, nil, nil
+	if d.BodyChunks != nil {
+		*d.BodyChunks = nil
+	}
 }
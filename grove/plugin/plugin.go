@@ -28,6 +28,7 @@ import (
 	"github.com/apache/trafficcontrol/grove/cachedata"
 	"github.com/apache/trafficcontrol/grove/cacheobj"
 	"github.com/apache/trafficcontrol/grove/config"
+	"github.com/apache/trafficcontrol/grove/purge"
 	"github.com/apache/trafficcontrol/grove/remapdata"
 	"github.com/apache/trafficcontrol/grove/stat"
 	"github.com/apache/trafficcontrol/grove/web"
@@ -70,6 +71,8 @@ type OnRequestData struct {
 	HTTPSConns    *web.ConnMap
 	RequestID     uint64
 	Context       *interface{}
+	// Invalidations are the soft invalidations of cached objects, which force them to be revalidated.
+	Invalidations *purge.Invalidations
 	cachedata.SrvrData
 }
 
@@ -84,12 +87,14 @@ type BeforeParentRequestData struct {
 type BeforeRespondData struct {
 	Req *http.Request
 	// CacheObj is the object to be cached, containing information about the origin request. The code, headers, and body should not be considered authoritative. Look at Code, Hdr, and Body instead, as the actual values about to be sent. Note CacheObj may be nil, if an error occurred (e.g. the Origin failed to respond).
-	CacheObj  *cacheobj.CacheObj
-	Code      *int
-	Hdr       *http.Header
-	Body      *[]byte
-	RemapRule string
-	Context   *interface{}
+	CacheObj *cacheobj.CacheObj
+	Code     *int
+	Hdr      *http.Header
+	Body     *[]byte
+	// BodyChunks is the body streamed in chunks, if the parent response was streamed. If it's non-nil, it's sent instead of Body. Plugins which modify the body must get its Bytes, set them as the Body, and set BodyChunks to nil.
+	BodyChunks **cacheobj.ChunkedBody
+	RemapRule  string
+	Context    *interface{}
 }
 
 type BeforeCacheLookUpData struct {
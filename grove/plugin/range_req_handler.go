@@ -151,6 +151,15 @@ func rangeReqHandleBeforeRespond(icfg interface{}, d BeforeRespondData) {
 	}
 
 	// mode != store_ranges
+	if d.BodyChunks != nil && *d.BodyChunks != nil {
+		// ranges are built from the whole body, so a streamed body must be read in full
+		bodyBytes, err := (*d.BodyChunks).Bytes()
+		if err != nil {
+			log.Errorf("range_req_handler reading streamed body: %v\n", err)
+			return
+		}
+		*d.Body, *d.BodyChunks = bodyBytes, nil
+	}
 	multipartBoundaryString := cfg.MultiPartBoundary
 	multipart := false
 	originalContentType := d.Hdr.Get("Content-type")
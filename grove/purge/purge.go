@@ -0,0 +1,142 @@
+package purge
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/trafficcontrol/grove/cacheobj"
+)
+
+// Matcher matches cache keys by their URL, by exact URL, URL prefix, or URL regular expression.
+//
+// The URL of a cache key is the remapped URL requested from the parent, with the query string if the rule caches it, without the method or Vary variant.
+type Matcher struct {
+	urls     map[string]struct{}
+	prefixes []string
+	regexes  []*regexp.Regexp
+}
+
+// NewMatcher creates a Matcher which matches any of the given URLs, URL prefixes, and URL regular expressions. Returns an error if a regular expression is invalid.
+func NewMatcher(urls []string, prefixes []string, regexes []string) (Matcher, error) {
+	m := Matcher{urls: map[string]struct{}{}, prefixes: prefixes}
+	for _, url := range urls {
+		m.urls[url] = struct{}{}
+	}
+	for _, reStr := range regexes {
+		re, err := regexp.Compile(reStr)
+		if err != nil {
+			return Matcher{}, errors.New("compiling regex '" + reStr + "': " + err.Error())
+		}
+		m.regexes = append(m.regexes, re)
+	}
+	return m, nil
+}
+
+// Empty returns whether the Matcher has no URLs, prefixes, or regexes, and thus matches nothing.
+func (m Matcher) Empty() bool {
+	return len(m.urls) == 0 && len(m.prefixes) == 0 && len(m.regexes) == 0
+}
+
+// Match returns whether the given cache key, which may be the key of a Vary variant, matches.
+func (m Matcher) Match(key string) bool {
+	url := KeyURL(key)
+	if _, ok := m.urls[url]; ok {
+		return true
+	}
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(url, prefix) {
+			return true
+		}
+	}
+	for _, re := range m.regexes {
+		if re.MatchString(url) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyURL returns the URL of the given cache key, as created by remapdata.RemapRule.CacheKey or cacheobj.VariantKey.
+func KeyURL(key string) string {
+	key = cacheobj.BaseKey(key)
+	if i := strings.Index(key, ":"); i >= 0 {
+		return key[i+1:]
+	}
+	return key
+}
+
+// Invalidations are soft invalidations, like ATS regex_revalidate: cached objects they match, which were received before the invalidation, must be revalidated with the parent before they're served. Each invalidation lasts until it expires, which should be longer than objects are cached.
+// Invalidations is safe for multiple goroutines.
+type Invalidations struct {
+	invalidations []invalidation
+	m             sync.RWMutex
+}
+
+type invalidation struct {
+	matcher Matcher
+	time    time.Time
+	expires time.Time
+}
+
+// NewInvalidations creates a new, empty Invalidations.
+func NewInvalidations() *Invalidations {
+	return &Invalidations{}
+}
+
+// Add invalidates objects matching the given Matcher which were received before the given time, until the given TTL after it. Expired invalidations are removed.
+func (iv *Invalidations) Add(m Matcher, t time.Time, ttl time.Duration) {
+	iv.m.Lock()
+	defer iv.m.Unlock()
+	now := time.Now()
+	live := make([]invalidation, 0, len(iv.invalidations)+1)
+	for _, inv := range iv.invalidations {
+		if inv.expires.After(now) {
+			live = append(live, inv)
+		}
+	}
+	iv.invalidations = append(live, invalidation{matcher: m, time: t, expires: t.Add(ttl)})
+}
+
+// Invalidated returns whether the object with the given cache key, received at the given time, is invalidated and must be revalidated.
+func (iv *Invalidations) Invalidated(key string, received time.Time) bool {
+	iv.m.RLock()
+	defer iv.m.RUnlock()
+	now := time.Now()
+	for _, inv := range iv.invalidations {
+		if received.Before(inv.time) && inv.expires.After(now) && inv.matcher.Match(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of invalidations which haven't expired.
+func (iv *Invalidations) Len() int {
+	iv.m.RLock()
+	defer iv.m.RUnlock()
+	now := time.Now()
+	n := 0
+	for _, inv := range iv.invalidations {
+		if inv.expires.After(now) {
+			n++
+		}
+	}
+	return n
+}
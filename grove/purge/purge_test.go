@@ -0,0 +1,89 @@
+package purge
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/grove/cacheobj"
+)
+
+func TestMatcher(t *testing.T) {
+	m, err := NewMatcher([]string{"http://example.net/foo.jpg"}, []string{"http://example.net/bar/"}, []string{`^http://example\.net/.*\.mp4$`})
+	if err != nil {
+		t.Fatalf("NewMatcher expected nil error, actual: %v", err)
+	}
+
+	variantKey := cacheobj.VariantKey("GET:http://example.net/foo.jpg", []string{"Accept-Encoding"}, http.Header{"Accept-Encoding": {"gzip"}})
+	for key, expected := range map[string]bool{
+		"GET:http://example.net/foo.jpg":       true,
+		variantKey:                             true,
+		"GET:http://example.net/foo.jpg?a=b":   false,
+		"GET:http://example.net/bar/":          true,
+		"GET:http://example.net/bar/baz.jpg":   true,
+		"GET:http://example.net/barbaz.jpg":    false,
+		"GET:http://example.net/a/b.mp4":       true,
+		"GET:http://example.net/a/b.mp4?c=d":   false,
+		"GET:http://other.example.net/foo.jpg": false,
+	} {
+		if actual := m.Match(key); actual != expected {
+			t.Errorf("Match('%v') expected %v, actual %v", key, expected, actual)
+		}
+	}
+
+	if _, err := NewMatcher(nil, nil, []string{"("}); err == nil {
+		t.Errorf("NewMatcher invalid regex expected error, actual nil")
+	}
+	if m, err := NewMatcher(nil, nil, nil); err != nil || !m.Empty() {
+		t.Errorf("NewMatcher no URLs expected empty, actual err %v empty %v", err, m.Empty())
+	}
+}
+
+func TestInvalidations(t *testing.T) {
+	iv := NewInvalidations()
+	m, err := NewMatcher(nil, []string{"http://example.net/"}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher expected nil error, actual: %v", err)
+	}
+
+	now := time.Now()
+	before := now.Add(-time.Minute)
+	after := now.Add(time.Minute)
+
+	if iv.Invalidated("GET:http://example.net/foo", before) {
+		t.Errorf("Invalidated with no invalidations expected false, actual true")
+	}
+
+	iv.Add(m, now, time.Hour)
+	if !iv.Invalidated("GET:http://example.net/foo", before) {
+		t.Errorf("Invalidated matching key received before expected true, actual false")
+	}
+	if iv.Invalidated("GET:http://example.net/foo", after) {
+		t.Errorf("Invalidated matching key received after expected false, actual true")
+	}
+	if iv.Invalidated("GET:http://other.example.net/foo", before) {
+		t.Errorf("Invalidated key not matching expected false, actual true")
+	}
+
+	iv.Add(m, now.Add(-2*time.Hour), time.Hour)
+	if iv.Len() != 1 {
+		t.Errorf("Len with one expired invalidation expected 1, actual %v", iv.Len())
+	}
+	if !iv.Invalidated("GET:http://example.net/foo", now.Add(-3*time.Hour)) {
+		t.Errorf("Invalidated matching key received before both expected true, actual false")
+	}
+}
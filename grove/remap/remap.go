@@ -130,6 +130,27 @@ func (p *RemappingProducer) FirstFQDN() string {
 	// TODO verify To is not allowed to be constructed with < 1 element
 	return strings.TrimPrefix(strings.TrimPrefix(p.rule.To[0].URL, "http://"), "https://")
 }
+
+// CollapseTimeout returns the rule's collapse timeout, or 0 if the rule doesn't have one.
+func (p *RemappingProducer) CollapseTimeout() time.Duration {
+	return time.Duration(p.rule.CollapseTimeoutMS) * time.Millisecond
+}
+
+// StaleWhileRevalidate returns the rule's stale-while-revalidate window, and whether it overrides that of responses.
+func (p *RemappingProducer) StaleWhileRevalidate() (time.Duration, bool) {
+	if p.rule.StaleWhileRevalidateMS == nil {
+		return 0, false
+	}
+	return time.Duration(*p.rule.StaleWhileRevalidateMS) * time.Millisecond, true
+}
+
+// StaleIfError returns the rule's stale-if-error window, and whether it overrides that of requests and responses.
+func (p *RemappingProducer) StaleIfError() (time.Duration, bool) {
+	if p.rule.StaleIfErrorMS == nil {
+		return 0, false
+	}
+	return time.Duration(*p.rule.StaleIfErrorMS) * time.Millisecond, true
+}
 func (p *RemappingProducer) ProxyStr() string {
 	if p.rule.To[0].ProxyURL != nil && p.rule.To[0].ProxyURL.Host != "" {
 		return p.rule.To[0].ProxyURL.Host
@@ -418,6 +439,16 @@ func LoadRemapRules(path string, pluginConfigLoaders map[string]plugin.LoadFunc,
 			rule.RetryNum = remapRules.RetryNum
 		}
 
+		if rule.CollapseTimeoutMS < 0 {
+			return nil, nil, nil, fmt.Errorf("error parsing rule %v collapse timeout must be positive: %v", rule.Name, rule.CollapseTimeoutMS)
+		}
+		if rule.StaleWhileRevalidateMS != nil && *rule.StaleWhileRevalidateMS < 0 {
+			return nil, nil, nil, fmt.Errorf("error parsing rule %v stale-while-revalidate must be positive: %v", rule.Name, *rule.StaleWhileRevalidateMS)
+		}
+		if rule.StaleIfErrorMS != nil && *rule.StaleIfErrorMS < 0 {
+			return nil, nil, nil, fmt.Errorf("error parsing rule %v stale-if-error must be positive: %v", rule.Name, *rule.StaleIfErrorMS)
+		}
+
 		if rule.PluginsShared == nil {
 			rule.PluginsShared = remapRules.PluginsShared
 		}
@@ -98,6 +98,12 @@ type RemapRuleBase struct {
 	RetryNum               *int                       `json:"retry_num"`
 	DSCP                   int                        `json:"dscp"`
 	PluginsShared          map[string]json.RawMessage `json:"plugins_shared"`
+	// CollapseTimeoutMS is the maximum time in milliseconds a request waits for a concurrent parent request for the same cache key, before making its own. If this is 0, the global config is used.
+	CollapseTimeoutMS int `json:"collapse_timeout_ms"`
+	// StaleWhileRevalidateMS overrides the RFC5861 stale-while-revalidate window of responses, in milliseconds. If nil, the window in the response Cache-Control is used.
+	StaleWhileRevalidateMS *int `json:"stale_while_revalidate_ms"`
+	// StaleIfErrorMS overrides the RFC5861 stale-if-error window of responses, in milliseconds. If nil, the window in the request and response Cache-Control is used.
+	StaleIfErrorMS *int `json:"stale_if_error_ms"`
 }
 
 type RemapRule struct {
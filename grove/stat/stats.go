@@ -54,6 +54,16 @@ type Stats interface {
 	CacheMisses() uint64
 	AddCacheMiss()
 
+	// CollapsedRequests is the number of requests which were served the object of a concurrent parent request for the same cache key, rather than making their own.
+	CollapsedRequests() uint64
+	AddCollapsedRequest()
+	// CollapseTimeouts is the number of requests which waited for a concurrent parent request for the same cache key until the collapse timeout, and then made their own.
+	CollapseTimeouts() uint64
+	AddCollapseTimeout()
+	// CollapseUnusable is the number of requests which waited for a concurrent parent request for the same cache key, but couldn't use its object, and made their own.
+	CollapseUnusable() uint64
+	AddCollapseUnusable()
+
 	CacheSize() uint64
 	CacheCapacity() uint64
 
@@ -65,16 +75,23 @@ type Stats interface {
 	CacheCapacityByName(string) (uint64, bool)
 	CacheNames() []string
 	CachePeek(string, string) (*cacheobj.CacheObj, bool)
+	CacheRemove(string, string) bool
 }
 
 func New(remapRules []remapdata.RemapRule, caches map[string]icache.Cache, cacheCapacityBytes uint64, httpConns *web.ConnMap, httpsConns *web.ConnMap, version string) Stats {
 	cacheHits := uint64(0)
 	cacheMisses := uint64(0)
+	collapsedRequests := uint64(0)
+	collapseTimeouts := uint64(0)
+	collapseUnusable := uint64(0)
 	return &stats{
 		system:             NewStatsSystem(version),
 		remap:              NewStatsRemaps(remapRules),
 		cacheHits:          &cacheHits,
 		cacheMisses:        &cacheMisses,
+		collapsedRequests:  &collapsedRequests,
+		collapseTimeouts:   &collapseTimeouts,
+		collapseUnusable:   &collapseUnusable,
 		caches:             caches,
 		cacheCapacityBytes: cacheCapacityBytes,
 		httpConns:          httpConns,
@@ -138,6 +155,9 @@ type stats struct {
 	remap              StatsRemaps
 	cacheHits          *uint64
 	cacheMisses        *uint64
+	collapsedRequests  *uint64
+	collapseTimeouts   *uint64
+	collapseUnusable   *uint64
 	caches             map[string]icache.Cache
 	cacheCapacityBytes uint64
 	httpConns          *web.ConnMap
@@ -161,6 +181,13 @@ func (s stats) AddCacheMiss()        { atomic.AddUint64(s.cacheMisses, 1) }
 func (s *stats) System() StatsSystem { return StatsSystem(s.system) }
 func (s *stats) Remap() StatsRemaps  { return s.remap }
 
+func (s stats) CollapsedRequests() uint64 { return atomic.LoadUint64(s.collapsedRequests) }
+func (s stats) AddCollapsedRequest()      { atomic.AddUint64(s.collapsedRequests, 1) }
+func (s stats) CollapseTimeouts() uint64  { return atomic.LoadUint64(s.collapseTimeouts) }
+func (s stats) AddCollapseTimeout()       { atomic.AddUint64(s.collapseTimeouts, 1) }
+func (s stats) CollapseUnusable() uint64  { return atomic.LoadUint64(s.collapseUnusable) }
+func (s stats) AddCollapseUnusable()      { atomic.AddUint64(s.collapseUnusable, 1) }
+
 // CacheSizeByName returns the size of tha cache for a particular cache
 func (s stats) CacheSizeByName(cName string) (uint64, bool) {
 	if cache, ok := s.caches[cName]; ok {
@@ -197,6 +224,11 @@ func (s stats) CachePeek(key, cacheName string) (*cacheobj.CacheObj, bool) {
 	return s.caches[cacheName].Peek(key)
 }
 
+// CacheRemove removes the key from the cache cacheName. Returns whether the key existed.
+func (s stats) CacheRemove(key, cacheName string) bool {
+	return s.caches[cacheName].Remove(key)
+}
+
 func (s stats) CacheCapacityByName(cName string) (uint64, bool) {
 	if cache, ok := s.caches[cName]; ok {
 		return cache.Capacity(), true
@@ -16,14 +16,30 @@ package thread
 
 import (
 	"sync"
+	"time"
 
 	cacheobj "github.com/apache/trafficcontrol/grove/cacheobj"
 )
 
 type Getter interface {
-	Get(key string, actualGet func() *cacheobj.CacheObj, canUse func(*cacheobj.CacheObj) bool, reqID uint64) (*cacheobj.CacheObj, uint64)
+	// Get returns the object for the key, from actualGet, or from a concurrent request for the same key. If timeout is positive, requests waiting for a concurrent request wait at most that long before making their own. Returns the object, the ID of the request which got it, and how the request was collapsed.
+	Get(key string, actualGet func() *cacheobj.CacheObj, canUse func(*cacheobj.CacheObj) bool, timeout time.Duration, reqID uint64) (*cacheobj.CacheObj, uint64, Collapse)
 }
 
+// Collapse is how a Getter request was collapsed into a concurrent request for the same key.
+type Collapse int
+
+const (
+	// CollapseNone indicates the request wasn't collapsed, because no other request for the key was in progress.
+	CollapseNone Collapse = iota
+	// CollapseWaited indicates the request waited for a concurrent request, and used its object.
+	CollapseWaited
+	// CollapseTimedOut indicates the request waited for a concurrent request until the timeout, and then made its own.
+	CollapseTimedOut
+	// CollapseUnusable indicates the request waited for a concurrent request, but couldn't use its object, and made its own.
+	CollapseUnusable
+)
+
 type GetterResp struct {
 	CacheObj *cacheobj.CacheObj
 	GetReqID uint64
@@ -40,7 +56,7 @@ func NewGetter() Getter {
 // Then, when other requests come in, they see that waiters[key] exists, and add themselves to it, and block reading from their chan.
 // Then, when the Author gets its response, it iterates over the Waiters and sends the response to all of them, at the same time (with the same lock, atomically) clearing the waiters for the next request that comes in.
 //
-// If the Author response can't be used, all Waiters make their own requests.
+// If the Author response can't be used, all Waiters make their own requests. Likewise, if the Author takes longer than the timeout, each Waiter stops waiting and makes its own request, so a slow or hung parent request doesn't hold up every requestor.
 // Note this assumes an uncacheable response for one request is likely uncacheable for all, and it's faster and less load on the origin if so.
 // If it's likely the author request is uncacheable, but a different waiter is cacheable for all other waiters, this will be more network, more origin load, and more work. If that's the case for you, consider creating another type that fulfills the Getter interface, and making the Getter configurable.
 type getter struct {
@@ -49,9 +65,9 @@ type getter struct {
 	waitersM sync.Mutex
 }
 
-func (g *getter) Get(key string, actualGet func() *cacheobj.CacheObj, canUse func(*cacheobj.CacheObj) bool, reqID uint64) (*cacheobj.CacheObj, uint64) {
+func (g *getter) Get(key string, actualGet func() *cacheobj.CacheObj, canUse func(*cacheobj.CacheObj) bool, timeout time.Duration, reqID uint64) (*cacheobj.CacheObj, uint64, Collapse) {
 	isAuthor := false
-	// Buffered for performance, so the author can iterate over all wait chans without blocking.
+	// Buffered for performance, so the author can iterate over all wait chans without blocking, including those of waiters which timed out.
 	// Note this is unused if isAuthor becomes true.
 	getChan := make(chan GetterResp, 1)
 
@@ -75,13 +91,24 @@ func (g *getter) Get(key string, actualGet func() *cacheobj.CacheObj, canUse fun
 		delete(g.waiters, key)
 		g.waitersM.Unlock()
 
-		return obj, reqID
+		return obj, reqID, CollapseNone
 	}
 
-	if waitResp := <-getChan; canUse(waitResp.CacheObj) {
-		return waitResp.CacheObj, waitResp.GetReqID
+	timeoutChan := (<-chan time.Time)(nil) // a nil chan never receives, so waiters with no timeout wait for the author
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutChan = timer.C
 	}
 
-	// if the Author response can't be used, all Waiters make their own requests
-	return actualGet(), reqID
+	select {
+	case waitResp := <-getChan:
+		if canUse(waitResp.CacheObj) {
+			return waitResp.CacheObj, waitResp.GetReqID, CollapseWaited
+		}
+		// if the Author response can't be used, all Waiters make their own requests
+		return actualGet(), reqID, CollapseUnusable
+	case <-timeoutChan:
+		return actualGet(), reqID, CollapseTimedOut
+	}
 }
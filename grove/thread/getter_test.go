@@ -0,0 +1,134 @@
+package thread
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/grove/cacheobj"
+)
+
+func TestGetterCollapse(t *testing.T) {
+	g := NewGetter()
+	release := make(chan struct{})
+	gets := uint64(0)
+	actualGet := func() *cacheobj.CacheObj {
+		if atomic.AddUint64(&gets, 1) == 1 {
+			<-release // the author blocks until all waiters are waiting
+		}
+		return &cacheobj.CacheObj{Code: 200}
+	}
+	canUse := func(*cacheobj.CacheObj) bool { return true }
+
+	authorDone := make(chan Collapse)
+	go func() {
+		_, _, collapse := g.Get("foo", actualGet, canUse, 0, 1)
+		authorDone <- collapse
+	}()
+	for atomic.LoadUint64(&gets) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	const numWaiters = 10
+	wg := sync.WaitGroup{}
+	collapses := make(chan Collapse, numWaiters)
+	for i := 0; i < numWaiters; i++ {
+		wg.Add(1)
+		go func(reqID uint64) {
+			defer wg.Done()
+			obj, getReqID, collapse := g.Get("foo", actualGet, canUse, 0, reqID)
+			if obj == nil || getReqID != 1 {
+				t.Errorf("waiter expected author object from request 1, actual %+v from %v", obj, getReqID)
+			}
+			collapses <- collapse
+		}(uint64(i + 2))
+	}
+	waitForWaiters(g.(*getter), "foo", numWaiters)
+	close(release)
+	wg.Wait()
+	close(collapses)
+
+	if collapse := <-authorDone; collapse != CollapseNone {
+		t.Errorf("author expected CollapseNone, actual %v", collapse)
+	}
+	for collapse := range collapses {
+		if collapse != CollapseWaited {
+			t.Errorf("waiter expected CollapseWaited, actual %v", collapse)
+		}
+	}
+	if gets != 1 {
+		t.Errorf("expected 1 actual get, actual %v", gets)
+	}
+}
+
+func TestGetterCollapseFallback(t *testing.T) {
+	g := NewGetter()
+	release := make(chan struct{})
+	defer close(release)
+	authorGet := func() *cacheobj.CacheObj {
+		<-release
+		return &cacheobj.CacheObj{Code: 200}
+	}
+	go g.Get("foo", authorGet, func(*cacheobj.CacheObj) bool { return true }, 0, 1)
+	waitForWaiters(g.(*getter), "foo", 0)
+
+	independentGet := func() *cacheobj.CacheObj { return &cacheobj.CacheObj{Code: 201} }
+	obj, getReqID, collapse := g.Get("foo", independentGet, func(*cacheobj.CacheObj) bool { return true }, 10*time.Millisecond, 2)
+	if collapse != CollapseTimedOut {
+		t.Errorf("waiter of hung author expected CollapseTimedOut, actual %v", collapse)
+	}
+	if obj.Code != 201 || getReqID != 2 {
+		t.Errorf("waiter of hung author expected its own object, actual code %v from %v", obj.Code, getReqID)
+	}
+}
+
+func TestGetterCollapseUnusable(t *testing.T) {
+	g := NewGetter()
+	release := make(chan struct{})
+	go g.Get("foo", func() *cacheobj.CacheObj {
+		<-release
+		return &cacheobj.CacheObj{Code: 500}
+	}, func(*cacheobj.CacheObj) bool { return true }, 0, 1)
+	waitForWaiters(g.(*getter), "foo", 0)
+
+	waiterDone := make(chan struct{})
+	go func() {
+		defer close(waiterDone)
+		canUse := func(obj *cacheobj.CacheObj) bool { return obj.Code != 500 }
+		obj, _, collapse := g.Get("foo", func() *cacheobj.CacheObj { return &cacheobj.CacheObj{Code: 200} }, canUse, time.Minute, 2)
+		if collapse != CollapseUnusable || obj.Code != 200 {
+			t.Errorf("waiter of unusable author expected CollapseUnusable and its own object, actual %v code %v", collapse, obj.Code)
+		}
+	}()
+	waitForWaiters(g.(*getter), "foo", 1)
+	close(release)
+	<-waiterDone
+}
+
+// waitForWaiters waits until the given number of requests are waiting for the author of the given key.
+func waitForWaiters(g *getter, key string, num int) {
+	for {
+		g.waitersM.Lock()
+		waiters, ok := g.waiters[key]
+		g.waitersM.Unlock()
+		if ok && len(waiters) == num {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -69,6 +69,13 @@ func (c *TierCache) Add(key string, val *cacheobj.CacheObj) bool {
 	return aevict || bevict
 }
 
+// Remove removes from both internal caches. Returns whether either contained the key.
+func (c *TierCache) Remove(key string) bool {
+	aok := c.first.Remove(key)
+	bok := c.second.Remove(key)
+	return aok || bok
+}
+
 // Size returns the size of the second cache. This is because, since all objects are added to both, they are presumed to have the same content, and the second is presumed to be larger.
 //
 // For example, if the first is a memory cache and the second is a disk cache, it's most useful to report the size used on disk.
@@ -81,9 +88,19 @@ func (c *TierCache) Close() {
 	c.second.Close()
 }
 
-// Keys returns the keys of the second tier only. The first is just an accelerator.
+// Keys returns the keys of both tiers. Objects are usually in both, but an object whose body is still being streamed from the parent is only in the first, because caches which store complete objects don't add it until its body is complete.
 func (c *TierCache) Keys() []string {
-	return c.second.Keys()
+	keys := c.second.Keys()
+	secondKeys := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		secondKeys[key] = struct{}{}
+	}
+	for _, key := range c.first.Keys() {
+		if _, ok := secondKeys[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
 }
 
 // Capacity returns the maximum size in bytes of the cache
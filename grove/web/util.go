@@ -16,6 +16,7 @@ package web
 
 import (
 	"errors"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -130,6 +131,45 @@ func Request(transport *http.Transport, r *http.Request) (int, http.Header, []by
 	return resp.StatusCode, resp.Header, body, reqTime, respTime, nil
 }
 
+// RequestStream makes the given request and returns its response code, headers, body, the request time, response time, and any error. Unlike Request, it returns as soon as the response headers are received, and the body must be read and closed by the caller.
+// If readTimeout is positive, a read of the body which receives nothing from the parent for readTimeout fails with ErrBodyReadTimeout, and the body is closed, so a stalled parent can't block readers forever.
+func RequestStream(transport *http.Transport, r *http.Request, readTimeout time.Duration) (int, http.Header, io.ReadCloser, time.Time, time.Time, error) {
+	log.Debugf("request streaming %v headers %v\n", r.RequestURI, r.Header)
+	reqTime := time.Now()
+	resp, err := transport.RoundTrip(r)
+	respTime := time.Now()
+	if err != nil {
+		return 0, nil, nil, reqTime, respTime, errors.New("request error: " + err.Error())
+	}
+	body := resp.Body
+	if readTimeout > 0 {
+		body = &readTimeoutBody{body: body, timeout: readTimeout}
+	}
+	return resp.StatusCode, resp.Header, body, reqTime, respTime, nil
+}
+
+// ErrBodyReadTimeout is returned by reads of a streamed response body which received nothing from the parent within the read timeout.
+var ErrBodyReadTimeout = errors.New("timed out reading response body")
+
+// readTimeoutBody is a response body whose reads fail with ErrBodyReadTimeout, closing the body, if they take longer than the timeout.
+type readTimeoutBody struct {
+	body    io.ReadCloser
+	timeout time.Duration
+}
+
+func (b *readTimeoutBody) Read(p []byte) (int, error) {
+	timer := time.AfterFunc(b.timeout, func() { b.body.Close() }) // closing the body unblocks the read
+	n, err := b.body.Read(p)
+	if !timer.Stop() {
+		return n, ErrBodyReadTimeout
+	}
+	return n, err
+}
+
+func (b *readTimeoutBody) Close() error {
+	return b.body.Close()
+}
+
 // Respond writes the given code, header, and body to the ResponseWriter. If connectionClose, a Connection: Close header is also written. Returns the bytes written, and any error.
 func Respond(w http.ResponseWriter, code int, header http.Header, body []byte, connectionClose bool) (uint64, error) {
 	// TODO move connectionClose to modhdr plugin
@@ -145,6 +185,18 @@ func Respond(w http.ResponseWriter, code int, header http.Header, body []byte, c
 	return uint64(bytesWritten), err
 }
 
+// RespondReader writes the given code and header to the ResponseWriter, and then copies the body from the given reader as it's read. If connectionClose, a Connection: Close header is also written. Returns the bytes written, and any error reading or writing the body.
+func RespondReader(w http.ResponseWriter, code int, header http.Header, body io.Reader, connectionClose bool) (uint64, error) {
+	dH := w.Header()
+	CopyHeaderTo(header, &dH)
+	if connectionClose {
+		dH.Add("Connection", "close")
+	}
+	w.WriteHeader(code)
+	bytesWritten, err := io.Copy(w, body)
+	return uint64(bytesWritten), err
+}
+
 // ServeReqErr writes the appropriate response to the client, via given writer, for a generic request error. Returns the code sent, the body bytes written, and any write error.
 func ServeReqErr(w http.ResponseWriter) (int, uint64, error) {
 	code := http.StatusBadRequest
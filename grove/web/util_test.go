@@ -0,0 +1,64 @@
+package web
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestStreamReadTimeout(t *testing.T) {
+	unstall := make(chan struct{})
+	parent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("abc"))
+		TryFlush(w)
+		<-unstall
+	}))
+	defer parent.Close()
+	defer close(unstall)
+
+	req, err := http.NewRequest(http.MethodGet, parent.URL, nil)
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+	code, _, body, _, _, err := RequestStream(&http.Transport{}, req, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("RequestStream expected no error, actual %v", err)
+	}
+	defer body.Close()
+	if code != http.StatusOK {
+		t.Errorf("RequestStream expected code %v, actual %v", http.StatusOK, code)
+	}
+
+	read := make(chan error)
+	go func() {
+		b, err := ioutil.ReadAll(body)
+		if string(b) != "abc" {
+			t.Errorf("reading stalled body expected 'abc' before the timeout, actual '%v'", string(b))
+		}
+		read <- err
+	}()
+	select {
+	case err := <-read:
+		if err != ErrBodyReadTimeout {
+			t.Errorf("reading stalled body expected error %v, actual %v", ErrBodyReadTimeout, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("reading stalled body expected to time out, actual still blocked")
+	}
+}
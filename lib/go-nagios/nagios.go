@@ -26,6 +26,7 @@ package nagios
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -40,8 +41,89 @@ const (
 	Ok       Status = 0
 	Warning  Status = 1
 	Critical Status = 2
+	Unknown  Status = 3
 )
 
+// String returns the name of the Status, as used in the first line of plugin
+// output, e.g. "CRITICAL".
+func (s Status) String() string {
+	switch s {
+	case Ok:
+		return "OK"
+	case Warning:
+		return "WARNING"
+	case Critical:
+		return "CRITICAL"
+	}
+	return "UNKNOWN"
+}
+
+// ThresholdStatus returns Critical if val is greater than crit, Warning if it
+// is greater than warn, and Ok otherwise.
+func ThresholdStatus(val float64, warn float64, crit float64) Status {
+	if val > crit {
+		return Critical
+	}
+	if val > warn {
+		return Warning
+	}
+	return Ok
+}
+
+// Perfdata is a single performance data value, which Nagios and Icinga graph
+// and store separately from a check's status.
+//
+// Warn, Crit, Min and Max are optional, and are omitted from the output when
+// nil.
+type Perfdata struct {
+	Label string   `json:"label"`
+	Value float64  `json:"value"`
+	UOM   string   `json:"uom,omitempty"`
+	Warn  *float64 `json:"warn,omitempty"`
+	Crit  *float64 `json:"crit,omitempty"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+}
+
+// String returns the perfdata in the plugin output format,
+// 'label'=value[UOM];[warn];[crit];[min];[max].
+func (p Perfdata) String() string {
+	fmtFloat := func(f *float64) string {
+		if f == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*f, 'f', -1, 64)
+	}
+	label := strings.Replace(p.Label, "'", "''", -1)
+	s := fmt.Sprintf("'%s'=%s%s;%s;%s;%s;%s", label, fmtFloat(&p.Value), p.UOM, fmtFloat(p.Warn), fmtFloat(p.Crit), fmtFloat(p.Min), fmtFloat(p.Max))
+	return strings.TrimRight(s, ";")
+}
+
+// Output returns the plugin output for the given Status, message, and
+// perfdata, e.g. "WARNING - 3 caches stale | 'stale'=3;1;5;0".
+//
+// Only the first line of msg is followed by the perfdata; any further lines
+// are long output, and are kept as they are.
+func Output(status Status, msg string, perfdata ...Perfdata) string {
+	msg = strings.TrimRight(msg, "\n")
+	first, rest := msg, ""
+	if i := strings.Index(msg, "\n"); i >= 0 {
+		first, rest = msg[:i], msg[i:]
+	}
+	out := status.String()
+	if first != "" {
+		out += " - " + first
+	}
+	if len(perfdata) > 0 {
+		strs := make([]string, 0, len(perfdata))
+		for _, p := range perfdata {
+			strs = append(strs, p.String())
+		}
+		out += " | " + strings.Join(strs, " ")
+	}
+	return out + rest
+}
+
 // Exit causes the current running program to exit by calling os.Exit with the
 // given Status as an exit code.
 //
@@ -55,3 +137,10 @@ func Exit(status Status, msg string) {
 	}
 	os.Exit(int(status))
 }
+
+// ExitWithPerfdata causes the current running program to exit with the given
+// Status as an exit code, after writing the plugin output for the Status,
+// message, and perfdata to stdout. See Output.
+func ExitWithPerfdata(status Status, msg string, perfdata ...Perfdata) {
+	Exit(status, Output(status, msg, perfdata...))
+}
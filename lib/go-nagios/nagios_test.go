@@ -0,0 +1,81 @@
+package nagios
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"testing"
+)
+
+func TestPerfdataString(t *testing.T) {
+	warn, crit, min := 5.0, 10.0, 0.0
+	tests := []struct {
+		perfdata Perfdata
+		expected string
+	}{
+		{Perfdata{Label: "stale", Value: 3}, "'stale'=3"},
+		{Perfdata{Label: "age", Value: 1.5, UOM: "s", Warn: &warn, Crit: &crit}, "'age'=1.5s;5;10"},
+		{Perfdata{Label: "pct", Value: 2, UOM: "%", Min: &min}, "'pct'=2%;;;0"},
+		{Perfdata{Label: "it's", Value: 0}, "'it''s'=0"},
+	}
+	for _, test := range tests {
+		if actual := test.perfdata.String(); actual != test.expected {
+			t.Errorf("expected perfdata '%s', actual '%s'", test.expected, actual)
+		}
+	}
+}
+
+func TestOutput(t *testing.T) {
+	crit := 10.0
+	perfdata := []Perfdata{{Label: "a", Value: 1}, {Label: "b", Value: 11, Crit: &crit}}
+	tests := []struct {
+		status   Status
+		msg      string
+		perfdata []Perfdata
+		expected string
+	}{
+		{Ok, "", nil, "OK"},
+		{Critical, "b is 11", perfdata, "CRITICAL - b is 11 | 'a'=1 'b'=11;;10"},
+		{Warning, "two monitors\ntm0: WARNING\ntm1: OK\n", perfdata[:1], "WARNING - two monitors | 'a'=1\ntm0: WARNING\ntm1: OK"},
+		{Status(42), "?", nil, "UNKNOWN - ?"},
+	}
+	for _, test := range tests {
+		if actual := Output(test.status, test.msg, test.perfdata...); actual != test.expected {
+			t.Errorf("expected output '%s', actual '%s'", test.expected, actual)
+		}
+	}
+}
+
+func TestThresholdStatus(t *testing.T) {
+	tests := []struct {
+		val      float64
+		expected Status
+	}{
+		{0, Ok},
+		{5, Ok},
+		{5.5, Warning},
+		{10, Warning},
+		{11, Critical},
+	}
+	for _, test := range tests {
+		if actual := ThresholdStatus(test.val, 5, 10); actual != test.expected {
+			t.Errorf("expected %v to be %v, actual %v", test.val, test.expected, actual)
+		}
+	}
+}
@@ -21,6 +21,7 @@ package rfc
 
 import "math"
 import "net/http"
+import "sort"
 import "strconv"
 import "strings"
 import "time"
@@ -284,6 +285,9 @@ func canStoreAuthenticated(reqCacheControl, respCacheControl CacheControlMap) bo
 // CanCache returns whether an object can be cached per RFC 7234, based on the
 // request headers, response headers, and response code.
 //
+// A response with a Vary of "*" is never cached, because it can never be
+// reused, per RFC7234§4.1.
+//
 // If strictRFC is false, this ignores request headers denying cacheability such
 // as `no-cache`, in order to protect origins.
 // TODO add options to ignore/violate request Cache-Control (to protect origins)
@@ -292,6 +296,9 @@ func CanCache(reqMethod string, reqHeaders http.Header, respCode int, respHeader
 	if _, ok := CacheableRequestMethods[reqMethod]; !ok {
 		return false // for now, we only support GET and HEAD as cacheable methods.
 	}
+	if vary := ParseVary(respHeaders); len(vary) == 1 && vary[0] == VaryAll {
+		return false
+	}
 
 	reqCacheControl := ParseCacheControl(reqHeaders)
 	respCacheControl := ParseCacheControl(respHeaders)
@@ -390,6 +397,86 @@ func FreshFor(respHeaders http.Header, respCC CacheControlMap, reqTime, respTime
 	return freshnessLifetime - currentAge
 }
 
+// Staleness returns how long a stored response has been stale, that is, how
+// much its current age exceeds its freshness lifetime per RFC7234§4.2, or 0 if
+// it's still fresh.
+//
+// respHeaders is the collection of headers passed in the original response
+// respCC is the parsed Cache-Control header that was present in the original response
+// reqTime is the time at which the request was made
+// respTime is the time at which the original response was received
+func Staleness(respHeaders http.Header, respCC CacheControlMap, reqTime, respTime time.Time) time.Duration {
+	if staleness := -FreshFor(respHeaders, respCC, reqTime, respTime); staleness > 0 {
+		return staleness
+	}
+	return 0
+}
+
+// CanServeStale returns whether a stored response with the given
+// Cache-Control may ever be served stale. Per RFC7234§5.2.2, responses with
+// must-revalidate, proxy-revalidate, no-cache or no-store must not be, which
+// per RFC5861 overrides any stale-while-revalidate or stale-if-error.
+func CanServeStale(respCC CacheControlMap) bool {
+	return !respCC.Has("must-revalidate") && !respCC.Has("proxy-revalidate") && !respCC.Has("no-cache") && !respCC.Has("no-store")
+}
+
+// StaleWhileRevalidate returns the stale-while-revalidate window of a stored
+// response with the given Cache-Control per RFC5861§3, within which it may be
+// served stale while it's revalidated in the background, and whether it has
+// one.
+func StaleWhileRevalidate(respCC CacheControlMap) (time.Duration, bool) {
+	if !CanServeStale(respCC) {
+		return 0, false
+	}
+	return getHTTPDeltaSecondsCacheControl(respCC, "stale-while-revalidate")
+}
+
+// StaleIfError returns the stale-if-error window of a stored response with the
+// given Cache-Control per RFC5861§4, within which it may be served stale if
+// revalidating it fails with an error, and whether it has one.
+//
+// The stale-if-error of the request is also honored, because per RFC5861§4 it
+// indicates the client will accept a stale response within its window. If
+// both the request and the response have one, the smaller window applies.
+func StaleIfError(reqCC CacheControlMap, respCC CacheControlMap) (time.Duration, bool) {
+	if !CanServeStale(respCC) {
+		return 0, false
+	}
+	respWindow, respOK := getHTTPDeltaSecondsCacheControl(respCC, "stale-if-error")
+	reqWindow, reqOK := getHTTPDeltaSecondsCacheControl(reqCC, "stale-if-error")
+	switch {
+	case respOK && reqOK && reqWindow < respWindow:
+		return reqWindow, true
+	case respOK:
+		return respWindow, true
+	}
+	return reqWindow, reqOK
+}
+
+// RequestAllowsStale returns whether a request with the given Cache-Control
+// allows a stored response to be served stale without revalidating it, per
+// RFC7234§5.2.1. It doesn't if the request has no-cache or min-fresh, a
+// max-age the response's current age exceeds, or a max-stale the response's
+// staleness exceeds.
+//
+// respHeaders is the collection of headers passed in the original response
+// respCC is the parsed Cache-Control header that was present in the original response
+// reqTime is the time at which the request was made
+// respTime is the time at which the original response was received
+func RequestAllowsStale(reqCC CacheControlMap, respHeaders http.Header, respCC CacheControlMap, reqTime, respTime time.Time) bool {
+	if reqCC.Has("no-cache") || reqCC.Has("min-fresh") {
+		return false
+	}
+	age := getCurrentAge(respHeaders, reqTime, respTime)
+	if maxAge, ok := getHTTPDeltaSecondsCacheControl(reqCC, "max-age"); ok && age > maxAge {
+		return false
+	}
+	if maxStale, ok := getHTTPDeltaSecondsCacheControl(reqCC, "max-stale"); ok && age-getFreshnessLifetime(respHeaders, respCC) > maxStale {
+		return false
+	}
+	return true
+}
+
 // Reuse is an "enumerated" type describing the necessary behavior of a cache
 // with regard to its cached objects.
 type Reuse int
@@ -425,25 +512,63 @@ func (r Reuse) String() string {
 	return "INVALID"
 }
 
-// selectedHeadersMatch checks the constraints in RFC7234§4.1.
-// TODO: change caching to key on URL+headers, so multiple requests for the same URL with different vary headers can be cached?
-func selectedHeadersMatch(reqHeaders http.Header, respReqHeaders http.Header, strictRFC bool) bool {
-	varyHeaders, ok := reqHeaders["vary"]
-	if !strictRFC && !ok {
-		return true
-	}
-	if len(varyHeaders) == 0 {
-		return true
+// VaryAll is the Vary header field value indicating that a response varies on
+// more than request headers, so that it never matches a later request.
+const VaryAll = "*"
+
+// ParseVary returns the names of the request header fields listed in the Vary
+// header fields of the given response headers, in canonical form, sorted, and
+// without duplicates. If the Vary is "*", only VaryAll is returned. If there's
+// no Vary, nil is returned.
+func ParseVary(respHeaders http.Header) []string {
+	names := []string(nil)
+	seen := map[string]struct{}{}
+	for _, vary := range respHeaders.Values("Vary") {
+		for _, name := range strings.Split(vary, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if name == VaryAll {
+				return []string{VaryAll}
+			}
+			name = http.CanonicalHeaderKey(name)
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
 	}
-	varyHeader := varyHeaders[0]
+	sort.Strings(names)
+	return names
+}
 
-	if varyHeader == "*" {
-		return false
+// NormalizedHeaderValue returns the values of the named header field, combined
+// into a single value with the whitespace around each comma-separated element
+// removed, so that values which differ only in syntax compare equal, per
+// RFC7234§4.1.
+func NormalizedHeaderValue(headers http.Header, name string) string {
+	elems := []string{}
+	for _, value := range headers.Values(name) {
+		for _, elem := range strings.Split(value, ",") {
+			if elem = strings.TrimSpace(elem); elem != "" {
+				elems = append(elems, elem)
+			}
+		}
 	}
-	varyHeader = strings.ToLower(varyHeader)
-	varyHeaderHeaders := strings.Split(varyHeader, ",")
-	for _, header := range varyHeaderHeaders {
-		if _, ok := respReqHeaders[header]; !ok {
+	return strings.Join(elems, ",")
+}
+
+// selectedHeadersMatch checks the constraints in RFC7234§4.1: the request
+// header fields named in the Vary of the stored response must match those of
+// the request which elicited it.
+func selectedHeadersMatch(reqHeaders http.Header, respHeaders http.Header, respReqHeaders http.Header) bool {
+	for _, name := range ParseVary(respHeaders) {
+		if name == VaryAll {
+			return false
+		}
+		if NormalizedHeaderValue(reqHeaders, name) != NormalizedHeaderValue(respReqHeaders, name) {
 			return false
 		}
 	}
@@ -496,7 +621,7 @@ func CanReuseStored(
 ) Reuse {
 	// TODO: remove allowed_stale, check in cache manager after revalidate fails? (since RFC7234§4.2.4 prohibits serving stale response unless disconnected).
 
-	if !selectedHeadersMatch(reqHeaders, respReqHeaders, strictRFC) {
+	if !selectedHeadersMatch(reqHeaders, respHeaders, respReqHeaders) {
 		return ReuseCannot
 	}
 
@@ -22,6 +22,7 @@ package rfc
 import (
 	"fmt"
 	"net/http"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -946,3 +947,146 @@ func BenchmarkCanReuseStored(b *testing.B) {
 		CanReuseStored(reqHdr, respHdr, reqCC, respCC, respReqHdrs, respReqTime, respRespTime, strictRFC)
 	}
 }
+
+func TestParseVary(t *testing.T) {
+	tests := []struct {
+		name     string
+		hdr      http.Header
+		expected []string
+	}{
+		{"no Vary", http.Header{}, nil},
+		{"single", http.Header{"Vary": {"Accept-Encoding"}}, []string{"Accept-Encoding"}},
+		{"canonicalized and sorted", http.Header{"Vary": {"accept-language, Accept-Encoding"}}, []string{"Accept-Encoding", "Accept-Language"}},
+		{"multiple fields and duplicates", http.Header{"Vary": {"Accept-Encoding,", "accept-encoding, Origin"}}, []string{"Accept-Encoding", "Origin"}},
+		{"star", http.Header{"Vary": {"Accept-Encoding, *"}}, []string{VaryAll}},
+	}
+	for _, test := range tests {
+		if actual := ParseVary(test.hdr); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("ParseVary %s: expected %v, actual %v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestCanCacheVaryAll(t *testing.T) {
+	respHdr := http.Header{"Cache-Control": {"max-age=60"}, "Vary": {"*"}}
+	if CanCache(http.MethodGet, http.Header{}, http.StatusOK, respHdr, false) {
+		t.Error("CanCache for Vary: * expected false, actual true")
+	}
+	respHdr.Set("Vary", "Accept-Encoding")
+	if !CanCache(http.MethodGet, http.Header{}, http.StatusOK, respHdr, false) {
+		t.Error("CanCache for Vary: Accept-Encoding expected true, actual false")
+	}
+}
+
+func TestCanReuseStoredVary(t *testing.T) {
+	now := time.Now()
+	respHdr := http.Header{
+		"Cache-Control": {"max-age=60"},
+		"Date":          {now.Format(time.RFC1123)},
+		"Vary":          {"Accept-Encoding"},
+	}
+	respCC := ParseCacheControl(respHdr)
+	respReqHdrs := http.Header{"Accept-Encoding": {"gzip, br"}}
+
+	tests := []struct {
+		name     string
+		reqHdr   http.Header
+		vary     string
+		expected Reuse
+	}{
+		{"matching", http.Header{"Accept-Encoding": {"gzip,br"}}, "Accept-Encoding", ReuseCan},
+		{"not matching", http.Header{"Accept-Encoding": {"identity"}}, "Accept-Encoding", ReuseCannot},
+		{"missing", http.Header{}, "Accept-Encoding", ReuseCannot},
+		{"star", http.Header{"Accept-Encoding": {"gzip, br"}}, "*", ReuseCannot},
+	}
+	for _, test := range tests {
+		respHdr.Set("Vary", test.vary)
+		if reuse := CanReuseStored(test.reqHdr, respHdr, CacheControlMap{}, respCC, respReqHdrs, now, now, false); reuse != test.expected {
+			t.Errorf("CanReuseStored with Vary %s: expected %v, actual %v", test.name, test.expected, reuse)
+		}
+	}
+}
+
+func TestStaleness(t *testing.T) {
+	now := time.Now()
+	date := now.Add(-90 * time.Second)
+	respHdr := http.Header{"Cache-Control": {"max-age=60"}, "Date": {date.Format(time.RFC1123)}}
+	respCC := ParseCacheControl(respHdr)
+	if staleness := Staleness(respHdr, respCC, date, date); staleness < 29*time.Second || staleness > 31*time.Second {
+		t.Errorf("Staleness of response 90s old with max-age=60 expected ~30s, actual %v", staleness)
+	}
+	respHdr.Set("Cache-Control", "max-age=120")
+	if staleness := Staleness(respHdr, ParseCacheControl(respHdr), date, date); staleness != 0 {
+		t.Errorf("Staleness of fresh response expected 0, actual %v", staleness)
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	tests := []struct {
+		cc       string
+		expected time.Duration
+		ok       bool
+	}{
+		{"max-age=60, stale-while-revalidate=30", 30 * time.Second, true},
+		{"max-age=60", 0, false},
+		{"max-age=60, stale-while-revalidate=invalid", 0, false},
+		{"max-age=60, stale-while-revalidate=30, must-revalidate", 0, false},
+		{"max-age=60, stale-while-revalidate=30, proxy-revalidate", 0, false},
+		{"no-cache, stale-while-revalidate=30", 0, false},
+	}
+	for _, test := range tests {
+		window, ok := StaleWhileRevalidate(ParseCacheControl(http.Header{"Cache-Control": {test.cc}}))
+		if window != test.expected || ok != test.ok {
+			t.Errorf("StaleWhileRevalidate '%s' expected %v %v, actual %v %v", test.cc, test.expected, test.ok, window, ok)
+		}
+	}
+}
+
+func TestStaleIfError(t *testing.T) {
+	tests := []struct {
+		reqCC    string
+		respCC   string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", "max-age=60, stale-if-error=300", 300 * time.Second, true},
+		{"", "max-age=60", 0, false},
+		{"stale-if-error=60", "max-age=60", 60 * time.Second, true},
+		{"stale-if-error=60", "max-age=60, stale-if-error=300", 60 * time.Second, true},
+		{"stale-if-error=600", "max-age=60, stale-if-error=300", 300 * time.Second, true},
+		{"stale-if-error=60", "max-age=60, stale-if-error=300, must-revalidate", 0, false},
+	}
+	for _, test := range tests {
+		reqCC := ParseCacheControl(http.Header{"Cache-Control": {test.reqCC}})
+		respCC := ParseCacheControl(http.Header{"Cache-Control": {test.respCC}})
+		if window, ok := StaleIfError(reqCC, respCC); window != test.expected || ok != test.ok {
+			t.Errorf("StaleIfError request '%s' response '%s' expected %v %v, actual %v %v", test.reqCC, test.respCC, test.expected, test.ok, window, ok)
+		}
+	}
+}
+
+func TestRequestAllowsStale(t *testing.T) {
+	date := time.Now().Add(-90 * time.Second)
+	respHdr := http.Header{"Cache-Control": {"max-age=60, stale-while-revalidate=60"}, "Date": {date.Format(time.RFC1123)}}
+	respCC := ParseCacheControl(respHdr)
+	tests := []struct {
+		reqCC    string
+		expected bool
+	}{
+		{"", true},
+		{"max-age=0", false},
+		{"max-age=60", false},
+		{"max-age=600", true},
+		{"max-stale", true},
+		{"max-stale=10", false},
+		{"max-stale=60", true},
+		{"no-cache", false},
+		{"min-fresh=10", false},
+	}
+	for _, test := range tests {
+		reqCC := ParseCacheControl(http.Header{"Cache-Control": {test.reqCC}})
+		if allows := RequestAllowsStale(reqCC, respHdr, respCC, date, date); allows != test.expected {
+			t.Errorf("RequestAllowsStale request '%s' of response 30s stale expected %v, actual %v", test.reqCC, test.expected, allows)
+		}
+	}
+}
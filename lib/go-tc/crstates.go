@@ -44,6 +44,18 @@ type IsAvailable struct {
 	DirectlyPolled bool      `json:"-"`
 	Status         string    `json:"status"`
 	LastPoll       time.Time `json:"lastPoll"`
+	// Votes are the Traffic Monitors which found the cache available and
+	// unavailable, when its availability was combined from theirs. It is nil
+	// for uncombined states.
+	Votes *CRStatesVotes `json:"votes,omitempty"`
+}
+
+// CRStatesVotes contains the names of the Traffic Monitors which found a cache
+// available and unavailable. It must not be modified once it's in an
+// IsAvailable, because IsAvailables are copied shallowly.
+type CRStatesVotes struct {
+	Available   []TrafficMonitorName `json:"available"`
+	Unavailable []TrafficMonitorName `json:"unavailable"`
 }
 
 // NewCRStates creates a new CR states object, initializing pointer members.
@@ -93,6 +93,27 @@ type DeliveryServiceSSLKeysV15 struct {
 	Expiration time.Time `json:"expiration,omitempty"`
 }
 
+// DeliveryServiceSSLKeysVersion contains information about a single stored
+// version of a Delivery Service's SSL keys.
+type DeliveryServiceSSLKeysVersion struct {
+	// Version is the version of the SSL keys.
+	Version string `json:"version"`
+	// Latest is whether or not this version is the one currently in use by
+	// the Delivery Service.
+	Latest bool `json:"latest"`
+	// Expiration is the expiration date of the version's certificate, if it
+	// is known.
+	Expiration time.Time `json:"expiration,omitempty"`
+}
+
+// DeliveryServiceSSLKeysVersionsResponse is the type of a response from
+// Traffic Ops to GET requests made to its
+// /deliveryservices/xmlId/{{XML ID}}/sslkeys/versions API endpoint.
+type DeliveryServiceSSLKeysVersionsResponse struct {
+	Response []DeliveryServiceSSLKeysVersion `json:"response"`
+	Alerts
+}
+
 // SSLKeyExpirationInformation contains information about an SSL key's expiration.
 type SSLKeyExpirationInformation struct {
 	DeliveryService string    `json:"deliveryservice"`
@@ -27,6 +27,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/apache/trafficcontrol/lib/go-log"
+
 	jsoniter "github.com/json-iterator/go"
 )
 
@@ -34,6 +36,28 @@ import (
 // monitoring thresholds.
 const ThresholdPrefix = "health.threshold."
 
+// ExpressionPrefix is the prefix of all Names of Parameters used to define
+// health threshold expressions. Such a Parameter's Name is the prefix, the
+// name of the expression, and one of the ExpressionParameter* suffixes, e.g.
+// "health.expression.overloaded.down".
+const ExpressionPrefix = "health.expression."
+
+// BondPrefix is the prefix of the Names of Parameters used to define the
+// member links of bonded network interfaces. Such a Parameter's Name is the
+// prefix and the name of the bonded interface, e.g. "health.bond.bond0", and
+// its Value is a comma-separated list of the names of its member interfaces,
+// e.g. "eth0,eth1".
+const BondPrefix = "health.bond."
+
+// These are the suffixes of the Names of Parameters used to define health
+// threshold expressions.
+const (
+	ExpressionParameterDown      = "down"
+	ExpressionParameterUp        = "up"
+	ExpressionParameterDownPolls = "downPolls"
+	ExpressionParameterUpPolls   = "upPolls"
+)
+
 // These are the names of statistics that can be used in thresholds for server
 // health.
 const (
@@ -476,6 +500,120 @@ type TMDeliveryService struct {
 	Topology           string   `json:"topology"`
 	Type               string   `json:"type"`
 	HostRegexes        []string `json:"hostRegexes"`
+	// Parameters are the Parameters of the Delivery Service's Profile with
+	// special meaning to Traffic Monitor.
+	Parameters TMDeliveryServiceParameters `json:"parameters"`
+}
+
+// These are the Names of the Parameters of Delivery Service Profiles which
+// define a Delivery Service's health service level objectives.
+const (
+	SLOParameterMinCacheGroupAvailable = "slo.cachegroup.available.min"
+	SLOParameterMax5xxRatio            = "slo.5xx.ratio.max"
+	SLOParameterMinHeadroom            = "slo.headroom.min"
+	SLOParameterTarget                 = "slo.target"
+	SLOParameterWindowMinutes          = "slo.window.minutes"
+)
+
+// TMDeliveryServiceParameters is a structure containing all of the Parameters
+// of a Delivery Service's Profile with special meaning to Traffic Monitor.
+//
+// The SLO* fields are the Delivery Service's health service level objectives.
+// An objective with a zero value isn't checked.
+type TMDeliveryServiceParameters struct {
+	// SLOMinCacheGroupAvailable is the minimum number of available cache
+	// servers in each Cache Group with cache servers assigned to the Delivery
+	// Service.
+	SLOMinCacheGroupAvailable int `json:"slo.cachegroup.available.min,omitempty"`
+	// SLOMax5xxRatio is the maximum ratio of 5xx responses to all responses,
+	// between 0 and 1.
+	SLOMax5xxRatio float64 `json:"slo.5xx.ratio.max,omitempty"`
+	// SLOMinHeadroom is the minimum ratio of the unused bandwidth of the
+	// Delivery Service's available cache servers to the bandwidth of all its
+	// cache servers, between 0 and 1.
+	SLOMinHeadroom float64 `json:"slo.headroom.min,omitempty"`
+	// SLOTarget is the ratio of the time the objectives must be met, between
+	// 0 and 1, e.g. 0.999. If 0, the default target is used.
+	SLOTarget float64 `json:"slo.target,omitempty"`
+	// SLOWindowMinutes is the length of the rolling window over which
+	// compliance with the objectives is computed, in minutes. If 0, the default
+	// window is used.
+	SLOWindowMinutes int `json:"slo.window.minutes,omitempty"`
+}
+
+// HasSLO returns whether the Parameters define any service level objective.
+func (params TMDeliveryServiceParameters) HasSLO() bool {
+	return params.SLOMinCacheGroupAvailable > 0 || params.SLOMax5xxRatio > 0 || params.SLOMinHeadroom > 0
+}
+
+// UnmarshalJSON implements the encoding/json.Unmarshaler interface. Numeric
+// Parameters may be JSON numbers or strings, because Traffic Ops only converts
+// integer Parameter Values to numbers.
+//
+// Invalid SLO Parameters are logged and ignored, leaving the Delivery Service
+// with no SLO, rather than failing to unmarshal the whole monitoring
+// configuration.
+func (params *TMDeliveryServiceParameters) UnmarshalJSON(bytes []byte) error {
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal(bytes, &raw); err != nil {
+		return err
+	}
+	slo, err := parseSLOParameters(raw)
+	if err != nil {
+		log.Errorf("Unmarshalling TMDeliveryServiceParameters: ignoring invalid SLO parameters: %v", err)
+		slo = TMDeliveryServiceParameters{}
+	}
+	*params = slo
+	return nil
+}
+
+// parseSLOParameters returns the SLO Parameters in the given raw Parameters,
+// or an error if any is invalid.
+func parseSLOParameters(raw map[string]interface{}) (TMDeliveryServiceParameters, error) {
+	ratio := func(name string) (float64, error) {
+		vi, ok := raw[name]
+		if !ok {
+			return 0, nil
+		}
+		v, err := strconv.ParseFloat(fmt.Sprintf("%v", vi), 64)
+		if err != nil || v < 0 || v > 1 {
+			return 0, fmt.Errorf("%s expected a number between 0 and 1, got %v", name, vi)
+		}
+		return v, nil
+	}
+	count := func(name string) (int, error) {
+		vi, ok := raw[name]
+		if !ok {
+			return 0, nil
+		}
+		v, err := strconv.Atoi(fmt.Sprintf("%v", vi))
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("%s expected a non-negative integer, got %v", name, vi)
+		}
+		return v, nil
+	}
+
+	params := TMDeliveryServiceParameters{}
+	var err error
+	if params.SLOMinCacheGroupAvailable, err = count(SLOParameterMinCacheGroupAvailable); err != nil {
+		return params, err
+	}
+	if params.SLOMax5xxRatio, err = ratio(SLOParameterMax5xxRatio); err != nil {
+		return params, err
+	}
+	if params.SLOMinHeadroom, err = ratio(SLOParameterMinHeadroom); err != nil {
+		return params, err
+	}
+	if params.SLOTarget, err = ratio(SLOParameterTarget); err != nil {
+		return params, err
+	}
+	if params.SLOTarget == 1 {
+		return params, fmt.Errorf("%s must be less than 1, to leave an error budget", SLOParameterTarget)
+	}
+	if params.SLOWindowMinutes, err = count(SLOParameterWindowMinutes); err != nil {
+		return params, err
+	}
+	return params, nil
 }
 
 // TMProfile is primarily a collection of the Parameters with special meaning
@@ -502,13 +640,52 @@ type TMParameters struct {
 	HealthPollingType       string `json:"health.polling.type"`
 	HistoryCount            int    `json:"history.count"`
 	MinFreeKbps             int64
+	// HealthProbeURL is the URL of the synthetic request the "probe" poller
+	// type makes through the cache server, along with polling it.
+	HealthProbeURL string `json:"health.probe.url"`
+	// HealthProbeStatus is the HTTP status code the probe must return. If 0,
+	// 200 is expected.
+	HealthProbeStatus int `json:"health.probe.status"`
+	// HealthProbeChecksum is the hex-encoded SHA-256 checksum the probe's
+	// response body must have. If empty, the body isn't checked.
+	HealthProbeChecksum string `json:"health.probe.checksum"`
+	// HealthProbeTTFB is the maximum time to the first byte of the probe's
+	// response, in milliseconds. If 0, the time isn't checked.
+	HealthProbeTTFB int `json:"health.probe.ttfb"`
 	// HealthThresholdJSONParameters contains the Parameters contained in the
 	// Thresholds field, formatted as individual string Parameters, rather than as
 	// a JSON object.
 	Thresholds map[string]HealthThreshold `json:"health_threshold,omitempty"`
+	// Expressions contains the health threshold expressions defined by
+	// Parameters with the ExpressionPrefix, by expression name.
+	Expressions map[string]HealthExpression `json:"health_expression,omitempty"`
+	// Bonds contains the names of the member interfaces of bonded interfaces
+	// defined by Parameters with the BondPrefix, by bonded interface name.
+	Bonds map[string][]string `json:"health_bond,omitempty"`
 	HealthThresholdJSONParameters
 }
 
+// HealthExpression is a health threshold expression, which marks a cache
+// server unavailable when its Down expression has been true for DownPolls
+// consecutive polls, and available again when its Up expression has been true
+// for UpPolls consecutive polls.
+//
+// The expressions themselves are evaluated by Traffic Monitor; here they are
+// only strings.
+type HealthExpression struct {
+	// Down is the expression which marks a cache server unavailable.
+	Down string `json:"down"`
+	// Up is the expression which marks a cache server available again. If
+	// empty, the cache server is marked available again when Down is false.
+	Up string `json:"up,omitempty"`
+	// DownPolls is the number of consecutive polls for which Down must be
+	// true. If 0, 1 is used.
+	DownPolls int `json:"downPolls,omitempty"`
+	// UpPolls is the number of consecutive polls for which Up must be true. If
+	// 0, 1 is used.
+	UpPolls int `json:"upPolls,omitempty"`
+}
+
 // HealthThresholdJSONParameters contains Parameters whose Thresholds must be met in order for
 // Caches using the Profile containing these Parameters to be marked as Healthy.
 type HealthThresholdJSONParameters struct {
@@ -607,6 +784,38 @@ func (params *TMParameters) UnmarshalJSON(bytes []byte) (err error) {
 		}
 	}
 
+	if vi, ok := raw["health.probe.url"]; ok {
+		if v, ok := vi.(string); !ok {
+			return fmt.Errorf("Unmarshalling TMParameters health.probe.url expected string, got %v", vi)
+		} else {
+			params.HealthProbeURL = v
+		}
+	}
+
+	if vi, ok := raw["health.probe.status"]; ok {
+		if v, ok := vi.(float64); !ok {
+			return fmt.Errorf("Unmarshalling TMParameters health.probe.status expected integer, got %v", vi)
+		} else {
+			params.HealthProbeStatus = int(v)
+		}
+	}
+
+	if vi, ok := raw["health.probe.checksum"]; ok {
+		if v, ok := vi.(string); !ok {
+			return fmt.Errorf("Unmarshalling TMParameters health.probe.checksum expected string, got %v", vi)
+		} else {
+			params.HealthProbeChecksum = v
+		}
+	}
+
+	if vi, ok := raw["health.probe.ttfb"]; ok {
+		if v, ok := vi.(float64); !ok {
+			return fmt.Errorf("Unmarshalling TMParameters health.probe.ttfb expected integer, got %v", vi)
+		} else {
+			params.HealthProbeTTFB = int(v)
+		}
+	}
+
 	if vi, ok := raw["history.count"]; ok {
 		if v, ok := vi.(float64); !ok {
 			return fmt.Errorf("Unmarshalling TMParameters history.count expected integer, got %v", vi)
@@ -615,6 +824,86 @@ func (params *TMParameters) UnmarshalJSON(bytes []byte) (err error) {
 		}
 	}
 
+	params.Expressions = map[string]HealthExpression{}
+	if vi, ok := raw["health_expression"]; ok {
+		// Traffic Monitor's backup of the monitoring configuration contains the parsed expressions.
+		bts, err := json.Marshal(vi)
+		if err != nil {
+			return fmt.Errorf("Unmarshalling TMParameters health_expression: %v", err)
+		}
+		if err := json.Unmarshal(bts, &params.Expressions); err != nil {
+			return fmt.Errorf("Unmarshalling TMParameters health_expression: %v", err)
+		}
+	}
+	for k, v := range raw {
+		if !strings.HasPrefix(k, ExpressionPrefix) {
+			continue
+		}
+		nameAndSuffix := k[len(ExpressionPrefix):]
+		dot := strings.LastIndex(nameAndSuffix, ".")
+		if dot < 1 {
+			return fmt.Errorf("Unmarshalling TMParameters `%s` parameter name not of the form `%s<name>.<%s|%s|%s|%s>`", k, ExpressionPrefix, ExpressionParameterDown, ExpressionParameterUp, ExpressionParameterDownPolls, ExpressionParameterUpPolls)
+		}
+		name := nameAndSuffix[:dot]
+		expr := params.Expressions[name]
+		vStr := fmt.Sprintf("%v", v)
+		switch suffix := nameAndSuffix[dot+1:]; suffix {
+		case ExpressionParameterDown:
+			expr.Down = vStr
+		case ExpressionParameterUp:
+			expr.Up = vStr
+		case ExpressionParameterDownPolls, ExpressionParameterUpPolls:
+			polls, err := strconv.Atoi(vStr)
+			if err != nil || polls < 1 {
+				return fmt.Errorf("Unmarshalling TMParameters `%s` parameter value not a positive integer: '%v'", k, v)
+			}
+			if suffix == ExpressionParameterDownPolls {
+				expr.DownPolls = polls
+			} else {
+				expr.UpPolls = polls
+			}
+		default:
+			return fmt.Errorf("Unmarshalling TMParameters `%s` parameter name not of the form `%s<name>.<%s|%s|%s|%s>`", k, ExpressionPrefix, ExpressionParameterDown, ExpressionParameterUp, ExpressionParameterDownPolls, ExpressionParameterUpPolls)
+		}
+		params.Expressions[name] = expr
+	}
+	for name, expr := range params.Expressions {
+		if expr.Down == "" {
+			return fmt.Errorf("Unmarshalling TMParameters health expression '%s' has no `%s%s.%s` parameter", name, ExpressionPrefix, name, ExpressionParameterDown)
+		}
+	}
+
+	params.Bonds = map[string][]string{}
+	if vi, ok := raw["health_bond"]; ok {
+		// Traffic Monitor's backup of the monitoring configuration contains the parsed bonds.
+		bts, err := json.Marshal(vi)
+		if err != nil {
+			return fmt.Errorf("Unmarshalling TMParameters health_bond: %v", err)
+		}
+		if err := json.Unmarshal(bts, &params.Bonds); err != nil {
+			return fmt.Errorf("Unmarshalling TMParameters health_bond: %v", err)
+		}
+	}
+	for k, v := range raw {
+		if !strings.HasPrefix(k, BondPrefix) {
+			continue
+		}
+		bond := k[len(BondPrefix):]
+		if bond == "" {
+			return fmt.Errorf("Unmarshalling TMParameters `%s` parameter name not of the form `%s<interface>`", k, BondPrefix)
+		}
+		members := []string{}
+		for _, member := range strings.Split(fmt.Sprintf("%v", v), ",") {
+			if member = strings.TrimSpace(member); member != "" {
+				members = append(members, member)
+			}
+		}
+		if len(members) == 0 {
+			return fmt.Errorf("Unmarshalling TMParameters `%s` parameter value not a comma-separated list of interface names: '%v'", k, v)
+		}
+		params.Bonds[bond] = members
+	}
+
 	params.Thresholds = make(map[string]HealthThreshold, len(raw))
 	for k, v := range raw {
 		if strings.HasPrefix(k, ThresholdPrefix) {
@@ -612,3 +612,171 @@ func TestTrafficMonitorTransformToMap(t *testing.T) {
 		t.Errorf("Incorrect number of IP addresses on converted traffic server's interface; expected: 1, got: %d", len(converted.TrafficServer["testHostname"].Interfaces[0].IPAddresses))
 	}
 }
+
+func TestTMParametersUnmarshalExpressions(t *testing.T) {
+	raw := `{
+		"health.threshold.loadavg": "25",
+		"health.expression.overloaded.down": "loadavg > 25 AND queryTime > 500",
+		"health.expression.overloaded.up": "loadavg < 20",
+		"health.expression.overloaded.downPolls": "3",
+		"health.expression.overloaded.upPolls": 5,
+		"health.expression.errors.rate.down": "rate(proxy.process.http.5xx_responses, 30s) > 100"
+	}`
+	var params TMParameters
+	if err := json.Unmarshal([]byte(raw), &params); err != nil {
+		t.Fatalf("Unexpected error unmarshalling expression parameters: %v", err)
+	}
+	expected := HealthExpression{Down: "loadavg > 25 AND queryTime > 500", Up: "loadavg < 20", DownPolls: 3, UpPolls: 5}
+	if actual := params.Expressions["overloaded"]; actual != expected {
+		t.Errorf("Expected expression 'overloaded' to be %+v, got: %+v", expected, actual)
+	}
+	if actual := params.Expressions["errors.rate"].Down; actual != "rate(proxy.process.http.5xx_responses, 30s) > 100" {
+		t.Errorf("Expected expression 'errors.rate' to have its down expression, got: %+v", params.Expressions["errors.rate"])
+	}
+	if _, ok := params.Thresholds["loadavg"]; !ok || len(params.Thresholds) != 1 {
+		t.Errorf("Expected only the loadavg threshold, got: %+v", params.Thresholds)
+	}
+
+	// The parsed expressions, as in Traffic Monitor's backup of the monitoring configuration, are read back.
+	bts, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("Unexpected error marshalling parameters: %v", err)
+	}
+	var backup TMParameters
+	if err := json.Unmarshal(bts, &backup); err != nil {
+		t.Fatalf("Unexpected error unmarshalling marshalled parameters: %v", err)
+	}
+	if actual := backup.Expressions["overloaded"]; actual != expected {
+		t.Errorf("Expected backed up expression 'overloaded' to be %+v, got: %+v", expected, actual)
+	}
+
+	invalid := []string{
+		`{"health.expression.overloaded.up": "loadavg < 20"}`,
+		`{"health.expression.overloaded.down": "loadavg > 25", "health.expression.overloaded.downPolls": "0"}`,
+		`{"health.expression.overloaded.sideways": "loadavg > 25"}`,
+		`{"health.expression.down": "loadavg > 25"}`,
+	}
+	for _, raw := range invalid {
+		if err := json.Unmarshal([]byte(raw), &TMParameters{}); err == nil {
+			t.Errorf("Expected an error unmarshalling %s, got none", raw)
+		}
+	}
+}
+
+func TestTMParametersUnmarshalBonds(t *testing.T) {
+	raw := `{
+		"health.bond.bond0": "eth0, eth1,",
+		"health.bond.bond1": "eth2"
+	}`
+	var params TMParameters
+	if err := json.Unmarshal([]byte(raw), &params); err != nil {
+		t.Fatalf("Unexpected error unmarshalling bond parameters: %v", err)
+	}
+	if actual := params.Bonds["bond0"]; len(actual) != 2 || actual[0] != "eth0" || actual[1] != "eth1" {
+		t.Errorf("Expected bond 'bond0' to have members [eth0 eth1], got: %v", actual)
+	}
+	if actual := params.Bonds["bond1"]; len(actual) != 1 || actual[0] != "eth2" {
+		t.Errorf("Expected bond 'bond1' to have members [eth2], got: %v", actual)
+	}
+
+	// The parsed bonds, as in Traffic Monitor's backup of the monitoring configuration, are read back.
+	bts, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("Unexpected error marshalling parameters: %v", err)
+	}
+	var backup TMParameters
+	if err := json.Unmarshal(bts, &backup); err != nil {
+		t.Fatalf("Unexpected error unmarshalling marshalled parameters: %v", err)
+	}
+	if len(backup.Bonds) != 2 || len(backup.Bonds["bond0"]) != 2 {
+		t.Errorf("Expected backed up bonds to be %v, got: %v", params.Bonds, backup.Bonds)
+	}
+
+	invalid := []string{
+		`{"health.bond.bond0": ""}`,
+		`{"health.bond.bond0": " , "}`,
+		`{"health.bond.": "eth0"}`,
+	}
+	for _, raw := range invalid {
+		if err := json.Unmarshal([]byte(raw), &TMParameters{}); err == nil {
+			t.Errorf("Expected an error unmarshalling %s, got none", raw)
+		}
+	}
+}
+
+func TestTMDeliveryServiceParametersUnmarshal(t *testing.T) {
+	raw := `{
+		"slo.cachegroup.available.min": 2,
+		"slo.5xx.ratio.max": "0.01",
+		"slo.headroom.min": 0.2,
+		"slo.target": "0.995",
+		"slo.window.minutes": "60",
+		"unrelated": "value"
+	}`
+	var params TMDeliveryServiceParameters
+	if err := json.Unmarshal([]byte(raw), &params); err != nil {
+		t.Fatalf("Unexpected error unmarshalling SLO parameters: %v", err)
+	}
+	expected := TMDeliveryServiceParameters{SLOMinCacheGroupAvailable: 2, SLOMax5xxRatio: 0.01, SLOMinHeadroom: 0.2, SLOTarget: 0.995, SLOWindowMinutes: 60}
+	if params != expected {
+		t.Errorf("Expected SLO parameters %+v, got: %+v", expected, params)
+	}
+	if !params.HasSLO() {
+		t.Error("Expected SLO parameters to have an SLO")
+	}
+
+	// Traffic Monitor's backup of the monitoring configuration is read back.
+	bts, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("Unexpected error marshalling SLO parameters: %v", err)
+	}
+	var backup TMDeliveryServiceParameters
+	if err := json.Unmarshal(bts, &backup); err != nil {
+		t.Fatalf("Unexpected error unmarshalling marshalled SLO parameters: %v", err)
+	}
+	if backup != expected {
+		t.Errorf("Expected backed up SLO parameters %+v, got: %+v", expected, backup)
+	}
+
+	if err := json.Unmarshal([]byte(`{"slo.target": "0.999"}`), &params); err != nil || params.HasSLO() {
+		t.Errorf("Expected a target alone not to be an SLO, got %+v, error: %v", params, err)
+	}
+
+	invalid := []string{
+		`{"slo.cachegroup.available.min": -1}`,
+		`{"slo.cachegroup.available.min": "two"}`,
+		`{"slo.5xx.ratio.max": "1.5"}`,
+		`{"slo.headroom.min": "most"}`,
+		`{"slo.target": 1}`,
+	}
+	for _, raw := range invalid {
+		invalidParams := TMDeliveryServiceParameters{SLOMinCacheGroupAvailable: 1}
+		if err := json.Unmarshal([]byte(`{"slo.cachegroup.available.min": 3, `+raw[1:]), &invalidParams); err != nil {
+			t.Errorf("Unexpected error unmarshalling %s: %v", raw, err)
+		} else if invalidParams != (TMDeliveryServiceParameters{}) {
+			t.Errorf("Expected invalid SLO parameters %s to be ignored, got %+v", raw, invalidParams)
+		}
+	}
+}
+
+func TestTrafficMonitorConfigInvalidSLO(t *testing.T) {
+	raw := `{
+		"deliveryServices": [
+			{"xmlId": "good", "parameters": {"slo.5xx.ratio.max": "0.01"}},
+			{"xmlId": "bad", "parameters": {"slo.5xx.ratio.max": "1.5"}}
+		]
+	}`
+	var cfg TrafficMonitorConfig
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		t.Fatalf("Expected a monitoring config with an invalid SLO parameter to unmarshal, got error: %v", err)
+	}
+	if len(cfg.DeliveryServices) != 2 {
+		t.Fatalf("Expected 2 delivery services, got %d", len(cfg.DeliveryServices))
+	}
+	if !cfg.DeliveryServices[0].Parameters.HasSLO() {
+		t.Errorf("Expected the delivery service with valid SLO parameters to have an SLO, got %+v", cfg.DeliveryServices[0].Parameters)
+	}
+	if cfg.DeliveryServices[1].Parameters.HasSLO() {
+		t.Errorf("Expected the delivery service with an invalid SLO parameter to have no SLO, got %+v", cfg.DeliveryServices[1].Parameters)
+	}
+}
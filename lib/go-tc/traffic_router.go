@@ -20,6 +20,7 @@ package tc
  */
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -30,8 +31,18 @@ const CoverageZonePollingPrefix = "coveragezone.polling."
 const CoverageZonePollingURL = CoverageZonePollingPrefix + "url"
 
 type CoverageZoneLocation struct {
-	Network  []string `json:"network,omitempty"`
-	Network6 []string `json:"network6,omitempty"`
+	Network     []string                 `json:"network,omitempty"`
+	Network6    []string                 `json:"network6,omitempty"`
+	Coordinates *CoverageZoneCoordinates `json:"coordinates,omitempty"`
+}
+
+// CoverageZoneCoordinates is the location of a coverage zone, which Traffic
+// Router uses to route clients in the zone to the nearest Cache Group when the
+// zone's own Cache Group can't serve them. Coverage Zone Files may give the
+// coordinates as either JSON numbers or strings.
+type CoverageZoneCoordinates struct {
+	Latitude  json.Number `json:"latitude"`
+	Longitude json.Number `json:"longitude"`
 }
 
 func (c *CoverageZoneLocation) GetFirstIPAddressOfType(isIPv4 bool) string {
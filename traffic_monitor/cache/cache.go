@@ -142,6 +142,26 @@ type Vitals struct {
 	BytesIn    uint64
 	KbpsOut    int64
 	MaxKbpsOut int64
+	// BondMembers is the number of member links of a bonded interface, or 0
+	// if the interface isn't bonded.
+	BondMembers int
+	// BondMembersUp is the number of member links of a bonded interface which
+	// are up.
+	BondMembersUp int
+	// BondMembersDown is a comma-separated list of the names of the member
+	// links of a bonded interface which are down, i.e. weren't in the polled
+	// data or had no speed.
+	BondMembersDown string
+}
+
+// BondCapacity returns the given capacity of a bonded interface, reduced in
+// proportion to its member links which are down. If the interface isn't
+// bonded, the capacity is returned unchanged.
+func (v Vitals) BondCapacity(capacity uint64) uint64 {
+	if v.BondMembers == 0 {
+		return capacity
+	}
+	return capacity * uint64(v.BondMembersUp) / uint64(v.BondMembers)
 }
 
 // Stat is a generic stat, including the untyped value and the time the stat was
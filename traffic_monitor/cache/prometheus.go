@@ -0,0 +1,381 @@
+package cache
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// prometheus parses the Prometheus text exposition format, as served by the
+// Prometheus exporters of non-ATS caching proxies such as nginx and Varnish,
+// and by the node exporter for system stats. Which metrics provide the
+// loadavg, interface and connection stats is configured by the
+// PrometheusStatNames of the Traffic Monitor config.
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/traffic_monitor/config"
+	"github.com/apache/trafficcontrol/traffic_monitor/todata"
+)
+
+// PrometheusConnectionsStat is the name of the miscellaneous stat to which
+// the configured Prometheus connections metric is written. It's the name
+// ATS uses, so that connection counts are reported the same way for all
+// caches.
+const PrometheusConnectionsStat = "proxy.process.http.current_client_connections"
+
+func init() {
+	registerDecoder("prometheus", prometheusParse, prometheusPrecompute)
+}
+
+// prometheusSelector selects the samples of a metric which have the given
+// label values.
+type prometheusSelector struct {
+	Name   string
+	Labels map[string]string
+}
+
+// prometheusSample is a single sample of the exposition format.
+type prometheusSample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// prometheusStatSelectors are the parsed selectors of the configured
+// PrometheusStatNames.
+type prometheusStatSelectors struct {
+	LoadavgOne          *prometheusSelector
+	LoadavgFive         *prometheusSelector
+	LoadavgFifteen      *prometheusSelector
+	BytesIn             *prometheusSelector
+	BytesOut            *prometheusSelector
+	InterfaceSpeed      *prometheusSelector
+	InterfaceSpeedScale float64
+	InterfaceLabel      string
+	Connections         *prometheusSelector
+}
+
+var prometheusStats = mustParsePrometheusStatNames(config.DefaultPrometheusStatNames)
+
+// SetPrometheusStatNames sets the names of the metrics from which the
+// "prometheus" stats format reads the stats Traffic Monitor needs. It must be
+// called before any cache server is polled.
+func SetPrometheusStatNames(names config.PrometheusStatNames) error {
+	selectors, err := parsePrometheusStatNames(names)
+	if err != nil {
+		return err
+	}
+	prometheusStats = selectors
+	return nil
+}
+
+func mustParsePrometheusStatNames(names config.PrometheusStatNames) prometheusStatSelectors {
+	selectors, err := parsePrometheusStatNames(names)
+	if err != nil {
+		panic(err)
+	}
+	return selectors
+}
+
+func parsePrometheusStatNames(names config.PrometheusStatNames) (prometheusStatSelectors, error) {
+	selectors := prometheusStatSelectors{
+		InterfaceSpeedScale: names.InterfaceSpeedScale,
+		InterfaceLabel:      names.InterfaceLabel,
+	}
+	for _, stat := range []struct {
+		Name     string
+		Selector **prometheusSelector
+	}{
+		{names.LoadavgOne, &selectors.LoadavgOne},
+		{names.LoadavgFive, &selectors.LoadavgFive},
+		{names.LoadavgFifteen, &selectors.LoadavgFifteen},
+		{names.BytesIn, &selectors.BytesIn},
+		{names.BytesOut, &selectors.BytesOut},
+		{names.InterfaceSpeed, &selectors.InterfaceSpeed},
+		{names.Connections, &selectors.Connections},
+	} {
+		if stat.Name == "" {
+			continue
+		}
+		sample, err := parsePrometheusSeries(stat.Name)
+		if err != nil {
+			return prometheusStatSelectors{}, fmt.Errorf("parsing Prometheus stat name '%s': %v", stat.Name, err)
+		}
+		*stat.Selector = &prometheusSelector{Name: sample.Name, Labels: sample.Labels}
+	}
+	if selectors.BytesOut == nil {
+		return prometheusStatSelectors{}, errors.New("the Prometheus bytes out stat name is required")
+	}
+	if selectors.InterfaceSpeedScale <= 0 {
+		return prometheusStatSelectors{}, errors.New("the Prometheus interface speed scale must be positive")
+	}
+	return selectors, nil
+}
+
+// matches returns whether the sample is selected by the selector.
+func (s *prometheusSelector) matches(sample prometheusSample) bool {
+	if s == nil || s.Name != sample.Name {
+		return false
+	}
+	for name, value := range s.Labels {
+		if sample.Labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func prometheusParse(cacheName string, data io.Reader, _ interface{}) (Statistics, map[string]interface{}, error) {
+	var stats Statistics
+	if data == nil {
+		log.Warnf("Cannot read stats data for cache '%s' - nil data reader", cacheName)
+		return stats, nil, errors.New("handler got nil reader")
+	}
+
+	samples, err := parsePrometheusText(data)
+	if err != nil {
+		return stats, nil, fmt.Errorf("parsing Prometheus stats for cache '%s': %v", cacheName, err)
+	}
+	if len(samples) < 1 {
+		return stats, nil, fmt.Errorf("no samples found in Prometheus stats for cache '%s'", cacheName)
+	}
+
+	selectors := prometheusStats
+	misc := make(map[string]interface{}, len(samples))
+	stats.Interfaces = map[string]Interface{}
+	connections := float64(0)
+	foundConnections := false
+	for _, sample := range samples {
+		misc[prometheusSampleKey(sample)] = sample.Value
+
+		switch {
+		case selectors.LoadavgOne.matches(sample):
+			stats.Loadavg.One = sample.Value
+		case selectors.LoadavgFive.matches(sample):
+			stats.Loadavg.Five = sample.Value
+		case selectors.LoadavgFifteen.matches(sample):
+			stats.Loadavg.Fifteen = sample.Value
+		case selectors.Connections.matches(sample):
+			connections += sample.Value
+			foundConnections = true
+		case selectors.BytesIn.matches(sample), selectors.BytesOut.matches(sample), selectors.InterfaceSpeed.matches(sample):
+			prometheusAddInterfaceStat(stats.Interfaces, selectors, sample)
+		}
+	}
+	if foundConnections {
+		misc[PrometheusConnectionsStat] = connections
+	}
+
+	if len(stats.Interfaces) < 1 {
+		return stats, nil, fmt.Errorf("cache '%s' had no interfaces", cacheName)
+	}
+	return stats, misc, nil
+}
+
+// prometheusAddInterfaceStat adds the value of an interface metric sample to
+// the interface named by its interface label.
+func prometheusAddInterfaceStat(ifaces map[string]Interface, selectors prometheusStatSelectors, sample prometheusSample) {
+	name, ok := sample.Labels[selectors.InterfaceLabel]
+	if !ok || name == "" {
+		log.Warnf("Prometheus sample '%s' appears to be network related, but has no '%s' label", prometheusSampleKey(sample), selectors.InterfaceLabel)
+		return
+	}
+	if math.IsNaN(sample.Value) || sample.Value < 0 || sample.Value > math.MaxUint64 {
+		log.Warnf("Prometheus sample '%s' for interface '%s' is out of range: %v", sample.Name, name, sample.Value)
+		return
+	}
+	iface := ifaces[name]
+	switch {
+	case selectors.BytesIn.matches(sample):
+		iface.BytesIn = uint64(sample.Value)
+	case selectors.BytesOut.matches(sample):
+		iface.BytesOut = uint64(sample.Value)
+	case selectors.InterfaceSpeed.matches(sample):
+		iface.Speed = int64(math.Round(sample.Value * selectors.InterfaceSpeedScale))
+	}
+	ifaces[name] = iface
+}
+
+// prometheusSampleKey returns the name under which a sample is reported in
+// the miscellaneous stats: the metric name, followed by its labels in braces
+// if it has any.
+func prometheusSampleKey(sample prometheusSample) string {
+	if len(sample.Labels) == 0 {
+		return sample.Name
+	}
+	names := make([]string, 0, len(sample.Labels))
+	for name := range sample.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	key := strings.Builder{}
+	key.WriteString(sample.Name)
+	key.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			key.WriteByte(',')
+		}
+		key.WriteString(name)
+		key.WriteString(`="`)
+		key.WriteString(sample.Labels[name])
+		key.WriteByte('"')
+	}
+	key.WriteByte('}')
+	return key.String()
+}
+
+// parsePrometheusText parses the samples of the Prometheus text exposition
+// format. Comments, including HELP and TYPE lines, are ignored.
+func parsePrometheusText(data io.Reader) ([]prometheusSample, error) {
+	samples := []prometheusSample{}
+	scanner := bufio.NewScanner(data)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sample, rest, err := parsePrometheusSeriesPrefix(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+		fields := strings.Fields(rest)
+		if len(fields) < 1 || len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: expected a value and optional timestamp after the metric", lineNum)
+		}
+		if sample.Value, err = strconv.ParseFloat(fields[0], 64); err != nil {
+			return nil, fmt.Errorf("line %d: parsing value '%s': %v", lineNum, fields[0], err)
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// parsePrometheusSeries parses a metric name with optional labels, e.g.
+// `http_requests_total{code="200"}`.
+func parsePrometheusSeries(series string) (prometheusSample, error) {
+	sample, rest, err := parsePrometheusSeriesPrefix(strings.TrimSpace(series))
+	if err != nil {
+		return prometheusSample{}, err
+	}
+	if strings.TrimSpace(rest) != "" {
+		return prometheusSample{}, fmt.Errorf("unexpected '%s' after the metric", rest)
+	}
+	return sample, nil
+}
+
+// parsePrometheusSeriesPrefix parses the metric name and labels at the start
+// of the given string, and returns the remainder of the string.
+func parsePrometheusSeriesPrefix(s string) (prometheusSample, string, error) {
+	sample := prometheusSample{}
+	nameEnd := strings.IndexAny(s, "{ \t")
+	if nameEnd < 0 {
+		nameEnd = len(s)
+	}
+	sample.Name = s[:nameEnd]
+	if sample.Name == "" {
+		return sample, "", errors.New("missing metric name")
+	}
+	s = s[nameEnd:]
+	if !strings.HasPrefix(s, "{") {
+		return sample, s, nil
+	}
+
+	sample.Labels = map[string]string{}
+	s = s[1:]
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if strings.HasPrefix(s, "}") {
+			return sample, s[1:], nil
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq < 1 {
+			return sample, "", fmt.Errorf("malformed labels of metric '%s'", sample.Name)
+		}
+		labelName := strings.TrimSpace(s[:eq])
+		s = strings.TrimLeft(s[eq+1:], " \t")
+		if !strings.HasPrefix(s, `"`) {
+			return sample, "", fmt.Errorf("label '%s' of metric '%s' has an unquoted value", labelName, sample.Name)
+		}
+		value, rest, err := parsePrometheusLabelValue(s[1:])
+		if err != nil {
+			return sample, "", fmt.Errorf("label '%s' of metric '%s': %v", labelName, sample.Name, err)
+		}
+		sample.Labels[labelName] = value
+		s = strings.TrimLeft(rest, " \t")
+		if strings.HasPrefix(s, ",") {
+			s = s[1:]
+		} else if !strings.HasPrefix(s, "}") {
+			return sample, "", fmt.Errorf("malformed labels of metric '%s'", sample.Name)
+		}
+	}
+}
+
+// parsePrometheusLabelValue parses a label value following its opening quote,
+// and returns the unescaped value and the remainder of the string following
+// its closing quote.
+func parsePrometheusLabelValue(s string) (string, string, error) {
+	value := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return value.String(), s[i+1:], nil
+		case '\\':
+			i++
+			if i >= len(s) {
+				return "", "", errors.New("unterminated escape sequence")
+			}
+			switch s[i] {
+			case 'n':
+				value.WriteByte('\n')
+			default:
+				value.WriteByte(s[i])
+			}
+		default:
+			value.WriteByte(s[i])
+		}
+	}
+	return "", "", errors.New("unterminated label value")
+}
+
+func prometheusPrecompute(cacheName string, data todata.TOData, stats Statistics, miscStats map[string]interface{}) PrecomputedData {
+	// Prometheus exporters have no notion of Delivery Services, so there are
+	// no Delivery Service stats to compute.
+	precomputed := PrecomputedData{DeliveryServiceStats: map[string]*DSStat{}}
+	for _, iface := range stats.Interfaces {
+		precomputed.OutBytes += iface.BytesOut
+		if iface.Speed > precomputed.MaxKbps {
+			precomputed.MaxKbps = iface.Speed
+		}
+	}
+	precomputed.MaxKbps *= 1000
+	return precomputed
+}
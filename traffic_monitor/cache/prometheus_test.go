@@ -0,0 +1,129 @@
+package cache
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/apache/trafficcontrol/traffic_monitor/config"
+	"github.com/apache/trafficcontrol/traffic_monitor/todata"
+)
+
+const testPrometheusStats = `# HELP node_load1 1m load average.
+# TYPE node_load1 gauge
+node_load1 0.5
+node_load5 0.25
+node_load15 0.125
+# TYPE node_network_receive_bytes_total counter
+node_network_receive_bytes_total{device="eth0"} 1000
+node_network_receive_bytes_total{device="lo"} 5
+node_network_transmit_bytes_total{device="eth0"} 2.5e+06 1612345678000
+node_network_transmit_bytes_total{device="lo"} 5
+node_network_speed_bytes{device="eth0"} 1.25e+09
+nginx_connections_active 12
+nginx_connections_reading 3
+varnish_backend_req{backend="b1",server="a \"quoted\" name"} 7
+`
+
+func TestPrometheusParse(t *testing.T) {
+	names := config.DefaultPrometheusStatNames
+	names.Connections = "nginx_connections_active"
+	if err := SetPrometheusStatNames(names); err != nil {
+		t.Fatalf("setting Prometheus stat names: %v", err)
+	}
+	defer SetPrometheusStatNames(config.DefaultPrometheusStatNames)
+
+	stats, misc, err := prometheusParse("test", strings.NewReader(testPrometheusStats), nil)
+	if err != nil {
+		t.Fatalf("expected: nil error, actual: %v", err)
+	}
+	if stats.Loadavg.One != 0.5 || stats.Loadavg.Five != 0.25 || stats.Loadavg.Fifteen != 0.125 {
+		t.Errorf("expected loadavg 0.5, 0.25, 0.125, actual: %+v", stats.Loadavg)
+	}
+	if len(stats.Interfaces) != 2 {
+		t.Fatalf("expected 2 interfaces, actual: %+v", stats.Interfaces)
+	}
+	eth0 := stats.Interfaces["eth0"]
+	if eth0.BytesIn != 1000 || eth0.BytesOut != 2500000 || eth0.Speed != 10000 {
+		t.Errorf("expected eth0 to have 1000 bytes in, 2500000 bytes out and a speed of 10000, actual: %+v", eth0)
+	}
+	if misc[PrometheusConnectionsStat] != float64(12) {
+		t.Errorf("expected %s to be 12, actual: %v", PrometheusConnectionsStat, misc[PrometheusConnectionsStat])
+	}
+	if key := `varnish_backend_req{backend="b1",server="a "quoted" name"}`; misc[key] != float64(7) {
+		t.Errorf("expected misc stat '%s' to be 7, actual: %v", key, misc[key])
+	}
+
+	precomputed := prometheusPrecompute("test", *todata.New(), stats, misc)
+	if precomputed.OutBytes != 2500005 {
+		t.Errorf("expected precomputed OutBytes 2500005, actual: %d", precomputed.OutBytes)
+	}
+	if precomputed.MaxKbps != 10000000 {
+		t.Errorf("expected precomputed MaxKbps 10000000, actual: %d", precomputed.MaxKbps)
+	}
+}
+
+func TestPrometheusParseSelectors(t *testing.T) {
+	names := config.PrometheusStatNames{
+		BytesOut:            `nginx_vts_server_bytes_total{direction="out",host="*"}`,
+		BytesIn:             `nginx_vts_server_bytes_total{direction="in",host="*"}`,
+		InterfaceSpeedScale: 1,
+		InterfaceLabel:      "host",
+	}
+	if err := SetPrometheusStatNames(names); err != nil {
+		t.Fatalf("setting Prometheus stat names: %v", err)
+	}
+	defer SetPrometheusStatNames(config.DefaultPrometheusStatNames)
+
+	data := `nginx_vts_server_bytes_total{host="*",direction="in"} 10
+nginx_vts_server_bytes_total{host="*",direction="out"} 20
+nginx_vts_server_bytes_total{host="example.com",direction="out"} 5
+`
+	stats, _, err := prometheusParse("test", strings.NewReader(data), nil)
+	if err != nil {
+		t.Fatalf("expected: nil error, actual: %v", err)
+	}
+	if iface := stats.Interfaces["*"]; len(stats.Interfaces) != 1 || iface.BytesIn != 10 || iface.BytesOut != 20 {
+		t.Errorf("expected one interface '*' with 10 bytes in and 20 bytes out, actual: %+v", stats.Interfaces)
+	}
+}
+
+func TestPrometheusParseErrors(t *testing.T) {
+	cases := map[string]string{
+		"no interfaces":      "node_load1 0.5\n",
+		"missing value":      "node_load1\n",
+		"invalid value":      "node_load1 high\n",
+		"unterminated label": `node_network_transmit_bytes_total{device="eth0} 1` + "\n",
+		"unquoted label":     "node_network_transmit_bytes_total{device=eth0} 1\n",
+	}
+	for name, data := range cases {
+		if _, _, err := prometheusParse("test", strings.NewReader(data), nil); err == nil {
+			t.Errorf("parsing stats with %s - expected: error, actual: nil", name)
+		}
+	}
+
+	if err := SetPrometheusStatNames(config.PrometheusStatNames{InterfaceSpeedScale: 1}); err == nil {
+		t.Error("setting Prometheus stat names without bytes out - expected: error, actual: nil")
+	}
+	if err := SetPrometheusStatNames(config.PrometheusStatNames{BytesOut: `bytes{device=`, InterfaceSpeedScale: 1}); err == nil {
+		t.Error("setting malformed Prometheus stat names - expected: error, actual: nil")
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -97,17 +98,118 @@ func (t *PollingProtocol) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// PeerCombinePolicy is how the availability of a cache server polled by this
+// Traffic Monitor and its peers is combined from their votes.
+type PeerCombinePolicy string
+
+const (
+	// PeerCombineOptimistic makes a cache server available if this Traffic
+	// Monitor or any available peer finds it available.
+	PeerCombineOptimistic = PeerCombinePolicy("optimistic")
+	// PeerCombineMajority makes a cache server available if more of this
+	// Traffic Monitor and its available peers find it available than not. A tie
+	// is broken by this Traffic Monitor's vote.
+	PeerCombineMajority = PeerCombinePolicy("majority")
+	// PeerCombineLocality is PeerCombineMajority, except that the votes of
+	// Traffic Monitors in the same Cache Group as the cache server have the
+	// weight PeerLocalityWeight.
+	PeerCombineLocality = PeerCombinePolicy("locality")
+	// PeerCombinePessimistic makes a cache server available only if this
+	// Traffic Monitor and every available peer find it available.
+	PeerCombinePessimistic = PeerCombinePolicy("pessimistic")
+	// InvalidPeerCombinePolicy is not a valid policy.
+	InvalidPeerCombinePolicy = PeerCombinePolicy("invalid_peer_combine_policy")
+)
+
+// String returns a string representation of this PeerCombinePolicy.
+func (p PeerCombinePolicy) String() string {
+	return string(p)
+}
+
+// PeerCombinePolicyFromString returns a PeerCombinePolicy based on the string
+// input.
+func PeerCombinePolicyFromString(s string) PeerCombinePolicy {
+	s = strings.ToLower(s)
+	switch s {
+	case PeerCombineOptimistic.String():
+		return PeerCombineOptimistic
+	case PeerCombineMajority.String():
+		return PeerCombineMajority
+	case PeerCombineLocality.String():
+		return PeerCombineLocality
+	case PeerCombinePessimistic.String():
+		return PeerCombinePessimistic
+	default:
+		return InvalidPeerCombinePolicy
+	}
+}
+
+// UnmarshalJSON implements the json.Unmarshaller interface
+func (p *PeerCombinePolicy) UnmarshalJSON(b []byte) error {
+	var s string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+	*p = PeerCombinePolicyFromString(s)
+	if *p == InvalidPeerCombinePolicy {
+		return errors.New("parsed invalid PeerCombinePolicy: " + s)
+	}
+	return nil
+}
+
+// PrometheusStatNames maps the statistics Traffic Monitor needs from cache
+// servers to the Prometheus metrics which provide them, for cache servers
+// polled with the "prometheus" stats format. Each name may be followed by
+// label matchers in braces, as in a Prometheus selector, to only use samples
+// with those label values. An empty name means the statistic isn't provided.
+type PrometheusStatNames struct {
+	// The metrics giving the one, five and fifteen minute load averages.
+	LoadavgOne     string `json:"loadavg_one"`
+	LoadavgFive    string `json:"loadavg_five"`
+	LoadavgFifteen string `json:"loadavg_fifteen"`
+	// The metrics giving the total bytes received and transmitted by each
+	// network interface.
+	BytesIn  string `json:"bytes_in"`
+	BytesOut string `json:"bytes_out"`
+	// The metric giving the speed of each network interface.
+	InterfaceSpeed string `json:"interface_speed"`
+	// The factor by which InterfaceSpeed values are multiplied to obtain the
+	// interface speed in megabits per second.
+	InterfaceSpeedScale float64 `json:"interface_speed_scale"`
+	// The label of the interface metrics naming the network interface.
+	InterfaceLabel string `json:"interface_label"`
+	// The metric giving the number of client connections, which is summed over
+	// all matching samples.
+	Connections string `json:"connections"`
+}
+
 // Config is the configuration for the application. It includes myriad data,
 // such as polling intervals and log locations.
 type Config struct {
 	// Sets the Internet Protocol version used for polling cache servers.
 	CachePollingProtocol PollingProtocol `json:"cache_polling_protocol"`
+	// The names of other CDNs to monitor, in addition to this Traffic
+	// Monitor's own. Each is monitored separately, and its endpoints are
+	// served under /cdn/{name}.
+	CDNs []string `json:"cdns"`
 	// A path to a file where CDN Snapshot backups are written.
 	CRConfigBackupFile string `json:"crconfig_backup_file"`
 	// The number of historical CDN Snapshots to store.
 	CRConfigHistoryCount uint64 `json:"crconfig_history_count"`
+	// The address on which to serve the CRStates gRPC service, e.g. ":9090".
+	// If empty, the service is not served.
+	CRStatesGRPCListener string `json:"crstates_grpc_listener"`
 	// Controls whether Distributed Polling is enabled.
 	DistributedPolling bool `json:"distributed_polling"`
+	// A file to which every event is appended, so that events persist across
+	// restarts and may be queried beyond MaxEvents. If empty, events are only
+	// kept in memory.
+	EventLogFile string `json:"event_log_file"`
+	// The maximum age of events kept in the EventLogFile. 0 means no limit.
+	EventLogMaxAge time.Duration `json:"-"`
+	// The maximum size in bytes of the EventLogFile. 0 means no limit.
+	EventLogMaxBytes uint64 `json:"event_log_max_bytes"`
 	// Defines an interval on which Traffic Monitor will flush its collected
 	// health data such that it is made available through the API.
 	HealthFlushInterval time.Duration `json:"-"`
@@ -136,9 +238,34 @@ type Config struct {
 	MaxEvents uint64 `json:"max_events"`
 	// The interval on which to poll for this TM's CDN's "monitoring config".
 	MonitorConfigPollingInterval time.Duration `json:"-"`
+	// How the availability of each cache server is combined from the votes of
+	// this Traffic Monitor and its peers.
+	PeerCombinePolicy PeerCombinePolicy `json:"peer_combine_policy"`
+	// The weight of the votes of Traffic Monitors in the same Cache Group as a
+	// cache server, with the "locality" PeerCombinePolicy. Other votes have a
+	// weight of 1.
+	PeerLocalityWeight float64 `json:"peer_locality_weight"`
 	// Specifies the minimum number of peers that must be available in order to
 	// participate in the optimistic health protocol.
 	PeerOptimisticQuorumMin int `json:"peer_optimistic_quorum_min"`
+	// A directory to which the raw results of polling cache servers are
+	// recorded, along with the monitoring configuration and CDN Snapshot they
+	// were polled with, so they can be replayed. If empty, polls aren't
+	// recorded.
+	PollRecordDir string `json:"poll_record_dir"`
+	// The maximum size in bytes of the recording of each cache server by each
+	// poller, after which it's rotated, keeping one previous recording. 0
+	// means no limit.
+	PollRecordMaxBytes uint64 `json:"poll_record_max_bytes"`
+	// A directory of recorded polls to replay instead of polling cache
+	// servers. If set, the recorded monitoring configuration and CDN Snapshot
+	// are used, and neither Traffic Ops nor peers are requested.
+	PollReplayDir string `json:"poll_replay_dir"`
+	// How many times faster than they were recorded polls are replayed.
+	PollReplaySpeed float64 `json:"poll_replay_speed"`
+	// The names of the Prometheus metrics that provide the statistics Traffic
+	// Monitor needs from cache servers using the "prometheus" stats format.
+	PrometheusStatNames PrometheusStatNames `json:"prometheus_stat_names"`
 	// The timeout for the API server for reading requests.
 	ServeReadTimeout time.Duration `json:"-"`
 	// The timeout for the API server for writing responses.
@@ -185,11 +312,29 @@ func GetAccessLogWriter(cfg Config) (io.WriteCloser, error) {
 	return accessW, nil
 }
 
+// DefaultPrometheusStatNames are the names of the metrics exported by the
+// Prometheus node exporter.
+var DefaultPrometheusStatNames = PrometheusStatNames{
+	LoadavgOne:          "node_load1",
+	LoadavgFive:         "node_load5",
+	LoadavgFifteen:      "node_load15",
+	BytesIn:             "node_network_receive_bytes_total",
+	BytesOut:            "node_network_transmit_bytes_total",
+	InterfaceSpeed:      "node_network_speed_bytes",
+	InterfaceSpeedScale: 8.0 / 1000000.0,
+	InterfaceLabel:      "device",
+}
+
 // DefaultConfig is the default configuration for the application, if no configuration file is given, or if a given config setting doesn't exist in the config file.
 var DefaultConfig = Config{
 	CachePollingProtocol:         Both,
+	CDNs:                         nil,
 	CRConfigBackupFile:           CRConfigBackupFile,
 	CRConfigHistoryCount:         100,
+	CRStatesGRPCListener:         "",
+	EventLogFile:                 "",
+	EventLogMaxAge:               7 * 24 * time.Hour,
+	EventLogMaxBytes:             100 * 1024 * 1024,
 	HealthFlushInterval:          200 * time.Millisecond,
 	HTTPPollingFormat:            HTTPPollingFormat,
 	HTTPTimeout:                  2 * time.Second,
@@ -201,7 +346,14 @@ var DefaultConfig = Config{
 	LogLocationWarning:           LogLocationStdout,
 	MaxEvents:                    200,
 	MonitorConfigPollingInterval: 5 * time.Second,
+	PeerCombinePolicy:            PeerCombineOptimistic,
+	PeerLocalityWeight:           2,
 	PeerOptimisticQuorumMin:      0,
+	PollRecordDir:                "",
+	PollRecordMaxBytes:           100 * 1024 * 1024,
+	PollReplayDir:                "",
+	PollReplaySpeed:              1,
+	PrometheusStatNames:          DefaultPrometheusStatNames,
 	ServeReadTimeout:             10 * time.Second,
 	ServeWriteTimeout:            10 * time.Second,
 	ShortHostnameOverride:        "",
@@ -227,6 +379,7 @@ func (c *Config) MarshalJSON() ([]byte, error) {
 		StatBufferIntervalMs           uint64 `json:"stat_buffer_interval_ms"`
 		ServeReadTimeoutMs             uint64 `json:"serve_read_timeout_ms"`
 		ServeWriteTimeoutMs            uint64 `json:"serve_write_timeout_ms"`
+		EventLogMaxAgeMs               uint64 `json:"event_log_max_age_ms"`
 		*Alias
 	}{
 		MonitorConfigPollingIntervalMs: uint64(c.MonitorConfigPollingInterval / time.Millisecond),
@@ -234,6 +387,7 @@ func (c *Config) MarshalJSON() ([]byte, error) {
 		HealthFlushIntervalMs:          uint64(c.HealthFlushInterval / time.Millisecond),
 		StatFlushIntervalMs:            uint64(c.StatFlushInterval / time.Millisecond),
 		StatBufferIntervalMs:           uint64(c.StatBufferInterval / time.Millisecond),
+		EventLogMaxAgeMs:               uint64(c.EventLogMaxAge / time.Millisecond),
 		Alias:                          (*Alias)(c),
 	})
 }
@@ -251,6 +405,7 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 		ServeWriteTimeoutMs            *uint64 `json:"serve_write_timeout_ms"`
 		TrafficOpsMinRetryIntervalMs   *uint64 `json:"traffic_ops_min_retry_interval_ms"`
 		TrafficOpsMaxRetryIntervalMs   *uint64 `json:"traffic_ops_max_retry_interval_ms"`
+		EventLogMaxAgeMs               *uint64 `json:"event_log_max_age_ms"`
 		*Alias
 	}{
 		Alias: (*Alias)(c),
@@ -287,12 +442,58 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 	if aux.TrafficOpsMaxRetryIntervalMs != nil {
 		c.TrafficOpsMaxRetryInterval = time.Duration(*aux.TrafficOpsMaxRetryIntervalMs) * time.Millisecond
 	}
+	if aux.EventLogMaxAgeMs != nil {
+		c.EventLogMaxAge = time.Duration(*aux.EventLogMaxAgeMs) * time.Millisecond
+	}
 	if c.StatPolling && c.DistributedPolling {
 		return errors.New("invalid configuration: stat_polling cannot be enabled if distributed_polling is also enabled")
 	}
+	if c.PeerLocalityWeight <= 0 {
+		return errors.New("invalid configuration: peer_locality_weight must be greater than 0")
+	}
+	if c.PollRecordDir != "" && c.PollReplayDir != "" {
+		return errors.New("invalid configuration: poll_record_dir cannot be set if poll_replay_dir is also set")
+	}
+	if c.PollReplaySpeed <= 0 {
+		return errors.New("invalid configuration: poll_replay_speed must be greater than 0")
+	}
+	cdns := make(map[string]struct{}, len(c.CDNs))
+	for _, cdn := range c.CDNs {
+		if cdn == "" || strings.Contains(cdn, "/") {
+			return fmt.Errorf("invalid configuration: cdns contains invalid CDN name '%s'", cdn)
+		}
+		if _, ok := cdns[cdn]; ok {
+			return fmt.Errorf("invalid configuration: cdns contains CDN '%s' more than once", cdn)
+		}
+		cdns[cdn] = struct{}{}
+	}
 	return nil
 }
 
+// ForCDN returns the configuration with which to monitor the given one of the
+// other CDNs in CDNs. The files and directories it writes are suffixed with the
+// CDN name, so they don't collide with those of this Traffic Monitor's own CDN,
+// and the CRStates gRPC service is only served for its own CDN. Distributed
+// polling is disabled, because it divides the cache groups among the CDN's own
+// Traffic Monitors, which don't include this one.
+func (c Config) ForCDN(cdn string) Config {
+	c.CDNs = nil
+	c.DistributedPolling = false
+	c.CRConfigBackupFile += "." + cdn
+	c.TMConfigBackupFile += "." + cdn
+	c.CRStatesGRPCListener = ""
+	if c.EventLogFile != "" {
+		c.EventLogFile += "." + cdn
+	}
+	if c.PollRecordDir != "" {
+		c.PollRecordDir = filepath.Join(c.PollRecordDir, cdn)
+	}
+	if c.PollReplayDir != "" {
+		c.PollReplayDir = filepath.Join(c.PollReplayDir, cdn)
+	}
+	return c
+}
+
 // Load loads the given config file. If an empty string is passed, the default config is returned.
 func Load(fileName string) (Config, error) {
 	cfg := DefaultConfig
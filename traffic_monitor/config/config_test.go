@@ -144,3 +144,71 @@ func TestConfigLoadDefaults(t *testing.T) {
 		t.Errorf("DistributedPolling default - expected: false, actual: %t", c.DistributedPolling)
 	}
 }
+
+func TestPollReplayConfigLoad(t *testing.T) {
+	c, err := LoadBytes([]byte(`{"stat_polling": true, "poll_replay_dir": "/tmp/recording", "poll_replay_speed": 10}`))
+	if err != nil {
+		t.Fatalf("loading replay config - expected: no error, actual: %v", err)
+	}
+	if c.PollReplayDir != "/tmp/recording" || c.PollReplaySpeed != 10 {
+		t.Errorf("replay config - expected: /tmp/recording at speed 10, actual: %s at speed %v", c.PollReplayDir, c.PollReplaySpeed)
+	}
+
+	for _, cfg := range []string{
+		`{"poll_record_dir": "/tmp/recording", "poll_replay_dir": "/tmp/recording"}`,
+		`{"poll_replay_dir": "/tmp/recording", "poll_replay_speed": 0}`,
+	} {
+		if _, err := LoadBytes([]byte(cfg)); err == nil {
+			t.Errorf("loading bad config %s - expected: error, actual: nil", cfg)
+		}
+	}
+}
+
+func TestCDNsConfigLoad(t *testing.T) {
+	c, err := LoadBytes([]byte(`{"cdns": ["cdn-b", "cdn-c"], "event_log_file": "/var/log/traffic_monitor/events.log", "poll_record_dir": "/tmp/recording", "crstates_grpc_listener": ":9090", "distributed_polling": true, "stat_polling": false}`))
+	if err != nil {
+		t.Fatalf("loading cdns config - expected: no error, actual: %v", err)
+	}
+	if len(c.CDNs) != 2 || c.CDNs[0] != "cdn-b" || c.CDNs[1] != "cdn-c" {
+		t.Errorf("cdns config - expected: [cdn-b cdn-c], actual: %v", c.CDNs)
+	}
+
+	cdnCfg := c.ForCDN("cdn-b")
+	if cdnCfg.CDNs != nil {
+		t.Errorf("ForCDN CDNs - expected: nil, actual: %v", cdnCfg.CDNs)
+	}
+	if expected := CRConfigBackupFile + ".cdn-b"; cdnCfg.CRConfigBackupFile != expected {
+		t.Errorf("ForCDN CRConfigBackupFile - expected: %s, actual: %s", expected, cdnCfg.CRConfigBackupFile)
+	}
+	if expected := TMConfigBackupFile + ".cdn-b"; cdnCfg.TMConfigBackupFile != expected {
+		t.Errorf("ForCDN TMConfigBackupFile - expected: %s, actual: %s", expected, cdnCfg.TMConfigBackupFile)
+	}
+	if expected := "/var/log/traffic_monitor/events.log.cdn-b"; cdnCfg.EventLogFile != expected {
+		t.Errorf("ForCDN EventLogFile - expected: %s, actual: %s", expected, cdnCfg.EventLogFile)
+	}
+	if expected := "/tmp/recording/cdn-b"; cdnCfg.PollRecordDir != expected {
+		t.Errorf("ForCDN PollRecordDir - expected: %s, actual: %s", expected, cdnCfg.PollRecordDir)
+	}
+	if cdnCfg.PollReplayDir != "" {
+		t.Errorf("ForCDN PollReplayDir - expected: empty, actual: %s", cdnCfg.PollReplayDir)
+	}
+	if cdnCfg.CRStatesGRPCListener != "" {
+		t.Errorf("ForCDN CRStatesGRPCListener - expected: empty, actual: %s", cdnCfg.CRStatesGRPCListener)
+	}
+	if cdnCfg.DistributedPolling {
+		t.Errorf("ForCDN DistributedPolling - expected: false, actual: true")
+	}
+	if c.CRConfigBackupFile != CRConfigBackupFile || len(c.CDNs) != 2 {
+		t.Errorf("ForCDN - expected: original config unchanged, actual: %+v", c)
+	}
+
+	for _, cfg := range []string{
+		`{"cdns": [""]}`,
+		`{"cdns": ["cdn/b"]}`,
+		`{"cdns": ["cdn-b", "cdn-b"]}`,
+	} {
+		if _, err := LoadBytes([]byte(cfg)); err == nil {
+			t.Errorf("loading bad config %s - expected: error, actual: nil", cfg)
+		}
+	}
+}
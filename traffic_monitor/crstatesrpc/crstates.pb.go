@@ -0,0 +1,674 @@
+//
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// The Go code is generated from this file with:
+//
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//     --go-grpc_out=. --go-grpc_opt=paths=source_relative crstates.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.27.1
+// 	protoc        (unknown)
+// source: crstates.proto
+
+package crstatesrpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type WatchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// local requests only the states of the cache servers this Traffic Monitor
+	// polls directly, like /publish/CrStates?local. It only has an effect
+	// with distributed polling.
+	Local bool `protobuf:"varint,1,opt,name=local,proto3" json:"local,omitempty"`
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_crstates_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crstates_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_crstates_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *WatchRequest) GetLocal() bool {
+	if x != nil {
+		return x.Local
+	}
+	return false
+}
+
+// CRStatesUpdate is a message of a Watch stream.
+type CRStatesUpdate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// snapshot is true for the first update of a stream, whose states are the
+	// complete states. The states of every other update are only those which
+	// were added or changed since the previous update.
+	Snapshot bool `protobuf:"varint,1,opt,name=snapshot,proto3" json:"snapshot,omitempty"`
+	// sequence is the number of the update in its stream, starting at 0 for the
+	// snapshot.
+	Sequence uint64 `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	// states are the added or changed states, or all of them in a snapshot.
+	States *CRStates `protobuf:"bytes,3,opt,name=states,proto3" json:"states,omitempty"`
+	// removed_caches are the names of the cache servers removed since the
+	// previous update.
+	RemovedCaches []string `protobuf:"bytes,4,rep,name=removed_caches,json=removedCaches,proto3" json:"removed_caches,omitempty"`
+	// removed_delivery_services are the names of the Delivery Services removed
+	// since the previous update.
+	RemovedDeliveryServices []string `protobuf:"bytes,5,rep,name=removed_delivery_services,json=removedDeliveryServices,proto3" json:"removed_delivery_services,omitempty"`
+}
+
+func (x *CRStatesUpdate) Reset() {
+	*x = CRStatesUpdate{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_crstates_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CRStatesUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CRStatesUpdate) ProtoMessage() {}
+
+func (x *CRStatesUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_crstates_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CRStatesUpdate.ProtoReflect.Descriptor instead.
+func (*CRStatesUpdate) Descriptor() ([]byte, []int) {
+	return file_crstates_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CRStatesUpdate) GetSnapshot() bool {
+	if x != nil {
+		return x.Snapshot
+	}
+	return false
+}
+
+func (x *CRStatesUpdate) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *CRStatesUpdate) GetStates() *CRStates {
+	if x != nil {
+		return x.States
+	}
+	return nil
+}
+
+func (x *CRStatesUpdate) GetRemovedCaches() []string {
+	if x != nil {
+		return x.RemovedCaches
+	}
+	return nil
+}
+
+func (x *CRStatesUpdate) GetRemovedDeliveryServices() []string {
+	if x != nil {
+		return x.RemovedDeliveryServices
+	}
+	return nil
+}
+
+// CRStates are the availability states of cache servers and Delivery
+// Services.
+type CRStates struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// caches are the states of cache servers, by name.
+	Caches map[string]*CacheState `protobuf:"bytes,1,rep,name=caches,proto3" json:"caches,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// delivery_services are the states of Delivery Services, by XMLID.
+	DeliveryServices map[string]*DeliveryServiceState `protobuf:"bytes,2,rep,name=delivery_services,json=deliveryServices,proto3" json:"delivery_services,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *CRStates) Reset() {
+	*x = CRStates{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_crstates_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CRStates) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CRStates) ProtoMessage() {}
+
+func (x *CRStates) ProtoReflect() protoreflect.Message {
+	mi := &file_crstates_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CRStates.ProtoReflect.Descriptor instead.
+func (*CRStates) Descriptor() ([]byte, []int) {
+	return file_crstates_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CRStates) GetCaches() map[string]*CacheState {
+	if x != nil {
+		return x.Caches
+	}
+	return nil
+}
+
+func (x *CRStates) GetDeliveryServices() map[string]*DeliveryServiceState {
+	if x != nil {
+		return x.DeliveryServices
+	}
+	return nil
+}
+
+// CacheState is the availability of a cache server. Unlike in
+// /publish/CrStates, the time it was last polled isn't included, because it
+// changes with every poll.
+type CacheState struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IsAvailable   bool   `protobuf:"varint,1,opt,name=is_available,json=isAvailable,proto3" json:"is_available,omitempty"`
+	Ipv4Available bool   `protobuf:"varint,2,opt,name=ipv4_available,json=ipv4Available,proto3" json:"ipv4_available,omitempty"`
+	Ipv6Available bool   `protobuf:"varint,3,opt,name=ipv6_available,json=ipv6Available,proto3" json:"ipv6_available,omitempty"`
+	Status        string `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	// votes are the Traffic Monitors which found the cache server available and
+	// unavailable, if its availability was combined from theirs.
+	Votes *Votes `protobuf:"bytes,5,opt,name=votes,proto3" json:"votes,omitempty"`
+}
+
+func (x *CacheState) Reset() {
+	*x = CacheState{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_crstates_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CacheState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CacheState) ProtoMessage() {}
+
+func (x *CacheState) ProtoReflect() protoreflect.Message {
+	mi := &file_crstates_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CacheState.ProtoReflect.Descriptor instead.
+func (*CacheState) Descriptor() ([]byte, []int) {
+	return file_crstates_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CacheState) GetIsAvailable() bool {
+	if x != nil {
+		return x.IsAvailable
+	}
+	return false
+}
+
+func (x *CacheState) GetIpv4Available() bool {
+	if x != nil {
+		return x.Ipv4Available
+	}
+	return false
+}
+
+func (x *CacheState) GetIpv6Available() bool {
+	if x != nil {
+		return x.Ipv6Available
+	}
+	return false
+}
+
+func (x *CacheState) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *CacheState) GetVotes() *Votes {
+	if x != nil {
+		return x.Votes
+	}
+	return nil
+}
+
+type Votes struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Available   []string `protobuf:"bytes,1,rep,name=available,proto3" json:"available,omitempty"`
+	Unavailable []string `protobuf:"bytes,2,rep,name=unavailable,proto3" json:"unavailable,omitempty"`
+}
+
+func (x *Votes) Reset() {
+	*x = Votes{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_crstates_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Votes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Votes) ProtoMessage() {}
+
+func (x *Votes) ProtoReflect() protoreflect.Message {
+	mi := &file_crstates_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Votes.ProtoReflect.Descriptor instead.
+func (*Votes) Descriptor() ([]byte, []int) {
+	return file_crstates_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Votes) GetAvailable() []string {
+	if x != nil {
+		return x.Available
+	}
+	return nil
+}
+
+func (x *Votes) GetUnavailable() []string {
+	if x != nil {
+		return x.Unavailable
+	}
+	return nil
+}
+
+// DeliveryServiceState is the availability of a Delivery Service.
+type DeliveryServiceState struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// disabled_locations are the names of the Cache Groups in which the
+	// Delivery Service has no available cache servers.
+	DisabledLocations []string `protobuf:"bytes,1,rep,name=disabled_locations,json=disabledLocations,proto3" json:"disabled_locations,omitempty"`
+	IsAvailable       bool     `protobuf:"varint,2,opt,name=is_available,json=isAvailable,proto3" json:"is_available,omitempty"`
+}
+
+func (x *DeliveryServiceState) Reset() {
+	*x = DeliveryServiceState{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_crstates_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeliveryServiceState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeliveryServiceState) ProtoMessage() {}
+
+func (x *DeliveryServiceState) ProtoReflect() protoreflect.Message {
+	mi := &file_crstates_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeliveryServiceState.ProtoReflect.Descriptor instead.
+func (*DeliveryServiceState) Descriptor() ([]byte, []int) {
+	return file_crstates_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DeliveryServiceState) GetDisabledLocations() []string {
+	if x != nil {
+		return x.DisabledLocations
+	}
+	return nil
+}
+
+func (x *DeliveryServiceState) GetIsAvailable() bool {
+	if x != nil {
+		return x.IsAvailable
+	}
+	return false
+}
+
+var File_crstates_proto protoreflect.FileDescriptor
+
+var file_crstates_proto_rawDesc = []byte{
+	0x0a, 0x0e, 0x63, 0x72, 0x73, 0x74, 0x61, 0x74, 0x65, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x29, 0x74, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c,
+	0x2e, 0x74, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e,
+	0x63, 0x72, 0x73, 0x74, 0x61, 0x74, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x22, 0x24, 0x0a, 0x0c, 0x57,
+	0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6c,
+	0x6f, 0x63, 0x61, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x6c, 0x6f, 0x63, 0x61,
+	0x6c, 0x22, 0xf8, 0x01, 0x0a, 0x0e, 0x43, 0x52, 0x53, 0x74, 0x61, 0x74, 0x65, 0x73, 0x55, 0x70,
+	0x64, 0x61, 0x74, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74,
+	0x12, 0x1a, 0x0a, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x08, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x4b, 0x0a, 0x06,
+	0x73, 0x74, 0x61, 0x74, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x33, 0x2e, 0x74,
+	0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x74, 0x72,
+	0x61, 0x66, 0x66, 0x69, 0x63, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x63, 0x72, 0x73,
+	0x74, 0x61, 0x74, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x52, 0x53, 0x74, 0x61, 0x74, 0x65,
+	0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x65, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x72, 0x65, 0x6d,
+	0x6f, 0x76, 0x65, 0x64, 0x5f, 0x63, 0x61, 0x63, 0x68, 0x65, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x0d, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x43, 0x61, 0x63, 0x68, 0x65, 0x73,
+	0x12, 0x3a, 0x0a, 0x19, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x5f, 0x64, 0x65, 0x6c, 0x69,
+	0x76, 0x65, 0x72, 0x79, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x73, 0x18, 0x05, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x17, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x44, 0x65, 0x6c, 0x69,
+	0x76, 0x65, 0x72, 0x79, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x73, 0x22, 0xd4, 0x03, 0x0a,
+	0x08, 0x43, 0x52, 0x53, 0x74, 0x61, 0x74, 0x65, 0x73, 0x12, 0x57, 0x0a, 0x06, 0x63, 0x61, 0x63,
+	0x68, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x3f, 0x2e, 0x74, 0x72, 0x61, 0x66,
+	0x66, 0x69, 0x63, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x74, 0x72, 0x61, 0x66, 0x66,
+	0x69, 0x63, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x63, 0x72, 0x73, 0x74, 0x61, 0x74,
+	0x65, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x52, 0x53, 0x74, 0x61, 0x74, 0x65, 0x73, 0x2e, 0x43,
+	0x61, 0x63, 0x68, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x63, 0x61, 0x63, 0x68,
+	0x65, 0x73, 0x12, 0x76, 0x0a, 0x11, 0x64, 0x65, 0x6c, 0x69, 0x76, 0x65, 0x72, 0x79, 0x5f, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x49, 0x2e,
+	0x74, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x74,
+	0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x63, 0x72,
+	0x73, 0x74, 0x61, 0x74, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x52, 0x53, 0x74, 0x61, 0x74,
+	0x65, 0x73, 0x2e, 0x44, 0x65, 0x6c, 0x69, 0x76, 0x65, 0x72, 0x79, 0x53, 0x65, 0x72, 0x76, 0x69,
+	0x63, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x10, 0x64, 0x65, 0x6c, 0x69, 0x76, 0x65,
+	0x72, 0x79, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x73, 0x1a, 0x70, 0x0a, 0x0b, 0x43, 0x61,
+	0x63, 0x68, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x4b, 0x0a, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x35, 0x2e, 0x74, 0x72, 0x61,
+	0x66, 0x66, 0x69, 0x63, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x74, 0x72, 0x61, 0x66,
+	0x66, 0x69, 0x63, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x63, 0x72, 0x73, 0x74, 0x61,
+	0x74, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x61, 0x63, 0x68, 0x65, 0x53, 0x74, 0x61, 0x74,
+	0x65, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x84, 0x01, 0x0a,
+	0x15, 0x44, 0x65, 0x6c, 0x69, 0x76, 0x65, 0x72, 0x79, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x55, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x3f, 0x2e, 0x74, 0x72, 0x61, 0x66, 0x66, 0x69,
+	0x63, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x74, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63,
+	0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x63, 0x72, 0x73, 0x74, 0x61, 0x74, 0x65, 0x73,
+	0x2e, 0x76, 0x31, 0x2e, 0x44, 0x65, 0x6c, 0x69, 0x76, 0x65, 0x72, 0x79, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a,
+	0x02, 0x38, 0x01, 0x22, 0xdd, 0x01, 0x0a, 0x0a, 0x43, 0x61, 0x63, 0x68, 0x65, 0x53, 0x74, 0x61,
+	0x74, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x69, 0x73, 0x5f, 0x61, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62,
+	0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x69, 0x73, 0x41, 0x76, 0x61, 0x69,
+	0x6c, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x25, 0x0a, 0x0e, 0x69, 0x70, 0x76, 0x34, 0x5f, 0x61, 0x76,
+	0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x69,
+	0x70, 0x76, 0x34, 0x41, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x25, 0x0a, 0x0e,
+	0x69, 0x70, 0x76, 0x36, 0x5f, 0x61, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x69, 0x70, 0x76, 0x36, 0x41, 0x76, 0x61, 0x69, 0x6c, 0x61,
+	0x62, 0x6c, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x46, 0x0a, 0x05, 0x76,
+	0x6f, 0x74, 0x65, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x30, 0x2e, 0x74, 0x72, 0x61,
+	0x66, 0x66, 0x69, 0x63, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x74, 0x72, 0x61, 0x66,
+	0x66, 0x69, 0x63, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x63, 0x72, 0x73, 0x74, 0x61,
+	0x74, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x6f, 0x74, 0x65, 0x73, 0x52, 0x05, 0x76, 0x6f,
+	0x74, 0x65, 0x73, 0x22, 0x47, 0x0a, 0x05, 0x56, 0x6f, 0x74, 0x65, 0x73, 0x12, 0x1c, 0x0a, 0x09,
+	0x61, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x09, 0x61, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x75, 0x6e,
+	0x61, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x0b, 0x75, 0x6e, 0x61, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x22, 0x68, 0x0a, 0x14,
+	0x44, 0x65, 0x6c, 0x69, 0x76, 0x65, 0x72, 0x79, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x53,
+	0x74, 0x61, 0x74, 0x65, 0x12, 0x2d, 0x0a, 0x12, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64,
+	0x5f, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x11, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x4c, 0x6f, 0x63, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x69, 0x73, 0x5f, 0x61, 0x76, 0x61, 0x69, 0x6c, 0x61,
+	0x62, 0x6c, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x69, 0x73, 0x41, 0x76, 0x61,
+	0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x32, 0x90, 0x01, 0x0a, 0x0f, 0x43, 0x52, 0x53, 0x74, 0x61,
+	0x74, 0x65, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x7d, 0x0a, 0x05, 0x57, 0x61,
+	0x74, 0x63, 0x68, 0x12, 0x37, 0x2e, 0x74, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x63, 0x6f, 0x6e,
+	0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x74, 0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x6d, 0x6f, 0x6e, 0x69,
+	0x74, 0x6f, 0x72, 0x2e, 0x63, 0x72, 0x73, 0x74, 0x61, 0x74, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x2e,
+	0x57, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x39, 0x2e, 0x74,
+	0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x74, 0x72,
+	0x61, 0x66, 0x66, 0x69, 0x63, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x63, 0x72, 0x73,
+	0x74, 0x61, 0x74, 0x65, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x52, 0x53, 0x74, 0x61, 0x74, 0x65,
+	0x73, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x30, 0x01, 0x42, 0x3e, 0x5a, 0x3c, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x70, 0x61, 0x63, 0x68, 0x65, 0x2f, 0x74,
+	0x72, 0x61, 0x66, 0x66, 0x69, 0x63, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2f, 0x74, 0x72,
+	0x61, 0x66, 0x66, 0x69, 0x63, 0x5f, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2f, 0x63, 0x72,
+	0x73, 0x74, 0x61, 0x74, 0x65, 0x73, 0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
+}
+
+var (
+	file_crstates_proto_rawDescOnce sync.Once
+	file_crstates_proto_rawDescData = file_crstates_proto_rawDesc
+)
+
+func file_crstates_proto_rawDescGZIP() []byte {
+	file_crstates_proto_rawDescOnce.Do(func() {
+		file_crstates_proto_rawDescData = protoimpl.X.CompressGZIP(file_crstates_proto_rawDescData)
+	})
+	return file_crstates_proto_rawDescData
+}
+
+var file_crstates_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_crstates_proto_goTypes = []interface{}{
+	(*WatchRequest)(nil),         // 0: trafficcontrol.trafficmonitor.crstates.v1.WatchRequest
+	(*CRStatesUpdate)(nil),       // 1: trafficcontrol.trafficmonitor.crstates.v1.CRStatesUpdate
+	(*CRStates)(nil),             // 2: trafficcontrol.trafficmonitor.crstates.v1.CRStates
+	(*CacheState)(nil),           // 3: trafficcontrol.trafficmonitor.crstates.v1.CacheState
+	(*Votes)(nil),                // 4: trafficcontrol.trafficmonitor.crstates.v1.Votes
+	(*DeliveryServiceState)(nil), // 5: trafficcontrol.trafficmonitor.crstates.v1.DeliveryServiceState
+	nil,                          // 6: trafficcontrol.trafficmonitor.crstates.v1.CRStates.CachesEntry
+	nil,                          // 7: trafficcontrol.trafficmonitor.crstates.v1.CRStates.DeliveryServicesEntry
+}
+var file_crstates_proto_depIdxs = []int32{
+	2, // 0: trafficcontrol.trafficmonitor.crstates.v1.CRStatesUpdate.states:type_name -> trafficcontrol.trafficmonitor.crstates.v1.CRStates
+	6, // 1: trafficcontrol.trafficmonitor.crstates.v1.CRStates.caches:type_name -> trafficcontrol.trafficmonitor.crstates.v1.CRStates.CachesEntry
+	7, // 2: trafficcontrol.trafficmonitor.crstates.v1.CRStates.delivery_services:type_name -> trafficcontrol.trafficmonitor.crstates.v1.CRStates.DeliveryServicesEntry
+	4, // 3: trafficcontrol.trafficmonitor.crstates.v1.CacheState.votes:type_name -> trafficcontrol.trafficmonitor.crstates.v1.Votes
+	3, // 4: trafficcontrol.trafficmonitor.crstates.v1.CRStates.CachesEntry.value:type_name -> trafficcontrol.trafficmonitor.crstates.v1.CacheState
+	5, // 5: trafficcontrol.trafficmonitor.crstates.v1.CRStates.DeliveryServicesEntry.value:type_name -> trafficcontrol.trafficmonitor.crstates.v1.DeliveryServiceState
+	0, // 6: trafficcontrol.trafficmonitor.crstates.v1.CRStatesService.Watch:input_type -> trafficcontrol.trafficmonitor.crstates.v1.WatchRequest
+	1, // 7: trafficcontrol.trafficmonitor.crstates.v1.CRStatesService.Watch:output_type -> trafficcontrol.trafficmonitor.crstates.v1.CRStatesUpdate
+	7, // [7:8] is the sub-list for method output_type
+	6, // [6:7] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_crstates_proto_init() }
+func file_crstates_proto_init() {
+	if File_crstates_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_crstates_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_crstates_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CRStatesUpdate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_crstates_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CRStates); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_crstates_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CacheState); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_crstates_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Votes); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_crstates_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeliveryServiceState); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_crstates_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_crstates_proto_goTypes,
+		DependencyIndexes: file_crstates_proto_depIdxs,
+		MessageInfos:      file_crstates_proto_msgTypes,
+	}.Build()
+	File_crstates_proto = out.File
+	file_crstates_proto_rawDesc = nil
+	file_crstates_proto_goTypes = nil
+	file_crstates_proto_depIdxs = nil
+}
@@ -0,0 +1,136 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package crstatesrpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// CRStatesServiceClient is the client API for CRStatesService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CRStatesServiceClient interface {
+	// Watch streams a snapshot of the states, followed by a delta each time
+	// they change, until the client cancels the stream. If this Traffic Monitor
+	// loses its optimistic peer quorum, the stream ends with an UNAVAILABLE
+	// status, like /publish/CrStates serves a 503.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (CRStatesService_WatchClient, error)
+}
+
+type cRStatesServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCRStatesServiceClient(cc grpc.ClientConnInterface) CRStatesServiceClient {
+	return &cRStatesServiceClient{cc}
+}
+
+func (c *cRStatesServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (CRStatesService_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CRStatesService_ServiceDesc.Streams[0], "/trafficcontrol.trafficmonitor.crstates.v1.CRStatesService/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cRStatesServiceWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CRStatesService_WatchClient interface {
+	Recv() (*CRStatesUpdate, error)
+	grpc.ClientStream
+}
+
+type cRStatesServiceWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *cRStatesServiceWatchClient) Recv() (*CRStatesUpdate, error) {
+	m := new(CRStatesUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CRStatesServiceServer is the server API for CRStatesService service.
+// All implementations must embed UnimplementedCRStatesServiceServer
+// for forward compatibility
+type CRStatesServiceServer interface {
+	// Watch streams a snapshot of the states, followed by a delta each time
+	// they change, until the client cancels the stream. If this Traffic Monitor
+	// loses its optimistic peer quorum, the stream ends with an UNAVAILABLE
+	// status, like /publish/CrStates serves a 503.
+	Watch(*WatchRequest, CRStatesService_WatchServer) error
+	mustEmbedUnimplementedCRStatesServiceServer()
+}
+
+// UnimplementedCRStatesServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedCRStatesServiceServer struct {
+}
+
+func (UnimplementedCRStatesServiceServer) Watch(*WatchRequest, CRStatesService_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedCRStatesServiceServer) mustEmbedUnimplementedCRStatesServiceServer() {}
+
+// UnsafeCRStatesServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CRStatesServiceServer will
+// result in compilation errors.
+type UnsafeCRStatesServiceServer interface {
+	mustEmbedUnimplementedCRStatesServiceServer()
+}
+
+func RegisterCRStatesServiceServer(s grpc.ServiceRegistrar, srv CRStatesServiceServer) {
+	s.RegisterService(&CRStatesService_ServiceDesc, srv)
+}
+
+func _CRStatesService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CRStatesServiceServer).Watch(m, &cRStatesServiceWatchServer{stream})
+}
+
+type CRStatesService_WatchServer interface {
+	Send(*CRStatesUpdate) error
+	grpc.ServerStream
+}
+
+type cRStatesServiceWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *cRStatesServiceWatchServer) Send(m *CRStatesUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// CRStatesService_ServiceDesc is the grpc.ServiceDesc for CRStatesService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CRStatesService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "trafficcontrol.trafficmonitor.crstates.v1.CRStatesService",
+	HandlerType: (*CRStatesServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _CRStatesService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "crstates.proto",
+}
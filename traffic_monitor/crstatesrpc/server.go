@@ -0,0 +1,124 @@
+package crstatesrpc
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/traffic_monitor/peer"
+)
+
+// Server implements the CRStatesService, publishing the combined states of a Traffic Monitor.
+type Server struct {
+	UnimplementedCRStatesServiceServer
+	combinedStates     peer.CRStatesThreadsafe
+	peerStates         peer.CRStatesPeersThreadsafe
+	distributedPolling bool
+}
+
+// NewServer creates a new Server, publishing the given combined states. The peer states are used to check for optimistic quorum, like /publish/CrStates does.
+func NewServer(combinedStates peer.CRStatesThreadsafe, peerStates peer.CRStatesPeersThreadsafe, distributedPolling bool) *Server {
+	return &Server{
+		combinedStates:     combinedStates,
+		peerStates:         peerStates,
+		distributedPolling: distributedPolling,
+	}
+}
+
+// Start listens on the given address, and starts a goroutine serving the given Server on it. It returns an error if it fails to listen.
+func Start(addr string, srv *Server) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listening on '%s': %v", addr, err)
+	}
+	grpcServer := grpc.NewServer()
+	RegisterCRStatesServiceServer(grpcServer, srv)
+	go func() {
+		if err := grpcServer.Serve(listener); err != nil {
+			log.Errorf("serving CRStates gRPC on '%s': %v", addr, err)
+		}
+	}()
+	return nil
+}
+
+// Watch sends a snapshot of the combined states, and then a delta each time they change, until the client cancels or this Traffic Monitor loses its optimistic quorum.
+func (s *Server) Watch(req *WatchRequest, stream CRStatesService_WatchServer) error {
+	changed, unsubscribe := s.combinedStates.Subscribe()
+	defer unsubscribe()
+
+	var last *CRStates
+	sequence := uint64(0)
+	for {
+		if err := s.checkQuorum(); err != nil {
+			return err
+		}
+		states := s.states(req.GetLocal())
+		var update *CRStatesUpdate
+		if last == nil {
+			update = &CRStatesUpdate{Snapshot: true, States: states}
+		} else {
+			update = Delta(last, states)
+		}
+		if update != nil {
+			update.Sequence = sequence
+			if err := stream.Send(update); err != nil {
+				return err
+			}
+			sequence++
+			last = states
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-changed:
+		}
+	}
+}
+
+// checkQuorum returns an Unavailable status error if optimistic quorum is enabled and this Traffic Monitor doesn't have it. See srvTRState in the datareq package.
+func (s *Server) checkQuorum() error {
+	if !s.peerStates.OptimisticQuorumEnabled() {
+		return nil
+	}
+	if optimisticQuorum, peersAvailable, peerCount, minimum := s.peerStates.HasOptimisticQuorum(); !optimisticQuorum {
+		return status.Errorf(codes.Unavailable, "number of peers available (%d/%d) is less than the minimum number of %d required for optimistic peer quorum", peersAvailable, peerCount, minimum)
+	}
+	return nil
+}
+
+// states returns the current combined states. If local is true and distributed polling is enabled, only the caches polled directly by this Traffic Monitor are included.
+func (s *Server) states(local bool) *CRStates {
+	states := s.combinedStates.Get()
+	if local && s.distributedPolling {
+		for name, available := range states.Caches {
+			if !available.DirectlyPolled {
+				delete(states.Caches, name)
+			}
+		}
+	}
+	return FromTC(states)
+}
@@ -0,0 +1,113 @@
+package crstatesrpc
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/peer"
+)
+
+func watch(t *testing.T, srv *Server, local bool) (CRStatesService_WatchClient, func()) {
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	RegisterCRStatesServiceServer(grpcServer, srv)
+	go grpcServer.Serve(listener)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	if err != nil {
+		t.Fatalf("dialing: %v", err)
+	}
+	stream, err := NewCRStatesServiceClient(conn).Watch(ctx, &WatchRequest{Local: local})
+	if err != nil {
+		t.Fatalf("watching: %v", err)
+	}
+	return stream, func() {
+		cancel()
+		conn.Close()
+		grpcServer.Stop()
+	}
+}
+
+func TestWatch(t *testing.T) {
+	combinedStates := peer.NewCRStatesThreadsafe()
+	combinedStates.AddCache("cache0", tc.IsAvailable{IsAvailable: true, DirectlyPolled: true})
+	combinedStates.AddCache("cache1", tc.IsAvailable{IsAvailable: true})
+	combinedStates.SetDeliveryService("ds0", tc.CRStatesDeliveryService{IsAvailable: true})
+
+	stream, done := watch(t, NewServer(combinedStates, peer.NewCRStatesPeersThreadsafe(0), true), true)
+	defer done()
+
+	update, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("receiving snapshot: %v", err)
+	}
+	if !update.Snapshot || update.Sequence != 0 {
+		t.Errorf("expected a snapshot with sequence 0, actual: snapshot %v sequence %d", update.Snapshot, update.Sequence)
+	}
+	if len(update.States.Caches) != 1 || update.States.Caches["cache0"] == nil {
+		t.Errorf("expected only the directly polled cache0 with local, actual: %v", update.States.Caches)
+	}
+	states := ApplyUpdate(nil, update)
+
+	combinedStates.AddCache("cache0", tc.IsAvailable{IsAvailable: true, DirectlyPolled: true, LastPoll: time.Now()})
+	combinedStates.SetCache("cache0", tc.IsAvailable{IsAvailable: false, DirectlyPolled: true, Status: "REPORTED - unavailable"})
+	combinedStates.DeleteDeliveryService("ds0")
+
+	for len(states.DeliveryServices) > 0 || states.Caches["cache0"].IsAvailable {
+		if update, err = stream.Recv(); err != nil {
+			t.Fatalf("receiving delta: %v", err)
+		}
+		if update.Snapshot || update.Sequence == 0 {
+			t.Errorf("expected a delta with a sequence after 0, actual: snapshot %v sequence %d", update.Snapshot, update.Sequence)
+		}
+		states = ApplyUpdate(states, update)
+	}
+	if cache0 := states.Caches["cache0"]; cache0.Status != "REPORTED - unavailable" {
+		t.Errorf("expected cache0 status 'REPORTED - unavailable', actual: '%s'", cache0.Status)
+	}
+}
+
+func TestWatchNoQuorum(t *testing.T) {
+	peerStates := peer.NewCRStatesPeersThreadsafe(1)
+	peerStates.Set(peer.Result{ID: "tm0", Available: false})
+	peerStates.Set(peer.Result{ID: "tm1", Available: false})
+	peerStates.SetPeers(map[tc.TrafficMonitorName]struct{}{"tm0": {}, "tm1": {}})
+
+	stream, done := watch(t, NewServer(peer.NewCRStatesThreadsafe(), peerStates, false), false)
+	defer done()
+
+	_, err := stream.Recv()
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("expected an Unavailable error without optimistic quorum, actual: %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package crstatesrpc
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+// FromTC converts the given CRStates to their protobuf message. The caches' LastPoll and DirectlyPolled are not converted.
+func FromTC(states tc.CRStates) *CRStates {
+	msg := &CRStates{
+		Caches:           make(map[string]*CacheState, len(states.Caches)),
+		DeliveryServices: make(map[string]*DeliveryServiceState, len(states.DeliveryService)),
+	}
+	for name, available := range states.Caches {
+		msg.Caches[string(name)] = cacheStateFromTC(available)
+	}
+	for name, ds := range states.DeliveryService {
+		msg.DeliveryServices[string(name)] = deliveryServiceStateFromTC(ds)
+	}
+	return msg
+}
+
+func cacheStateFromTC(available tc.IsAvailable) *CacheState {
+	state := &CacheState{
+		IsAvailable:   available.IsAvailable,
+		Ipv4Available: available.Ipv4Available,
+		Ipv6Available: available.Ipv6Available,
+		Status:        available.Status,
+	}
+	if available.Votes != nil {
+		state.Votes = &Votes{
+			Available:   monitorNames(available.Votes.Available),
+			Unavailable: monitorNames(available.Votes.Unavailable),
+		}
+	}
+	return state
+}
+
+func deliveryServiceStateFromTC(ds tc.CRStatesDeliveryService) *DeliveryServiceState {
+	state := &DeliveryServiceState{IsAvailable: ds.IsAvailable}
+	for _, cg := range ds.DisabledLocations {
+		state.DisabledLocations = append(state.DisabledLocations, string(cg))
+	}
+	return state
+}
+
+func monitorNames(names []tc.TrafficMonitorName) []string {
+	strs := make([]string, 0, len(names))
+	for _, name := range names {
+		strs = append(strs, string(name))
+	}
+	return strs
+}
+
+// ToTC converts the given protobuf message to CRStates. Because they aren't in the message, the caches' LastPoll is zero and DirectlyPolled is false.
+func ToTC(msg *CRStates) tc.CRStates {
+	states := tc.NewCRStates(len(msg.GetCaches()), len(msg.GetDeliveryServices()))
+	for name, state := range msg.GetCaches() {
+		available := tc.IsAvailable{
+			IsAvailable:   state.GetIsAvailable(),
+			Ipv4Available: state.GetIpv4Available(),
+			Ipv6Available: state.GetIpv6Available(),
+			Status:        state.GetStatus(),
+		}
+		if votes := state.GetVotes(); votes != nil {
+			available.Votes = &tc.CRStatesVotes{
+				Available:   []tc.TrafficMonitorName{},
+				Unavailable: []tc.TrafficMonitorName{},
+			}
+			for _, tm := range votes.GetAvailable() {
+				available.Votes.Available = append(available.Votes.Available, tc.TrafficMonitorName(tm))
+			}
+			for _, tm := range votes.GetUnavailable() {
+				available.Votes.Unavailable = append(available.Votes.Unavailable, tc.TrafficMonitorName(tm))
+			}
+		}
+		states.Caches[tc.CacheName(name)] = available
+	}
+	for name, state := range msg.GetDeliveryServices() {
+		ds := tc.CRStatesDeliveryService{
+			DisabledLocations: []tc.CacheGroupName{},
+			IsAvailable:       state.GetIsAvailable(),
+		}
+		for _, cg := range state.GetDisabledLocations() {
+			ds.DisabledLocations = append(ds.DisabledLocations, tc.CacheGroupName(cg))
+		}
+		states.DeliveryService[tc.DeliveryServiceName(name)] = ds
+	}
+	return states
+}
+
+// Delta returns the update from the old states to the new states: the states which were added or changed, and the names of those which were removed. If nothing changed, it returns nil.
+// The update's Sequence is not set.
+func Delta(old *CRStates, new *CRStates) *CRStatesUpdate {
+	update := &CRStatesUpdate{States: &CRStates{
+		Caches:           map[string]*CacheState{},
+		DeliveryServices: map[string]*DeliveryServiceState{},
+	}}
+	for name, state := range new.GetCaches() {
+		if oldState, ok := old.GetCaches()[name]; !ok || !proto.Equal(oldState, state) {
+			update.States.Caches[name] = state
+		}
+	}
+	for name := range old.GetCaches() {
+		if _, ok := new.GetCaches()[name]; !ok {
+			update.RemovedCaches = append(update.RemovedCaches, name)
+		}
+	}
+	for name, state := range new.GetDeliveryServices() {
+		if oldState, ok := old.GetDeliveryServices()[name]; !ok || !proto.Equal(oldState, state) {
+			update.States.DeliveryServices[name] = state
+		}
+	}
+	for name := range old.GetDeliveryServices() {
+		if _, ok := new.GetDeliveryServices()[name]; !ok {
+			update.RemovedDeliveryServices = append(update.RemovedDeliveryServices, name)
+		}
+	}
+	if len(update.States.Caches) == 0 && len(update.States.DeliveryServices) == 0 && len(update.RemovedCaches) == 0 && len(update.RemovedDeliveryServices) == 0 {
+		return nil
+	}
+	sort.Strings(update.RemovedCaches)
+	sort.Strings(update.RemovedDeliveryServices)
+	return update
+}
+
+// ApplyUpdate applies an update received from a Watch stream to the given states, and returns them. If the update is a snapshot, it returns the update's states, replacing the given ones, which may be nil.
+// Clients of the service use this to keep the current states from a stream of updates.
+func ApplyUpdate(states *CRStates, update *CRStatesUpdate) *CRStates {
+	if update.GetSnapshot() || states == nil {
+		states = &CRStates{}
+	}
+	if states.Caches == nil {
+		states.Caches = map[string]*CacheState{}
+	}
+	if states.DeliveryServices == nil {
+		states.DeliveryServices = map[string]*DeliveryServiceState{}
+	}
+	for name, state := range update.GetStates().GetCaches() {
+		states.Caches[name] = state
+	}
+	for _, name := range update.GetRemovedCaches() {
+		delete(states.Caches, name)
+	}
+	for name, state := range update.GetStates().GetDeliveryServices() {
+		states.DeliveryServices[name] = state
+	}
+	for _, name := range update.GetRemovedDeliveryServices() {
+		delete(states.DeliveryServices, name)
+	}
+	return states
+}
@@ -0,0 +1,107 @@
+package crstatesrpc
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+func testStates() tc.CRStates {
+	states := tc.NewCRStates(2, 1)
+	states.Caches["cache0"] = tc.IsAvailable{IsAvailable: true, Ipv4Available: true, Ipv6Available: true, Status: "REPORTED - available", LastPoll: time.Now()}
+	states.Caches["cache1"] = tc.IsAvailable{IsAvailable: false, Ipv4Available: false, Ipv6Available: true, Status: "REPORTED - unavailable", Votes: &tc.CRStatesVotes{
+		Available:   []tc.TrafficMonitorName{"tm0"},
+		Unavailable: []tc.TrafficMonitorName{"tm1", "tm2"},
+	}}
+	states.DeliveryService["ds0"] = tc.CRStatesDeliveryService{IsAvailable: true, DisabledLocations: []tc.CacheGroupName{"cg0"}}
+	return states
+}
+
+func TestConvert(t *testing.T) {
+	states := testStates()
+	converted := ToTC(FromTC(states))
+	if len(converted.Caches) != len(states.Caches) || len(converted.DeliveryService) != len(states.DeliveryService) {
+		t.Fatalf("expected %d caches and %d delivery services, actual: %+v", len(states.Caches), len(states.DeliveryService), converted)
+	}
+	for name, expected := range states.Caches {
+		actual := converted.Caches[name]
+		if actual.IsAvailable != expected.IsAvailable || actual.Ipv4Available != expected.Ipv4Available || actual.Ipv6Available != expected.Ipv6Available || actual.Status != expected.Status {
+			t.Errorf("cache %s: expected %+v, actual %+v", name, expected, actual)
+		}
+		if !actual.LastPoll.IsZero() {
+			t.Errorf("cache %s: expected no last poll, actual %v", name, actual.LastPoll)
+		}
+		if (expected.Votes == nil) != (actual.Votes == nil) {
+			t.Errorf("cache %s: expected votes %+v, actual %+v", name, expected.Votes, actual.Votes)
+		} else if expected.Votes != nil && (len(actual.Votes.Available) != 1 || len(actual.Votes.Unavailable) != 2 || actual.Votes.Unavailable[1] != "tm2") {
+			t.Errorf("cache %s: expected votes %+v, actual %+v", name, *expected.Votes, *actual.Votes)
+		}
+	}
+	if ds := converted.DeliveryService["ds0"]; !ds.IsAvailable || len(ds.DisabledLocations) != 1 || ds.DisabledLocations[0] != "cg0" {
+		t.Errorf("delivery service ds0: expected %+v, actual %+v", states.DeliveryService["ds0"], ds)
+	}
+}
+
+func TestDelta(t *testing.T) {
+	oldStates := testStates()
+	newStates := oldStates.Copy()
+
+	// a new poll alone isn't a change
+	cache0 := newStates.Caches["cache0"]
+	cache0.LastPoll = cache0.LastPoll.Add(time.Second)
+	newStates.Caches["cache0"] = cache0
+	if update := Delta(FromTC(oldStates), FromTC(newStates)); update != nil {
+		t.Fatalf("expected no delta for only a new poll time, actual: %v", update)
+	}
+
+	cache0.IsAvailable = false
+	newStates.Caches["cache0"] = cache0
+	newStates.Caches["cache2"] = tc.IsAvailable{IsAvailable: true, Status: "ONLINE"}
+	delete(newStates.Caches, "cache1")
+	delete(newStates.DeliveryService, "ds0")
+	newStates.DeliveryService["ds1"] = tc.CRStatesDeliveryService{IsAvailable: true}
+
+	update := Delta(FromTC(oldStates), FromTC(newStates))
+	if update == nil {
+		t.Fatal("expected a delta, actual: nil")
+	}
+	if len(update.States.Caches) != 2 || update.States.Caches["cache0"] == nil || update.States.Caches["cache2"] == nil {
+		t.Errorf("expected changed caches cache0 and cache2, actual: %v", update.States.Caches)
+	}
+	if len(update.RemovedCaches) != 1 || update.RemovedCaches[0] != "cache1" {
+		t.Errorf("expected removed caches [cache1], actual: %v", update.RemovedCaches)
+	}
+	if len(update.States.DeliveryServices) != 1 || update.States.DeliveryServices["ds1"] == nil {
+		t.Errorf("expected changed delivery services ds1, actual: %v", update.States.DeliveryServices)
+	}
+	if len(update.RemovedDeliveryServices) != 1 || update.RemovedDeliveryServices[0] != "ds0" {
+		t.Errorf("expected removed delivery services [ds0], actual: %v", update.RemovedDeliveryServices)
+	}
+
+	applied := ApplyUpdate(ApplyUpdate(nil, &CRStatesUpdate{Snapshot: true, States: FromTC(oldStates)}), update)
+	if expected := FromTC(newStates); !proto.Equal(expected, applied) {
+		t.Errorf("expected applying the delta to give %v, actual: %v", expected, applied)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/apache/trafficcontrol/lib/go-log"
 	"github.com/apache/trafficcontrol/lib/go-tc"
 	"github.com/apache/trafficcontrol/traffic_monitor/cache"
+	"github.com/apache/trafficcontrol/traffic_monitor/health"
 	"github.com/apache/trafficcontrol/traffic_monitor/threadsafe"
 	"github.com/apache/trafficcontrol/traffic_monitor/todata"
 
@@ -104,12 +105,11 @@ func interfaceStatus(inf tc.ServerInterfaceInfo, result cache.Result) (string, b
 		cacheError = "; " + result.Error.Error()
 	}
 	vitalsMap := result.InterfaceVitals
-	vitals, ok := vitalsMap[inf.Name]
-	if !ok {
+	if _, ok := vitalsMap[inf.Name]; !ok {
 		return "not found in health polling data" + cacheError, false
 	}
-	if inf.MaxBandwidth != nil && *inf.MaxBandwidth < uint64(vitals.KbpsOut) {
-		return "maximum bandwidth exceeded" + cacheError, false
+	if available, why := health.EvalInterface(vitalsMap, inf); !available {
+		return why + cacheError, false
 	}
 	return "available", true
 }
@@ -0,0 +1,84 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package datareq
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/ds"
+	"github.com/apache/trafficcontrol/traffic_monitor/threadsafe"
+
+	"github.com/json-iterator/go"
+)
+
+// JSONCapacityForecasts represents the structure we wish to serialize to JSON, for capacity forecasts.
+type JSONCapacityForecasts struct {
+	CacheGroups      map[tc.CacheGroupName]ds.CapacityForecast      `json:"cacheGroups"`
+	DeliveryServices map[tc.DeliveryServiceName]ds.CapacityForecast `json:"deliveryServices"`
+}
+
+func srvAPICapacityForecast(params url.Values, errorCount threadsafe.Uint, path string, capacityForecasts ds.CapacityForecasts) ([]byte, int) {
+	cacheGroups, dses, err := newCapacityForecastFilter(params)
+	if err != nil {
+		HandleErr(errorCount, path, err)
+		return []byte(err.Error()), http.StatusBadRequest
+	}
+	forecasts := JSONCapacityForecasts{}
+	forecasts.CacheGroups, forecasts.DeliveryServices = capacityForecasts.Get(cacheGroups, dses, time.Now())
+	json := jsoniter.ConfigFastest
+	bytes, err := json.Marshal(forecasts)
+	return WrapErrCode(errorCount, path, bytes, err)
+}
+
+// newCapacityForecastFilter takes the HTTP query parameters and returns the cache groups and delivery services to include, from the `cachegroup` and `deliveryservice` parameters, which may be comma-delimited lists. If neither is given, the returned maps are empty, and all cache groups and delivery services are included.
+func newCapacityForecastFilter(params url.Values) (map[tc.CacheGroupName]struct{}, map[tc.DeliveryServiceName]struct{}, error) {
+	for param := range params {
+		if param != "cachegroup" && param != "deliveryservice" {
+			return nil, nil, fmt.Errorf("invalid query parameter '%v'", param)
+		}
+	}
+	cacheGroups := map[tc.CacheGroupName]struct{}{}
+	for _, name := range splitParam(params["cachegroup"]) {
+		cacheGroups[tc.CacheGroupName(name)] = struct{}{}
+	}
+	dses := map[tc.DeliveryServiceName]struct{}{}
+	for _, name := range splitParam(params["deliveryservice"]) {
+		dses[tc.DeliveryServiceName(name)] = struct{}{}
+	}
+	return cacheGroups, dses, nil
+}
+
+// splitParam returns the non-empty values of the given query parameter values, which may be comma-delimited lists.
+func splitParam(vals []string) []string {
+	names := []string{}
+	for _, val := range vals {
+		for _, name := range strings.Split(val, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/apache/trafficcontrol/lib/go-rfc"
 	"github.com/apache/trafficcontrol/lib/go-util"
 	"github.com/apache/trafficcontrol/traffic_monitor/config"
+	"github.com/apache/trafficcontrol/traffic_monitor/ds"
 	"github.com/apache/trafficcontrol/traffic_monitor/health"
 	"github.com/apache/trafficcontrol/traffic_monitor/peer"
 	"github.com/apache/trafficcontrol/traffic_monitor/threadsafe"
@@ -53,9 +54,12 @@ func MakeDispatchMap(
 	statMaxKbpses threadsafe.CacheKbpses,
 	healthHistory threadsafe.ResultHistory,
 	dsStats threadsafe.DSStatsReader,
+	dsSLOs ds.SLOs,
+	capacityForecasts ds.CapacityForecasts,
 	events health.ThreadsafeEvents,
 	staticAppData config.StaticAppData,
 	healthPollInterval time.Duration,
+	serveWriteTimeout time.Duration,
 	lastHealthDurations threadsafe.DurationMap,
 	fetchCount threadsafe.Uint,
 	healthIteration threadsafe.Uint,
@@ -96,9 +100,12 @@ func MakeDispatchMap(
 		"/publish/DsStats": wrap(WrapParams(func(params url.Values, path string) ([]byte, int) {
 			return srvDSStats(params, errorCount, path, toData, dsStats)
 		}, rfc.ApplicationJSON)),
-		"/publish/EventLog": wrap(WrapErr(errorCount, func() ([]byte, error) {
-			return srvEventLog(events)
+		"/publish/EventLog": wrap(WrapParams(func(params url.Values, path string) ([]byte, int) {
+			return srvEventLog(params, errorCount, path, events)
 		}, rfc.ApplicationJSON)),
+		"/publish/EventStream": wrap(func(w http.ResponseWriter, r *http.Request) {
+			srvEventStream(w, r, errorCount, toData, events, eventStreamDuration(serveWriteTimeout))
+		}),
 		"/publish/PeerStates": wrap(WrapParams(func(params url.Values, path string) ([]byte, int) {
 			return srvPeerStates(params, errorCount, path, toData, peerStates)
 		}, rfc.ApplicationJSON)),
@@ -138,12 +145,21 @@ func MakeDispatchMap(
 		"/api/bandwidth-capacity-kbps": wrap(WrapBytes(func() []byte {
 			return srvAPIBandwidthCapacityKbps(statMaxKbpses)
 		}, rfc.ApplicationJSON)),
+		"/api/ds-slo": wrap(WrapParams(func(params url.Values, path string) ([]byte, int) {
+			return srvAPIDSSLO(params, errorCount, path, dsSLOs)
+		}, rfc.ApplicationJSON)),
+		"/api/capacity-forecast": wrap(WrapParams(func(params url.Values, path string) ([]byte, int) {
+			return srvAPICapacityForecast(params, errorCount, path, capacityForecasts)
+		}, rfc.ApplicationJSON)),
 		"/api/monitor-config": wrap(WrapErr(errorCount, func() ([]byte, error) {
 			return srvMonitorConfig(monitorConfig)
 		}, rfc.ApplicationJSON)),
 		"/api/crconfig-history": wrap(WrapErr(errorCount, func() ([]byte, error) {
 			return srvAPICRConfigHist(toSession)
 		}, rfc.ApplicationJSON)),
+		"/metrics": wrap(WrapBytes(func() []byte {
+			return srvMetrics(toData, combinedStates, peerStates, healthHistory, dsStats, events, errorCount)
+		}, PrometheusContentType)),
 	}
 	return addTrailingSlashEndpoints(dispatchMap)
 }
@@ -0,0 +1,68 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package datareq
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/ds"
+	"github.com/apache/trafficcontrol/traffic_monitor/threadsafe"
+
+	"github.com/json-iterator/go"
+)
+
+// JSONDSSLOs represents the structure we wish to serialize to JSON, for delivery service SLOs.
+type JSONDSSLOs struct {
+	DeliveryServices map[tc.DeliveryServiceName]ds.DSSLO `json:"deliveryServices"`
+}
+
+func srvAPIDSSLO(params url.Values, errorCount threadsafe.Uint, path string, dsSLOs ds.SLOs) ([]byte, int) {
+	dses, err := newDSSLOFilter(params)
+	if err != nil {
+		HandleErr(errorCount, path, err)
+		return []byte(err.Error()), http.StatusBadRequest
+	}
+	json := jsoniter.ConfigFastest
+	bytes, err := json.Marshal(JSONDSSLOs{DeliveryServices: dsSLOs.Get(dses, time.Now())})
+	return WrapErrCode(errorCount, path, bytes, err)
+}
+
+// newDSSLOFilter takes the HTTP query parameters and returns the delivery services to include, from the `deliveryservice` parameter, which may be a comma-delimited list. If it isn't given, the returned map is empty, and all delivery services are included.
+func newDSSLOFilter(params url.Values) (map[tc.DeliveryServiceName]struct{}, error) {
+	for param := range params {
+		if param != "deliveryservice" {
+			return nil, fmt.Errorf("invalid query parameter '%v'", param)
+		}
+	}
+	dses := map[tc.DeliveryServiceName]struct{}{}
+	for _, val := range params["deliveryservice"] {
+		for _, name := range strings.Split(val, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				dses[tc.DeliveryServiceName(name)] = struct{}{}
+			}
+		}
+	}
+	return dses, nil
+}
@@ -20,7 +20,15 @@
 package datareq
 
 import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/apache/trafficcontrol/traffic_monitor/health"
+	"github.com/apache/trafficcontrol/traffic_monitor/threadsafe"
 
 	"github.com/json-iterator/go"
 )
@@ -30,7 +38,96 @@ type JSONEvents struct {
 	Events []health.Event `json:"events"`
 }
 
-func srvEventLog(events health.ThreadsafeEvents) ([]byte, error) {
+func srvEventLog(params url.Values, errorCount threadsafe.Uint, path string, events health.ThreadsafeEvents) ([]byte, int) {
 	json := jsoniter.ConfigFastest
-	return json.Marshal(JSONEvents{Events: events.Get()})
+	if len(params) == 0 {
+		bytes, err := json.Marshal(JSONEvents{Events: events.Get()})
+		return WrapErrCode(errorCount, path, bytes, err)
+	}
+
+	query, err := NewEventQuery(params)
+	if err != nil {
+		HandleErr(errorCount, path, err)
+		return []byte(err.Error()), http.StatusBadRequest
+	}
+	matches, err := events.Query(query)
+	if err != nil {
+		return WrapErrCode(errorCount, path, nil, err)
+	}
+	bytes, err := json.Marshal(JSONEvents{Events: matches})
+	return WrapErrCode(errorCount, path, bytes, err)
+}
+
+// NewEventQuery takes the HTTP query parameters and creates a health.EventQuery.
+// Query parameters used are `startTime`, `endTime`, `hostname`, `type`, `isAvailable`, `limit` and `offset`.
+// The `startTime` and `endTime` are the number of milliseconds since the epoch. The `hostname` and `type` may be comma-delimited lists.
+func NewEventQuery(params url.Values) (health.EventQuery, error) {
+	validParams := map[string]struct{}{"startTime": struct{}{}, "endTime": struct{}{}, "hostname": struct{}{}, "type": struct{}{}, "isAvailable": struct{}{}, "limit": struct{}{}, "offset": struct{}{}}
+	for param := range params {
+		if _, ok := validParams[param]; !ok {
+			return health.EventQuery{}, fmt.Errorf("invalid query parameter '%v'", param)
+		}
+	}
+
+	query := health.EventQuery{}
+	parseTime := func(param string) (time.Time, error) {
+		if params.Get(param) == "" {
+			return time.Time{}, nil
+		}
+		ms, err := strconv.ParseInt(params.Get(param), 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid query parameter %s '%v': must be milliseconds since the epoch", param, params.Get(param))
+		}
+		return time.Unix(0, ms*int64(time.Millisecond)), nil
+	}
+	var err error
+	if query.Start, err = parseTime("startTime"); err != nil {
+		return health.EventQuery{}, err
+	}
+	if query.End, err = parseTime("endTime"); err != nil {
+		return health.EventQuery{}, err
+	}
+
+	splitNames := func(param string) map[string]struct{} {
+		names := map[string]struct{}{}
+		for _, val := range params[param] {
+			for _, name := range strings.Split(val, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					names[name] = struct{}{}
+				}
+			}
+		}
+		return names
+	}
+	query.Hostnames = splitNames("hostname")
+	query.Types = map[string]struct{}{}
+	for eventType := range splitNames("type") {
+		query.Types[strings.ToUpper(eventType)] = struct{}{}
+	}
+
+	if val := params.Get("isAvailable"); val != "" {
+		available, err := strconv.ParseBool(val)
+		if err != nil {
+			return health.EventQuery{}, fmt.Errorf("invalid query parameter isAvailable '%v': must be a boolean", val)
+		}
+		query.Available = &available
+	}
+
+	parseNonNegative := func(param string) (int, error) {
+		if params.Get(param) == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(params.Get(param))
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid query parameter %s '%v': must be a non-negative integer", param, params.Get(param))
+		}
+		return n, nil
+	}
+	if query.Limit, err = parseNonNegative("limit"); err != nil {
+		return health.EventQuery{}, err
+	}
+	if query.Offset, err = parseNonNegative("offset"); err != nil {
+		return health.EventQuery{}, err
+	}
+	return query, nil
 }
@@ -0,0 +1,76 @@
+package datareq
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/traffic_monitor/health"
+	"github.com/apache/trafficcontrol/traffic_monitor/threadsafe"
+)
+
+func TestNewEventQuery(t *testing.T) {
+	params, _ := url.ParseQuery("startTime=1600000000000&endTime=1600003600000&hostname=edge1,edge2&type=edge&isAvailable=false&limit=10&offset=20")
+	query, err := NewEventQuery(params)
+	if err != nil {
+		t.Fatalf("expected: nil error, actual: %v", err)
+	}
+	if !query.Start.Equal(time.Unix(1600000000, 0)) || !query.End.Equal(time.Unix(1600003600, 0)) {
+		t.Errorf("expected a time range of 1600000000 to 1600003600, actual: %v to %v", query.Start.Unix(), query.End.Unix())
+	}
+	if _, ok := query.Hostnames["edge2"]; len(query.Hostnames) != 2 || !ok {
+		t.Errorf("expected hostnames edge1 and edge2, actual: %v", query.Hostnames)
+	}
+	if _, ok := query.Types["EDGE"]; !ok {
+		t.Errorf("expected the type to be upper-cased, actual: %v", query.Types)
+	}
+	if query.Available == nil || *query.Available || query.Limit != 10 || query.Offset != 20 {
+		t.Errorf("expected isAvailable false, limit 10 and offset 20, actual: %+v", query)
+	}
+
+	for _, invalid := range []string{"bogus=1", "startTime=yesterday", "isAvailable=maybe", "limit=-1", "offset=a"} {
+		params, _ := url.ParseQuery(invalid)
+		if _, err := NewEventQuery(params); err == nil {
+			t.Errorf("query '%s' - expected: error, actual: nil", invalid)
+		}
+	}
+}
+
+func TestSrvEventLog(t *testing.T) {
+	events := health.NewThreadsafeEvents(10)
+	events.Add(health.Event{Name: "edge1", Type: "EDGE", Available: false})
+	events.Add(health.Event{Name: "edge2", Type: "EDGE", Available: true})
+
+	body, code := srvEventLog(url.Values{"hostname": []string{"edge2"}}, threadsafe.NewUint(), "/publish/EventLog", events)
+	if code != http.StatusOK {
+		t.Fatalf("expected: %d, actual: %d %s", http.StatusOK, code, body)
+	}
+	if !strings.Contains(string(body), `"name":"edge2"`) || strings.Contains(string(body), `"name":"edge1"`) {
+		t.Errorf("expected only the edge2 event, actual: %s", body)
+	}
+
+	if _, code := srvEventLog(url.Values{"limit": []string{"many"}}, threadsafe.NewUint(), "/publish/EventLog", events); code != http.StatusBadRequest {
+		t.Errorf("invalid limit - expected: %d, actual: %d", http.StatusBadRequest, code)
+	}
+}
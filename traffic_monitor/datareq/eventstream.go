@@ -0,0 +1,250 @@
+package datareq
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/health"
+	"github.com/apache/trafficcontrol/traffic_monitor/threadsafe"
+	"github.com/apache/trafficcontrol/traffic_monitor/todata"
+
+	"github.com/json-iterator/go"
+)
+
+// ContentTypeEventStream is the Content-Type of a Server-Sent Events stream.
+const ContentTypeEventStream = "text/event-stream"
+
+// EventStreamHeartbeatInterval is how often a comment is written to an idle event stream, to keep intermediaries from closing the connection.
+const EventStreamHeartbeatInterval = 15 * time.Second
+
+// EventStreamRetryMs is the reconnection time sent to event stream clients, in milliseconds.
+const EventStreamRetryMs = 500
+
+// EventFilter filters health events by the cache, cachegroup, or delivery service they concern. See the `NewEventFilter` documentation for details on which query parameters are used to filter.
+type EventFilter struct {
+	caches           map[tc.CacheName]struct{}
+	cachegroups      map[tc.CacheGroupName]struct{}
+	deliveryServices map[tc.DeliveryServiceName]struct{}
+	lastEventID      *uint64
+}
+
+// UseEvent returns whether the given event is in this filter. Events must match every given filter, and match any of the names given to each filter.
+// Peer events don't concern any cache or delivery service, and so are only in a filter with no cache, cachegroup, or delivery service.
+func (f *EventFilter) UseEvent(e health.Event, toData todata.TOData) bool {
+	if f.lastEventID != nil && e.Index <= *f.lastEventID {
+		return false
+	}
+	if e.Type == health.DeliveryServiceEventType {
+		if len(f.caches) != 0 || len(f.cachegroups) != 0 {
+			return false
+		}
+		if len(f.deliveryServices) == 0 {
+			return true
+		}
+		_, ok := f.deliveryServices[tc.DeliveryServiceName(e.Name)]
+		return ok
+	}
+
+	cacheName := tc.CacheName(e.Name)
+	if _, isCache := toData.ServerTypes[cacheName]; !isCache {
+		return len(f.caches) == 0 && len(f.cachegroups) == 0 && len(f.deliveryServices) == 0
+	}
+	if _, ok := f.caches[cacheName]; len(f.caches) != 0 && !ok {
+		return false
+	}
+	if _, ok := f.cachegroups[toData.ServerCachegroups[cacheName]]; len(f.cachegroups) != 0 && !ok {
+		return false
+	}
+	if len(f.deliveryServices) == 0 {
+		return true
+	}
+	for _, ds := range toData.ServerDeliveryServices[cacheName] {
+		if _, ok := f.deliveryServices[ds]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// NewEventFilter takes the HTTP query parameters and the request's Last-Event-ID header, and creates an EventFilter.
+// Query parameters used are `cache`, `cachegroup`, `deliveryservice` and `lastEventId`, and each of the names may be a comma-delimited list.
+// If `deliveryservice` is given, cache events are included for caches assigned to any of the delivery services.
+// If `lastEventId` or the Last-Event-ID header is given, stored events after that index are included, so a reconnecting client misses none.
+func NewEventFilter(params url.Values, lastEventIDHeader string) (*EventFilter, error) {
+	validParams := map[string]struct{}{"cache": struct{}{}, "cachegroup": struct{}{}, "deliveryservice": struct{}{}, "lastEventId": struct{}{}}
+	for param := range params {
+		if _, ok := validParams[param]; !ok {
+			return nil, fmt.Errorf("invalid query parameter '%v'", param)
+		}
+	}
+
+	splitNames := func(param string) []string {
+		names := []string{}
+		for _, val := range params[param] {
+			for _, name := range strings.Split(val, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					names = append(names, name)
+				}
+			}
+		}
+		return names
+	}
+
+	filter := &EventFilter{
+		caches:           map[tc.CacheName]struct{}{},
+		cachegroups:      map[tc.CacheGroupName]struct{}{},
+		deliveryServices: map[tc.DeliveryServiceName]struct{}{},
+	}
+	for _, name := range splitNames("cache") {
+		filter.caches[tc.CacheName(name)] = struct{}{}
+	}
+	for _, name := range splitNames("cachegroup") {
+		filter.cachegroups[tc.CacheGroupName(name)] = struct{}{}
+	}
+	for _, name := range splitNames("deliveryservice") {
+		filter.deliveryServices[tc.DeliveryServiceName(name)] = struct{}{}
+	}
+
+	lastEventID := lastEventIDHeader
+	if lastEventID == "" {
+		lastEventID = params.Get("lastEventId")
+	}
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid last event ID '%v': must be an event index", lastEventID)
+		}
+		filter.lastEventID = &id
+	}
+	return filter, nil
+}
+
+// srvEventStream streams health events to the client as Server-Sent Events, as they are added, until the client disconnects.
+// Because the HTTP server's write timeout applies to the whole response, the stream is ended just before maxDuration; clients are expected to reconnect with the Last-Event-ID, which EventSource clients do automatically. If maxDuration is 0, the stream never ends.
+func srvEventStream(w http.ResponseWriter, r *http.Request, errorCount threadsafe.Uint, toData todata.TODataThreadsafe, events health.ThreadsafeEvents, maxDuration time.Duration) {
+	filter, err := NewEventFilter(r.URL.Query(), r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		HandleErr(errorCount, r.URL.EscapedPath(), err)
+		w.WriteHeader(http.StatusBadRequest)
+		log.Write(w, []byte(err.Error()), r.URL.EscapedPath())
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		HandleErr(errorCount, r.URL.EscapedPath(), fmt.Errorf("response writer %T does not support flushing", w))
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Write(w, []byte(http.StatusText(http.StatusInternalServerError)), r.URL.EscapedPath())
+		return
+	}
+
+	stored, newEvents, unsubscribe := events.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", ContentTypeEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", EventStreamRetryMs); err != nil {
+		log.Warnf("writing event stream %v: %v\n", r.URL.EscapedPath(), err)
+		return
+	}
+
+	// Stored events are only replayed to clients resuming from a previous event; new clients only receive new events.
+	if filter.lastEventID != nil {
+		td := toData.Get()
+		for i := len(stored) - 1; i >= 0; i-- {
+			if !filter.UseEvent(stored[i], td) {
+				continue
+			}
+			if err := writeServerSentEvent(w, stored[i]); err != nil {
+				log.Warnf("writing event stream %v: %v\n", r.URL.EscapedPath(), err)
+				return
+			}
+		}
+	}
+	flusher.Flush()
+
+	var end <-chan time.Time
+	if maxDuration > 0 {
+		endTimer := time.NewTimer(maxDuration)
+		defer endTimer.Stop()
+		end = endTimer.C
+	}
+	heartbeat := time.NewTicker(EventStreamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-end:
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				log.Warnf("writing event stream %v: %v\n", r.URL.EscapedPath(), err)
+				return
+			}
+			flusher.Flush()
+		case e, ok := <-newEvents:
+			if !ok {
+				log.Warnf("event stream %v fell behind, closing it for the client to reconnect\n", r.URL.EscapedPath())
+				return
+			}
+			if !filter.UseEvent(e, toData.Get()) {
+				continue
+			}
+			if err := writeServerSentEvent(w, e); err != nil {
+				log.Warnf("writing event stream %v: %v\n", r.URL.EscapedPath(), err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// eventStreamDuration returns how long an event stream may last before the HTTP server's write timeout closes the connection.
+func eventStreamDuration(writeTimeout time.Duration) time.Duration {
+	if writeTimeout <= 0 {
+		return 0
+	}
+	if writeTimeout > 2*time.Second {
+		return writeTimeout - time.Second
+	}
+	return writeTimeout / 2
+}
+
+// writeServerSentEvent writes the given event as a Server-Sent Event, with its index as the event ID.
+func writeServerSentEvent(w io.Writer, e health.Event) error {
+	json := jsoniter.ConfigFastest
+	bts, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshalling event: %v", err)
+	}
+	_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", e.Index, bts)
+	return err
+}
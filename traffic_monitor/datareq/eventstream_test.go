@@ -0,0 +1,145 @@
+package datareq
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/health"
+	"github.com/apache/trafficcontrol/traffic_monitor/threadsafe"
+	"github.com/apache/trafficcontrol/traffic_monitor/todata"
+)
+
+func testEventStreamTOData() todata.TOData {
+	toData := *todata.New()
+	toData.ServerTypes["edge1"] = tc.CacheTypeEdge
+	toData.ServerTypes["edge2"] = tc.CacheTypeEdge
+	toData.ServerCachegroups["edge1"] = "cg1"
+	toData.ServerCachegroups["edge2"] = "cg2"
+	toData.ServerDeliveryServices["edge1"] = []tc.DeliveryServiceName{"ds1"}
+	return toData
+}
+
+func TestEventFilter(t *testing.T) {
+	toData := testEventStreamTOData()
+	edge1 := health.Event{Name: "edge1", Type: "EDGE", Index: 5}
+	edge2 := health.Event{Name: "edge2", Type: "EDGE", Index: 6}
+	ds1 := health.Event{Name: "ds1", Type: health.DeliveryServiceEventType, Index: 7}
+	peer := health.Event{Name: "tm1", Type: "PEER", Index: 8}
+
+	cases := []struct {
+		Query    string
+		Expected []health.Event
+	}{
+		{"", []health.Event{edge1, edge2, ds1, peer}},
+		{"cache=edge1", []health.Event{edge1}},
+		{"cache=edge1,edge2", []health.Event{edge1, edge2}},
+		{"cachegroup=cg2", []health.Event{edge2}},
+		{"cache=edge1&cachegroup=cg2", nil},
+		{"deliveryservice=ds1", []health.Event{edge1, ds1}},
+		{"lastEventId=6", []health.Event{ds1, peer}},
+	}
+	for _, c := range cases {
+		params, _ := url.ParseQuery(c.Query)
+		filter, err := NewEventFilter(params, "")
+		if err != nil {
+			t.Fatalf("query '%s' - expected: nil error, actual: %v", c.Query, err)
+		}
+		actual := []health.Event{}
+		for _, e := range []health.Event{edge1, edge2, ds1, peer} {
+			if filter.UseEvent(e, toData) {
+				actual = append(actual, e)
+			}
+		}
+		if len(actual) != len(c.Expected) {
+			t.Errorf("query '%s' - expected: %+v, actual: %+v", c.Query, c.Expected, actual)
+			continue
+		}
+		for i := range actual {
+			if actual[i] != c.Expected[i] {
+				t.Errorf("query '%s' - expected: %+v, actual: %+v", c.Query, c.Expected, actual)
+				break
+			}
+		}
+	}
+
+	if _, err := NewEventFilter(url.Values{"bogus": []string{"1"}}, ""); err == nil {
+		t.Error("invalid query parameter - expected: error, actual: nil")
+	}
+	if _, err := NewEventFilter(url.Values{}, "not-a-number"); err == nil {
+		t.Error("invalid Last-Event-ID - expected: error, actual: nil")
+	}
+}
+
+func TestSrvEventStream(t *testing.T) {
+	toData := todata.NewThreadsafe()
+	for name, cacheType := range testEventStreamTOData().ServerTypes {
+		toData.Get().ServerTypes[name] = cacheType
+	}
+	events := health.NewThreadsafeEvents(10)
+	events.Add(health.Event{Name: "edge1", Type: "EDGE"})
+	events.Add(health.Event{Name: "edge2", Type: "EDGE"})
+
+	req := httptest.NewRequest(http.MethodGet, "/publish/EventStream?cache=edge1,edge2", nil)
+	req.Header.Set("Last-Event-ID", "0")
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		srvEventStream(w, req, threadsafe.NewUint(), toData, events, 200*time.Millisecond)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the event stream to end after its max duration")
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != ContentTypeEventStream {
+		t.Errorf("expected Content-Type %s, actual: %s", ContentTypeEventStream, ct)
+	}
+	body := w.Body.String()
+	if !strings.HasPrefix(body, "retry: ") {
+		t.Errorf("expected the stream to start with a retry time, actual: %s", body)
+	}
+	if strings.Contains(body, "id: 0\n") {
+		t.Errorf("expected events up to the Last-Event-ID not to be replayed, actual: %s", body)
+	}
+	if !strings.Contains(body, "id: 1\ndata: {") || !strings.Contains(body, `"name":"edge2"`) {
+		t.Errorf("expected the event after the Last-Event-ID to be replayed, actual: %s", body)
+	}
+}
+
+func TestEventStreamDuration(t *testing.T) {
+	if actual := eventStreamDuration(0); actual != 0 {
+		t.Errorf("no write timeout - expected: 0, actual: %v", actual)
+	}
+	if actual := eventStreamDuration(10 * time.Second); actual != 9*time.Second {
+		t.Errorf("10s write timeout - expected: 9s, actual: %v", actual)
+	}
+	if actual := eventStreamDuration(time.Second); actual != 500*time.Millisecond {
+		t.Errorf("1s write timeout - expected: 500ms, actual: %v", actual)
+	}
+}
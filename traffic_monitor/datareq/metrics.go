@@ -0,0 +1,238 @@
+package datareq
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/cache"
+	"github.com/apache/trafficcontrol/traffic_monitor/dsdata"
+	"github.com/apache/trafficcontrol/traffic_monitor/health"
+	"github.com/apache/trafficcontrol/traffic_monitor/peer"
+	"github.com/apache/trafficcontrol/traffic_monitor/threadsafe"
+	"github.com/apache/trafficcontrol/traffic_monitor/todata"
+)
+
+// PrometheusContentType is the Content-Type of the Prometheus text exposition
+// format.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metricsWriter writes metrics in the Prometheus text exposition format.
+type metricsWriter struct {
+	buf bytes.Buffer
+}
+
+// family writes the HELP and TYPE lines of a metric family.
+func (w *metricsWriter) family(name, metricType, help string) {
+	w.buf.WriteString("# HELP " + name + " " + help + "\n")
+	w.buf.WriteString("# TYPE " + name + " " + metricType + "\n")
+}
+
+// sample writes a sample of a metric, with the given label names and values
+// in alternating order.
+func (w *metricsWriter) sample(name string, value float64, labels ...string) {
+	w.buf.WriteString(name)
+	if len(labels) > 0 {
+		w.buf.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.buf.WriteByte(',')
+			}
+			w.buf.WriteString(labels[i] + `="` + escapeLabelValue(labels[i+1]) + `"`)
+		}
+		w.buf.WriteByte('}')
+	}
+	w.buf.WriteByte(' ')
+	w.buf.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	w.buf.WriteByte('\n')
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func srvMetrics(
+	toData todata.TODataThreadsafe,
+	combinedStates peer.CRStatesThreadsafe,
+	peerStates peer.CRStatesPeersThreadsafe,
+	healthHistory threadsafe.ResultHistory,
+	dsStats threadsafe.DSStatsReader,
+	events health.ThreadsafeEvents,
+	errorCount threadsafe.Uint,
+) []byte {
+	w := metricsWriter{}
+	writeCacheMetrics(&w, toData.Get(), combinedStates.GetCaches(), healthHistory.Get())
+	writeDeliveryServiceMetrics(&w, combinedStates.GetDeliveryServices(), dsStats.Get())
+	writePeerMetrics(&w, peerStates.GetCRStatesPeersInfo())
+
+	w.family("traffic_monitor_events_total", "counter", "Total number of health events recorded.")
+	w.sample("traffic_monitor_events_total", float64(events.Count()))
+	w.family("traffic_monitor_errors_total", "counter", "Total number of errors serving requests and polling.")
+	w.sample("traffic_monitor_errors_total", float64(errorCount.Get()))
+	return w.buf.Bytes()
+}
+
+func writeCacheMetrics(w *metricsWriter, toData todata.TOData, caches map[tc.CacheName]tc.IsAvailable, healthHistory cache.ResultHistory) {
+	names := make([]string, 0, len(caches))
+	for name := range caches {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	availability := []struct {
+		Name  string
+		Help  string
+		Value func(tc.IsAvailable) bool
+	}{
+		{"traffic_monitor_cache_available", "Whether the cache server is available, combined with peers.", func(a tc.IsAvailable) bool { return a.IsAvailable }},
+		{"traffic_monitor_cache_ipv4_available", "Whether the cache server is available over IPv4, combined with peers.", func(a tc.IsAvailable) bool { return a.Ipv4Available }},
+		{"traffic_monitor_cache_ipv6_available", "Whether the cache server is available over IPv6, combined with peers.", func(a tc.IsAvailable) bool { return a.Ipv6Available }},
+	}
+	for _, metric := range availability {
+		w.family(metric.Name, "gauge", metric.Help)
+		for _, name := range names {
+			cacheName := tc.CacheName(name)
+			w.sample(metric.Name, boolToFloat(metric.Value(caches[cacheName])),
+				"cache", name,
+				"cachegroup", string(toData.ServerCachegroups[cacheName]),
+				"type", string(toData.ServerTypes[cacheName]))
+		}
+	}
+
+	w.family("traffic_monitor_cache_interface_bandwidth_kbps", "gauge", "Outgoing bandwidth of the cache server interface in the latest health poll, in kilobits per second.")
+	for _, name := range names {
+		results := healthHistory[tc.CacheName(name)]
+		if len(results) < 1 {
+			continue
+		}
+		for _, iface := range sortedInterfaceNames(results[0].InterfaceVitals) {
+			w.sample("traffic_monitor_cache_interface_bandwidth_kbps", float64(results[0].InterfaceVitals[iface].KbpsOut), "cache", name, "interface", iface)
+		}
+	}
+	w.family("traffic_monitor_cache_interface_max_bandwidth_kbps", "gauge", "Maximum outgoing bandwidth of the cache server interface, in kilobits per second.")
+	for _, name := range names {
+		results := healthHistory[tc.CacheName(name)]
+		if len(results) < 1 {
+			continue
+		}
+		for _, iface := range sortedInterfaceNames(results[0].InterfaceVitals) {
+			w.sample("traffic_monitor_cache_interface_max_bandwidth_kbps", float64(results[0].InterfaceVitals[iface].MaxKbpsOut), "cache", name, "interface", iface)
+		}
+	}
+
+	w.family("traffic_monitor_cache_poll_duration_seconds", "gauge", "Duration of the latest health poll request to the cache server.")
+	for _, name := range names {
+		results := healthHistory[tc.CacheName(name)]
+		if len(results) < 1 {
+			continue
+		}
+		w.sample("traffic_monitor_cache_poll_duration_seconds", results[0].RequestTime.Seconds(), "cache", name)
+	}
+}
+
+func sortedInterfaceNames(vitals map[string]cache.Vitals) []string {
+	names := make([]string, 0, len(vitals))
+	for name := range vitals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeDeliveryServiceMetrics(w *metricsWriter, states map[tc.DeliveryServiceName]tc.CRStatesDeliveryService, stats dsdata.StatsReadonly) {
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	w.family("traffic_monitor_deliveryservice_available", "gauge", "Whether the delivery service is available.")
+	for _, name := range names {
+		w.sample("traffic_monitor_deliveryservice_available", boolToFloat(states[tc.DeliveryServiceName(name)].IsAvailable), "deliveryservice", name)
+	}
+
+	totals := map[string]*dsdata.StatCacheStats{}
+	for _, name := range names {
+		if stat, ok := stats.Get(tc.DeliveryServiceName(name)); ok {
+			totals[name] = stat.Total()
+		}
+	}
+	w.family("traffic_monitor_deliveryservice_kbps", "gauge", "Outgoing bandwidth of the delivery service across all caches, in kilobits per second.")
+	for _, name := range names {
+		if total := totals[name]; total != nil {
+			w.sample("traffic_monitor_deliveryservice_kbps", total.Kbps.Value, "deliveryservice", name)
+		}
+	}
+	w.family("traffic_monitor_deliveryservice_tps", "gauge", "Transactions per second of the delivery service across all caches, by response status class.")
+	for _, name := range names {
+		total := totals[name]
+		if total == nil {
+			continue
+		}
+		for _, class := range []struct {
+			Status string
+			TPS    float64
+		}{
+			{"2xx", total.Tps2xx.Value},
+			{"3xx", total.Tps3xx.Value},
+			{"4xx", total.Tps4xx.Value},
+			{"5xx", total.Tps5xx.Value},
+		} {
+			w.sample("traffic_monitor_deliveryservice_tps", class.TPS, "deliveryservice", name, "status", class.Status)
+		}
+	}
+}
+
+func writePeerMetrics(w *metricsWriter, peers peer.CRStatesPeersInfo) {
+	crStates := peers.GetCrStates()
+	names := make([]string, 0, len(crStates))
+	for name := range crStates {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	w.family("traffic_monitor_peer_available", "gauge", "Whether the peer Traffic Monitor is available.")
+	for _, name := range names {
+		w.sample("traffic_monitor_peer_available", boolToFloat(peers.GetPeerAvailability(tc.TrafficMonitorName(name))), "peer", name)
+	}
+	w.family("traffic_monitor_peer_caches_unavailable", "gauge", "Number of cache servers the peer Traffic Monitor reports as unavailable.")
+	for _, name := range names {
+		unavailable := 0
+		for _, state := range crStates[tc.TrafficMonitorName(name)].Caches {
+			if !state.IsAvailable {
+				unavailable++
+			}
+		}
+		w.sample("traffic_monitor_peer_caches_unavailable", float64(unavailable), "peer", name)
+	}
+}
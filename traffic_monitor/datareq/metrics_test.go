@@ -0,0 +1,101 @@
+package datareq
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/cache"
+	"github.com/apache/trafficcontrol/traffic_monitor/dsdata"
+	"github.com/apache/trafficcontrol/traffic_monitor/todata"
+)
+
+func TestWriteCacheMetrics(t *testing.T) {
+	toData := *todata.New()
+	toData.ServerCachegroups["edge1"] = "cg1"
+	toData.ServerTypes["edge1"] = "EDGE"
+	caches := map[tc.CacheName]tc.IsAvailable{
+		"edge1": {IsAvailable: true, Ipv4Available: true},
+		"edge2": {},
+	}
+	healthHistory := cache.ResultHistory{
+		"edge1": {{
+			RequestTime:     250 * time.Millisecond,
+			InterfaceVitals: map[string]cache.Vitals{"eth0": {KbpsOut: 1500, MaxKbpsOut: 10000000}},
+		}},
+	}
+
+	w := metricsWriter{}
+	writeCacheMetrics(&w, toData, caches, healthHistory)
+	actual := w.buf.String()
+	for _, expected := range []string{
+		"# TYPE traffic_monitor_cache_available gauge\n",
+		`traffic_monitor_cache_available{cache="edge1",cachegroup="cg1",type="EDGE"} 1` + "\n",
+		`traffic_monitor_cache_available{cache="edge2",cachegroup="",type=""} 0` + "\n",
+		`traffic_monitor_cache_ipv6_available{cache="edge1",cachegroup="cg1",type="EDGE"} 0` + "\n",
+		`traffic_monitor_cache_interface_bandwidth_kbps{cache="edge1",interface="eth0"} 1500` + "\n",
+		`traffic_monitor_cache_interface_max_bandwidth_kbps{cache="edge1",interface="eth0"} 1e+07` + "\n",
+		`traffic_monitor_cache_poll_duration_seconds{cache="edge1"} 0.25` + "\n",
+	} {
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expected metrics to contain '%s', actual: %s", strings.TrimSpace(expected), actual)
+		}
+	}
+}
+
+func TestWriteDeliveryServiceMetrics(t *testing.T) {
+	stats := dsdata.NewStats(1)
+	stat := dsdata.NewStat()
+	stat.TotalStats.Kbps.Value = 123.5
+	stat.TotalStats.Tps2xx.Value = 10
+	stat.TotalStats.Tps5xx.Value = 0.5
+	stats.DeliveryService["ds1"] = stat
+	states := map[tc.DeliveryServiceName]tc.CRStatesDeliveryService{
+		"ds1": {IsAvailable: true},
+		"ds2": {IsAvailable: false},
+	}
+
+	w := metricsWriter{}
+	writeDeliveryServiceMetrics(&w, states, *stats)
+	actual := w.buf.String()
+	for _, expected := range []string{
+		`traffic_monitor_deliveryservice_available{deliveryservice="ds1"} 1` + "\n",
+		`traffic_monitor_deliveryservice_available{deliveryservice="ds2"} 0` + "\n",
+		`traffic_monitor_deliveryservice_kbps{deliveryservice="ds1"} 123.5` + "\n",
+		`traffic_monitor_deliveryservice_tps{deliveryservice="ds1",status="2xx"} 10` + "\n",
+		`traffic_monitor_deliveryservice_tps{deliveryservice="ds1",status="5xx"} 0.5` + "\n",
+	} {
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expected metrics to contain '%s', actual: %s", strings.TrimSpace(expected), actual)
+		}
+	}
+	if strings.Contains(actual, `traffic_monitor_deliveryservice_kbps{deliveryservice="ds2"}`) {
+		t.Errorf("expected no kbps for a delivery service without stats, actual: %s", actual)
+	}
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	if actual := escapeLabelValue("a\"b\\c\nd"); actual != `a\"b\\c\nd` {
+		t.Errorf(`expected: a\"b\\c\nd, actual: %s`, actual)
+	}
+}
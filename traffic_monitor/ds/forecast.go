@@ -0,0 +1,398 @@
+package ds
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/cache"
+	"github.com/apache/trafficcontrol/traffic_monitor/dsdata"
+	"github.com/apache/trafficcontrol/traffic_monitor/todata"
+)
+
+// ForecastBucketDuration is the period of time whose samples are averaged into a single value of the forecast history.
+const ForecastBucketDuration = 5 * time.Minute
+
+// ForecastHistory is how long the forecast history is kept.
+const ForecastHistory = 7 * 24 * time.Hour
+
+// ForecastSeason is the period of the seasonal model. The history must be at least this long for the model to be seasonal.
+const ForecastSeason = 24 * time.Hour
+
+// ForecastHorizon is how far ahead the time to saturation is projected.
+const ForecastHorizon = 30 * 24 * time.Hour
+
+// CapacityForecasts keeps a rolling history of the bandwidth and capacity of each cache group and delivery service, and forecasts them.
+// It is safe for multiple goroutines, with a single writer.
+type CapacityForecasts struct {
+	m                *sync.RWMutex
+	cacheGroups      *map[tc.CacheGroupName]*forecastHistories
+	deliveryServices *map[tc.DeliveryServiceName]*forecastHistories
+}
+
+// forecastHistories are the bandwidth and capacity histories of a cache group or delivery service.
+type forecastHistories struct {
+	kbps         forecastHistory
+	capacityKbps forecastHistory
+}
+
+// forecastHistory is the history of a single stat, as the average of each bucket. Samples are averaged into the current bucket in place, and it's appended to the completed buckets when a sample of a later bucket is added, so a sample doesn't copy the history.
+type forecastHistory struct {
+	// buckets are the completed buckets, oldest first.
+	buckets []forecastBucket
+	// current is the bucket samples are being averaged into. Its Samples is 0 if there have been no samples.
+	current forecastBucket
+}
+
+// forecastBucket is the average of the samples of a stat within a ForecastBucketDuration.
+type forecastBucket struct {
+	// Time is the start of the bucket.
+	Time time.Time
+	// Val is the average of the samples.
+	Val float64
+	// Samples is the number of samples averaged into Val.
+	Samples uint64
+}
+
+// CapacityForecast is the forecast of the bandwidth of a cache group or delivery service.
+type CapacityForecast struct {
+	// Kbps is the average bandwidth of the latest bucket of the history.
+	Kbps float64 `json:"kbps"`
+	// CapacityKbps is the average capacity of the available caches of the latest bucket of the history.
+	CapacityKbps float64 `json:"capacityKbps"`
+	// HistoryHours is the length of the history the forecast was made from.
+	HistoryHours float64 `json:"historyHours"`
+	// Seasonal is whether the history is long enough for the model to include the daily pattern. If not, the forecast is only the trend.
+	Seasonal bool `json:"seasonal"`
+	// TrendKbpsPerDay is the change in bandwidth per day, not counting the daily pattern, or nil if the history is too short.
+	TrendKbpsPerDay *float64 `json:"trendKbpsPerDay"`
+	// ObservedPeak is the highest bucket of the last ForecastSeason.
+	ObservedPeak *ForecastPeak `json:"observedPeak"`
+	// ForecastPeak is the highest forecast bandwidth of the next ForecastSeason, or nil if the history is too short.
+	ForecastPeak *ForecastPeak `json:"forecastPeak"`
+	// SaturationTime is the first time within the ForecastHorizon the forecast bandwidth reaches the current capacity, or nil if it doesn't.
+	SaturationTime *time.Time `json:"saturationTime"`
+	// HoursToSaturation is the number of hours until the SaturationTime, or nil if there is none.
+	HoursToSaturation *float64 `json:"hoursToSaturation"`
+}
+
+// ForecastPeak is a peak of the bandwidth.
+type ForecastPeak struct {
+	Time time.Time `json:"time"`
+	Kbps float64   `json:"kbps"`
+	// Utilization is the ratio of the bandwidth to the current capacity, or nil if the capacity isn't known.
+	Utilization *float64 `json:"utilization"`
+}
+
+// NewCapacityForecasts creates a new, empty CapacityForecasts.
+func NewCapacityForecasts() CapacityForecasts {
+	return CapacityForecasts{
+		m:                &sync.RWMutex{},
+		cacheGroups:      &map[tc.CacheGroupName]*forecastHistories{},
+		deliveryServices: &map[tc.DeliveryServiceName]*forecastHistories{},
+	}
+}
+
+// Update adds the bandwidth and capacity of each cache group and delivery service to their histories, from the latest delivery service stats, cache states, and cache vitals in the stat history, which must be newest first.
+// The bandwidth of a cache group is that of all its caches, and its capacity that of its available caches. The capacity of a delivery service is that of its available caches, which may be shared with other delivery services.
+func (f CapacityForecasts) Update(dsStats *dsdata.Stats, crStates tc.CRStates, toData todata.TOData, statInfoHistory cache.ResultInfoHistory, now time.Time) {
+	type sample struct{ kbps, capacityKbps float64 }
+	addVitals := func(s *sample, cacheName tc.CacheName) bool {
+		infos := statInfoHistory[cacheName]
+		if len(infos) == 0 {
+			return false
+		}
+		s.kbps += float64(infos[0].Vitals.KbpsOut)
+		if crStates.Caches[cacheName].IsAvailable {
+			s.capacityKbps += float64(infos[0].Vitals.MaxKbpsOut)
+		}
+		return true
+	}
+
+	cgSamples := map[tc.CacheGroupName]*sample{}
+	for cacheName, cg := range toData.ServerCachegroups {
+		s, ok := cgSamples[cg]
+		if !ok {
+			s = &sample{}
+		}
+		if addVitals(s, cacheName) {
+			cgSamples[cg] = s
+		}
+	}
+
+	dsSamples := map[tc.DeliveryServiceName]*sample{}
+	for dsName, stat := range dsStats.DeliveryService {
+		s := &sample{kbps: stat.TotalStats.Kbps.Value}
+		for _, cacheName := range toData.DeliveryServiceServers[dsName] {
+			if infos := statInfoHistory[cacheName]; len(infos) > 0 && crStates.Caches[cacheName].IsAvailable {
+				s.capacityKbps += float64(infos[0].Vitals.MaxKbpsOut)
+			}
+		}
+		dsSamples[dsName] = s
+	}
+
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	for cg := range *f.cacheGroups {
+		if _, ok := cgSamples[cg]; !ok {
+			delete(*f.cacheGroups, cg)
+		}
+	}
+	for cg, s := range cgSamples {
+		histories, ok := (*f.cacheGroups)[cg]
+		if !ok {
+			histories = &forecastHistories{}
+			(*f.cacheGroups)[cg] = histories
+		}
+		histories.kbps.add(s.kbps, now)
+		histories.capacityKbps.add(s.capacityKbps, now)
+	}
+
+	for dsName := range *f.deliveryServices {
+		if _, ok := dsSamples[dsName]; !ok {
+			delete(*f.deliveryServices, dsName)
+		}
+	}
+	for dsName, s := range dsSamples {
+		histories, ok := (*f.deliveryServices)[dsName]
+		if !ok {
+			histories = &forecastHistories{}
+			(*f.deliveryServices)[dsName] = histories
+		}
+		histories.kbps.add(s.kbps, now)
+		histories.capacityKbps.add(s.capacityKbps, now)
+	}
+}
+
+// add averages the value into the history's bucket of the given time. When the time is in a later bucket than the current one, the current bucket is completed, and the buckets older than the ForecastHistory are removed.
+// It must be called with the CapacityForecasts mutex held for writing.
+func (h *forecastHistory) add(val float64, now time.Time) {
+	start := now.Truncate(ForecastBucketDuration)
+	if h.current.Samples > 0 && h.current.Time.Equal(start) {
+		h.current.Samples++
+		h.current.Val += (val - h.current.Val) / float64(h.current.Samples)
+		return
+	}
+	if h.current.Samples > 0 {
+		h.buckets = append(h.buckets, h.current)
+	}
+	h.current = forecastBucket{Time: start, Val: val, Samples: 1}
+
+	oldest := now.Add(-ForecastHistory)
+	expired := 0
+	for expired < len(h.buckets) && h.buckets[expired].Time.Before(oldest) {
+		expired++
+	}
+	if expired > 0 {
+		h.buckets = append(h.buckets[:0], h.buckets[expired:]...)
+	}
+}
+
+// newestFirst returns a copy of the history, including the current bucket, newest first.
+// It must be called with the CapacityForecasts mutex held.
+func (h *forecastHistory) newestFirst() []forecastBucket {
+	if h.current.Samples == 0 {
+		return nil
+	}
+	vals := make([]forecastBucket, 0, len(h.buckets)+1)
+	vals = append(vals, h.current)
+	for i := len(h.buckets) - 1; i >= 0; i-- {
+		vals = append(vals, h.buckets[i])
+	}
+	return vals
+}
+
+// Get returns the forecasts of the given cache groups and delivery services at the given time. If both cacheGroups and dses are empty, all of them are returned.
+func (f CapacityForecasts) Get(cacheGroups map[tc.CacheGroupName]struct{}, dses map[tc.DeliveryServiceName]struct{}, now time.Time) (map[tc.CacheGroupName]CapacityForecast, map[tc.DeliveryServiceName]CapacityForecast) {
+	f.m.RLock()
+	defer f.m.RUnlock()
+
+	all := len(cacheGroups) == 0 && len(dses) == 0
+	cgForecasts := map[tc.CacheGroupName]CapacityForecast{}
+	for cg, histories := range *f.cacheGroups {
+		if _, ok := cacheGroups[cg]; all || ok {
+			cgForecasts[cg] = forecast(histories.kbps.newestFirst(), histories.capacityKbps.newestFirst(), now)
+		}
+	}
+	dsForecasts := map[tc.DeliveryServiceName]CapacityForecast{}
+	for dsName, histories := range *f.deliveryServices {
+		if _, ok := dses[dsName]; all || ok {
+			dsForecasts[dsName] = forecast(histories.kbps.newestFirst(), histories.capacityKbps.newestFirst(), now)
+		}
+	}
+	return cgForecasts, dsForecasts
+}
+
+// forecastModel is a linear trend plus, if the history is at least a ForecastSeason long, the average daily deviation from the trend of each bucket of the day.
+type forecastModel struct {
+	start     time.Time
+	intercept float64
+	// slope is the change per hour.
+	slope    float64
+	seasonal []float64
+}
+
+// forecastModelIterations is the number of times the trend and seasonal components are alternately fit to the history without the other. A trend fit with the daily pattern in the history would be skewed by the part of the pattern at either end.
+const forecastModelIterations = 4
+
+// newForecastModel fits a model to the given history, which is newest first. It returns nil if the history has fewer than two buckets.
+func newForecastModel(history []forecastBucket) *forecastModel {
+	if len(history) < 2 {
+		return nil
+	}
+	model := &forecastModel{start: history[len(history)-1].Time}
+	seasonal := history[0].Time.Sub(model.start) >= ForecastSeason-ForecastBucketDuration
+	buckets := int(ForecastSeason / ForecastBucketDuration)
+
+	for i := 0; i < forecastModelIterations; i++ {
+		model.fitTrend(history)
+		if !seasonal {
+			break
+		}
+		sums := make([]float64, buckets)
+		counts := make([]int, buckets)
+		for _, v := range history {
+			y := v.Val
+			b := seasonalBucket(v.Time)
+			sums[b] += y - model.trend(v.Time)
+			counts[b]++
+		}
+		model.seasonal = make([]float64, buckets)
+		for b := range sums {
+			if counts[b] > 0 {
+				model.seasonal[b] = sums[b] / float64(counts[b])
+			}
+		}
+	}
+	return model
+}
+
+// fitTrend fits the trend to the history, less the seasonal component, by least squares.
+func (m *forecastModel) fitTrend(history []forecastBucket) {
+	n := float64(len(history))
+	sumX, sumY, sumXY, sumXX := 0.0, 0.0, 0.0, 0.0
+	for _, v := range history {
+		x := v.Time.Sub(m.start).Hours()
+		y := v.Val
+		if m.seasonal != nil {
+			y -= m.seasonal[seasonalBucket(v.Time)]
+		}
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	m.slope = 0
+	if denominator := n*sumXX - sumX*sumX; denominator != 0 {
+		m.slope = (n*sumXY - sumX*sumY) / denominator
+	}
+	m.intercept = (sumY - m.slope*sumX) / n
+}
+
+// seasonalBucket returns the index of the bucket of the season, i.e. of the day, of the given time.
+func seasonalBucket(t time.Time) int {
+	return int(t.Sub(t.Truncate(ForecastSeason)) / ForecastBucketDuration)
+}
+
+func (m *forecastModel) trend(t time.Time) float64 {
+	return m.intercept + m.slope*t.Sub(m.start).Hours()
+}
+
+func (m *forecastModel) predict(t time.Time) float64 {
+	val := m.trend(t)
+	if m.seasonal != nil {
+		val += m.seasonal[seasonalBucket(t)]
+	}
+	return math.Max(val, 0)
+}
+
+// forecast forecasts the bandwidth from the histories of the bandwidth and capacity, which are newest first.
+func forecast(kbpsHistory []forecastBucket, capacityHistory []forecastBucket, now time.Time) CapacityForecast {
+	fc := CapacityForecast{}
+	if len(kbpsHistory) == 0 {
+		return fc
+	}
+	fc.Kbps = kbpsHistory[0].Val
+	if len(capacityHistory) > 0 {
+		fc.CapacityKbps = capacityHistory[0].Val
+	}
+	fc.HistoryHours = kbpsHistory[0].Time.Add(ForecastBucketDuration).Sub(kbpsHistory[len(kbpsHistory)-1].Time).Hours()
+
+	utilization := func(kbps float64) *float64 {
+		if fc.CapacityKbps <= 0 {
+			return nil
+		}
+		u := kbps / fc.CapacityKbps
+		return &u
+	}
+
+	for _, v := range kbpsHistory {
+		if v.Time.Before(now.Add(-ForecastSeason)) {
+			break
+		}
+		if kbps := v.Val; fc.ObservedPeak == nil || kbps > fc.ObservedPeak.Kbps {
+			fc.ObservedPeak = &ForecastPeak{Time: v.Time, Kbps: kbps}
+		}
+	}
+	if fc.ObservedPeak != nil {
+		fc.ObservedPeak.Utilization = utilization(fc.ObservedPeak.Kbps)
+	}
+
+	model := newForecastModel(kbpsHistory)
+	if model == nil {
+		return fc
+	}
+	fc.Seasonal = model.seasonal != nil
+	trend := model.slope * 24
+	fc.TrendKbpsPerDay = &trend
+
+	first := now.Truncate(ForecastBucketDuration).Add(ForecastBucketDuration)
+	for t := first; t.Before(first.Add(ForecastSeason)); t = t.Add(ForecastBucketDuration) {
+		if kbps := model.predict(t); fc.ForecastPeak == nil || kbps > fc.ForecastPeak.Kbps {
+			fc.ForecastPeak = &ForecastPeak{Time: t, Kbps: kbps}
+		}
+	}
+	fc.ForecastPeak.Utilization = utilization(fc.ForecastPeak.Kbps)
+
+	if fc.CapacityKbps <= 0 {
+		return fc
+	}
+	saturation := time.Time{}
+	if fc.Kbps >= fc.CapacityKbps {
+		saturation = now
+	} else {
+		for t := first; !t.After(now.Add(ForecastHorizon)); t = t.Add(ForecastBucketDuration) {
+			if model.predict(t) >= fc.CapacityKbps {
+				saturation = t
+				break
+			}
+		}
+	}
+	if !saturation.IsZero() {
+		hours := saturation.Sub(now).Hours()
+		fc.SaturationTime = &saturation
+		fc.HoursToSaturation = &hours
+	}
+	return fc
+}
@@ -0,0 +1,125 @@
+package ds
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/cache"
+	"github.com/apache/trafficcontrol/traffic_monitor/dsdata"
+	"github.com/apache/trafficcontrol/traffic_monitor/todata"
+)
+
+func TestCapacityForecasts(t *testing.T) {
+	dsName := tc.DeliveryServiceName("ds0")
+	toData := todata.New()
+	toData.DeliveryServiceServers[dsName] = []tc.CacheName{"a0", "a1"}
+	toData.ServerCachegroups = map[tc.CacheName]tc.CacheGroupName{"a0": "cg-a", "a1": "cg-a"}
+
+	crStates := tc.NewCRStates(2, 0)
+	crStates.Caches["a0"] = tc.IsAvailable{IsAvailable: true}
+	crStates.Caches["a1"] = tc.IsAvailable{IsAvailable: true}
+
+	forecasts := NewCapacityForecasts()
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Each cache's bandwidth peaks daily at 06:00, and grows by 100kbps a day. At 2000kbps of capacity, the cache group saturates at the peak early in its seventh day.
+	kbpsAt := func(now time.Time) float64 {
+		elapsed := now.Sub(start).Hours()
+		return 300 + 100*math.Sin(2*math.Pi*elapsed/24) + 100*elapsed/24
+	}
+	now := start
+	update := func() {
+		kbps := kbpsAt(now)
+		infos := cache.ResultInfoHistory{
+			"a0": {{Vitals: cache.Vitals{KbpsOut: int64(kbps), MaxKbpsOut: 1000}}},
+			"a1": {{Vitals: cache.Vitals{KbpsOut: int64(kbps), MaxKbpsOut: 1000}}},
+		}
+		dsStats := dsdata.NewStats(1)
+		stat := dsdata.NewStat()
+		stat.TotalStats.Kbps.Value = 2 * kbps
+		dsStats.DeliveryService[dsName] = stat
+		forecasts.Update(dsStats, crStates, *toData, infos, now)
+	}
+
+	update()
+	cgForecasts, _ := forecasts.Get(nil, nil, now)
+	fc := cgForecasts["cg-a"]
+	if fc.ObservedPeak == nil || fc.TrendKbpsPerDay != nil || fc.ForecastPeak != nil {
+		t.Errorf("expected only an observed peak from a single sample, actual: %+v", fc)
+	}
+
+	for now = now.Add(time.Minute); now.Before(start.Add(3 * 24 * time.Hour)); now = now.Add(time.Minute) {
+		update()
+	}
+
+	cgForecasts, dsForecasts := forecasts.Get(nil, nil, now)
+	for name, fc := range map[string]CapacityForecast{"cg-a": cgForecasts["cg-a"], "ds0": dsForecasts[dsName]} {
+		if fc.CapacityKbps != 2000 {
+			t.Errorf("%s: expected capacity 2000, actual: %v", name, fc.CapacityKbps)
+		}
+		if math.Abs(fc.HistoryHours-72) > 0.1 {
+			t.Errorf("%s: expected 72 hours of history, actual: %v", name, fc.HistoryHours)
+		}
+		if !fc.Seasonal {
+			t.Errorf("%s: expected a seasonal forecast from 3 days of history", name)
+		}
+		if fc.TrendKbpsPerDay == nil || math.Abs(*fc.TrendKbpsPerDay-200) > 20 {
+			t.Errorf("%s: expected a trend of about 200kbps per day, actual: %v", name, fc.TrendKbpsPerDay)
+		}
+		if fc.ForecastPeak == nil {
+			t.Fatalf("%s: expected a forecast peak, actual: nil", name)
+		}
+		if hour := fc.ForecastPeak.Time.Hour(); hour < 5 || hour > 7 {
+			t.Errorf("%s: expected the forecast peak at about 06:00, actual: %v", name, fc.ForecastPeak.Time)
+		}
+		if expected := 2 * kbpsAt(fc.ForecastPeak.Time); math.Abs(fc.ForecastPeak.Kbps-expected) > 50 {
+			t.Errorf("%s: expected the forecast peak to be about %v, actual: %v", name, expected, fc.ForecastPeak.Kbps)
+		}
+		if fc.ForecastPeak.Utilization == nil || *fc.ForecastPeak.Utilization != fc.ForecastPeak.Kbps/2000 {
+			t.Errorf("%s: expected the forecast peak utilization to be relative to the capacity, actual: %v", name, fc.ForecastPeak.Utilization)
+		}
+		if fc.ObservedPeak == nil || fc.ObservedPeak.Time.Hour() < 5 || fc.ObservedPeak.Time.Hour() > 7 {
+			t.Errorf("%s: expected the observed peak at about 06:00, actual: %+v", name, fc.ObservedPeak)
+		}
+		if fc.HoursToSaturation == nil || *fc.HoursToSaturation < 72 || *fc.HoursToSaturation > 78 {
+			t.Errorf("%s: expected saturation early in the seventh day, actual: %v", name, fc.SaturationTime)
+		}
+	}
+
+	cgForecasts, dsForecasts = forecasts.Get(map[tc.CacheGroupName]struct{}{"cg-a": {}}, nil, now)
+	if len(cgForecasts) != 1 || len(dsForecasts) != 0 {
+		t.Errorf("expected only the forecast of cache group cg-a, actual: %v cache groups and %v delivery services", len(cgForecasts), len(dsForecasts))
+	}
+
+	// the history is kept compact
+	forecasts.m.RLock()
+	history := (*forecasts.cacheGroups)["cg-a"].kbps.newestFirst()
+	forecasts.m.RUnlock()
+	if expected := int(3 * 24 * time.Hour / ForecastBucketDuration); len(history) != expected {
+		t.Errorf("expected %d buckets of history, actual: %d", expected, len(history))
+	}
+	if history[0].Samples != uint64(ForecastBucketDuration/time.Minute) {
+		t.Errorf("expected the latest bucket to average %d samples, actual: %d", ForecastBucketDuration/time.Minute, history[0].Samples)
+	}
+}
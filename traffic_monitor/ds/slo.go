@@ -0,0 +1,340 @@
+package ds
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/cache"
+	"github.com/apache/trafficcontrol/traffic_monitor/dsdata"
+	"github.com/apache/trafficcontrol/traffic_monitor/todata"
+)
+
+// DefaultSLOTarget is the ratio of samples which must meet a delivery service's objectives, if its Profile doesn't give one.
+const DefaultSLOTarget = 0.999
+
+// DefaultSLOWindow is the rolling window over which compliance is computed, if a delivery service's Profile doesn't give one.
+const DefaultSLOWindow = 24 * time.Hour
+
+// SLOBucketDuration is the period of time whose samples are counted together. The rolling window moves in steps of this duration.
+const SLOBucketDuration = time.Minute
+
+// SLOBurnRateWindows are the windows over which error budget burn rates are reported. A short and a long window let alerts page quickly on fast burns, without paging on brief ones.
+var SLOBurnRateWindows = []time.Duration{5 * time.Minute, time.Hour}
+
+// SLOs tracks each delivery service's compliance with the service level objectives of its Profile, across stat polls.
+// It is safe for multiple goroutines.
+type SLOs struct {
+	m    *sync.RWMutex
+	dses *map[tc.DeliveryServiceName]*sloState
+}
+
+type sloState struct {
+	params  tc.TMDeliveryServiceParameters
+	current SLOSample
+	// buckets are the counts of samples in each SLOBucketDuration, oldest first.
+	buckets []sloBucket
+}
+
+type sloBucket struct {
+	start      time.Time
+	total      uint64
+	violations SLOViolations
+}
+
+// SLOViolations are the numbers of samples in which each objective wasn't met, and in which any objective wasn't met.
+type SLOViolations struct {
+	Any                    uint64 `json:"any"`
+	MinCacheGroupAvailable uint64 `json:"minCacheGroupAvailable"`
+	Max5xxRatio            uint64 `json:"max5xxRatio"`
+	MinHeadroom            uint64 `json:"minHeadroom"`
+}
+
+func (v *SLOViolations) add(o SLOViolations) {
+	v.Any += o.Any
+	v.MinCacheGroupAvailable += o.MinCacheGroupAvailable
+	v.Max5xxRatio += o.Max5xxRatio
+	v.MinHeadroom += o.MinHeadroom
+}
+
+// SLOSample is the state of a delivery service's service level indicators at a stat poll.
+type SLOSample struct {
+	Time time.Time `json:"time"`
+	// Compliant is whether every objective which could be evaluated was met.
+	Compliant bool `json:"compliant"`
+	// CacheGroupsBelowMin are the cache groups with fewer available caches than the objective.
+	CacheGroupsBelowMin []tc.CacheGroupName `json:"cacheGroupsBelowMin"`
+	// Ratio5xx is the ratio of 5xx responses to all responses, or nil if there were no responses.
+	Ratio5xx *float64 `json:"5xxRatio"`
+	// Headroom is the ratio of the unused bandwidth of the delivery service's available caches to the bandwidth of all its caches, or nil if the bandwidth of its caches isn't known.
+	Headroom *float64 `json:"headroom"`
+	// violations are the objectives this sample didn't meet.
+	violations SLOViolations
+	// evaluated is whether any objective could be evaluated. If not, the sample isn't counted.
+	evaluated bool
+}
+
+// SLOObjectives are a delivery service's service level objectives. An objective with a zero value isn't checked.
+type SLOObjectives struct {
+	MinCacheGroupAvailable int     `json:"minCacheGroupAvailable"`
+	Max5xxRatio            float64 `json:"max5xxRatio"`
+	MinHeadroom            float64 `json:"minHeadroom"`
+	Target                 float64 `json:"target"`
+	WindowMinutes          int     `json:"windowMinutes"`
+}
+
+// DSSLO is a delivery service's compliance with its service level objectives.
+type DSSLO struct {
+	Objectives SLOObjectives `json:"objectives"`
+	// Current is the latest sample of the delivery service's service level indicators.
+	Current SLOSample `json:"current"`
+	// Samples is the number of samples in the window in which an objective could be evaluated.
+	Samples uint64 `json:"samples"`
+	// Violations are the number of samples in the window in which objectives weren't met.
+	Violations SLOViolations `json:"violations"`
+	// Compliance is the ratio of samples in the window which met every objective, or nil if there are no samples.
+	Compliance *float64 `json:"compliance"`
+	// ErrorBudgetRemaining is the ratio of the error budget of the window - the ratio of samples allowed to violate the objectives by the target - which hasn't been used, or nil if there are no samples. It is negative once the budget is exhausted.
+	ErrorBudgetRemaining *float64 `json:"errorBudgetRemaining"`
+	// BurnRates are the rates at which the error budget was used over each of the SLOBurnRateWindows, by window, e.g. "5m0s". A rate of 1 uses exactly the budget over the whole window.
+	BurnRates map[string]float64 `json:"burnRates"`
+}
+
+// NewSLOs creates a new, empty SLOs.
+func NewSLOs() SLOs {
+	return SLOs{m: &sync.RWMutex{}, dses: &map[tc.DeliveryServiceName]*sloState{}}
+}
+
+// Update adds a sample of each delivery service with service level objectives, from the latest stats, cache states, and cache vitals in the stat history, which must be newest first.
+func (s SLOs) Update(dsStats *dsdata.Stats, crStates tc.CRStates, toData todata.TOData, mc tc.TrafficMonitorConfigMap, statInfoHistory cache.ResultInfoHistory, now time.Time) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for dsName := range *s.dses {
+		if !mc.DeliveryService[string(dsName)].Parameters.HasSLO() {
+			delete(*s.dses, dsName)
+		}
+	}
+
+	for dsNameStr, ds := range mc.DeliveryService {
+		params := ds.Parameters
+		if !params.HasSLO() {
+			continue
+		}
+		dsName := tc.DeliveryServiceName(dsNameStr)
+		state, ok := (*s.dses)[dsName]
+		if !ok || !sameObjectives(state.params, params) {
+			state = &sloState{}
+			(*s.dses)[dsName] = state
+		}
+		state.params = params
+
+		sample := evalSLOSample(params, dsStats.DeliveryService[dsName], toData.DeliveryServiceServers[dsName], toData.ServerCachegroups, crStates, statInfoHistory, now)
+		state.current = sample
+		if sample.evaluated {
+			start := now.Truncate(SLOBucketDuration)
+			if n := len(state.buckets); n == 0 || !state.buckets[n-1].start.Equal(start) {
+				state.buckets = append(state.buckets, sloBucket{start: start})
+			}
+			bucket := &state.buckets[len(state.buckets)-1]
+			bucket.total++
+			bucket.violations.add(sample.violations)
+		}
+		state.prune(now)
+	}
+}
+
+// sameObjectives returns whether the objectives of a and b are the same, and so their samples may be counted together. A changed target or window doesn't change the samples.
+func sameObjectives(a tc.TMDeliveryServiceParameters, b tc.TMDeliveryServiceParameters) bool {
+	return a.SLOMinCacheGroupAvailable == b.SLOMinCacheGroupAvailable && a.SLOMax5xxRatio == b.SLOMax5xxRatio && a.SLOMinHeadroom == b.SLOMinHeadroom
+}
+
+// prune removes the buckets which have left the window.
+func (s *sloState) prune(now time.Time) {
+	oldest := now.Add(-sloWindow(s.params)).Truncate(SLOBucketDuration)
+	i := 0
+	for i < len(s.buckets) && s.buckets[i].start.Before(oldest) {
+		i++
+	}
+	s.buckets = s.buckets[i:]
+}
+
+func sloTarget(params tc.TMDeliveryServiceParameters) float64 {
+	if params.SLOTarget <= 0 {
+		return DefaultSLOTarget
+	}
+	return params.SLOTarget
+}
+
+func sloWindow(params tc.TMDeliveryServiceParameters) time.Duration {
+	if params.SLOWindowMinutes <= 0 {
+		return DefaultSLOWindow
+	}
+	return time.Duration(params.SLOWindowMinutes) * time.Minute
+}
+
+// evalSLOSample evaluates the delivery service's objectives against its current stats, and the availability and vitals of its caches.
+func evalSLOSample(
+	params tc.TMDeliveryServiceParameters,
+	stat *dsdata.Stat,
+	dsCaches []tc.CacheName,
+	serverCachegroups map[tc.CacheName]tc.CacheGroupName,
+	crStates tc.CRStates,
+	statInfoHistory cache.ResultInfoHistory,
+	now time.Time,
+) SLOSample {
+	sample := SLOSample{Time: now, Compliant: true, CacheGroupsBelowMin: []tc.CacheGroupName{}}
+
+	if params.SLOMinCacheGroupAvailable > 0 && len(dsCaches) > 0 {
+		sample.evaluated = true
+		cgAvailable := map[tc.CacheGroupName]int{}
+		for _, cacheName := range dsCaches {
+			cg, ok := serverCachegroups[cacheName]
+			if !ok {
+				continue
+			}
+			available := cgAvailable[cg]
+			if crStates.Caches[cacheName].IsAvailable {
+				available++
+			}
+			cgAvailable[cg] = available
+		}
+		for cg, available := range cgAvailable {
+			if available < params.SLOMinCacheGroupAvailable {
+				sample.CacheGroupsBelowMin = append(sample.CacheGroupsBelowMin, cg)
+			}
+		}
+		sort.Slice(sample.CacheGroupsBelowMin, func(i, j int) bool { return sample.CacheGroupsBelowMin[i] < sample.CacheGroupsBelowMin[j] })
+		if len(sample.CacheGroupsBelowMin) > 0 {
+			sample.violations.MinCacheGroupAvailable = 1
+		}
+	}
+
+	if stat != nil && stat.TotalStats.TpsTotal.Value > 0 {
+		ratio := stat.TotalStats.Tps5xx.Value / stat.TotalStats.TpsTotal.Value
+		sample.Ratio5xx = &ratio
+		if params.SLOMax5xxRatio > 0 {
+			sample.evaluated = true
+			if ratio > params.SLOMax5xxRatio {
+				sample.violations.Max5xxRatio = 1
+			}
+		}
+	}
+
+	capacityKbps, unusedKbps := int64(0), int64(0)
+	for _, cacheName := range dsCaches {
+		infos := statInfoHistory[cacheName]
+		if len(infos) == 0 || infos[0].Vitals.MaxKbpsOut <= 0 {
+			continue
+		}
+		vitals := infos[0].Vitals
+		capacityKbps += vitals.MaxKbpsOut
+		if crStates.Caches[cacheName].IsAvailable && vitals.KbpsOut < vitals.MaxKbpsOut {
+			unusedKbps += vitals.MaxKbpsOut - vitals.KbpsOut
+		}
+	}
+	if capacityKbps > 0 {
+		headroom := float64(unusedKbps) / float64(capacityKbps)
+		sample.Headroom = &headroom
+		if params.SLOMinHeadroom > 0 {
+			sample.evaluated = true
+			if headroom < params.SLOMinHeadroom {
+				sample.violations.MinHeadroom = 1
+			}
+		}
+	}
+
+	if sample.violations.MinCacheGroupAvailable > 0 || sample.violations.Max5xxRatio > 0 || sample.violations.MinHeadroom > 0 {
+		sample.violations.Any = 1
+		sample.Compliant = false
+	}
+	return sample
+}
+
+// Get returns the compliance of each delivery service with service level objectives at the given time. If dses isn't empty, only the delivery services in it are returned.
+func (s SLOs) Get(dses map[tc.DeliveryServiceName]struct{}, now time.Time) map[tc.DeliveryServiceName]DSSLO {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	slos := make(map[tc.DeliveryServiceName]DSSLO, len(*s.dses))
+	for dsName, state := range *s.dses {
+		if _, ok := dses[dsName]; len(dses) > 0 && !ok {
+			continue
+		}
+		slos[dsName] = state.report(now)
+	}
+	return slos
+}
+
+// report computes the delivery service's compliance over its window, and its error budget burn rates, from its buckets.
+func (s *sloState) report(now time.Time) DSSLO {
+	target := sloTarget(s.params)
+	window := sloWindow(s.params)
+	budget := 1 - target
+
+	slo := DSSLO{
+		Objectives: SLOObjectives{
+			MinCacheGroupAvailable: s.params.SLOMinCacheGroupAvailable,
+			Max5xxRatio:            s.params.SLOMax5xxRatio,
+			MinHeadroom:            s.params.SLOMinHeadroom,
+			Target:                 target,
+			WindowMinutes:          int(window / time.Minute),
+		},
+		Current:   s.current,
+		BurnRates: make(map[string]float64, len(SLOBurnRateWindows)),
+	}
+
+	oldest := now.Add(-window).Truncate(SLOBucketDuration)
+	for _, bucket := range s.buckets {
+		if bucket.start.Before(oldest) {
+			continue
+		}
+		slo.Samples += bucket.total
+		slo.Violations.add(bucket.violations)
+	}
+	if slo.Samples > 0 {
+		violationRatio := float64(slo.Violations.Any) / float64(slo.Samples)
+		compliance := 1 - violationRatio
+		remaining := 1 - violationRatio/budget
+		slo.Compliance = &compliance
+		slo.ErrorBudgetRemaining = &remaining
+	}
+
+	for _, burnWindow := range SLOBurnRateWindows {
+		total, violations := uint64(0), uint64(0)
+		oldest := now.Add(-burnWindow).Truncate(SLOBucketDuration)
+		for _, bucket := range s.buckets {
+			if bucket.start.Before(oldest) {
+				continue
+			}
+			total += bucket.total
+			violations += bucket.violations.Any
+		}
+		rate := 0.0
+		if total > 0 {
+			rate = float64(violations) / float64(total) / budget
+		}
+		slo.BurnRates[burnWindow.String()] = rate
+	}
+	return slo
+}
@@ -0,0 +1,138 @@
+package ds
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/cache"
+	"github.com/apache/trafficcontrol/traffic_monitor/dsdata"
+	"github.com/apache/trafficcontrol/traffic_monitor/todata"
+)
+
+func TestSLOs(t *testing.T) {
+	dsName := tc.DeliveryServiceName("ds0")
+	mc := tc.TrafficMonitorConfigMap{DeliveryService: map[string]tc.TMDeliveryService{
+		"ds0": {XMLID: "ds0", Parameters: tc.TMDeliveryServiceParameters{SLOMinCacheGroupAvailable: 2, SLOMax5xxRatio: 0.01, SLOMinHeadroom: 0.25, SLOTarget: 0.9, SLOWindowMinutes: 60}},
+		"ds1": {XMLID: "ds1"},
+	}}
+	toData := todata.New()
+	toData.DeliveryServiceServers[dsName] = []tc.CacheName{"a0", "a1", "b0", "b1"}
+	toData.ServerCachegroups = map[tc.CacheName]tc.CacheGroupName{"a0": "cg-a", "a1": "cg-a", "b0": "cg-b", "b1": "cg-b"}
+
+	crStates := tc.NewCRStates(4, 0)
+	for _, cacheName := range toData.DeliveryServiceServers[dsName] {
+		crStates.Caches[cacheName] = tc.IsAvailable{IsAvailable: true}
+	}
+	infos := cache.ResultInfoHistory{}
+	for _, cacheName := range toData.DeliveryServiceServers[dsName] {
+		infos[cacheName] = []cache.ResultInfo{{Vitals: cache.Vitals{KbpsOut: 500, MaxKbpsOut: 1000}}}
+	}
+	dsStats := dsdata.NewStats(1)
+	stat := dsdata.NewStat()
+	stat.TotalStats.TpsTotal.Value = 1000
+	stat.TotalStats.Tps5xx.Value = 5
+	dsStats.DeliveryService[dsName] = stat
+
+	slos := NewSLOs()
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 50 compliant minutes, then 10 minutes with cache group b down to one cache, and 5xx responses over the objective.
+	for i := 0; i < 50; i++ {
+		slos.Update(dsStats, crStates, *toData, mc, infos, start.Add(time.Duration(i)*time.Minute))
+	}
+	crStates.Caches["b1"] = tc.IsAvailable{IsAvailable: false}
+	stat.TotalStats.Tps5xx.Value = 20
+	for i := 50; i < 60; i++ {
+		slos.Update(dsStats, crStates, *toData, mc, infos, start.Add(time.Duration(i)*time.Minute))
+	}
+	now := start.Add(59 * time.Minute)
+
+	all := slos.Get(nil, now)
+	if len(all) != 1 {
+		t.Fatalf("expected only the delivery service with objectives, actual %+v", all)
+	}
+	slo := all[dsName]
+	if slo.Current.Compliant {
+		t.Error("expected current sample not to be compliant")
+	}
+	if len(slo.Current.CacheGroupsBelowMin) != 1 || slo.Current.CacheGroupsBelowMin[0] != "cg-b" {
+		t.Errorf("expected cache group cg-b below min, actual %v", slo.Current.CacheGroupsBelowMin)
+	}
+	if slo.Current.Ratio5xx == nil || *slo.Current.Ratio5xx != 0.02 {
+		t.Errorf("expected 5xx ratio 0.02, actual %v", slo.Current.Ratio5xx)
+	}
+	// 3 available caches with 500kbps unused, of 4 caches with 1000kbps
+	if slo.Current.Headroom == nil || *slo.Current.Headroom != 0.375 {
+		t.Errorf("expected headroom 0.375, actual %v", slo.Current.Headroom)
+	}
+	if slo.Samples != 60 {
+		t.Errorf("expected 60 samples, actual %v", slo.Samples)
+	}
+	expectedViolations := SLOViolations{Any: 10, MinCacheGroupAvailable: 10, Max5xxRatio: 10}
+	if slo.Violations != expectedViolations {
+		t.Errorf("expected violations %+v, actual %+v", expectedViolations, slo.Violations)
+	}
+	if slo.Compliance == nil || !floatEqual(*slo.Compliance, 50.0/60.0) {
+		t.Errorf("expected compliance 50/60, actual %v", slo.Compliance)
+	}
+	// 1/6 of samples violated an objective, of a budget of 1/10.
+	if slo.ErrorBudgetRemaining == nil || !floatEqual(*slo.ErrorBudgetRemaining, 1-(10.0/60.0)/0.1) {
+		t.Errorf("expected error budget remaining -2/3, actual %v", slo.ErrorBudgetRemaining)
+	}
+	if rate := slo.BurnRates["5m0s"]; !floatEqual(rate, 10) {
+		t.Errorf("expected 5m burn rate 10, actual %v", rate)
+	}
+	if rate := slo.BurnRates["1h0m0s"]; !floatEqual(rate, (10.0/60.0)/0.1) {
+		t.Errorf("expected 1h burn rate 5/3, actual %v", rate)
+	}
+
+	// the compliant samples leave the window
+	later := start.Add(110 * time.Minute)
+	slos.Update(dsStats, crStates, *toData, mc, infos, later)
+	if slo := slos.Get(nil, later)[dsName]; slo.Samples != 11 || slo.Violations.Any != 11 {
+		t.Errorf("expected only the 11 violating samples in the window, actual %v samples, %+v violations", slo.Samples, slo.Violations)
+	}
+
+	if filtered := slos.Get(map[tc.DeliveryServiceName]struct{}{"ds1": {}}, later); len(filtered) != 0 {
+		t.Errorf("expected no delivery services in filter without objectives, actual %+v", filtered)
+	}
+
+	// changed objectives discard the samples
+	mc.DeliveryService["ds0"] = tc.TMDeliveryService{XMLID: "ds0", Parameters: tc.TMDeliveryServiceParameters{SLOMinCacheGroupAvailable: 1}}
+	slos.Update(dsStats, crStates, *toData, mc, infos, later.Add(time.Minute))
+	if slo := slos.Get(nil, later.Add(time.Minute))[dsName]; slo.Samples != 1 || slo.Violations.Any != 0 || slo.Objectives.Target != DefaultSLOTarget {
+		t.Errorf("expected one compliant sample with the default target after objectives changed, actual %+v", slo)
+	}
+
+	// removed objectives remove the delivery service
+	mc.DeliveryService["ds0"] = tc.TMDeliveryService{XMLID: "ds0"}
+	slos.Update(dsStats, crStates, *toData, mc, infos, later.Add(2*time.Minute))
+	if all := slos.Get(nil, later.Add(2*time.Minute)); len(all) != 0 {
+		t.Errorf("expected no delivery services after objectives removed, actual %+v", all)
+	}
+}
+
+func floatEqual(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
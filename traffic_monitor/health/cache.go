@@ -87,19 +87,29 @@ func GetVitals(newResult *cache.Result, prevResult *cache.Result, mc *tc.Traffic
 		return
 	}
 
+	bonds := mc.Profile[ts.Profile].Parameters.Bonds
 	for _, monitoredInterface := range monitoredInterfaces {
 		ifaceName := monitoredInterface.Name
 		iface, exists := newResult.Interfaces()[ifaceName]
-		if !exists {
+
+		var ifaceVitals cache.Vitals
+		if members := bonds[ifaceName]; len(members) > 0 {
+			bondVitals, ok := getBondVitals(iface, exists, members, newResult.Interfaces())
+			if !ok {
+				log.Warnf("monitored bonded interface %v and its members %v do not exist in cache %v", ifaceName, members, newResult.ID)
+				continue
+			}
+			ifaceVitals = bondVitals
+		} else if !exists {
 			// monitored interface doesn't exist in Result interfaces, skip
 			log.Warnf("monitored interface %v does not exist in cache %v", ifaceName, newResult.ID)
 			continue
-		}
-
-		ifaceVitals := cache.Vitals{
-			BytesIn:    iface.BytesIn,
-			BytesOut:   iface.BytesOut,
-			MaxKbpsOut: iface.Speed * 1000,
+		} else {
+			ifaceVitals = cache.Vitals{
+				BytesIn:    iface.BytesIn,
+				BytesOut:   iface.BytesOut,
+				MaxKbpsOut: iface.Speed * 1000,
+			}
 		}
 
 		if prevResult != nil && prevResult.InterfaceVitals != nil && prevResult.InterfaceVitals[ifaceName].BytesOut != 0 {
@@ -109,10 +119,10 @@ func GetVitals(newResult *cache.Result, prevResult *cache.Result, mc *tc.Traffic
 		newResult.InterfaceVitals[ifaceName] = ifaceVitals
 
 		// Overflow possible
-		newResult.Vitals.BytesOut += iface.BytesOut
-		newResult.Vitals.BytesIn += iface.BytesIn
+		newResult.Vitals.BytesOut += ifaceVitals.BytesOut
+		newResult.Vitals.BytesIn += ifaceVitals.BytesIn
 		// TODO JvD: Should we really be running this code every second for every cache polled????? I don't think so.
-		newResult.Vitals.MaxKbpsOut += iface.Speed * 1000
+		newResult.Vitals.MaxKbpsOut += ifaceVitals.MaxKbpsOut
 	}
 
 	if prevResult != nil && prevResult.Vitals.BytesOut != 0 {
@@ -122,6 +132,49 @@ func GetVitals(newResult *cache.Result, prevResult *cache.Result, mc *tc.Traffic
 
 }
 
+// getBondVitals returns the vitals of a bonded interface with the given member
+// links, from the polled interfaces. Its capacity is the sum of the speeds of
+// its members which are up, so it's reduced when members go down; members
+// which aren't in the polled interfaces, or have no speed, are down. If the
+// bonded interface itself isn't in the polled interfaces, its bytes are the
+// sum of its members'.
+//
+// If none of the members are in the polled interfaces, their states can't be
+// known, and the bonded interface's vitals are returned as if it weren't
+// bonded. If neither it nor its members are, this returns false.
+func getBondVitals(bond cache.Interface, bondExists bool, members []string, interfaces map[string]cache.Interface) (cache.Vitals, bool) {
+	vitals := cache.Vitals{BondMembers: len(members)}
+	polledMembers := 0
+	down := []string{}
+	for _, name := range members {
+		member, ok := interfaces[name]
+		if ok {
+			polledMembers++
+			vitals.BytesIn += member.BytesIn
+			vitals.BytesOut += member.BytesOut
+		}
+		if !ok || member.Speed <= 0 {
+			down = append(down, name)
+			continue
+		}
+		vitals.BondMembersUp++
+		vitals.MaxKbpsOut += member.Speed * 1000
+	}
+	vitals.BondMembersDown = strings.Join(down, ",")
+
+	if polledMembers == 0 {
+		if !bondExists {
+			return cache.Vitals{}, false
+		}
+		return cache.Vitals{BytesIn: bond.BytesIn, BytesOut: bond.BytesOut, MaxKbpsOut: bond.Speed * 1000}, true
+	}
+	if bondExists {
+		vitals.BytesIn = bond.BytesIn
+		vitals.BytesOut = bond.BytesOut
+	}
+	return vitals, true
+}
+
 // EvalCacheWithStatusInfo evaluates whether the given cache should be marked
 // available, taking the server's configured Status into account as well as its
 // polling information.
@@ -170,11 +223,19 @@ func EvalInterface(infVitals map[string]cache.Vitals, inf tc.ServerInterfaceInfo
 		return false, "not found in polled data"
 	}
 
+	if vitals.BondMembers > 0 && vitals.BondMembersUp == 0 {
+		return false, "all bond members down"
+	}
+
 	if inf.MaxBandwidth == nil {
 		return true, ""
 	}
 
-	if *inf.MaxBandwidth < uint64(vitals.KbpsOut) {
+	// A bonded interface's maximum bandwidth is reduced by its members which are down.
+	if maxBandwidth := vitals.BondCapacity(*inf.MaxBandwidth); maxBandwidth < uint64(vitals.KbpsOut) {
+		if maxBandwidth < *inf.MaxBandwidth {
+			return false, fmt.Sprintf("maximum bandwidth exceeded (reduced to %d kbps with bond members %s down)", maxBandwidth, vitals.BondMembersDown)
+		}
 		return false, "maximum bandwidth exceeded"
 	}
 
@@ -209,24 +270,8 @@ func EvalAggregate(result cache.ResultInfo, resultStats *threadsafe.ResultStatVa
 	computedStats := cache.ComputedStats()
 
 	for stat, threshold := range profile.Parameters.Thresholds {
-		resultStat := interface{}(nil)
-		computedStatF, ok := computedStats[stat]
+		resultStatNum, ok := thresholdStat(stat, computedStats, result, serverInfo, profile, resultStats)
 		if !ok {
-			if resultStats == nil {
-				continue
-			}
-			resultStatHistory := resultStats.Load(stat)
-			if len(resultStatHistory) == 0 {
-				continue
-			}
-			resultStat = resultStatHistory[0].Val
-		} else {
-			resultStat = computedStatF(result, serverInfo, profile, dummyCombinedState)
-		}
-
-		resultStatNum, ok := util.ToNumeric(resultStat)
-		if !ok {
-			log.Errorf("health.EvalCache threshold stat %s was not a number: %v", stat, resultStat)
 			continue
 		}
 
@@ -238,6 +283,33 @@ func EvalAggregate(result cache.ResultInfo, resultStats *threadsafe.ResultStatVa
 	return avail, eventDescVal, eventMsg
 }
 
+// thresholdStat returns the numeric value of the given stat for a threshold,
+// either computed from the result or the latest in the resultStats, which may
+// be nil. It returns false if the stat doesn't exist or isn't a number.
+func thresholdStat(stat string, computedStats map[string]cache.StatComputeFunc, result cache.ResultInfo, serverInfo tc.TrafficServer, profile tc.TMProfile, resultStats *threadsafe.ResultStatValHistory) (float64, bool) {
+	resultStat := interface{}(nil)
+	computedStatF, ok := computedStats[stat]
+	if !ok {
+		if resultStats == nil {
+			return 0, false
+		}
+		resultStatHistory := resultStats.Load(stat)
+		if len(resultStatHistory) == 0 {
+			return 0, false
+		}
+		resultStat = resultStatHistory[0].Val
+	} else {
+		resultStat = computedStatF(result, serverInfo, profile, dummyCombinedState)
+	}
+
+	resultStatNum, ok := util.ToNumeric(resultStat)
+	if !ok {
+		log.Errorf("health.EvalCache threshold stat %s was not a number: %v", stat, resultStat)
+		return 0, false
+	}
+	return resultStatNum, true
+}
+
 // getProcessAvailableTuple gets a function to process an availability tuple
 // based on the protocol used.
 func getProcessAvailableTuple(protocol config.PollingProtocol) func(cache.AvailableTuple, tc.TrafficServer) bool {
@@ -267,7 +339,8 @@ func getProcessAvailableTuple(protocol config.PollingProtocol) func(cache.Availa
 
 // CalcAvailability calculates the availability of each cache in results.
 // statResultHistory may be nil, in which case stats won't be used to calculate
-// availability.
+// availability. The health threshold expressions of each cache's Profile are
+// evaluated with, and update, the expressionStates.
 func CalcAvailability(
 	results []cache.Result,
 	pollerName string,
@@ -278,6 +351,7 @@ func CalcAvailability(
 	localStates peer.CRStatesThreadsafe,
 	events ThreadsafeEvents,
 	protocol config.PollingProtocol,
+	expressionStates ExpressionStates,
 ) {
 	localCacheStatuses := localCacheStatusThreadsafe.Get().Copy()
 	var statResultsVal *threadsafe.CacheStatHistory
@@ -334,10 +408,19 @@ func CalcAvailability(
 		var aggWhyAvailable string
 		var aggUnavailableStat string
 
+		var resultStats *threadsafe.ResultStatValHistory
 		if statResultsVal != nil {
-			aggIsAvailable, aggWhyAvailable, aggUnavailableStat = EvalAggregate(cache.ToInfo(result), &statResultsVal.Stats, &mc)
-		} else {
-			aggIsAvailable, aggWhyAvailable, aggUnavailableStat = EvalAggregate(cache.ToInfo(result), nil, &mc)
+			resultStats = &statResultsVal.Stats
+		}
+		aggIsAvailable, aggWhyAvailable, aggUnavailableStat = EvalAggregate(resultInfo, resultStats, &mc)
+
+		// Like thresholds, expressions don't apply to ONLINE caches. They're evaluated even when a threshold has made the cache unavailable, so their state keeps following the cache, but a threshold's reason takes precedence.
+		if tc.CacheStatusFromString(serverInfo.ServerStatus) != tc.CacheStatusOnline {
+			if exprAvailable, exprWhy, exprName := expressionStates.Eval(resultInfo, pollerName, resultStats, &mc); !exprAvailable && aggIsAvailable {
+				aggIsAvailable = false
+				aggWhyAvailable = eventDesc(tc.CacheStatusFromString(serverInfo.ServerStatus), exprWhy)
+				aggUnavailableStat = exprName
+			}
 		}
 
 		if result.UsingIPv4 {
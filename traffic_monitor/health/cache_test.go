@@ -428,10 +428,10 @@ func TestCalcAvailabilityThresholds(t *testing.T) {
 	original := results[0].Statistics.Interfaces
 	statResultHistory := (*threadsafe.ResultStatHistory)(nil)
 	results[0].Statistics.Interfaces = make(map[string]cache.Interface)
-	CalcAvailability(results, pollerName, statResultHistory, mc, toData, localCacheStatusThreadsafe, localStates, events, config.Both)
+	CalcAvailability(results, pollerName, statResultHistory, mc, toData, localCacheStatusThreadsafe, localStates, events, config.Both, NewExpressionStates())
 	results[0].Statistics.Interfaces = original
 
-	CalcAvailability(results, pollerName, statResultHistory, mc, toData, localCacheStatusThreadsafe, localStates, events, config.Both)
+	CalcAvailability(results, pollerName, statResultHistory, mc, toData, localCacheStatusThreadsafe, localStates, events, config.Both, NewExpressionStates())
 
 	// ensure that the DisabledLocations is an empty, non-nil slice
 	for _, ds := range localStates.GetDeliveryServices() {
@@ -478,7 +478,7 @@ func TestCalcAvailabilityThresholds(t *testing.T) {
 	GetVitals(&healthResult, &result, nil)
 	healthPollerName := "health"
 	healthResults := []cache.Result{healthResult}
-	CalcAvailability(healthResults, healthPollerName, nil, mc, toData, localCacheStatusThreadsafe, localStates, events, config.Both)
+	CalcAvailability(healthResults, healthPollerName, nil, mc, toData, localCacheStatusThreadsafe, localStates, events, config.Both, NewExpressionStates())
 
 	localCacheStatuses = localCacheStatusThreadsafe.Get()
 	if _, ok := localCacheStatuses[result.ID]; !ok {
@@ -575,3 +575,93 @@ func TestEvalInterface(t *testing.T) {
 		t.Errorf("Incorrect reason for interface exceeding threshold to be unavailable; expected: 'maximum bandwidth exceeded', got: '%s'", why)
 	}
 }
+
+func TestBondedInterfaceGetVitals(t *testing.T) {
+	serverID := "bonded"
+	tmcm := tc.TrafficMonitorConfigMap{
+		TrafficServer: map[string]tc.TrafficServer{
+			serverID: {
+				Profile: "bondedProfile",
+				Interfaces: []tc.ServerInterfaceInfo{
+					{Name: "bond0", Monitor: true},
+					{Name: "bond1", Monitor: true},
+				},
+			},
+		},
+		Profile: map[string]tc.TMProfile{
+			"bondedProfile": {
+				Parameters: tc.TMParameters{
+					Bonds: map[string][]string{
+						"bond0": {"p1p1", "p1p2"},
+						"bond1": {"p3p1", "p3p2"},
+					},
+				},
+			},
+		},
+	}
+
+	// bond0 is polled along with its members, one of which is down; only bond1's members are polled
+	result := cache.Result{
+		ID: serverID,
+		Statistics: cache.Statistics{
+			Interfaces: map[string]cache.Interface{
+				"bond0": {Speed: 20000, BytesIn: 300, BytesOut: 3000},
+				"p1p1":  {Speed: 10000, BytesIn: 300, BytesOut: 3000},
+				"p1p2":  {Speed: -1},
+				"p3p1":  {Speed: 10000, BytesIn: 100, BytesOut: 1000},
+				"p3p2":  {Speed: 10000, BytesIn: 200, BytesOut: 2000},
+			},
+		},
+		Time: time.Now(),
+	}
+	GetVitals(&result, nil, &tmcm)
+
+	expectedBond0 := cache.Vitals{BytesIn: 300, BytesOut: 3000, MaxKbpsOut: 10000000, BondMembers: 2, BondMembersUp: 1, BondMembersDown: "p1p2"}
+	if actual := result.InterfaceVitals["bond0"]; actual != expectedBond0 {
+		t.Errorf("Expected bond0 with a member down to have vitals %+v, actual: %+v", expectedBond0, actual)
+	}
+	expectedBond1 := cache.Vitals{BytesIn: 300, BytesOut: 3000, MaxKbpsOut: 20000000, BondMembers: 2, BondMembersUp: 2}
+	if actual := result.InterfaceVitals["bond1"]; actual != expectedBond1 {
+		t.Errorf("Expected bond1 to have the sum of its members' vitals %+v, actual: %+v", expectedBond1, actual)
+	}
+	if result.Vitals.MaxKbpsOut != 30000000 {
+		t.Errorf("Expected the cache's capacity to be reduced by the member down to %d, actual: %d", 30000000, result.Vitals.MaxKbpsOut)
+	}
+
+	// without member stats, the bond is monitored as if it weren't bonded
+	delete(result.Statistics.Interfaces, "p1p1")
+	delete(result.Statistics.Interfaces, "p1p2")
+	result.InterfaceVitals = nil
+	result.Vitals = cache.Vitals{}
+	GetVitals(&result, nil, &tmcm)
+	expectedBond0 = cache.Vitals{BytesIn: 300, BytesOut: 3000, MaxKbpsOut: 20000000}
+	if actual := result.InterfaceVitals["bond0"]; actual != expectedBond0 {
+		t.Errorf("Expected bond0 without member stats to have vitals %+v, actual: %+v", expectedBond0, actual)
+	}
+}
+
+func TestEvalBondedInterface(t *testing.T) {
+	maxKbps := uint64(200)
+	inf := tc.ServerInterfaceInfo{Name: "bond0", Monitor: true, MaxBandwidth: &maxKbps}
+
+	vitals := map[string]cache.Vitals{
+		"bond0": {KbpsOut: 150, BondMembers: 2, BondMembersUp: 2},
+	}
+	if available, why := EvalInterface(vitals, inf); !available {
+		t.Errorf("Expected bond with all members up within threshold to be available, but it wasn't: %s", why)
+	}
+
+	vitals["bond0"] = cache.Vitals{KbpsOut: 150, BondMembers: 2, BondMembersUp: 1, BondMembersDown: "p1p2"}
+	available, why := EvalInterface(vitals, inf)
+	if available {
+		t.Error("Expected bond exceeding its threshold reduced by a member down to be unavailable, but it wasn't")
+	}
+	if expected := "maximum bandwidth exceeded (reduced to 100 kbps with bond members p1p2 down)"; why != expected {
+		t.Errorf("Incorrect reason for bond exceeding reduced threshold to be unavailable; expected: '%s', got: '%s'", expected, why)
+	}
+
+	vitals["bond0"] = cache.Vitals{KbpsOut: 0, BondMembers: 2, BondMembersDown: "p1p1,p1p2"}
+	if available, why = EvalInterface(vitals, inf); available || why != "all bond members down" {
+		t.Errorf("Expected bond with all members down to be unavailable with reason 'all bond members down', got: %v '%s'", available, why)
+	}
+}
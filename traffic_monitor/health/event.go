@@ -63,12 +63,18 @@ type Event struct {
 
 // Events provides safe access for multiple goroutines readers and a single writer to a stored Events slice.
 type ThreadsafeEvents struct {
-	events    *[]Event
-	m         *sync.RWMutex
-	nextIndex *uint64
-	max       uint64
+	events      *[]Event
+	m           *sync.RWMutex
+	nextIndex   *uint64
+	max         uint64
+	subscribers *map[uint64]chan Event
+	nextSubID   *uint64
+	store       *EventStore
 }
 
+// SubscriptionBufferSize is the number of events which may be queued for a subscriber before it is considered too slow, and its subscription is closed.
+const SubscriptionBufferSize = 256
+
 func copyEvents(a []Event) []Event {
 	b := make([]Event, len(a), len(a))
 	copy(b, a)
@@ -78,7 +84,21 @@ func copyEvents(a []Event) []Event {
 // NewEvents creates a new single-writer-multiple-reader Threadsafe object
 func NewThreadsafeEvents(maxEvents uint64) ThreadsafeEvents {
 	i := uint64(0)
-	return ThreadsafeEvents{m: &sync.RWMutex{}, events: &[]Event{}, nextIndex: &i, max: maxEvents}
+	subID := uint64(0)
+	return ThreadsafeEvents{m: &sync.RWMutex{}, events: &[]Event{}, nextIndex: &i, max: maxEvents, subscribers: &map[uint64]chan Event{}, nextSubID: &subID}
+}
+
+// NewPersistentThreadsafeEvents creates a new single-writer-multiple-reader Threadsafe object, which also appends every event to the given store. The latest stored events are loaded, and event indices continue from the last stored event.
+func NewPersistentThreadsafeEvents(maxEvents uint64, store *EventStore) (ThreadsafeEvents, error) {
+	events := NewThreadsafeEvents(maxEvents)
+	stored, nextIndex, err := store.Latest(int(maxEvents))
+	if err != nil {
+		return ThreadsafeEvents{}, fmt.Errorf("loading stored events: %v", err)
+	}
+	*events.events = stored
+	*events.nextIndex = nextIndex
+	events.store = store
+	return events, nil
 }
 
 // Get returns the internal slice of Events for reading. This MUST NOT be modified. If modification is necessary, copy the slice.
@@ -102,5 +122,62 @@ func (o *ThreadsafeEvents) Add(e Event) {
 	// o.m.Lock()
 	*o.events = events
 	*o.nextIndex++
+	if o.store != nil {
+		if err := o.store.Append(e); err != nil {
+			log.Errorf("storing event: %v", err)
+		}
+	}
+	for id, sub := range *o.subscribers {
+		select {
+		case sub <- e:
+		default:
+			log.Warnf("event subscriber %d fell more than %d events behind, closing its subscription", id, SubscriptionBufferSize)
+			close(sub)
+			delete(*o.subscribers, id)
+		}
+	}
 	o.m.Unlock()
 }
+
+// Subscribe returns the currently stored events, and a channel on which every event added afterwards will be sent, in order. The stored events are returned atomically with the subscription, so no event is missed or duplicated between them.
+//
+// If the subscriber falls SubscriptionBufferSize events behind, the channel is closed. The returned func MUST be called when the subscriber is done, to release the subscription.
+func (o *ThreadsafeEvents) Subscribe() ([]Event, <-chan Event, func()) {
+	o.m.Lock()
+	defer o.m.Unlock()
+	id := *o.nextSubID
+	*o.nextSubID++
+	sub := make(chan Event, SubscriptionBufferSize)
+	(*o.subscribers)[id] = sub
+	unsubscribe := func() {
+		o.m.Lock()
+		defer o.m.Unlock()
+		if _, ok := (*o.subscribers)[id]; ok {
+			close(sub)
+			delete(*o.subscribers, id)
+		}
+	}
+	return *o.events, sub, unsubscribe
+}
+
+// Count returns the total number of events which have been added, including
+// those which have since been dropped from the stored slice.
+func (o *ThreadsafeEvents) Count() uint64 {
+	o.m.RLock()
+	defer o.m.RUnlock()
+	return *o.nextIndex
+}
+
+// Query returns the events matching the given query, newest first. If the events are persistent, the store is queried, otherwise the events in memory are.
+func (o *ThreadsafeEvents) Query(q EventQuery) ([]Event, error) {
+	if o.store != nil {
+		return o.store.Query(q)
+	}
+	matches := []Event{}
+	for _, e := range o.Get() {
+		if q.Match(e) {
+			matches = append(matches, e)
+		}
+	}
+	return q.page(matches), nil
+}
@@ -0,0 +1,64 @@
+package health
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"testing"
+)
+
+func TestThreadsafeEventsSubscribe(t *testing.T) {
+	events := NewThreadsafeEvents(10)
+	events.Add(Event{Name: "before"})
+
+	stored, sub, unsubscribe := events.Subscribe()
+	if len(stored) != 1 || stored[0].Name != "before" {
+		t.Fatalf("expected the stored event 'before', actual: %+v", stored)
+	}
+	events.Add(Event{Name: "after"})
+	if e := <-sub; e.Name != "after" || e.Index != 1 {
+		t.Errorf("expected event 'after' with index 1, actual: %+v", e)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	if _, ok := <-sub; ok {
+		t.Error("expected the subscription to be closed after unsubscribing")
+	}
+	events.Add(Event{Name: "unsubscribed"})
+	if events.Count() != 3 {
+		t.Errorf("expected 3 events added, actual: %d", events.Count())
+	}
+}
+
+func TestThreadsafeEventsSubscribeSlow(t *testing.T) {
+	events := NewThreadsafeEvents(10)
+	_, sub, unsubscribe := events.Subscribe()
+	defer unsubscribe()
+	for i := 0; i < SubscriptionBufferSize+1; i++ {
+		events.Add(Event{Name: "event"})
+	}
+	received := 0
+	for range sub {
+		received++
+	}
+	if received != SubscriptionBufferSize {
+		t.Errorf("expected a slow subscriber to receive %d events before being closed, actual: %d", SubscriptionBufferSize, received)
+	}
+}
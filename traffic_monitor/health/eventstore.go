@@ -0,0 +1,362 @@
+package health
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+
+	"github.com/json-iterator/go"
+)
+
+// EventStoreAgeCompactionInterval is the minimum time between compactions of an EventStore to remove events older than its max age.
+const EventStoreAgeCompactionInterval = time.Minute
+
+// eventStoreMaxLineBytes is the largest stored event which will be read.
+const eventStoreMaxLineBytes = 1024 * 1024
+
+// EventStore is an append-only file of events, one JSON object per line, oldest first.
+//
+// When the file grows larger than its max bytes, or its oldest event becomes older than its max age, it is compacted by rewriting it without its oldest events. Size compaction removes events down to 3/4 of the max bytes, so that every append doesn't rewrite the file.
+//
+// Compaction after the store is opened happens in the background, so appends and queries aren't blocked while a large file is rewritten.
+type EventStore struct {
+	path     string
+	maxBytes uint64
+	maxAge   time.Duration
+
+	m                 sync.Mutex
+	file              *os.File
+	size              int64
+	oldest            time.Time
+	lastAgeCompaction time.Time
+	compacting        bool
+	compactions       sync.WaitGroup
+}
+
+// OpenEventStore opens the event store at the given path, creating it if it doesn't exist, and compacts it.
+// A maxBytes of 0 means the store is not limited in size, and a maxAge of 0 means it is not limited in age.
+func OpenEventStore(path string, maxBytes uint64, maxAge time.Duration) (*EventStore, error) {
+	s := &EventStore{path: path, maxBytes: maxBytes, maxAge: maxAge}
+	if info, err := os.Stat(path); err == nil {
+		s.size = info.Size()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("getting event store '%s' size: %v", path, err)
+	}
+	if err := s.compact(time.Now()); err != nil {
+		return nil, fmt.Errorf("compacting event store '%s': %v", path, err)
+	}
+	return s, nil
+}
+
+// Close waits for any background compaction to finish, and closes the event store's file. The store must not be used after it is closed.
+func (s *EventStore) Close() error {
+	s.compactions.Wait()
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.file.Close()
+}
+
+// Append appends the given event to the store, starting a background compaction if it has exceeded its max bytes or max age.
+func (s *EventStore) Append(e Event) error {
+	json := jsoniter.ConfigFastest
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshalling event: %v", err)
+	}
+	line = append(line, '\n')
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("writing event to '%s': %v", s.path, err)
+	}
+	if s.oldest.IsZero() {
+		s.oldest = time.Time(e.Time)
+	}
+
+	if !s.compacting && s.needsCompaction(time.Now()) {
+		s.compacting = true
+		s.compactions.Add(1)
+		go s.compactInBackground()
+	}
+	return nil
+}
+
+// needsCompaction returns whether the store has exceeded its max bytes or max age. It must be called with the store's mutex held.
+func (s *EventStore) needsCompaction(now time.Time) bool {
+	tooBig := s.maxBytes > 0 && uint64(s.size) > s.maxBytes
+	tooOld := s.maxAge > 0 && !s.oldest.IsZero() && now.Sub(s.oldest) > s.maxAge && now.Sub(s.lastAgeCompaction) > EventStoreAgeCompactionInterval
+	return tooBig || tooOld
+}
+
+// compactInBackground compacts the store until it no longer needs compaction, because events appended during a compaction may have exceeded its limits again. It must be started with compacting set, and it clears compacting when it's done. If compaction fails, it's retried by the next append.
+func (s *EventStore) compactInBackground() {
+	defer s.compactions.Done()
+	for {
+		err := s.compact(time.Now())
+		if err != nil {
+			log.Errorf("compacting event store '%s': %v", s.path, err)
+		}
+		s.m.Lock()
+		if err != nil || !s.needsCompaction(time.Now()) {
+			s.compacting = false
+			s.m.Unlock()
+			return
+		}
+		s.m.Unlock()
+	}
+}
+
+// Latest returns the last n stored events, newest first, and the index the next event should be given.
+func (s *EventStore) Latest(n int) ([]Event, uint64, error) {
+	events, err := s.Query(EventQuery{Limit: n})
+	if err != nil {
+		return nil, 0, err
+	}
+	nextIndex := uint64(0)
+	if len(events) > 0 {
+		nextIndex = events[0].Index + 1
+	}
+	return events, nextIndex, nil
+}
+
+// Query returns the stored events matching the given query, newest first.
+func (s *EventStore) Query(q EventQuery) ([]Event, error) {
+	// The file is opened and its size read together, so only complete lines are read, even if events are appended or the store is compacted while reading.
+	s.m.Lock()
+	file, err := os.Open(s.path)
+	size := s.size
+	s.m.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("opening event store '%s': %v", s.path, err)
+	}
+	defer file.Close()
+
+	// With a limit, only the newest offset+limit matches are needed, so older matches are discarded as the file is read.
+	window := 0
+	if q.Limit > 0 {
+		window = q.Offset + q.Limit
+	}
+	matches := []Event{}
+	err = readStoredEvents(io.LimitReader(file, size), func(e Event, line []byte) {
+		if !q.Match(e) {
+			return
+		}
+		matches = append(matches, e)
+		if window > 0 && len(matches) > 2*window {
+			matches = append(matches[:0], matches[len(matches)-window:]...)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading event store '%s': %v", s.path, err)
+	}
+	for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+		matches[i], matches[j] = matches[j], matches[i]
+	}
+	return q.page(matches), nil
+}
+
+// compact rewrites the store without events older than its max age, and without its oldest events until it is within its max bytes, and (re)opens the store's file for appending.
+//
+// The store's mutex is only held while the events appended during the rewrite are copied and the file is replaced, so appends and queries can continue while the store is read and rewritten. It must not be called by multiple threads.
+func (s *EventStore) compact(now time.Time) error {
+	type storedLine struct {
+		time time.Time
+		line []byte
+	}
+	s.m.Lock()
+	readSize := s.size
+	s.m.Unlock()
+
+	lines := []storedLine{}
+	size := int64(0)
+	if file, err := os.Open(s.path); err == nil {
+		err = readStoredEvents(io.LimitReader(file, readSize), func(e Event, line []byte) {
+			if s.maxAge > 0 && now.Sub(time.Time(e.Time)) > s.maxAge {
+				return
+			}
+			lines = append(lines, storedLine{time: time.Time(e.Time), line: append([]byte(nil), line...)})
+			size += int64(len(line)) + 1
+		})
+		file.Close()
+		if err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if s.maxBytes > 0 && uint64(size) > s.maxBytes {
+		target := int64(s.maxBytes / 4 * 3)
+		for len(lines) > 0 && size > target {
+			size -= int64(len(lines[0].line)) + 1
+			lines = lines[1:]
+		}
+	}
+
+	buf := bytes.Buffer{}
+	for _, line := range lines {
+		buf.Write(line.line)
+		buf.WriteByte('\n')
+	}
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("creating compacted events: %v", err)
+	}
+	defer tmp.Close()
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("writing compacted events: %v", err)
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	appended, err := s.copyAppended(tmp, readSize)
+	if err != nil {
+		return fmt.Errorf("copying events appended during compaction: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing compacted events: %v", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("replacing events with compacted events: %v", err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening compacted events: %v", err)
+	}
+	if s.file != nil {
+		if err := s.file.Close(); err != nil {
+			log.Warnf("closing event store '%s' before compaction: %v", s.path, err)
+		}
+	}
+	s.file = file
+	s.size = size + appended
+	s.oldest = time.Time{}
+	if len(lines) > 0 {
+		s.oldest = lines[0].time
+	}
+	s.lastAgeCompaction = now
+	return nil
+}
+
+// copyAppended copies the events appended to the store after its first readSize bytes to w, and returns the number of bytes copied. It must be called with the store's mutex held.
+func (s *EventStore) copyAppended(w io.Writer, readSize int64) (int64, error) {
+	if s.size <= readSize {
+		return 0, nil
+	}
+	file, err := os.Open(s.path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(readSize, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.CopyN(w, file, s.size-readSize)
+}
+
+// readStoredEvents calls f with each event read from r, and its line. Malformed lines are logged and skipped, so a line partially written before a crash doesn't make the store unreadable.
+func readStoredEvents(r io.Reader, f func(e Event, line []byte)) error {
+	json := jsoniter.ConfigFastest
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), eventStoreMaxLineBytes)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		e := Event{}
+		if err := json.Unmarshal(line, &e); err != nil {
+			log.Warnf("skipping malformed stored event '%s': %v", line, err)
+			continue
+		}
+		f(e, line)
+	}
+	return scanner.Err()
+}
+
+// EventQuery filters and paginates events. Zero values don't filter.
+type EventQuery struct {
+	// Start is the earliest time of events to include.
+	Start time.Time
+	// End is the latest time of events to include.
+	End time.Time
+	// Hostnames are the names or hostnames of events to include.
+	Hostnames map[string]struct{}
+	// Types are the upper-case types of events to include. Event types are matched case-insensitively.
+	Types map[string]struct{}
+	// Available, if not nil, includes only events with this availability.
+	Available *bool
+	// Limit is the maximum number of events to return.
+	Limit int
+	// Offset is the number of matching events, newest first, to skip.
+	Offset int
+}
+
+// Match returns whether the given event matches the query's filters.
+func (q EventQuery) Match(e Event) bool {
+	t := time.Time(e.Time)
+	if !q.Start.IsZero() && t.Before(q.Start) {
+		return false
+	}
+	if !q.End.IsZero() && t.After(q.End) {
+		return false
+	}
+	if len(q.Hostnames) > 0 {
+		_, nameOK := q.Hostnames[e.Name]
+		_, hostnameOK := q.Hostnames[e.Hostname]
+		if !nameOK && !hostnameOK {
+			return false
+		}
+	}
+	if _, ok := q.Types[strings.ToUpper(e.Type)]; len(q.Types) > 0 && !ok {
+		return false
+	}
+	if q.Available != nil && e.Available != *q.Available {
+		return false
+	}
+	return true
+}
+
+// page returns the query's page of the given matching events.
+func (q EventQuery) page(events []Event) []Event {
+	if q.Offset >= len(events) {
+		return []Event{}
+	}
+	events = events[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(events) {
+		events = events[:q.Limit]
+	}
+	return events
+}
@@ -0,0 +1,159 @@
+package health
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEventStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	store, err := OpenEventStore(path, 0, 0)
+	if err != nil {
+		t.Fatalf("opening event store: %v", err)
+	}
+	events, err := NewPersistentThreadsafeEvents(2, store)
+	if err != nil {
+		t.Fatalf("loading events: %v", err)
+	}
+	now := time.Now()
+	events.Add(Event{Time: Time(now.Add(-2 * time.Hour)), Name: "edge1", Hostname: "edge1.example.net", Type: "EDGE", Available: false})
+	events.Add(Event{Time: Time(now.Add(-time.Hour)), Name: "edge2", Hostname: "edge2.example.net", Type: "EDGE", Available: true})
+	events.Add(Event{Time: Time(now), Name: "tm1", Hostname: "tm1", Type: "PEER", Available: true})
+	if err := store.Close(); err != nil {
+		t.Fatalf("closing event store: %v", err)
+	}
+
+	// Reopening the store, as after a restart, loads the latest events and continues their indices.
+	store, err = OpenEventStore(path, 0, 0)
+	if err != nil {
+		t.Fatalf("reopening event store: %v", err)
+	}
+	defer store.Close()
+	events, err = NewPersistentThreadsafeEvents(2, store)
+	if err != nil {
+		t.Fatalf("reloading events: %v", err)
+	}
+	if latest := events.Get(); len(latest) != 2 || latest[0].Name != "tm1" || latest[1].Name != "edge2" {
+		t.Errorf("expected the latest 2 events tm1 and edge2, actual: %+v", latest)
+	}
+	events.Add(Event{Time: Time(now), Name: "edge1", Type: "EDGE", Available: true})
+	if latest := events.Get(); latest[0].Index != 3 {
+		t.Errorf("expected a new event after reopening to have index 3, actual: %d", latest[0].Index)
+	}
+
+	unavailable := false
+	cases := []struct {
+		Query    EventQuery
+		Expected []uint64
+	}{
+		{EventQuery{}, []uint64{3, 2, 1, 0}},
+		{EventQuery{Hostnames: map[string]struct{}{"edge1.example.net": {}}}, []uint64{0}},
+		{EventQuery{Hostnames: map[string]struct{}{"edge1": {}}}, []uint64{3, 0}},
+		{EventQuery{Types: map[string]struct{}{"PEER": {}}}, []uint64{2}},
+		{EventQuery{Available: &unavailable}, []uint64{0}},
+		{EventQuery{Start: now.Add(-90 * time.Minute), End: now.Add(-30 * time.Minute)}, []uint64{1}},
+		{EventQuery{Limit: 2, Offset: 1}, []uint64{2, 1}},
+		{EventQuery{Offset: 10}, []uint64{}},
+	}
+	for _, c := range cases {
+		actual, err := events.Query(c.Query)
+		if err != nil {
+			t.Fatalf("querying events %+v: %v", c.Query, err)
+		}
+		indices := []uint64{}
+		for _, e := range actual {
+			indices = append(indices, e.Index)
+		}
+		if len(indices) != len(c.Expected) {
+			t.Errorf("query %+v - expected indices %v, actual: %v", c.Query, c.Expected, indices)
+			continue
+		}
+		for i := range indices {
+			if indices[i] != c.Expected[i] {
+				t.Errorf("query %+v - expected indices %v, actual: %v", c.Query, c.Expected, indices)
+				break
+			}
+		}
+	}
+}
+
+func TestEventStoreRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	store, err := OpenEventStore(path, 1000, time.Hour)
+	if err != nil {
+		t.Fatalf("opening event store: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.Append(Event{Time: Time(time.Now().Add(-2 * time.Hour)), Index: 0, Name: "old"}); err != nil {
+		t.Fatalf("appending event: %v", err)
+	}
+	for i := uint64(1); i <= 20; i++ {
+		if err := store.Append(Event{Time: Time(time.Now()), Index: i, Name: "new"}); err != nil {
+			t.Fatalf("appending event: %v", err)
+		}
+	}
+	store.compactions.Wait()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("getting event store size: %v", err)
+	}
+	if info.Size() > 1000 {
+		t.Errorf("expected the event store to be at most 1000 bytes, actual: %d", info.Size())
+	}
+	events, err := store.Query(EventQuery{})
+	if err != nil {
+		t.Fatalf("querying events: %v", err)
+	}
+	if len(events) == 0 || events[0].Index != 20 {
+		t.Fatalf("expected the newest events to be kept, actual: %+v", events)
+	}
+	for _, e := range events {
+		if e.Name == "old" {
+			t.Errorf("expected the event older than the max age to be removed, actual: %+v", events)
+		}
+	}
+}
+
+func TestEventStoreMalformedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	data := `{"time":1600000000,"index":0,"name":"edge1"}
+{"time":1600000001,"ind
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("writing event store: %v", err)
+	}
+	store, err := OpenEventStore(path, 0, 0)
+	if err != nil {
+		t.Fatalf("opening event store with a partial line: %v", err)
+	}
+	defer store.Close()
+	events, nextIndex, err := store.Latest(10)
+	if err != nil {
+		t.Fatalf("getting latest events: %v", err)
+	}
+	if len(events) != 1 || nextIndex != 1 {
+		t.Errorf("expected 1 event and a next index of 1, actual: %+v, %d", events, nextIndex)
+	}
+}
@@ -0,0 +1,376 @@
+package health
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+// DefaultRateWindow is the window of a rate() in a health expression which doesn't give one.
+const DefaultRateWindow = time.Minute
+
+// Expression is a parsed health threshold expression. Expressions are comparisons of stats to numbers, such as `loadavg > 25` or `rate(proxy.process.http.5xx_responses, 30s) >= 100`, combined with AND, OR, NOT and parentheses.
+//
+// The grammar is:
+//
+//	expression := and ("OR" and)*
+//	and        := unary ("AND" unary)*
+//	unary      := "NOT" unary | "(" expression ")" | comparison
+//	comparison := operand (">" | ">=" | "<" | "<=" | "=") number
+//	operand    := stat | "rate(" stat ["," duration] ")"
+//
+// Keywords are case-insensitive. A rate is the per-second change of the stat over the window, which defaults to DefaultRateWindow.
+type Expression struct {
+	root exprNode
+	// Stats are the names of the stats the expression uses.
+	Stats []string
+	// MaxWindow is the largest rate window in the expression, or 0 if it has no rates.
+	MaxWindow time.Duration
+}
+
+// StatSample is a stat's value at a poll.
+type StatSample struct {
+	Time time.Time
+	Val  float64
+}
+
+// ExpressionStats provides the samples of the stats an Expression is evaluated against. Samples are ordered oldest first; the last sample is the current value.
+type ExpressionStats map[string][]StatSample
+
+// Eval evaluates the expression against the given stats. If the expression can't be evaluated, because a stat is missing or a rate doesn't have enough samples, ok is false.
+// AND and OR short-circuit, so `a AND b` is false if `a` is false even if `b` can't be evaluated.
+func (e *Expression) Eval(stats ExpressionStats) (result bool, ok bool) {
+	return e.root.eval(stats)
+}
+
+// String returns the expression in its canonical form.
+func (e *Expression) String() string {
+	return e.root.String()
+}
+
+type exprNode interface {
+	eval(stats ExpressionStats) (bool, bool)
+	String() string
+}
+
+type orNode struct{ left, right exprNode }
+
+func (n orNode) eval(stats ExpressionStats) (bool, bool) {
+	left, leftOK := n.left.eval(stats)
+	if leftOK && left {
+		return true, true
+	}
+	right, rightOK := n.right.eval(stats)
+	if rightOK && right {
+		return true, true
+	}
+	return false, leftOK && rightOK
+}
+
+func (n orNode) String() string { return "(" + n.left.String() + " OR " + n.right.String() + ")" }
+
+type andNode struct{ left, right exprNode }
+
+func (n andNode) eval(stats ExpressionStats) (bool, bool) {
+	left, leftOK := n.left.eval(stats)
+	if leftOK && !left {
+		return false, true
+	}
+	right, rightOK := n.right.eval(stats)
+	if rightOK && !right {
+		return false, true
+	}
+	ok := leftOK && rightOK
+	return ok, ok
+}
+
+func (n andNode) String() string { return "(" + n.left.String() + " AND " + n.right.String() + ")" }
+
+type notNode struct{ operand exprNode }
+
+func (n notNode) eval(stats ExpressionStats) (bool, bool) {
+	val, ok := n.operand.eval(stats)
+	return !val, ok
+}
+
+func (n notNode) String() string { return "NOT " + n.operand.String() }
+
+type comparisonNode struct {
+	stat      string
+	rate      bool
+	window    time.Duration
+	threshold tc.HealthThreshold
+}
+
+func (n comparisonNode) eval(stats ExpressionStats) (bool, bool) {
+	val, ok := n.value(stats)
+	if !ok {
+		return false, false
+	}
+	return inThreshold(n.threshold, val), true
+}
+
+// value returns the stat, or its rate, from the given samples.
+func (n comparisonNode) value(stats ExpressionStats) (float64, bool) {
+	samples := stats[n.stat]
+	if len(samples) == 0 {
+		return 0, false
+	}
+	latest := samples[len(samples)-1]
+	if !n.rate {
+		return latest.Val, true
+	}
+	// the oldest sample within the window
+	first := -1
+	for i, sample := range samples {
+		if latest.Time.Sub(sample.Time) <= n.window {
+			first = i
+			break
+		}
+	}
+	if first < 0 || first == len(samples)-1 {
+		return 0, false
+	}
+	oldest := samples[first]
+	elapsed := latest.Time.Sub(oldest.Time).Seconds()
+	if elapsed <= 0 || latest.Val < oldest.Val {
+		return 0, false // counter reset
+	}
+	return (latest.Val - oldest.Val) / elapsed, true
+}
+
+func (n comparisonNode) operand() string {
+	if !n.rate {
+		return n.stat
+	}
+	return "rate(" + n.stat + ", " + n.window.String() + ")"
+}
+
+func (n comparisonNode) String() string {
+	return n.operand() + " " + n.threshold.Comparator + " " + strconv.FormatFloat(n.threshold.Val, 'g', -1, 64)
+}
+
+// ParseExpression parses a health threshold expression. See Expression for the grammar.
+func ParseExpression(s string) (*Expression, error) {
+	tokens, err := tokenizeExpression(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &expressionParser{tokens: tokens, stats: map[string]struct{}{}}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected '%s' after expression", p.tokens[p.pos])
+	}
+	expr := &Expression{root: root, MaxWindow: p.maxWindow}
+	for stat := range p.stats {
+		expr.Stats = append(expr.Stats, stat)
+	}
+	return expr, nil
+}
+
+// tokenizeExpression splits an expression into parentheses, commas, comparators, and words, which are everything else, e.g. keywords, stat names and numbers.
+func tokenizeExpression(s string) ([]string, error) {
+	tokens := []string{}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '>' || c == '<' || c == '=':
+			if (c == '>' || c == '<') && i+1 < len(s) && s[i+1] == '=' {
+				tokens = append(tokens, s[i:i+2])
+				i += 2
+			} else {
+				tokens = append(tokens, string(c))
+				i++
+			}
+		default:
+			start := i
+			for i < len(s) && !unicode.IsSpace(rune(s[i])) && !strings.ContainsRune("(),<>=", rune(s[i])) {
+				i++
+			}
+			tokens = append(tokens, s[start:i])
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("empty expression")
+	}
+	return tokens, nil
+}
+
+type expressionParser struct {
+	tokens    []string
+	pos       int
+	stats     map[string]struct{}
+	maxWindow time.Duration
+}
+
+func (p *expressionParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *expressionParser) next() string {
+	tok := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *expressionParser) expect(tok string) error {
+	if actual := p.next(); actual != tok {
+		if actual == "" {
+			return fmt.Errorf("expected '%s', found end of expression", tok)
+		}
+		return fmt.Errorf("expected '%s', found '%s'", tok, actual)
+	}
+	return nil
+}
+
+func (p *expressionParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "AND") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseUnary() (exprNode, error) {
+	switch tok := p.peek(); {
+	case strings.EqualFold(tok, "NOT"):
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	case tok == "(":
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *expressionParser) parseComparison() (exprNode, error) {
+	node := comparisonNode{}
+	stat := p.next()
+	if strings.EqualFold(stat, "rate") && p.peek() == "(" {
+		p.next()
+		node.rate = true
+		node.window = DefaultRateWindow
+		if stat = p.next(); !isExpressionStat(stat) {
+			return nil, fmt.Errorf("expected a stat name in rate(), found '%s'", stat)
+		}
+		if p.peek() == "," {
+			p.next()
+			windowStr := p.next()
+			window, err := time.ParseDuration(windowStr)
+			if err != nil || window <= 0 {
+				return nil, fmt.Errorf("invalid rate window '%s': must be a positive duration, e.g. 30s", windowStr)
+			}
+			node.window = window
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		if node.window > p.maxWindow {
+			p.maxWindow = node.window
+		}
+	} else if !isExpressionStat(stat) {
+		if stat == "" {
+			return nil, errors.New("expected a stat name, found end of expression")
+		}
+		return nil, fmt.Errorf("expected a stat name, found '%s'", stat)
+	}
+	node.stat = stat
+	p.stats[stat] = struct{}{}
+
+	comparator := p.next()
+	switch comparator {
+	case ">", ">=", "<", "<=", "=":
+	default:
+		return nil, fmt.Errorf("expected a comparator after '%s', found '%s'", node.operand(), comparator)
+	}
+	valStr := p.next()
+	val, err := strconv.ParseFloat(valStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("expected a number after '%s %s', found '%s'", node.operand(), comparator, valStr)
+	}
+	node.threshold = tc.HealthThreshold{Val: val, Comparator: comparator}
+	return node, nil
+}
+
+// isExpressionStat returns whether the given token may be a stat name, i.e. it isn't punctuation, a keyword, or empty.
+func isExpressionStat(tok string) bool {
+	switch {
+	case tok == "" || strings.ContainsAny(tok, "(),<>="):
+		return false
+	case strings.EqualFold(tok, "AND") || strings.EqualFold(tok, "OR") || strings.EqualFold(tok, "NOT"):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,210 @@
+package health
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/cache"
+	"github.com/apache/trafficcontrol/traffic_monitor/threadsafe"
+)
+
+func TestParseExpression(t *testing.T) {
+	valid := map[string]string{
+		"loadavg > 25": "loadavg > 25",
+		"loadavg>25 and queryTime>=500 or not kbps<1e3":       "((loadavg > 25 AND queryTime >= 500) OR NOT kbps < 1000)",
+		"loadavg > 25 AND (queryTime > 500 OR kbps = 0)":      "(loadavg > 25 AND (queryTime > 500 OR kbps = 0))",
+		"rate(proxy.process.http.5xx_responses, 30s) > 100":   "rate(proxy.process.http.5xx_responses, 30s) > 100",
+		"RATE(proxy.process.http.completed_requests) <= -0.5": "rate(proxy.process.http.completed_requests, 1m0s) <= -0.5",
+	}
+	for s, expected := range valid {
+		expr, err := ParseExpression(s)
+		if err != nil {
+			t.Errorf("parsing '%s' - expected: nil error, actual: %v", s, err)
+			continue
+		}
+		if actual := expr.String(); actual != expected {
+			t.Errorf("parsing '%s' - expected: %s, actual: %s", s, expected, actual)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"loadavg",
+		"loadavg >",
+		"loadavg > high",
+		"loadavg != 25",
+		"> 25",
+		"loadavg > 25 AND",
+		"(loadavg > 25",
+		"loadavg > 25)",
+		"rate(loadavg, soon) > 1",
+		"rate(loadavg, -1s) > 1",
+		"AND > 1",
+	}
+	for _, s := range invalid {
+		if _, err := ParseExpression(s); err == nil {
+			t.Errorf("parsing '%s' - expected: error, actual: nil", s)
+		}
+	}
+}
+
+func TestExpressionEval(t *testing.T) {
+	now := time.Now()
+	stats := ExpressionStats{
+		"loadavg":  {{Time: now, Val: 30}},
+		"requests": {{Time: now.Add(-90 * time.Second), Val: 0}, {Time: now.Add(-20 * time.Second), Val: 1000}, {Time: now, Val: 3000}},
+	}
+	cases := []struct {
+		Expr     string
+		Expected bool
+		OK       bool
+	}{
+		{"loadavg > 25", true, true},
+		{"loadavg > 25 AND missing > 1", false, false},
+		{"loadavg < 25 AND missing > 1", false, true},
+		{"loadavg > 25 OR missing > 1", true, true},
+		{"NOT loadavg > 25", false, true},
+		{"rate(requests, 30s) = 100", true, true},
+		{"rate(requests) > 0", true, true},
+		{"rate(loadavg) > 0", false, false},
+	}
+	for _, c := range cases {
+		expr, err := ParseExpression(c.Expr)
+		if err != nil {
+			t.Fatalf("parsing '%s': %v", c.Expr, err)
+		}
+		if actual, ok := expr.Eval(stats); actual != c.Expected || ok != c.OK {
+			t.Errorf("evaluating '%s' - expected: %t (ok %t), actual: %t (ok %t)", c.Expr, c.Expected, c.OK, actual, ok)
+		}
+	}
+}
+
+func TestExpressionStatesHysteresis(t *testing.T) {
+	mc := tc.TrafficMonitorConfigMap{
+		TrafficServer: map[string]tc.TrafficServer{"edge": {HostName: "edge", Profile: "EDGE_PROFILE", ServerStatus: string(tc.CacheStatusReported)}},
+		Profile: map[string]tc.TMProfile{"EDGE_PROFILE": {Name: "EDGE_PROFILE", Parameters: tc.TMParameters{
+			Expressions: map[string]tc.HealthExpression{
+				"overloaded": {Down: "loadavg > 25", Up: "loadavg < 20", DownPolls: 2, UpPolls: 3},
+			},
+		}}},
+	}
+	states := NewExpressionStates()
+	start := time.Now()
+	// loadavg per poll, and whether the cache is expected to be available after it
+	polls := []struct {
+		LoadAvg   float64
+		Available bool
+	}{
+		{30, true},  // 1 poll over the down level
+		{10, true},  // resets the count
+		{30, true},  // 1
+		{30, false}, // 2 consecutive polls - marked down
+		{22, false}, // between the levels - stays down
+		{15, false}, // 1 poll under the up level
+		{15, false}, // 2
+		{15, true},  // 3 consecutive polls - marked up
+		{22, true},  // between the levels - stays up
+	}
+	for i, poll := range polls {
+		result := cache.ResultInfo{ID: "edge", Time: start.Add(time.Duration(i) * time.Second), Vitals: cache.Vitals{LoadAvg: poll.LoadAvg}}
+		available, why, expr := states.Eval(result, "health", nil, &mc)
+		if available != poll.Available {
+			t.Errorf("poll %d with loadavg %v - expected available: %t, actual: %t (%s)", i, poll.LoadAvg, poll.Available, available, why)
+		}
+		if !available && expr != "health.expression.overloaded" {
+			t.Errorf("poll %d - expected the unavailable expression to be health.expression.overloaded, actual: %s", i, expr)
+		}
+	}
+}
+
+func TestExpressionStatesUnpolledStat(t *testing.T) {
+	mc := tc.TrafficMonitorConfigMap{
+		TrafficServer: map[string]tc.TrafficServer{"edge": {HostName: "edge", Profile: "EDGE_PROFILE"}},
+		Profile: map[string]tc.TMProfile{"EDGE_PROFILE": {Name: "EDGE_PROFILE", Parameters: tc.TMParameters{
+			Expressions: map[string]tc.HealthExpression{
+				"errors": {Down: "rate(errors, 10s) > 5"},
+			},
+		}}},
+	}
+	states := NewExpressionStates()
+	start := time.Now()
+	statHistory := threadsafe.NewResultStatValHistory()
+
+	eval := func(offset time.Duration, errors float64, withStats bool) bool {
+		result := cache.ResultInfo{ID: "edge", Time: start.Add(offset)}
+		if !withStats {
+			available, _, _ := states.Eval(result, "health", nil, &mc)
+			return available
+		}
+		statHistory.Store("errors", []tc.ResultStatVal{{Time: result.Time, Val: errors, Span: 1}})
+		available, _, _ := states.Eval(result, "stat", &statHistory, &mc)
+		return available
+	}
+
+	if !eval(0, 0, true) {
+		t.Error("expected the first poll, without a rate, to be available")
+	}
+	// Health polls don't have the stat, so don't change the state.
+	if !eval(time.Second, 0, false) {
+		t.Error("expected a poll without the stat to be available")
+	}
+	if eval(2*time.Second, 20, true) {
+		t.Error("expected a rate of 10/s to be unavailable")
+	}
+	if eval(3*time.Second, 0, false) {
+		t.Error("expected a poll without the stat not to mark the cache available again")
+	}
+	if !eval(4*time.Second, 20, true) {
+		t.Error("expected a rate under 5/s to mark the cache available again")
+	}
+}
+
+func TestExpressionStatesPerPollerCounts(t *testing.T) {
+	mc := tc.TrafficMonitorConfigMap{
+		TrafficServer: map[string]tc.TrafficServer{"edge": {HostName: "edge", Profile: "EDGE_PROFILE", ServerStatus: string(tc.CacheStatusReported)}},
+		Profile: map[string]tc.TMProfile{"EDGE_PROFILE": {Name: "EDGE_PROFILE", Parameters: tc.TMParameters{
+			Expressions: map[string]tc.HealthExpression{
+				"overloaded": {Down: "loadavg > 25", DownPolls: 2},
+			},
+		}}},
+	}
+	states := NewExpressionStates()
+	start := time.Now()
+	statHistory := threadsafe.NewResultStatValHistory()
+	eval := func(offset time.Duration, pollerName string) bool {
+		result := cache.ResultInfo{ID: "edge", Time: start.Add(offset), Vitals: cache.Vitals{LoadAvg: 30}}
+		available, _, _ := states.Eval(result, pollerName, &statHistory, &mc)
+		return available
+	}
+
+	// loadavg is computed, so it's in both pollers' results, but each poller's polls are counted separately.
+	if !eval(0, "health") {
+		t.Error("expected 1 health poll over the down level to be available")
+	}
+	if !eval(time.Second, "stat") {
+		t.Error("expected 1 health poll and 1 stat poll over the down level to be available")
+	}
+	if eval(2*time.Second, "health") {
+		t.Error("expected 2 consecutive health polls over the down level to be unavailable")
+	}
+}
@@ -0,0 +1,201 @@
+package health
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/cache"
+	"github.com/apache/trafficcontrol/traffic_monitor/threadsafe"
+)
+
+// ExpressionStates tracks the state of each cache server's health threshold expressions across polls: whether each expression has marked the cache unavailable, how many consecutive polls have met the condition to change that, and the stat samples for rates.
+// It is safe for multiple goroutines, so the health and stat pollers share the same state.
+type ExpressionStates struct {
+	m        *sync.Mutex
+	compiled *map[string]compiledExpression
+	caches   *map[tc.CacheName]map[string]*expressionState
+}
+
+type compiledExpression struct {
+	expr *Expression
+	err  error
+}
+
+type expressionState struct {
+	params tc.HealthExpression
+	down   bool
+	// polls is the number of consecutive polls which met the condition to change the state, per poller. Computed stats are in both the health and stat pollers' results, so counting each poller separately keeps one poller's results from counting toward the other's consecutive polls.
+	polls   map[string]int
+	samples ExpressionStats
+}
+
+// NewExpressionStates creates a new, empty ExpressionStates.
+func NewExpressionStates() ExpressionStates {
+	return ExpressionStates{
+		m:        &sync.Mutex{},
+		compiled: &map[string]compiledExpression{},
+		caches:   &map[tc.CacheName]map[string]*expressionState{},
+	}
+}
+
+// compile returns the parsed expression, parsing each distinct expression once. Invalid expressions are logged when first parsed, and return nil.
+// It must be called with the mutex held.
+func (s ExpressionStates) compile(name string, exprStr string) *Expression {
+	if c, ok := (*s.compiled)[exprStr]; ok {
+		return c.expr
+	}
+	expr, err := ParseExpression(exprStr)
+	if err != nil {
+		log.Errorf("health expression '%s' is invalid and will be ignored: '%s': %v", name, exprStr, err)
+	}
+	(*s.compiled)[exprStr] = compiledExpression{expr: expr, err: err}
+	return expr
+}
+
+// Eval evaluates the health threshold expressions of the given cache server's Profile against the result from the named poller, and returns whether the cache server is available, a description of why it isn't, and the name of the Parameter prefix of the expression which made it unavailable.
+//
+// An expression marks the cache server unavailable once its down expression is true for its down polls consecutive polls of the same poller. It then marks it available again once its up expression - or if it has none, the negation of its down expression - is true for its up polls consecutive polls. Polls in which an expression can't be evaluated, for example because the poller doesn't poll one of its stats, are not counted either way.
+func (s ExpressionStates) Eval(result cache.ResultInfo, pollerName string, resultStats *threadsafe.ResultStatValHistory, mc *tc.TrafficMonitorConfigMap) (bool, string, string) {
+	cacheName := tc.CacheName(result.ID)
+	serverInfo := mc.TrafficServer[result.ID]
+	profile := mc.Profile[serverInfo.Profile]
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if len(profile.Parameters.Expressions) == 0 {
+		delete(*s.caches, cacheName)
+		return true, "", ""
+	}
+	states, ok := (*s.caches)[cacheName]
+	if !ok {
+		states = map[string]*expressionState{}
+		(*s.caches)[cacheName] = states
+	}
+	for name := range states {
+		if _, ok := profile.Parameters.Expressions[name]; !ok {
+			delete(states, name)
+		}
+	}
+
+	names := make([]string, 0, len(profile.Parameters.Expressions))
+	for name := range profile.Parameters.Expressions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	computedStats := cache.ComputedStats()
+	available, why, unavailableExpr := true, "", ""
+	for _, name := range names {
+		params := profile.Parameters.Expressions[name]
+		down := s.compile(name, params.Down)
+		if down == nil {
+			continue
+		}
+		var up *Expression
+		if params.Up != "" {
+			if up = s.compile(name, params.Up); up == nil {
+				continue
+			}
+		}
+
+		state, ok := states[name]
+		if !ok || state.params != params {
+			state = &expressionState{params: params, polls: map[string]int{}, samples: ExpressionStats{}}
+			states[name] = state
+		}
+
+		// Only the stats in this poll's result are evaluated, so a stat polled by one poller isn't counted again by another.
+		polled := ExpressionStats{}
+		maxWindow := down.MaxWindow
+		stats := down.Stats
+		if up != nil {
+			stats = append(append([]string{}, stats...), up.Stats...)
+			if up.MaxWindow > maxWindow {
+				maxWindow = up.MaxWindow
+			}
+		}
+		for _, stat := range stats {
+			val, ok := thresholdStat(stat, computedStats, result, serverInfo, profile, resultStats)
+			if !ok {
+				continue
+			}
+			samples := state.samples[stat]
+			if len(samples) == 0 || samples[len(samples)-1].Time.Before(result.Time) {
+				samples = append(samples, StatSample{Time: result.Time, Val: val})
+			}
+			for len(samples) > 1 && result.Time.Sub(samples[0].Time) > maxWindow {
+				samples = samples[1:]
+			}
+			state.samples[stat] = samples
+			polled[stat] = samples
+		}
+
+		if !state.down {
+			if met, ok := down.Eval(polled); ok {
+				state.polls[pollerName] = countPoll(state.polls[pollerName], met)
+			}
+			if state.polls[pollerName] >= pollsOrDefault(params.DownPolls) {
+				state.down, state.polls = true, map[string]int{}
+			}
+		} else {
+			met, ok := false, false
+			if up != nil {
+				met, ok = up.Eval(polled)
+			} else {
+				met, ok = down.Eval(polled)
+				met = !met
+			}
+			if ok {
+				state.polls[pollerName] = countPoll(state.polls[pollerName], met)
+			}
+			if state.polls[pollerName] >= pollsOrDefault(params.UpPolls) {
+				state.down, state.polls = false, map[string]int{}
+			}
+		}
+
+		if state.down && available {
+			available = false
+			why = fmt.Sprintf("health expression %s (%s)", name, params.Down)
+			unavailableExpr = tc.ExpressionPrefix + name
+		}
+	}
+	return available, why, unavailableExpr
+}
+
+// countPoll returns the new count of consecutive polls which met a condition.
+func countPoll(polls int, met bool) int {
+	if !met {
+		return 0
+	}
+	return polls + 1
+}
+
+func pollsOrDefault(polls int) int {
+	if polls < 1 {
+		return 1
+	}
+	return polls
+}
@@ -0,0 +1,324 @@
+package manager
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-rfc"
+	"github.com/apache/trafficcontrol/traffic_monitor/cache"
+	"github.com/apache/trafficcontrol/traffic_monitor/config"
+	"github.com/apache/trafficcontrol/traffic_monitor/crstatesrpc"
+	"github.com/apache/trafficcontrol/traffic_monitor/datareq"
+	"github.com/apache/trafficcontrol/traffic_monitor/ds"
+	"github.com/apache/trafficcontrol/traffic_monitor/handler"
+	"github.com/apache/trafficcontrol/traffic_monitor/health"
+	"github.com/apache/trafficcontrol/traffic_monitor/peer"
+	"github.com/apache/trafficcontrol/traffic_monitor/poller"
+	"github.com/apache/trafficcontrol/traffic_monitor/threadsafe"
+	"github.com/apache/trafficcontrol/traffic_monitor/todata"
+	"github.com/apache/trafficcontrol/traffic_monitor/towrap"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// cdnMonitor is the monitoring of a single CDN: its Traffic Ops session and data, pollers, peers, and the data its endpoints serve.
+// Nothing is shared between the cdnMonitors of a Traffic Monitor except the HTTP server, so each CDN is monitored exactly as if it were the only one.
+type cdnMonitor struct {
+	// cdn is the name of the CDN, or empty for this Traffic Monitor's own CDN, whose name is given by Traffic Ops.
+	cdn string
+	cfg config.Config
+
+	opsConfig        threadsafe.OpsConfig
+	opsConfigChannel chan<- handler.OpsConfig
+	sessionChannel   chan<- towrap.TrafficOpsSessionThreadsafe
+	healthTickChan   <-chan uint64
+
+	toSession             towrap.TrafficOpsSessionThreadsafe
+	toData                todata.TODataThreadsafe
+	localStates           peer.CRStatesThreadsafe
+	peerStates            peer.CRStatesPeersThreadsafe
+	distributedPeerStates peer.CRStatesPeersThreadsafe
+	combinedStates        peer.CRStatesThreadsafe
+	statInfoHistory       threadsafe.ResultInfoHistory
+	statResultHistory     threadsafe.ResultStatHistory
+	statMaxKbpses         threadsafe.CacheKbpses
+	healthHistory         threadsafe.ResultHistory
+	lastStats             threadsafe.LastStats
+	dsStats               threadsafe.DSStatsReader
+	dsSLOs                ds.SLOs
+	capacityForecasts     ds.CapacityForecasts
+	events                health.ThreadsafeEvents
+	healthPollInterval    time.Duration
+	lastHealthDurations   threadsafe.DurationMap
+	fetchCount            threadsafe.Uint
+	healthIteration       threadsafe.Uint
+	errorCount            threadsafe.Uint
+	localCacheStatus      threadsafe.CacheAvailableStatus
+	statUnpolledCaches    threadsafe.UnpolledCaches
+	healthUnpolledCaches  threadsafe.UnpolledCaches
+	monitorConfig         threadsafe.TrafficMonitorConfigMap
+}
+
+// startCDNMonitor starts the pollers and managers monitoring the given CDN. The CDN is empty for this Traffic Monitor's own CDN.
+// The CDN isn't monitored until its Traffic Ops session is created by the ops config manager.
+func startCDNMonitor(cdn string, cfg config.Config, appData config.StaticAppData) (cdnMonitor, error) {
+	toSession := towrap.NewTrafficOpsSessionThreadsafe(nil, nil, cfg.CRConfigHistoryCount, cfg)
+
+	localStates := peer.NewCRStatesThreadsafe() // this is the local state as discoverer by this traffic_monitor
+	fetchCount := threadsafe.NewUint()          // note this is the number of individual caches fetched from, not the number of times all the caches were polled.
+	healthIteration := threadsafe.NewUint()
+	errorCount := threadsafe.NewUint()
+
+	toData := todata.NewThreadsafe()
+
+	events := health.NewThreadsafeEvents(cfg.MaxEvents)
+	if cfg.EventLogFile != "" {
+		eventStore, err := health.OpenEventStore(cfg.EventLogFile, cfg.EventLogMaxBytes, cfg.EventLogMaxAge)
+		if err != nil {
+			return cdnMonitor{}, fmt.Errorf("opening event log file: %v", err)
+		}
+		if events, err = health.NewPersistentThreadsafeEvents(cfg.MaxEvents, eventStore); err != nil {
+			return cdnMonitor{}, fmt.Errorf("loading event log file: %v", err)
+		}
+	}
+
+	cacheHealthHandler := cache.NewHandler()
+	cacheHealthPoller := poller.NewCache("health", true, cacheHealthHandler, cfg, appData)
+	cacheStatHandler := cache.NewPrecomputeHandler(toData)
+	cacheStatPoller := poller.NewCache("stat", false, cacheStatHandler, cfg, appData)
+	monitorConfigPoller := poller.NewMonitorConfig(cfg.MonitorConfigPollingInterval)
+	peerHandler := peer.NewHandler()
+	peerPoller := poller.NewPeer(peerHandler, cfg, appData)
+	distributedPeerHandler := peer.NewHandler()
+	distributedPeerPoller := poller.NewPeer(distributedPeerHandler, cfg, appData)
+
+	go monitorConfigPoller.Poll()
+	go cacheHealthPoller.Poll()
+	if cfg.StatPolling {
+		go cacheStatPoller.Poll()
+	}
+	go peerPoller.Poll()
+	if cfg.DistributedPolling {
+		go distributedPeerPoller.Poll()
+	}
+
+	var cachesChangedForStatMgr chan struct{}
+	var cachesChangedForHealthMgr chan struct{}
+	var cachesChanged chan struct{}
+	if cfg.StatPolling {
+		cachesChangedForStatMgr = make(chan struct{})
+		cachesChanged = cachesChangedForStatMgr
+	} else {
+		cachesChangedForHealthMgr = make(chan struct{})
+		cachesChanged = cachesChangedForHealthMgr
+	}
+	peerStates := peer.NewCRStatesPeersThreadsafe(cfg.PeerOptimisticQuorumMin) // each peer's last state is saved in this map
+	distributedPeerStates := peer.NewCRStatesPeersThreadsafe(0)
+
+	monitorConfig := StartMonitorConfigManager(
+		monitorConfigPoller.ConfigChannel,
+		localStates,
+		peerStates,
+		distributedPeerStates,
+		cacheStatPoller.ConfigChannel,
+		cacheHealthPoller.ConfigChannel,
+		peerPoller.ConfigChannel,
+		distributedPeerPoller.ConfigChannel,
+		monitorConfigPoller.IntervalChan,
+		cachesChanged,
+		cfg,
+		appData,
+		toSession,
+		toData,
+		cdn == "",
+	)
+
+	expressionStates := health.NewExpressionStates() // shared by the health and stat managers, so expressions keep one state per cache
+	dsSLOs := ds.NewSLOs()
+	capacityForecasts := ds.NewCapacityForecasts()
+	combinedStates, combineStateFunc := StartStateCombiner(events, peerStates, localStates, toData, monitorConfig, cfg, appData.Hostname)
+
+	StartPeerManager(
+		peerHandler.ResultChannel,
+		peerStates,
+		events,
+		combineStateFunc,
+	)
+
+	statInfoHistory, statResultHistory, statMaxKbpses, _, lastKbpsStats, dsStats, statUnpolledCaches, localCacheStatus := StartStatHistoryManager(
+		cacheStatHandler.ResultChan(),
+		localStates,
+		combinedStates,
+		toData,
+		cachesChangedForStatMgr,
+		cfg,
+		monitorConfig,
+		events,
+		expressionStates,
+		dsSLOs,
+		capacityForecasts,
+		combineStateFunc,
+	)
+
+	lastHealthDurations, healthHistory, healthUnpolledCaches := StartHealthResultManager(
+		cacheHealthHandler.ResultChan(),
+		toData,
+		localStates,
+		monitorConfig,
+		fetchCount,
+		cfg,
+		events,
+		expressionStates,
+		localCacheStatus,
+		cachesChangedForHealthMgr,
+		combineStateFunc,
+	)
+
+	StartDistributedPeerManager(
+		distributedPeerHandler.ResultChannel,
+		localStates,
+		distributedPeerStates,
+		events,
+		healthUnpolledCaches,
+	)
+
+	if cfg.CRStatesGRPCListener != "" {
+		if err := crstatesrpc.Start(cfg.CRStatesGRPCListener, crstatesrpc.NewServer(combinedStates, peerStates, cfg.DistributedPolling)); err != nil {
+			return cdnMonitor{}, fmt.Errorf("starting CRStates gRPC server: %v", err)
+		}
+	}
+
+	return cdnMonitor{
+		cdn:                   cdn,
+		cfg:                   cfg,
+		opsConfig:             threadsafe.NewOpsConfig(),
+		opsConfigChannel:      monitorConfigPoller.OpsConfigChannel,
+		sessionChannel:        monitorConfigPoller.SessionChannel,
+		healthTickChan:        cacheHealthPoller.TickChan,
+		toSession:             toSession,
+		toData:                toData,
+		localStates:           localStates,
+		peerStates:            peerStates,
+		distributedPeerStates: distributedPeerStates,
+		combinedStates:        combinedStates,
+		statInfoHistory:       statInfoHistory,
+		statResultHistory:     statResultHistory,
+		statMaxKbpses:         statMaxKbpses,
+		healthHistory:         healthHistory,
+		lastStats:             lastKbpsStats,
+		dsStats:               dsStats,
+		dsSLOs:                dsSLOs,
+		capacityForecasts:     capacityForecasts,
+		events:                events,
+		healthPollInterval:    cacheHealthPoller.Config.Interval,
+		lastHealthDurations:   lastHealthDurations,
+		fetchCount:            fetchCount,
+		healthIteration:       healthIteration,
+		errorCount:            errorCount,
+		localCacheStatus:      localCacheStatus,
+		statUnpolledCaches:    statUnpolledCaches,
+		healthUnpolledCaches:  healthUnpolledCaches,
+		monitorConfig:         monitorConfig,
+	}, nil
+}
+
+// pathPrefix returns the prefix of the paths of the CDN's endpoints: none for this Traffic Monitor's own CDN, and /cdn/{name} for any other.
+func (m cdnMonitor) pathPrefix() string {
+	if m.cdn == "" {
+		return ""
+	}
+	return "/cdn/" + m.cdn
+}
+
+// endpoints returns the HTTP endpoints serving the CDN's data, at its path prefix.
+func (m cdnMonitor) endpoints(staticAppData config.StaticAppData) map[string]http.HandlerFunc {
+	dispatchMap := datareq.MakeDispatchMap(
+		m.opsConfig,
+		m.toSession,
+		m.localStates,
+		m.peerStates,
+		m.distributedPeerStates,
+		m.combinedStates,
+		m.statInfoHistory,
+		m.statResultHistory,
+		m.statMaxKbpses,
+		m.healthHistory,
+		m.dsStats,
+		m.dsSLOs,
+		m.capacityForecasts,
+		m.events,
+		staticAppData,
+		m.healthPollInterval,
+		m.cfg.ServeWriteTimeout,
+		m.lastHealthDurations,
+		m.fetchCount,
+		m.healthIteration,
+		m.errorCount,
+		m.toData,
+		m.localCacheStatus,
+		m.lastStats,
+		m.statUnpolledCaches,
+		m.healthUnpolledCaches,
+		m.monitorConfig,
+		m.cfg.StatPolling,
+		m.cfg.DistributedPolling,
+	)
+	return prefixEndpoints(m.pathPrefix(), dispatchMap)
+}
+
+// prefixEndpoints returns the given endpoints with the given prefix added to their paths.
+func prefixEndpoints(prefix string, endpoints map[string]http.HandlerFunc) map[string]http.HandlerFunc {
+	if prefix == "" {
+		return endpoints
+	}
+	prefixed := make(map[string]http.HandlerFunc, len(endpoints))
+	for path, f := range endpoints {
+		prefixed[prefix+path] = f
+	}
+	return prefixed
+}
+
+// MonitoredCDNsPath is the path of the endpoint listing the CDNs this Traffic Monitor monitors, and the path prefixes of their endpoints.
+const MonitoredCDNsPath = "/api/cdns"
+
+// monitoredCDN is a CDN monitored by this Traffic Monitor, as listed by the MonitoredCDNsPath endpoint.
+type monitoredCDN struct {
+	// CDN is the name of the CDN. The name of this Traffic Monitor's own CDN is empty until it's given by Traffic Ops.
+	CDN string `json:"cdn"`
+	// Own is whether the CDN is this Traffic Monitor's own CDN, whose Traffic Routers use this Traffic Monitor.
+	Own bool `json:"own"`
+	// PathPrefix is the prefix of the paths of the CDN's endpoints.
+	PathPrefix string `json:"pathPrefix"`
+}
+
+// monitoredCDNsEndpoint returns the handler of the MonitoredCDNsPath endpoint, so clients can find the endpoints of each monitored CDN.
+func monitoredCDNsEndpoint(monitors []cdnMonitor) http.HandlerFunc {
+	return datareq.WrapErr(monitors[0].errorCount, func() ([]byte, error) {
+		cdns := make([]monitoredCDN, 0, len(monitors))
+		for _, monitor := range monitors {
+			cdns = append(cdns, monitoredCDN{CDN: monitor.opsConfig.Get().CdnName, Own: monitor.cdn == "", PathPrefix: monitor.pathPrefix()})
+		}
+		json := jsoniter.ConfigFastest
+		return json.Marshal(cdns)
+	}, rfc.ApplicationJSON)
+}
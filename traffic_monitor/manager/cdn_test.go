@@ -0,0 +1,98 @@
+package manager
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/apache/trafficcontrol/traffic_monitor/handler"
+	"github.com/apache/trafficcontrol/traffic_monitor/threadsafe"
+)
+
+func TestPrefixEndpoints(t *testing.T) {
+	f := func(w http.ResponseWriter, r *http.Request) {}
+	endpoints := map[string]http.HandlerFunc{
+		"/publish/CrStates":   f,
+		"/publish/CrStates/":  f,
+		"/api/cache-statuses": f,
+	}
+
+	if own := (cdnMonitor{}).pathPrefix(); own != "" {
+		t.Errorf("own CDN path prefix - expected: empty, actual: %s", own)
+	}
+	if unprefixed := prefixEndpoints("", endpoints); len(unprefixed) != len(endpoints) || unprefixed["/publish/CrStates"] == nil {
+		t.Errorf("own CDN endpoints - expected: unchanged, actual: %v", unprefixed)
+	}
+
+	prefix := (cdnMonitor{cdn: "cdn-b"}).pathPrefix()
+	prefixed := prefixEndpoints(prefix, endpoints)
+	if len(prefixed) != len(endpoints) {
+		t.Fatalf("prefixed endpoints - expected: %d, actual: %d", len(endpoints), len(prefixed))
+	}
+	for _, path := range []string{"/cdn/cdn-b/publish/CrStates", "/cdn/cdn-b/publish/CrStates/", "/cdn/cdn-b/api/cache-statuses"} {
+		if prefixed[path] == nil {
+			t.Errorf("prefixed endpoints - expected: %s, actual: missing", path)
+		}
+	}
+}
+
+func TestCDNOpsConfig(t *testing.T) {
+	opsConfig := handler.OpsConfig{CdnName: "cdn-a", Url: "https://to.example.net", Username: "tm"}
+
+	if own := (cdnMonitor{}).cdnOpsConfig(opsConfig); own != opsConfig {
+		t.Errorf("own CDN ops config - expected: %+v, actual: %+v", opsConfig, own)
+	}
+
+	other := (cdnMonitor{cdn: "cdn-b"}).cdnOpsConfig(opsConfig)
+	if other.CdnName != "cdn-b" {
+		t.Errorf("other CDN ops config CDN - expected: cdn-b, actual: %s", other.CdnName)
+	}
+	if other.Url != opsConfig.Url || other.Username != opsConfig.Username {
+		t.Errorf("other CDN ops config - expected: Traffic Ops of %+v, actual: %+v", opsConfig, other)
+	}
+	if opsConfig.CdnName != "cdn-a" {
+		t.Errorf("ops config - expected: unchanged, actual: %+v", opsConfig)
+	}
+}
+
+func TestMonitoredCDNsEndpoint(t *testing.T) {
+	own := cdnMonitor{opsConfig: threadsafe.NewOpsConfig(), errorCount: threadsafe.NewUint()}
+	own.opsConfig.Set(handler.OpsConfig{CdnName: "cdn-a"})
+	other := cdnMonitor{cdn: "cdn-b", opsConfig: threadsafe.NewOpsConfig(), errorCount: threadsafe.NewUint()}
+	other.opsConfig.Set(handler.OpsConfig{CdnName: "cdn-b"})
+
+	w := httptest.NewRecorder()
+	monitoredCDNsEndpoint([]cdnMonitor{own, other})(w, httptest.NewRequest(http.MethodGet, MonitoredCDNsPath, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("monitored CDNs - expected: status 200, actual: %d", w.Code)
+	}
+	cdns := []monitoredCDN{}
+	if err := json.Unmarshal(w.Body.Bytes(), &cdns); err != nil {
+		t.Fatalf("monitored CDNs - expected: JSON, actual: '%s': %v", w.Body.String(), err)
+	}
+	expected := []monitoredCDN{{CDN: "cdn-a", Own: true, PathPrefix: ""}, {CDN: "cdn-b", Own: false, PathPrefix: "/cdn/cdn-b"}}
+	if !reflect.DeepEqual(expected, cdns) {
+		t.Errorf("monitored CDNs - expected: %+v, actual: %+v", expected, cdns)
+	}
+}
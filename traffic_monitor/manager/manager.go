@@ -29,153 +29,29 @@ import (
 	"golang.org/x/sys/unix"
 
 	"github.com/apache/trafficcontrol/lib/go-log"
-	"github.com/apache/trafficcontrol/traffic_monitor/cache"
 	"github.com/apache/trafficcontrol/traffic_monitor/config"
-	"github.com/apache/trafficcontrol/traffic_monitor/handler"
-	"github.com/apache/trafficcontrol/traffic_monitor/health"
-	"github.com/apache/trafficcontrol/traffic_monitor/peer"
-	"github.com/apache/trafficcontrol/traffic_monitor/poller"
 	"github.com/apache/trafficcontrol/traffic_monitor/threadsafe"
-	"github.com/apache/trafficcontrol/traffic_monitor/todata"
-	"github.com/apache/trafficcontrol/traffic_monitor/towrap"
 )
 
 //
 // Start starts the poller and handler goroutines
 //
 func Start(opsConfigFile string, cfg config.Config, appData config.StaticAppData, trafficMonitorConfigFileName string) error {
-	toSession := towrap.NewTrafficOpsSessionThreadsafe(nil, nil, cfg.CRConfigHistoryCount, cfg)
-
-	localStates := peer.NewCRStatesThreadsafe() // this is the local state as discoverer by this traffic_monitor
-	fetchCount := threadsafe.NewUint()          // note this is the number of individual caches fetched from, not the number of times all the caches were polled.
-	healthIteration := threadsafe.NewUint()
-	errorCount := threadsafe.NewUint()
-
-	toData := todata.NewThreadsafe()
-
-	cacheHealthHandler := cache.NewHandler()
-	cacheHealthPoller := poller.NewCache(true, cacheHealthHandler, cfg, appData)
-	cacheStatHandler := cache.NewPrecomputeHandler(toData)
-	cacheStatPoller := poller.NewCache(false, cacheStatHandler, cfg, appData)
-	monitorConfigPoller := poller.NewMonitorConfig(cfg.MonitorConfigPollingInterval)
-	peerHandler := peer.NewHandler()
-	peerPoller := poller.NewPeer(peerHandler, cfg, appData)
-	distributedPeerHandler := peer.NewHandler()
-	distributedPeerPoller := poller.NewPeer(distributedPeerHandler, cfg, appData)
-
-	go monitorConfigPoller.Poll()
-	go cacheHealthPoller.Poll()
-	if cfg.StatPolling {
-		go cacheStatPoller.Poll()
-	}
-	go peerPoller.Poll()
-	if cfg.DistributedPolling {
-		go distributedPeerPoller.Poll()
+	monitors := make([]cdnMonitor, 0, len(cfg.CDNs)+1)
+	monitor, err := startCDNMonitor("", cfg, appData)
+	if err != nil {
+		return err
 	}
-
-	events := health.NewThreadsafeEvents(cfg.MaxEvents)
-
-	var cachesChangedForStatMgr chan struct{}
-	var cachesChangedForHealthMgr chan struct{}
-	var cachesChanged chan struct{}
-	if cfg.StatPolling {
-		cachesChangedForStatMgr = make(chan struct{})
-		cachesChanged = cachesChangedForStatMgr
-	} else {
-		cachesChangedForHealthMgr = make(chan struct{})
-		cachesChanged = cachesChangedForHealthMgr
+	monitors = append(monitors, monitor)
+	for _, cdn := range cfg.CDNs {
+		cdnMonitor, err := startCDNMonitor(cdn, cfg.ForCDN(cdn), appData)
+		if err != nil {
+			return fmt.Errorf("starting monitoring of CDN '%s': %v", cdn, err)
+		}
+		monitors = append(monitors, cdnMonitor)
 	}
-	peerStates := peer.NewCRStatesPeersThreadsafe(cfg.PeerOptimisticQuorumMin) // each peer's last state is saved in this map
-	distributedPeerStates := peer.NewCRStatesPeersThreadsafe(0)
-
-	monitorConfig := StartMonitorConfigManager(
-		monitorConfigPoller.ConfigChannel,
-		localStates,
-		peerStates,
-		distributedPeerStates,
-		cacheStatPoller.ConfigChannel,
-		cacheHealthPoller.ConfigChannel,
-		peerPoller.ConfigChannel,
-		distributedPeerPoller.ConfigChannel,
-		monitorConfigPoller.IntervalChan,
-		cachesChanged,
-		cfg,
-		appData,
-		toSession,
-		toData,
-	)
-
-	combinedStates, combineStateFunc := StartStateCombiner(events, peerStates, localStates, toData)
-
-	StartPeerManager(
-		peerHandler.ResultChannel,
-		peerStates,
-		events,
-		combineStateFunc,
-	)
 
-	statInfoHistory, statResultHistory, statMaxKbpses, _, lastKbpsStats, dsStats, statUnpolledCaches, localCacheStatus := StartStatHistoryManager(
-		cacheStatHandler.ResultChan(),
-		localStates,
-		combinedStates,
-		toData,
-		cachesChangedForStatMgr,
-		cfg,
-		monitorConfig,
-		events,
-		combineStateFunc,
-	)
-
-	lastHealthDurations, healthHistory, healthUnpolledCaches := StartHealthResultManager(
-		cacheHealthHandler.ResultChan(),
-		toData,
-		localStates,
-		monitorConfig,
-		fetchCount,
-		cfg,
-		events,
-		localCacheStatus,
-		cachesChangedForHealthMgr,
-		combineStateFunc,
-	)
-
-	StartDistributedPeerManager(
-		distributedPeerHandler.ResultChannel,
-		localStates,
-		distributedPeerStates,
-		events,
-		healthUnpolledCaches,
-	)
-
-	if _, err := StartOpsConfigManager(
-		opsConfigFile,
-		toSession,
-		toData,
-		[]chan<- handler.OpsConfig{monitorConfigPoller.OpsConfigChannel},
-		[]chan<- towrap.TrafficOpsSessionThreadsafe{monitorConfigPoller.SessionChannel},
-		localStates,
-		peerStates,
-		distributedPeerStates,
-		combinedStates,
-		statInfoHistory,
-		statResultHistory,
-		statMaxKbpses,
-		healthHistory,
-		lastKbpsStats,
-		dsStats,
-		events,
-		appData,
-		cacheHealthPoller.Config.Interval,
-		lastHealthDurations,
-		fetchCount,
-		healthIteration,
-		errorCount,
-		localCacheStatus,
-		statUnpolledCaches,
-		healthUnpolledCaches,
-		monitorConfig,
-		cfg,
-	); err != nil {
+	if _, err := StartOpsConfigManager(opsConfigFile, monitors, appData, cfg); err != nil {
 		return fmt.Errorf("starting ops config manager: %v", err)
 	}
 
@@ -183,7 +59,10 @@ func Start(opsConfigFile string, cfg config.Config, appData config.StaticAppData
 		return fmt.Errorf("starting monitor config file poller: %v", err)
 	}
 
-	healthTickListener(cacheHealthPoller.TickChan, healthIteration)
+	for _, cdnMonitor := range monitors[1:] {
+		go healthTickListener(cdnMonitor.healthTickChan, cdnMonitor.healthIteration)
+	}
+	healthTickListener(monitor.healthTickChan, monitor.healthIteration)
 	return nil
 }
 
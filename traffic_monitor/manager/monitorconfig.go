@@ -135,6 +135,7 @@ func StartMonitorConfigManager(
 	staticAppData config.StaticAppData,
 	toSession towrap.TrafficOpsSessionThreadsafe,
 	toData todata.TODataThreadsafe,
+	ownCDN bool,
 ) threadsafe.TrafficMonitorConfigMap {
 	monitorConfig := threadsafe.NewTrafficMonitorConfigMap()
 	go monitorConfigListen(monitorConfig,
@@ -152,6 +153,7 @@ func StartMonitorConfigManager(
 		staticAppData,
 		toSession,
 		toData,
+		ownCDN,
 	)
 	return monitorConfig
 }
@@ -209,6 +211,7 @@ func monitorConfigListen(
 	staticAppData config.StaticAppData,
 	toSession towrap.TrafficOpsSessionThreadsafe,
 	toData todata.TODataThreadsafe,
+	ownCDN bool,
 ) {
 	defer func() {
 		if err := recover(); err != nil {
@@ -229,10 +232,6 @@ func monitorConfigListen(
 			log.Errorln("Updating Traffic Ops Data: " + err.Error())
 		}
 
-		healthURLs := map[string]poller.PollConfig{}
-		statURLs := map[string]poller.PollConfig{}
-		peerURLs := map[string]poller.PeerPollConfig{}
-
 		intervals, err := getIntervals(monitorConfig, cfg, logMissingIntervalParams)
 		logMissingIntervalParams = false // only log missing parameters once
 		if err != nil {
@@ -246,6 +245,7 @@ func monitorConfigListen(
 			monitorConfig.TrafficMonitor,
 			monitorConfig.TrafficServer,
 			monitorConfig.CacheGroup,
+			ownCDN,
 		)
 		if err != nil {
 			log.Errorf("getting cachegroups to poll: %s", err.Error())
@@ -268,45 +268,9 @@ func monitorConfigListen(
 			if _, exists := localStates.GetCache(cacheName); !exists {
 				localStates.AddCache(cacheName, tc.IsAvailable{IsAvailable: false, DirectlyPolled: isDirectlyPolled})
 			}
-
-			if !isDirectlyPolled {
-				continue
-			}
-
-			pollURLStr := monitorConfig.Profile[srv.Profile].Parameters.HealthPollingURL
-			if pollURLStr == "" {
-				log.Errorf("monitor config server %v profile %v has no polling URL; can't poll", srv.HostName, srv.Profile)
-				continue
-			}
-
-			format := monitorConfig.Profile[srv.Profile].Parameters.HealthPollingFormat
-			if format == "" {
-				format = cache.DefaultStatsType
-				log.Infof("health.polling.format for '%v' is empty, using default '%v'", srv.HostName, format)
-			}
-
-			pollType := monitorConfig.Profile[srv.Profile].Parameters.HealthPollingType
-			if pollType == "" {
-				pollType = poller.DefaultPollerType
-				log.Infof("health.polling.type for '%v' is empty, using default '%v'", srv.HostName, pollType)
-			}
-
-			pollURL4Str, pollURL6Str := createServerHealthPollURLs(pollURLStr, srv)
-
-			connTimeout := trafficOpsHealthConnectionTimeoutToDuration(monitorConfig.Profile[srv.Profile].Parameters.HealthConnectionTimeout)
-			if connTimeout == 0 {
-				connTimeout = DefaultHealthConnectionTimeout
-				log.Warnln("profile " + srv.Profile + " health.connection.timeout Parameter is missing or zero, using default " + DefaultHealthConnectionTimeout.String())
-			}
-
-			healthURLs[srv.HostName] = poller.PollConfig{URL: pollURL4Str, URLv6: pollURL6Str, Host: srv.FQDN, Timeout: connTimeout, Format: format, PollType: pollType}
-
-			statURL4 := createServerStatPollURL(pollURL4Str)
-			statURL6 := createServerStatPollURL(pollURL6Str)
-			statURLs[srv.HostName] = poller.PollConfig{URL: statURL4, URLv6: statURL6, Host: srv.FQDN, Timeout: connTimeout, Format: format, PollType: pollType}
 		}
+		healthURLs, statURLs := getCachePollConfigs(monitorConfig, cacheGroupsToPoll, cfg)
 
-		peerSet := map[tc.TrafficMonitorName]struct{}{}
 		tmsByGroup := make(map[string][]tc.TrafficMonitor)
 		for _, srv := range monitorConfig.TrafficMonitor {
 			if srv.ServerStatus != thisTMStatus {
@@ -315,22 +279,16 @@ func monitorConfigListen(
 			tmsByGroup[srv.Location] = append(tmsByGroup[srv.Location], srv)
 		}
 
-		for _, srv := range monitorConfig.TrafficMonitor {
-			if srv.HostName == staticAppData.Hostname || (cfg.DistributedPolling && srv.Location != thisTMGroup) {
-				continue
-			}
-			if srv.ServerStatus != thisTMStatus {
-				continue
-			}
-			// TODO: the URL should be config driven. -jse
-			peerURL := fmt.Sprintf("http://%s:%d/publish/CrStates?raw", srv.FQDN, srv.Port)
-			peerURLs[srv.HostName] = poller.PeerPollConfig{URLs: []string{peerURL}}
-			peerSet[tc.TrafficMonitorName(srv.HostName)] = struct{}{}
+		peerURLs := map[string]poller.PeerPollConfig{}
+		peerSet := map[tc.TrafficMonitorName]struct{}{}
+		// Peers are live, so they aren't polled when replaying a recording.
+		if cfg.PollReplayDir == "" {
+			peerURLs, peerSet = getPeerPollConfigs(monitorConfig.TrafficMonitor, staticAppData.Hostname, thisTMGroup, thisTMStatus, cfg.DistributedPolling)
 		}
 		distributedPeerURLs := make(map[string]poller.PeerPollConfig)
-		distributedPeerSet := make(map[tc.TrafficMonitorName]struct{}, len(tmsByGroup)-1)
+		distributedPeerSet := make(map[tc.TrafficMonitorName]struct{}, len(tmsByGroup))
 		for tmGroup, tms := range tmsByGroup {
-			if tmGroup == thisTMGroup {
+			if tmGroup == thisTMGroup || cfg.PollReplayDir != "" {
 				continue
 			}
 			distributedPeerURLs[tmGroup] = poller.PeerPollConfig{URLs: getDistributedPeerURLs(tms)}
@@ -379,16 +337,117 @@ func monitorConfigListen(
 	}
 }
 
+// getCachePollConfigs returns the health and stat poll configs of the REPORTED and ADMIN_DOWN caches in the given cache groups to poll.
+func getCachePollConfigs(monitorConfig tc.TrafficMonitorConfigMap, cacheGroupsToPoll map[string]tc.TMCacheGroup, cfg config.Config) (map[string]poller.PollConfig, map[string]poller.PollConfig) {
+	healthURLs := map[string]poller.PollConfig{}
+	statURLs := map[string]poller.PollConfig{}
+	for _, srv := range monitorConfig.TrafficServer {
+		srvStatus := tc.CacheStatusFromString(srv.ServerStatus)
+		if srvStatus != tc.CacheStatusReported && srvStatus != tc.CacheStatusAdminDown {
+			continue
+		}
+		if _, isDirectlyPolled := cacheGroupsToPoll[srv.CacheGroup]; !isDirectlyPolled {
+			continue
+		}
+
+		pollURLStr := monitorConfig.Profile[srv.Profile].Parameters.HealthPollingURL
+		if pollURLStr == "" {
+			log.Errorf("monitor config server %v profile %v has no polling URL; can't poll", srv.HostName, srv.Profile)
+			continue
+		}
+
+		format := monitorConfig.Profile[srv.Profile].Parameters.HealthPollingFormat
+		if format == "" {
+			format = cache.DefaultStatsType
+			log.Infof("health.polling.format for '%v' is empty, using default '%v'", srv.HostName, format)
+		}
+
+		pollType := monitorConfig.Profile[srv.Profile].Parameters.HealthPollingType
+		if pollType == "" {
+			pollType = poller.DefaultPollerType
+			log.Infof("health.polling.type for '%v' is empty, using default '%v'", srv.HostName, pollType)
+		}
+		if cfg.PollReplayDir != "" {
+			pollType = poller.PollerTypeReplay
+		}
+
+		pollURL4Str, pollURL6Str := createServerHealthPollURLs(pollURLStr, srv)
+
+		connTimeout := trafficOpsHealthConnectionTimeoutToDuration(monitorConfig.Profile[srv.Profile].Parameters.HealthConnectionTimeout)
+		if connTimeout == 0 {
+			connTimeout = DefaultHealthConnectionTimeout
+			log.Warnln("profile " + srv.Profile + " health.connection.timeout Parameter is missing or zero, using default " + DefaultHealthConnectionTimeout.String())
+		}
+
+		// Only health polls probe, so each cache is probed once per health poll interval, rather than by every poller.
+		probe := poller.ProbeConfig{}
+		if pollType == poller.PollerTypeProbe {
+			params := monitorConfig.Profile[srv.Profile].Parameters
+			probe = poller.ProbeConfig{
+				URL:      params.HealthProbeURL,
+				Status:   params.HealthProbeStatus,
+				Checksum: params.HealthProbeChecksum,
+				MaxTTFB:  time.Duration(params.HealthProbeTTFB) * time.Millisecond,
+			}
+			if probe.URL == "" {
+				log.Warnf("health.polling.type for '%v' is '%v' but health.probe.url is empty, only polling stats", srv.HostName, pollType)
+			}
+		}
+
+		healthURLs[srv.HostName] = poller.PollConfig{URL: pollURL4Str, URLv6: pollURL6Str, Host: srv.FQDN, Timeout: connTimeout, Format: format, PollType: pollType, Probe: probe}
+
+		statURL4 := createServerStatPollURL(pollURL4Str)
+		statURL6 := createServerStatPollURL(pollURL6Str)
+		statURLs[srv.HostName] = poller.PollConfig{URL: statURL4, URLv6: statURL6, Host: srv.FQDN, Timeout: connTimeout, Format: format, PollType: pollType}
+	}
+	return healthURLs, statURLs
+}
+
+// getPeerPollConfigs returns the poll configs of this Traffic Monitor's peers, and the set of them: the other Traffic Monitors in the monitoring config with its status, and with distributed polling, in its cache group.
+// Each Traffic Monitor serves the states of its own CDN at /publish/CrStates, so the peers of a CDN other than this Traffic Monitor's own are that CDN's Traffic Monitors, and they're polled the same way.
+func getPeerPollConfigs(monitors map[string]tc.TrafficMonitor, hostname string, thisTMGroup string, thisTMStatus string, distributedPolling bool) (map[string]poller.PeerPollConfig, map[tc.TrafficMonitorName]struct{}) {
+	peerURLs := map[string]poller.PeerPollConfig{}
+	peerSet := map[tc.TrafficMonitorName]struct{}{}
+	for _, srv := range monitors {
+		if srv.HostName == hostname || (distributedPolling && srv.Location != thisTMGroup) {
+			continue
+		}
+		if srv.ServerStatus != thisTMStatus {
+			continue
+		}
+		// TODO: the URL should be config driven. -jse
+		peerURL := fmt.Sprintf("http://%s:%d/publish/CrStates?raw", srv.FQDN, srv.Port)
+		peerURLs[srv.HostName] = poller.PeerPollConfig{URLs: []string{peerURL}}
+		peerSet[tc.TrafficMonitorName(srv.HostName)] = struct{}{}
+	}
+	return peerURLs, peerSet
+}
+
 // getCacheGroupsToPoll returns the name of this Traffic Monitor's cache group,
 // the status of this Traffic Monitor, and the set of cache groups it needs to poll.
+//
+// If the monitoring config isn't of this Traffic Monitor's own CDN, this
+// Traffic Monitor isn't in it. It then has no cache group, it has the ONLINE
+// status, so its peers are the CDN's ONLINE Traffic Monitors, and it polls every
+// cache group, as without distributed polling.
 func getCacheGroupsToPoll(distributedPolling bool, hostname string, monitors map[string]tc.TrafficMonitor,
-	caches map[string]tc.TrafficServer, allCacheGroups map[string]tc.TMCacheGroup) (string, string, map[string]tc.TMCacheGroup, error) {
+	caches map[string]tc.TrafficServer, allCacheGroups map[string]tc.TMCacheGroup, ownCDN bool) (string, string, map[string]tc.TMCacheGroup, error) {
 	tmGroupSet := make(map[string]tc.TMCacheGroup)
 	cacheGroupSet := make(map[string]tc.TMCacheGroup)
 	tmGroupToPolledCacheGroups := make(map[string]map[string]tc.TMCacheGroup)
 	thisTMGroup := ""
 	thisTMStatus := ""
 
+	for _, c := range caches {
+		status := tc.CacheStatusFromString(c.ServerStatus)
+		if status == tc.CacheStatusOnline || status == tc.CacheStatusReported || status == tc.CacheStatusAdminDown {
+			cacheGroupSet[c.CacheGroup] = allCacheGroups[c.CacheGroup]
+		}
+	}
+	if !ownCDN {
+		return "", string(tc.CacheStatusOnline), cacheGroupSet, nil
+	}
+
 	for _, tm := range monitors {
 		if tm.HostName == hostname {
 			thisTMStatus = tm.ServerStatus
@@ -411,13 +470,6 @@ func getCacheGroupsToPoll(distributedPolling bool, hostname string, monitors map
 		}
 	}
 
-	for _, c := range caches {
-		status := tc.CacheStatusFromString(c.ServerStatus)
-		if status == tc.CacheStatusOnline || status == tc.CacheStatusReported || status == tc.CacheStatusAdminDown {
-			cacheGroupSet[c.CacheGroup] = allCacheGroups[c.CacheGroup]
-		}
-	}
-
 	if !distributedPolling {
 		return thisTMGroup, thisTMStatus, cacheGroupSet, nil
 	}
@@ -24,6 +24,8 @@ import (
 	"testing"
 
 	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/config"
+	"github.com/apache/trafficcontrol/traffic_monitor/poller"
 )
 
 func TestCreateServerHealthPollURL(t *testing.T) {
@@ -392,7 +394,7 @@ func TestGetCacheGroupsToPoll(t *testing.T) {
 			ExpectErr: false,
 		},
 	} {
-		tmGroup, tmStatus, toPoll, err := getCacheGroupsToPoll(tc.DistributedPolling, tc.TMName, monitors, caches, cacheGroups)
+		tmGroup, tmStatus, toPoll, err := getCacheGroupsToPoll(tc.DistributedPolling, tc.TMName, monitors, caches, cacheGroups, true)
 		if tc.ExpectErr != (err != nil) {
 			t.Errorf("getting cachegroups to poll -- expect error: %t, actual error: %v", tc.ExpectErr, err)
 		}
@@ -407,3 +409,63 @@ func TestGetCacheGroupsToPoll(t *testing.T) {
 		}
 	}
 }
+
+func TestOtherCDNPollConfigs(t *testing.T) {
+	monitorConfig := tc.TrafficMonitorConfigMap{
+		TrafficMonitor: map[string]tc.TrafficMonitor{
+			"tm-b1": {HostName: "tm-b1", FQDN: "tm-b1.cdn-b.example.net", Port: 80, Location: "tm-group-b", ServerStatus: "ONLINE"},
+			"tm-b2": {HostName: "tm-b2", FQDN: "tm-b2.cdn-b.example.net", Port: 80, Location: "tm-group-b", ServerStatus: "OFFLINE"},
+		},
+		TrafficServer: map[string]tc.TrafficServer{
+			"edge-b1": {
+				HostName:     "edge-b1",
+				FQDN:         "edge-b1.cdn-b.example.net",
+				CacheGroup:   "cache-group-b",
+				Profile:      "EDGE_B",
+				ServerStatus: "REPORTED",
+				Interfaces: []tc.ServerInterfaceInfo{{
+					IPAddresses: []tc.ServerIPAddress{{Address: "192.0.2.1", ServiceAddress: true}},
+					Monitor:     true,
+					Name:        "eth0",
+				}},
+			},
+			"edge-b2": {HostName: "edge-b2", CacheGroup: "cache-group-b", Profile: "EDGE_B", ServerStatus: "OFFLINE"},
+		},
+		CacheGroup: map[string]tc.TMCacheGroup{
+			"cache-group-b": {Name: "cache-group-b"},
+			"tm-group-b":    {Name: "tm-group-b"},
+		},
+		Profile: map[string]tc.TMProfile{
+			"EDGE_B": {Name: "EDGE_B", Parameters: tc.TMParameters{HealthPollingURL: "http://${hostname}/_astats?application=&inf.name=${interface_name}", HealthConnectionTimeout: 2000}},
+		},
+	}
+
+	// This Traffic Monitor, tm-a, isn't in the monitoring config of another CDN.
+	if _, _, _, err := getCacheGroupsToPoll(false, "tm-a", monitorConfig.TrafficMonitor, monitorConfig.TrafficServer, monitorConfig.CacheGroup, true); err == nil {
+		t.Error("getting cachegroups to poll of own CDN without this Traffic Monitor - expected: error, actual: nil")
+	}
+	tmGroup, tmStatus, toPoll, err := getCacheGroupsToPoll(false, "tm-a", monitorConfig.TrafficMonitor, monitorConfig.TrafficServer, monitorConfig.CacheGroup, false)
+	if err != nil {
+		t.Fatalf("getting cachegroups to poll of other CDN - expected: no error, actual: %v", err)
+	}
+	if tmGroup != "" || tmStatus != "ONLINE" {
+		t.Errorf("other CDN TM group and status - expected: none and ONLINE, actual: '%s' and '%s'", tmGroup, tmStatus)
+	}
+
+	healthURLs, statURLs := getCachePollConfigs(monitorConfig, toPoll, config.DefaultConfig)
+	if len(healthURLs) != 1 || healthURLs["edge-b1"].URL != "http://192.0.2.1/_astats?application=system&inf.name=eth0" {
+		t.Errorf("other CDN health poll configs - expected: edge-b1, actual: %+v", healthURLs)
+	}
+	if len(statURLs) != 1 || statURLs["edge-b1"].Host != "edge-b1.cdn-b.example.net" {
+		t.Errorf("other CDN stat poll configs - expected: edge-b1, actual: %+v", statURLs)
+	}
+
+	peerURLs, peerSet := getPeerPollConfigs(monitorConfig.TrafficMonitor, "tm-a", tmGroup, tmStatus, false)
+	expectedPeerURLs := map[string]poller.PeerPollConfig{"tm-b1": {URLs: []string{"http://tm-b1.cdn-b.example.net:80/publish/CrStates?raw"}}}
+	if !reflect.DeepEqual(expectedPeerURLs, peerURLs) {
+		t.Errorf("other CDN peer poll configs - expected: %+v, actual: %+v", expectedPeerURLs, peerURLs)
+	}
+	if _, ok := peerSet["tm-b1"]; !ok || len(peerSet) != 1 {
+		t.Errorf("other CDN peers - expected: tm-b1, actual: %v", peerSet)
+	}
+}
@@ -67,6 +67,9 @@ func StartStatHistoryManager(
 	cfg config.Config,
 	monitorConfig threadsafe.TrafficMonitorConfigMap,
 	events health.ThreadsafeEvents,
+	expressionStates health.ExpressionStates,
+	dsSLOs ds.SLOs,
+	capacityForecasts ds.CapacityForecasts,
 	combineState func(),
 ) (threadsafe.ResultInfoHistory, threadsafe.ResultStatHistory, threadsafe.CacheKbpses, threadsafe.DurationMap, threadsafe.LastStats, threadsafe.DSStatsReader, threadsafe.UnpolledCaches, threadsafe.CacheAvailableStatus) {
 	statInfoHistory := threadsafe.NewResultInfoHistory()
@@ -96,7 +99,7 @@ func StartStatHistoryManager(
 		if haveCachesChanged() {
 			statUnpolledCaches.SetNewCaches(getNewCaches(localStates, monitorConfig))
 		}
-		processStatResults(results, statInfoHistory, statResultHistory, statMaxKbpses, combinedStates, lastStats, toData.Get(), dsStats, lastStatEndTimes, lastStatDurations, statUnpolledCaches, monitorConfig.Get(), precomputedData, lastResults, localStates, events, localCacheStatus, combineState, cfg.CachePollingProtocol)
+		processStatResults(results, statInfoHistory, statResultHistory, statMaxKbpses, combinedStates, lastStats, toData.Get(), dsStats, lastStatEndTimes, lastStatDurations, statUnpolledCaches, monitorConfig.Get(), precomputedData, lastResults, localStates, events, expressionStates, dsSLOs, capacityForecasts, localCacheStatus, combineState, cfg.CachePollingProtocol)
 	}
 
 	go func() {
@@ -249,6 +252,9 @@ func processStatResults(
 	lastResults map[tc.CacheName]cache.Result,
 	localStates peer.CRStatesThreadsafe,
 	events health.ThreadsafeEvents,
+	expressionStates health.ExpressionStates,
+	dsSLOs ds.SLOs,
+	capacityForecasts ds.CapacityForecasts,
 	localCacheStatusThreadsafe threadsafe.CacheAvailableStatus,
 	combineState func(),
 	pollingProtocol config.PollingProtocol,
@@ -309,9 +315,12 @@ func processStatResults(
 
 	dsStats.Set(*newDsStats)
 	lastStats.Set(*lastStatsCopy)
+	now := time.Now()
+	dsSLOs.Update(newDsStats, combinedStates, toData, mc, statInfoHistory, now)
+	capacityForecasts.Update(newDsStats, combinedStates, toData, statInfoHistory, now)
 
 	pollerName := "stat"
-	health.CalcAvailability(results, pollerName, &statResultHistoryThreadsafe, mc, toData, localCacheStatusThreadsafe, localStates, events, pollingProtocol)
+	health.CalcAvailability(results, pollerName, &statResultHistoryThreadsafe, mc, toData, localCacheStatusThreadsafe, localStates, events, pollingProtocol, expressionStates)
 	combineState()
 
 	endTime := time.Now()
@@ -21,18 +21,21 @@ package manager
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/apache/trafficcontrol/lib/go-log"
 	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/config"
 	"github.com/apache/trafficcontrol/traffic_monitor/health"
 	"github.com/apache/trafficcontrol/traffic_monitor/peer"
+	"github.com/apache/trafficcontrol/traffic_monitor/threadsafe"
 	"github.com/apache/trafficcontrol/traffic_monitor/todata"
 )
 
 // StartStateCombiner starts the State Combiner goroutine, and returns the threadsafe CombinedStates, and a func to signal to combine states.
-func StartStateCombiner(events health.ThreadsafeEvents, peerStates peer.CRStatesPeersThreadsafe, localStates peer.CRStatesThreadsafe, toData todata.TODataThreadsafe) (peer.CRStatesThreadsafe, func()) {
+func StartStateCombiner(events health.ThreadsafeEvents, peerStates peer.CRStatesPeersThreadsafe, localStates peer.CRStatesThreadsafe, toData todata.TODataThreadsafe, monitorConfig threadsafe.TrafficMonitorConfigMap, cfg config.Config, hostname string) (peer.CRStatesThreadsafe, func()) {
 	combinedStates := peer.NewCRStatesThreadsafe()
 
 	// the chan buffer just reduces the number of goroutines on our infinite buffer hack in combineState(), no real writer will block, since combineState() writes in a goroutine.
@@ -47,18 +50,109 @@ func StartStateCombiner(events health.ThreadsafeEvents, peerStates peer.CRStates
 	go func() {
 		overrideMap := map[tc.CacheName]bool{}
 		for range combineStateChan {
-			combineCrStates(events, true, peerStates.GetCRStatesPeersInfo(), localStates.Get(), combinedStates, overrideMap, toData.Get())
+			voting := votingConfig{policy: cfg.PeerCombinePolicy, localName: tc.TrafficMonitorName(hostname), localityWeight: cfg.PeerLocalityWeight}
+			if voting.policy == config.PeerCombineLocality {
+				voting.monitorCacheGroups = monitorCacheGroups(monitorConfig.Get())
+			}
+			combineCrStates(events, voting, peerStates.GetCRStatesPeersInfo(), localStates.Get(), combinedStates, overrideMap, toData.Get())
 		}
 	}()
 
 	return combinedStates, combineState
 }
 
+// votingConfig is how the votes of this Traffic Monitor and its peers on the availability of a cache are combined.
+type votingConfig struct {
+	policy         config.PeerCombinePolicy
+	localName      tc.TrafficMonitorName
+	localityWeight float64
+	// monitorCacheGroups are the Cache Groups of the Traffic Monitors, for the locality policy.
+	monitorCacheGroups map[tc.TrafficMonitorName]tc.CacheGroupName
+}
+
+// weight returns the weight of the given Traffic Monitor's vote on a cache in the given Cache Group.
+func (v votingConfig) weight(monitor tc.TrafficMonitorName, cacheGroup tc.CacheGroupName) float64 {
+	if v.policy == config.PeerCombineLocality && cacheGroup != "" && v.monitorCacheGroups[monitor] == cacheGroup {
+		return v.localityWeight
+	}
+	return 1
+}
+
+func monitorCacheGroups(monitorConfig tc.TrafficMonitorConfigMap) map[tc.TrafficMonitorName]tc.CacheGroupName {
+	cacheGroups := make(map[tc.TrafficMonitorName]tc.CacheGroupName, len(monitorConfig.TrafficMonitor))
+	for _, monitor := range monitorConfig.TrafficMonitor {
+		cacheGroups[tc.TrafficMonitorName(monitor.HostName)] = tc.CacheGroupName(monitor.Location)
+	}
+	return cacheGroups
+}
+
+// cacheVote is a Traffic Monitor's state of a cache, and the weight of its vote.
+type cacheVote struct {
+	monitor tc.TrafficMonitorName
+	state   tc.IsAvailable
+	weight  float64
+}
+
+// cacheVotes returns the votes of this Traffic Monitor and every available peer which has a state for the cache, ordered by name.
+func cacheVotes(cacheName tc.CacheName, localCacheState tc.IsAvailable, voting votingConfig, peerCrStatesInfo peer.CRStatesPeersInfo, toData todata.TOData) []cacheVote {
+	cacheGroup := toData.ServerCachegroups[cacheName]
+	votes := []cacheVote{{monitor: voting.localName, state: localCacheState, weight: voting.weight(voting.localName, cacheGroup)}}
+	for peerName, peerCrStates := range peerCrStatesInfo.GetCrStates() {
+		if !peerCrStatesInfo.GetPeerAvailability(peerName) {
+			continue
+		}
+		state, ok := peerCrStates.Caches[cacheName]
+		if !ok {
+			continue
+		}
+		votes = append(votes, cacheVote{monitor: peerName, state: state, weight: voting.weight(peerName, cacheGroup)})
+	}
+	sort.Slice(votes, func(i, j int) bool { return votes[i].monitor < votes[j].monitor })
+	return votes
+}
+
+// combineVotes returns whether the cache is available, and available over IPv4 and IPv6, by the votes and the given policy, which must be majority, locality or pessimistic. Ties are broken by the local state.
+func combineVotes(policy config.PeerCombinePolicy, votes []cacheVote, localCacheState tc.IsAvailable) (bool, bool, bool) {
+	decide := func(availability func(tc.IsAvailable) bool) bool {
+		available, unavailable := 0.0, 0.0
+		for _, vote := range votes {
+			if availability(vote.state) {
+				available += vote.weight
+			} else {
+				unavailable += vote.weight
+			}
+		}
+		if policy == config.PeerCombinePessimistic {
+			return unavailable == 0
+		}
+		if available != unavailable {
+			return available > unavailable
+		}
+		return availability(localCacheState)
+	}
+	return decide(func(s tc.IsAvailable) bool { return s.IsAvailable }),
+		decide(func(s tc.IsAvailable) bool { return s.Ipv4Available }),
+		decide(func(s tc.IsAvailable) bool { return s.Ipv6Available })
+}
+
+// votesRecord returns the names of the Traffic Monitors which voted the cache available and unavailable.
+func votesRecord(votes []cacheVote) *tc.CRStatesVotes {
+	record := &tc.CRStatesVotes{Available: []tc.TrafficMonitorName{}, Unavailable: []tc.TrafficMonitorName{}}
+	for _, vote := range votes {
+		if vote.state.IsAvailable {
+			record.Available = append(record.Available, vote.monitor)
+		} else {
+			record.Unavailable = append(record.Unavailable, vote.monitor)
+		}
+	}
+	return record
+}
+
 func combineCacheState(
 	cacheName tc.CacheName,
 	localCacheState tc.IsAvailable,
 	events health.ThreadsafeEvents,
-	peerOptimistic bool,
+	voting votingConfig,
 	peerCrStatesInfo peer.CRStatesPeersInfo,
 	combinedStates peer.CRStatesThreadsafe,
 	overrideMap map[tc.CacheName]bool,
@@ -70,15 +164,30 @@ func combineCacheState(
 	ipv4Available := localCacheState.Ipv4Available
 	ipv6Available := localCacheState.Ipv6Available
 	override := overrideMap[cacheName]
+	votes := cacheVotes(cacheName, localCacheState, voting, peerCrStatesInfo, toData)
 
-	if localCacheState.Ipv4Available && localCacheState.Ipv6Available {
-		// we don't care about the peers, we got a "good one", and we're optimistic
-		if override {
-			overrideCondition = "cleared; healthy locally"
+	switch voting.policy {
+	case config.PeerCombineMajority, config.PeerCombineLocality, config.PeerCombinePessimistic:
+		localAvailable := available
+		available, ipv4Available, ipv6Available = combineVotes(voting.policy, votes, localCacheState)
+		if available != localAvailable {
+			if !override {
+				record := votesRecord(votes)
+				overrideCondition = fmt.Sprintf("detected; %s by %s vote, available on [%s], unavailable on [%s]", availableOrUnavailable(available), voting.policy, joinMonitorNames(record.Available), joinMonitorNames(record.Unavailable))
+				overrideMap[cacheName] = true
+			}
+		} else if override {
+			overrideCondition = fmt.Sprintf("cleared; %s vote agrees with local state", voting.policy)
 			overrideMap[cacheName] = false
 		}
-	} else if peerOptimistic {
-		if !peerCrStatesInfo.HasAvailablePeers() {
+	default:
+		if localCacheState.Ipv4Available && localCacheState.Ipv6Available {
+			// we don't care about the peers, we got a "good one", and we're optimistic
+			if override {
+				overrideCondition = "cleared; healthy locally"
+				overrideMap[cacheName] = false
+			}
+		} else if !peerCrStatesInfo.HasAvailablePeers() {
 			if override {
 				overrideCondition = "irrelevant; no peers online"
 				overrideMap[cacheName] = false
@@ -133,7 +242,22 @@ func combineCacheState(
 				IPv6Available: ipv6Available})
 	}
 
-	combinedStates.AddCache(cacheName, tc.IsAvailable{IsAvailable: available, Ipv4Available: ipv4Available, Ipv6Available: ipv6Available, DirectlyPolled: localCacheState.DirectlyPolled, Status: localCacheState.Status, LastPoll: localCacheState.LastPoll})
+	combinedStates.AddCache(cacheName, tc.IsAvailable{IsAvailable: available, Ipv4Available: ipv4Available, Ipv6Available: ipv6Available, DirectlyPolled: localCacheState.DirectlyPolled, Status: localCacheState.Status, LastPoll: localCacheState.LastPoll, Votes: votesRecord(votes)})
+}
+
+func availableOrUnavailable(available bool) string {
+	if available {
+		return "available"
+	}
+	return "unavailable"
+}
+
+func joinMonitorNames(names []tc.TrafficMonitorName) string {
+	strs := make([]string, 0, len(names))
+	for _, name := range names {
+		strs = append(strs, name.String())
+	}
+	return strings.Join(strs, ", ")
 }
 
 func combineDSState(
@@ -195,9 +319,9 @@ func pruneCombinedCaches(combinedStates peer.CRStatesThreadsafe, localStates tc.
 	}
 }
 
-func combineCrStates(events health.ThreadsafeEvents, peerOptimistic bool, peerCrStatesInfo peer.CRStatesPeersInfo, localStates tc.CRStates, combinedStates peer.CRStatesThreadsafe, overrideMap map[tc.CacheName]bool, toData todata.TOData) {
+func combineCrStates(events health.ThreadsafeEvents, voting votingConfig, peerCrStatesInfo peer.CRStatesPeersInfo, localStates tc.CRStates, combinedStates peer.CRStatesThreadsafe, overrideMap map[tc.CacheName]bool, toData todata.TOData) {
 	for cacheName, localCacheState := range localStates.Caches { // localStates gets pruned when servers are disabled, it's the source of truth
-		combineCacheState(cacheName, localCacheState, events, peerOptimistic, peerCrStatesInfo, combinedStates, overrideMap, toData)
+		combineCacheState(cacheName, localCacheState, events, voting, peerCrStatesInfo, combinedStates, overrideMap, toData)
 	}
 
 	for deliveryServiceName, localDeliveryService := range localStates.DeliveryService {
@@ -21,10 +21,12 @@ package manager
 
 import (
 	"math/rand"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/config"
 	"github.com/apache/trafficcontrol/traffic_monitor/health"
 	"github.com/apache/trafficcontrol/traffic_monitor/peer"
 	"github.com/apache/trafficcontrol/traffic_monitor/todata"
@@ -55,7 +57,7 @@ func TestCombineCacheState(t *testing.T) {
 		},
 	}
 	events := health.NewThreadsafeEvents(1)
-	peerOptimistic := true
+	voting := votingConfig{policy: config.PeerCombineOptimistic, localName: "TestTM-00"}
 	peerStates := peer.NewCRStatesPeersThreadsafe(1)
 	peerStates.SetTimeout(time.Duration(rand.Int63()))
 	peerResult := peer.Result{
@@ -88,7 +90,7 @@ func TestCombineCacheState(t *testing.T) {
 	}
 
 	for _, localCacheState := range localCacheStates {
-		combineCacheState(cacheName, localCacheState, events, peerOptimistic, peerStates.GetCRStatesPeersInfo(), combinedStates, overrideMap, toData)
+		combineCacheState(cacheName, localCacheState, events, voting, peerStates.GetCRStatesPeersInfo(), combinedStates, overrideMap, toData)
 
 		if !combinedStates.Get().Caches[cacheName].IsAvailable {
 			t.Fatalf("cache is unavailable and should be available")
@@ -111,7 +113,7 @@ func TestCombineCacheStateCacheDown(t *testing.T) {
 	}
 
 	events := health.NewThreadsafeEvents(1)
-	peerOptimistic := true
+	voting := votingConfig{policy: config.PeerCombineOptimistic, localName: "TestTM-00"}
 	peerStates := peer.NewCRStatesPeersThreadsafe(1)
 	peerStates.SetTimeout(time.Duration(rand.Int63()))
 	peerResult := peer.Result{
@@ -143,7 +145,7 @@ func TestCombineCacheStateCacheDown(t *testing.T) {
 		cacheName: tc.CacheTypeEdge,
 	}
 
-	combineCacheState(cacheName, localCacheState, events, peerOptimistic, peerStates.GetCRStatesPeersInfo(), combinedStates, overrideMap, toData)
+	combineCacheState(cacheName, localCacheState, events, voting, peerStates.GetCRStatesPeersInfo(), combinedStates, overrideMap, toData)
 
 	if !combinedStates.Get().Caches[cacheName].IsAvailable {
 		t.Fatalf("cache is unavailable and should be available")
@@ -155,3 +157,81 @@ func TestCombineCacheStateCacheDown(t *testing.T) {
 		t.Fatalf("cache IPv6 is unavailable and should be available")
 	}
 }
+
+func TestCombineCacheStateVotes(t *testing.T) {
+	cacheName := tc.CacheName("testCache")
+	up := tc.IsAvailable{IsAvailable: true, Ipv4Available: true, Ipv6Available: true}
+	down := tc.IsAvailable{}
+
+	peerStates := peer.NewCRStatesPeersThreadsafe(1)
+	peerSet := map[tc.TrafficMonitorName]struct{}{}
+	peerVotes := map[tc.TrafficMonitorName]tc.IsAvailable{"TestTM-01": down, "TestTM-02": down, "TestTM-03": up, "TestTM-04": down}
+	for name, state := range peerVotes {
+		peerStates.Set(peer.Result{
+			ID:         name,
+			Available:  true,
+			PeerStates: tc.CRStates{Caches: map[tc.CacheName]tc.IsAvailable{cacheName: state}},
+			Time:       time.Now(),
+		})
+		peerSet[name] = struct{}{}
+	}
+	peerStates.SetPeers(peerSet)
+
+	toData := todata.TOData{
+		ServerTypes:       map[tc.CacheName]tc.CacheType{cacheName: tc.CacheTypeEdge},
+		ServerCachegroups: map[tc.CacheName]tc.CacheGroupName{cacheName: "cg-near"},
+	}
+	monitorCacheGroups := map[tc.TrafficMonitorName]tc.CacheGroupName{"TestTM-00": "cg-near", "TestTM-01": "cg-far", "TestTM-02": "cg-far", "TestTM-03": "cg-near", "TestTM-04": "cg-far"}
+
+	tests := []struct {
+		policy    config.PeerCombinePolicy
+		weight    float64
+		expected  bool
+		overrides bool
+	}{
+		{policy: config.PeerCombineOptimistic, expected: true, overrides: false},
+		{policy: config.PeerCombineMajority, expected: false, overrides: true},
+		{policy: config.PeerCombineLocality, weight: 2, expected: true, overrides: false}, // 2+2 available, 3 unavailable
+		{policy: config.PeerCombineLocality, weight: 1.4, expected: false, overrides: true},
+		{policy: config.PeerCombinePessimistic, expected: false, overrides: true},
+	}
+	for _, test := range tests {
+		events := health.NewThreadsafeEvents(10)
+		combinedStates := peer.NewCRStatesThreadsafe()
+		overrideMap := map[tc.CacheName]bool{}
+		voting := votingConfig{policy: test.policy, localName: "TestTM-00", localityWeight: test.weight, monitorCacheGroups: monitorCacheGroups}
+
+		combineCacheState(cacheName, up, events, voting, peerStates.GetCRStatesPeersInfo(), combinedStates, overrideMap, toData)
+
+		combined := combinedStates.Get().Caches[cacheName]
+		if combined.IsAvailable != test.expected || combined.Ipv4Available != test.expected || combined.Ipv6Available != test.expected {
+			t.Errorf("policy %s weight %v: expected available %v, actual %+v", test.policy, test.weight, test.expected, combined)
+		}
+		if overrideMap[cacheName] != test.overrides {
+			t.Errorf("policy %s weight %v: expected override %v, actual %v", test.policy, test.weight, test.overrides, overrideMap[cacheName])
+		}
+		if test.overrides && len(events.Get()) != 1 {
+			t.Errorf("policy %s weight %v: expected an override event, actual %+v", test.policy, test.weight, events.Get())
+		}
+		if combined.Votes == nil {
+			t.Fatalf("policy %s: expected votes, actual nil", test.policy)
+		}
+		expectedAvailable := []tc.TrafficMonitorName{"TestTM-00", "TestTM-03"}
+		expectedUnavailable := []tc.TrafficMonitorName{"TestTM-01", "TestTM-02", "TestTM-04"}
+		if !reflect.DeepEqual(combined.Votes.Available, expectedAvailable) || !reflect.DeepEqual(combined.Votes.Unavailable, expectedUnavailable) {
+			t.Errorf("policy %s: expected votes available %v unavailable %v, actual %+v", test.policy, expectedAvailable, expectedUnavailable, *combined.Votes)
+		}
+	}
+}
+
+func TestCombineVotesTie(t *testing.T) {
+	up := tc.IsAvailable{IsAvailable: true, Ipv4Available: true}
+	down := tc.IsAvailable{}
+	votes := []cacheVote{{monitor: "local", state: up, weight: 1}, {monitor: "peer", state: down, weight: 1}}
+	if available, ipv4, ipv6 := combineVotes(config.PeerCombineMajority, votes, up); !available || !ipv4 || ipv6 {
+		t.Errorf("expected a tie to be broken by the local state, actual available %v ipv4 %v ipv6 %v", available, ipv4, ipv6)
+	}
+	if available, _, _ := combineVotes(config.PeerCombineMajority, votes, down); available {
+		t.Errorf("expected a tie to be broken by the local state, actual available")
+	}
+}
@@ -32,14 +32,39 @@ const defaultMapCapacity = 8
 // This could be made lock-free, if the performance was necessary
 // TODO add separate locks for Caches and DeliveryService maps?
 type CRStatesThreadsafe struct {
-	crStates *tc.CRStates
-	m        *sync.RWMutex
+	crStates    *tc.CRStates
+	subscribers *map[chan struct{}]struct{}
+	m           *sync.RWMutex
 }
 
 // NewCRStatesThreadsafe creates a new CRStatesThreadsafe object safe for multiple goroutine readers and a single writer.
 func NewCRStatesThreadsafe() CRStatesThreadsafe {
 	crs := tc.NewCRStates(defaultMapCapacity, defaultMapCapacity)
-	return CRStatesThreadsafe{m: &sync.RWMutex{}, crStates: &crs}
+	return CRStatesThreadsafe{m: &sync.RWMutex{}, crStates: &crs, subscribers: &map[chan struct{}]struct{}{}}
+}
+
+// Subscribe returns a channel which receives whenever the states are set, and a func to unsubscribe, which must be called when the subscriber is done.
+// Notifications are coalesced: the channel has a buffer of one, and a notification is dropped if one is already waiting, so a slow subscriber sees only that the states changed since it last received, and must Get them.
+func (t *CRStatesThreadsafe) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	t.m.Lock()
+	(*t.subscribers)[ch] = struct{}{}
+	t.m.Unlock()
+	return ch, func() {
+		t.m.Lock()
+		delete(*t.subscribers, ch)
+		t.m.Unlock()
+	}
+}
+
+// notify notifies all subscribers that the states were set. Callers must hold the write lock.
+func (t *CRStatesThreadsafe) notify() {
+	for ch := range *t.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
 }
 
 // Get returns the internal Crstates object for reading.
@@ -84,6 +109,7 @@ func (t *CRStatesThreadsafe) SetCache(cacheName tc.CacheName, available tc.IsAva
 	t.m.Lock()
 	if _, ok := t.crStates.Caches[cacheName]; ok {
 		t.crStates.Caches[cacheName] = available
+		t.notify()
 	}
 	t.m.Unlock()
 }
@@ -92,6 +118,7 @@ func (t *CRStatesThreadsafe) SetCache(cacheName tc.CacheName, available tc.IsAva
 func (t *CRStatesThreadsafe) AddCache(cacheName tc.CacheName, available tc.IsAvailable) {
 	t.m.Lock()
 	t.crStates.Caches[cacheName] = available
+	t.notify()
 	t.m.Unlock()
 }
 
@@ -99,6 +126,7 @@ func (t *CRStatesThreadsafe) AddCache(cacheName tc.CacheName, available tc.IsAva
 func (t *CRStatesThreadsafe) DeleteCache(name tc.CacheName) {
 	t.m.Lock()
 	delete(t.crStates.Caches, name)
+	t.notify()
 	t.m.Unlock()
 }
 
@@ -106,6 +134,7 @@ func (t *CRStatesThreadsafe) DeleteCache(name tc.CacheName) {
 func (t *CRStatesThreadsafe) SetDeliveryService(name tc.DeliveryServiceName, ds tc.CRStatesDeliveryService) {
 	t.m.Lock()
 	t.crStates.DeliveryService[name] = ds
+	t.notify()
 	t.m.Unlock()
 }
 
@@ -113,6 +142,7 @@ func (t *CRStatesThreadsafe) SetDeliveryService(name tc.DeliveryServiceName, ds
 func (t *CRStatesThreadsafe) DeleteDeliveryService(name tc.DeliveryServiceName) {
 	t.m.Lock()
 	delete(t.crStates.DeliveryService, name)
+	t.notify()
 	t.m.Unlock()
 }
 
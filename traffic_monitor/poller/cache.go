@@ -21,6 +21,7 @@ package poller
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"math/rand"
 	"runtime"
@@ -33,11 +34,15 @@ import (
 )
 
 type CachePoller struct {
+	// Name is the name of the poller, e.g. "health" or "stat", which names its poll recordings.
+	Name           string
 	Config         CachePollerConfig
 	ConfigChannel  chan CachePollerConfig
 	TickChan       chan uint64
 	GlobalContexts map[string]interface{}
 	Handler        handler.Handler
+	// Recorder records the results of polls, if polls are recorded. If nil, polls aren't recorded.
+	Recorder *PollRecorder
 }
 
 type PollConfig struct {
@@ -47,6 +52,7 @@ type PollConfig struct {
 	Timeout  time.Duration
 	Format   string
 	PollType string
+	Probe    ProbeConfig
 }
 
 type CachePollerConfig struct {
@@ -56,9 +62,11 @@ type CachePollerConfig struct {
 	PollingProtocol config.PollingProtocol
 }
 
-// NewCache creates and returns a new CachePoller.
+// NewCache creates and returns a new CachePoller, with the given name.
 // If tick is false, CachePoller.TickChan() will return nil.
+// If the config has a poll record directory, the poller records its polls there.
 func NewCache(
+	name string,
 	tick bool,
 	handler handler.Handler,
 	cfg config.Config,
@@ -68,7 +76,12 @@ func NewCache(
 	if tick {
 		tickChan = make(chan uint64)
 	}
+	var recorder *PollRecorder
+	if cfg.PollRecordDir != "" {
+		recorder = NewPollRecorder(cfg.PollRecordDir, cfg.PollRecordMaxBytes)
+	}
 	return CachePoller{
+		Name:          name,
 		TickChan:      tickChan,
 		ConfigChannel: make(chan CachePollerConfig),
 		Config: CachePollerConfig{
@@ -76,6 +89,7 @@ func NewCache(
 		},
 		GlobalContexts: GetGlobalContexts(cfg, appData),
 		Handler:        handler,
+		Recorder:       recorder,
 	}
 }
 
@@ -95,7 +109,12 @@ func (p CachePoller) Poll() {
 		deletions, additions := diffConfigs(p.Config, newConfig)
 		for _, id := range deletions {
 			killChan := killChans[id]
-			go func() { killChan <- struct{}{} }() // go - we don't want to wait for old polls to die.
+			go func(id string) { // go - we don't want to wait for old polls to die.
+				killChan <- struct{}{}
+				if p.Recorder != nil {
+					p.Recorder.CloseRecording(p.Name, id)
+				}
+			}(id)
 			delete(killChans, id)
 		}
 		for _, info := range additions {
@@ -114,20 +133,33 @@ func (p CachePoller) Poll() {
 				Timeout:     info.Timeout,
 				NoKeepAlive: info.NoKeepAlive,
 				PollerID:    info.ID,
+				Probe:       info.Probe,
+				CachePoller: p.Name,
 			}
 			pollerCtx := interface{}(nil)
 			if pollerObj.Init != nil {
 				pollerCtx = pollerObj.Init(pollerCfg, p.GlobalContexts[info.PollType])
 			}
-			go poller(info.Interval, info.ID, info.PollingProtocol, info.URL, info.URLv6, info.Host, info.Format, p.Handler, pollerObj.Poll, pollerCtx, kill)
+			pollFunc := pollerObj.Poll
+			if p.Recorder != nil {
+				pollFunc = p.Recorder.wrap(p.Name, info, pollFunc)
+			}
+			// Replay polls are returned when they're due, so they're paced by the recording rather than the interval.
+			paced := info.PollType == PollerTypeReplay
+			go poller(info.Interval, paced, info.ID, info.PollingProtocol, info.URL, info.URLv6, info.Host, info.Format, p.Handler, pollFunc, pollerCtx, kill)
 		}
 		p.Config = newConfig
 	}
+	if p.Recorder != nil {
+		p.Recorder.Close()
+	}
 }
 
 // TODO iterationCount and/or p.TickChan?
+// If paced is true, the poller polls again as soon as each poll is handled, rather than every interval, because pollFunc waits until the next poll is due.
 func poller(
 	interval time.Duration,
+	paced bool,
 	id string,
 	pollingProtocol config.PollingProtocol,
 	url string,
@@ -139,9 +171,18 @@ func poller(
 	pollCtx interface{},
 	die <-chan struct{},
 ) {
-	pollSpread := time.Duration(rand.Float64()*float64(interval/time.Nanosecond)) * time.Nanosecond
-	time.Sleep(pollSpread)
-	tick := time.NewTicker(interval)
+	var tickChan <-chan time.Time
+	if paced {
+		alwaysTick := make(chan time.Time)
+		close(alwaysTick)
+		tickChan = alwaysTick
+	} else {
+		pollSpread := time.Duration(rand.Float64()*float64(interval/time.Nanosecond)) * time.Nanosecond
+		time.Sleep(pollSpread)
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+		tickChan = tick.C
+	}
 	lastTime := time.Now()
 	oscillateProtocols := false
 	if pollingProtocol == config.Both {
@@ -150,14 +191,14 @@ func poller(
 	usingIPv4 := pollingProtocol != config.IPv6Only
 	for {
 		select {
-		case <-tick.C:
+		case <-tickChan:
 			if (usingIPv4 && url == "") || (!usingIPv4 && url6 == "") {
 				usingIPv4 = !usingIPv4
 				continue
 			}
 
 			realInterval := time.Now().Sub(lastTime)
-			if realInterval > interval+(time.Millisecond*100) {
+			if !paced && realInterval > interval+(time.Millisecond*100) {
 				log.Debugf("Intended Duration: %v Actual Duration: %v\n", interval, realInterval)
 			}
 			lastTime = time.Now()
@@ -171,6 +212,14 @@ func poller(
 			}
 
 			bts, reqEnd, reqTime, err := pollFunc(pollCtx, pollUrl, host, pollID)
+			if errors.Is(err, ErrRecordingEnded) {
+				log.Infof("poll recording of '%s' ended, no longer polling it\n", id)
+				<-die
+				return
+			}
+			if httpCtx, ok := pollCtx.(*HTTPPollCtx); ok && httpCtx.Replay != nil {
+				usingIPv4 = httpCtx.Replay.UsingIPv4
+			}
 			rdr := io.Reader(nil)
 			if bts != nil {
 				rdr = bytes.NewReader(bts) // TODO change handler to take bytes? Benchmark?
@@ -185,7 +234,6 @@ func poller(
 
 			<-pollFinishedChan
 		case <-die:
-			tick.Stop()
 			return
 		}
 	}
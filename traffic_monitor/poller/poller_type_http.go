@@ -92,6 +92,10 @@ type HTTPPollCtx struct {
 	PollerID     string
 	HTTPHeader   http.Header
 	FormatAccept string
+	// Probe is the synthetic request made through the cache after each poll by the probe poller type. It is nil for other poller types, and for probe pollers without a probe URL.
+	Probe *ProbePollCtx
+	// Replay is the recording replayed by the replay poller type. It is nil for other poller types.
+	Replay *ReplayPollCtx
 }
 
 func httpPoll(ctxI interface{}, url string, host string, pollID uint64) ([]byte, time.Time, time.Duration, error) {
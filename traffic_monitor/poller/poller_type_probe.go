@@ -0,0 +1,187 @@
+package poller
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/traffic_monitor/config"
+)
+
+// PollerTypeProbe polls the cache's stats like PollerTypeHTTP, and then makes a synthetic request through the cache for a Delivery Service URL. If the probe's response isn't as expected, the poll fails, and the cache is marked unavailable, even though its stats were fetched.
+// This catches caches whose stats plugin answers, but which fail to serve clients, for example because the proxy returns 502s.
+const PollerTypeProbe = "probe"
+
+// DefaultProbeStatus is the HTTP status code expected of a probe which doesn't configure one.
+const DefaultProbeStatus = http.StatusOK
+
+func init() {
+	AddPollerType(PollerTypeProbe, probeGlobalInit, probeInit, probePoll)
+}
+
+// ProbeConfig is the synthetic request made by the probe poller type, and what its response must be.
+type ProbeConfig struct {
+	// URL is the URL of the request. The request is sent to the cache's polled IP address, with the URL's host as its Host and TLS server name, and the URL's port, or the default port of its scheme.
+	URL string
+	// Status is the HTTP status code the response must have. If 0, DefaultProbeStatus is used.
+	Status int
+	// Checksum is the hex-encoded SHA-256 checksum the response body must have. If empty, the body is read but not checked.
+	Checksum string
+	// MaxTTFB is the maximum time to the first byte of the response. If 0, it isn't checked.
+	MaxTTFB time.Duration
+}
+
+// ProbePollGlobalCtx is the global context of the probe poller type.
+type ProbePollGlobalCtx struct {
+	HTTP *HTTPPollGlobalCtx
+	// Client makes probe requests. It doesn't keep connections alive, because a connection to one cache must never be reused for a request to another cache for the same URL, and it doesn't follow redirects, because it's the cache's response which is checked.
+	Client *http.Client
+}
+
+// ProbePollCtx is the probe request of a particular probe poller.
+type ProbePollCtx struct {
+	Config    ProbeConfig
+	Client    *http.Client
+	UserAgent string
+}
+
+// probeAddrKey is the request context key of the IP address a probe request is sent to.
+type probeAddrKey struct{}
+
+var probeDialer = &net.Dialer{}
+
+// dialProbe dials the probed cache's address from the request context, rather than the address of the probe URL's host.
+func dialProbe(ctx context.Context, network string, addr string) (net.Conn, error) {
+	if ip, ok := ctx.Value(probeAddrKey{}).(string); ok {
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		addr = net.JoinHostPort(ip, port)
+	}
+	return probeDialer.DialContext(ctx, network, addr)
+}
+
+func probeGlobalInit(cfg config.Config, appData config.StaticAppData) interface{} {
+	return &ProbePollGlobalCtx{
+		HTTP: httpGlobalInit(cfg, appData).(*HTTPPollGlobalCtx),
+		Client: &http.Client{
+			Transport: &http.Transport{
+				DialContext:       dialProbe,
+				DisableKeepAlives: true,
+			},
+			Timeout: cfg.HTTPTimeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func probeInit(cfg PollerConfig, globalCtxI interface{}) interface{} {
+	gctx := (globalCtxI).(*ProbePollGlobalCtx)
+	ctx := httpInit(cfg, gctx.HTTP).(*HTTPPollCtx)
+	if cfg.Probe.URL == "" {
+		return ctx
+	}
+	client := gctx.Client
+	if cfg.Timeout != 0 {
+		clientCopy := *client
+		clientCopy.Timeout = cfg.Timeout
+		client = &clientCopy
+	}
+	ctx.Probe = &ProbePollCtx{
+		Config:    cfg.Probe,
+		Client:    client,
+		UserAgent: ctx.UserAgent,
+	}
+	return ctx
+}
+
+func probePoll(ctxI interface{}, url string, host string, pollID uint64) ([]byte, time.Time, time.Duration, error) {
+	ctx := (ctxI).(*HTTPPollCtx)
+	bts, reqEnd, reqTime, err := httpPoll(ctx, url, host, pollID)
+	if err != nil || ctx.Probe == nil {
+		return bts, reqEnd, reqTime, err
+	}
+	if err := ctx.Probe.Probe(url); err != nil {
+		return nil, reqEnd, reqTime, fmt.Errorf("id %v probe %v error: %v", ctx.PollerID, ctx.Probe.Config.URL, err)
+	}
+	return bts, reqEnd, reqTime, nil
+}
+
+// Probe makes the probe request through the cache polled at pollURL, and returns an error if the response isn't as expected.
+func (p *ProbePollCtx) Probe(pollURL string) error {
+	polled, err := url.Parse(pollURL)
+	if err != nil {
+		return errors.New("parsing poll URL: " + err.Error())
+	}
+	req, err := http.NewRequest(http.MethodGet, p.Config.URL, nil)
+	if err != nil {
+		return errors.New("creating HTTP request: " + err.Error())
+	}
+	req.Header.Set("User-Agent", p.UserAgent)
+
+	ttfb := time.Duration(0)
+	start := time.Now()
+	trace := &httptrace.ClientTrace{
+		GotFirstResponseByte: func() { ttfb = time.Since(start) },
+	}
+	reqCtx := httptrace.WithClientTrace(context.WithValue(context.Background(), probeAddrKey{}, polled.Hostname()), trace)
+	resp, err := p.Client.Do(req.WithContext(reqCtx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	status := p.Config.Status
+	if status == 0 {
+		status = DefaultProbeStatus
+	}
+	if resp.StatusCode != status {
+		return fmt.Errorf("bad HTTP status: %v, expected %v", resp.StatusCode, status)
+	}
+	if p.Config.MaxTTFB > 0 && ttfb > p.Config.MaxTTFB {
+		return fmt.Errorf("time to first byte %v exceeds %v", ttfb, p.Config.MaxTTFB)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return errors.New("reading body: " + err.Error())
+	}
+	if p.Config.Checksum == "" {
+		return nil
+	}
+	if checksum := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(checksum, p.Config.Checksum) {
+		return fmt.Errorf("body checksum %v, expected %v", checksum, p.Config.Checksum)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package poller
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/traffic_monitor/config"
+)
+
+const probeTestHost = "video.demo1.mycdn.test"
+
+func TestProbePoll(t *testing.T) {
+	probeBody := "probe body"
+	probeStatus := http.StatusOK
+	probeDelay := time.Duration(0)
+	cache := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_astats" {
+			w.Write([]byte(`{"ats":{}}`))
+			return
+		}
+		if r.Host != probeTestHost+":"+r.URL.Query().Get("port") {
+			t.Errorf("expected probe Host '%s', actual '%s'", probeTestHost, r.Host)
+		}
+		time.Sleep(probeDelay)
+		w.WriteHeader(probeStatus)
+		w.Write([]byte(probeBody))
+	}))
+	defer cache.Close()
+
+	cacheURL, err := url.Parse(cache.URL)
+	if err != nil {
+		t.Fatalf("parsing test server URL: %v", err)
+	}
+	port := cacheURL.Port()
+	checksum := sha256.Sum256([]byte(probeBody))
+
+	gctx := probeGlobalInit(config.DefaultConfig, config.StaticAppData{UserAgent: "probe-test"})
+	ctx := probeInit(PollerConfig{
+		PollerID: "cache0",
+		Probe: ProbeConfig{
+			URL:      "http://" + probeTestHost + ":" + port + "/probe.bin?port=" + port,
+			Checksum: hex.EncodeToString(checksum[:]),
+			MaxTTFB:  time.Second,
+		},
+	}, gctx)
+
+	pollURL := cache.URL + "/_astats"
+	if bts, _, _, err := probePoll(ctx, pollURL, probeTestHost, 1); err != nil {
+		t.Errorf("expected a successful probe, actual error: %v", err)
+	} else if string(bts) != `{"ats":{}}` {
+		t.Errorf("expected the polled stats, actual: %s", bts)
+	}
+
+	probeStatus = http.StatusBadGateway
+	if bts, _, _, err := probePoll(ctx, pollURL, probeTestHost, 2); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("expected a probe returning 502 to fail with its status, actual error: %v", err)
+	} else if bts != nil {
+		t.Errorf("expected a failed probe to return no stats, actual: %s", bts)
+	}
+
+	probeStatus = http.StatusOK
+	probeBody = "corrupted body"
+	if _, _, _, err := probePoll(ctx, pollURL, probeTestHost, 3); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("expected a probe with the wrong body to fail its checksum, actual error: %v", err)
+	}
+
+	probeBody = "probe body"
+	probeDelay = 1500 * time.Millisecond
+	if _, _, _, err := probePoll(ctx, pollURL, probeTestHost, 4); err == nil || !strings.Contains(err.Error(), "time to first byte") {
+		t.Errorf("expected a slow probe to fail its time to first byte, actual error: %v", err)
+	}
+
+	// Without a probe URL, the probe poller type only polls stats.
+	statCtx := probeInit(PollerConfig{PollerID: "cache0"}, gctx)
+	if _, _, _, err := probePoll(statCtx, pollURL, probeTestHost, 5); err != nil {
+		t.Errorf("expected a probe poller without a probe URL to only poll stats, actual error: %v", err)
+	}
+}
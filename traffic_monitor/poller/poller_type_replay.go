@@ -0,0 +1,228 @@
+package poller
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/traffic_monitor/config"
+
+	"github.com/json-iterator/go"
+)
+
+// PollerTypeReplay replays the recorded results of polling a cache, instead of polling it. Each recorded poll is returned when it is due, at the recorded pace scaled by the replay speed, and with its recorded time moved to when the replay started, so the intervals between polls, and thus the rates computed from them, are the same as when they were recorded.
+// Cache pollers use this poller type, regardless of the health.polling.type of the cache, when Traffic Monitor is configured to replay a recording.
+const PollerTypeReplay = "replay"
+
+// ErrRecordingEnded is the error returned by a PollerFunc which has no more polls, after which the cache isn't polled again.
+var ErrRecordingEnded = errors.New("poll recording ended")
+
+func init() {
+	AddPollerType(PollerTypeReplay, replayGlobalInit, replayInit, replayPoll)
+}
+
+// ReplayPollGlobalCtx is the global context of the replay poller type.
+type ReplayPollGlobalCtx struct {
+	Dir   string
+	Speed float64
+	// First is the time of the earliest poll in the recording.
+	First time.Time
+
+	m          *sync.Mutex
+	start      *time.Time
+	recordings map[string]*replayRecording
+}
+
+func replayGlobalInit(cfg config.Config, appData config.StaticAppData) interface{} {
+	gctx := &ReplayPollGlobalCtx{
+		Dir:        cfg.PollReplayDir,
+		Speed:      cfg.PollReplaySpeed,
+		m:          &sync.Mutex{},
+		start:      &time.Time{},
+		recordings: map[string]*replayRecording{},
+	}
+	if cfg.PollReplayDir == "" {
+		return gctx
+	}
+	first, err := firstRecordedPoll(cfg.PollReplayDir)
+	if err != nil {
+		log.Errorf("reading poll recording '%s': %v", cfg.PollReplayDir, err)
+	}
+	gctx.First = first
+	return gctx
+}
+
+// replayInit returns an HTTPPollCtx, because stats decoders read the Content-Type of the response from it. The recording is shared by every poller of the same cache, so a poller created for a new monitoring config continues where the previous poller left off.
+func replayInit(cfg PollerConfig, globalCtxI interface{}) interface{} {
+	gctx := (globalCtxI).(*ReplayPollGlobalCtx)
+	path := RecordingPath(gctx.Dir, cfg.CachePoller, cfg.PollerID)
+
+	gctx.m.Lock()
+	recording, ok := gctx.recordings[path]
+	if !ok {
+		recording = openReplayRecording(path)
+		gctx.recordings[path] = recording
+	}
+	gctx.m.Unlock()
+
+	return &HTTPPollCtx{
+		PollerID:   cfg.PollerID,
+		HTTPHeader: http.Header{},
+		Replay:     &ReplayPollCtx{Global: gctx, recording: recording, UsingIPv4: true},
+	}
+}
+
+// ReplayPollCtx is the recording replayed by a particular replay poller.
+type ReplayPollCtx struct {
+	Global *ReplayPollGlobalCtx
+	// UsingIPv4 is whether the last replayed poll was over IPv4, which the poller uses instead of its own protocol.
+	UsingIPv4 bool
+
+	recording *replayRecording
+}
+
+// replayRecording is an open poll recording. Its mutex must be held to read it.
+type replayRecording struct {
+	m       sync.Mutex
+	path    string
+	file    *os.File
+	rotated *os.File
+	reader  *bufio.Reader
+}
+
+// openReplayRecording opens the recording at the given path, preceded by its rotated recording, if there is one.
+func openReplayRecording(path string) *replayRecording {
+	r := &replayRecording{path: path}
+	file, err := os.Open(path)
+	if err != nil {
+		log.Warnf("no poll recording to replay for '%s': %v", path, err)
+		return r
+	}
+	r.file = file
+	r.reader = bufio.NewReader(file)
+	if rotated, err := os.Open(RotatedRecordingPath(path)); err == nil {
+		r.rotated = rotated
+		r.reader = bufio.NewReader(io.MultiReader(rotated, file))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		log.Warnf("opening rotated poll recording of '%s', replaying only the current recording: %v", path, err)
+	}
+	return r
+}
+
+// next returns the next recorded poll, or ErrRecordingEnded if there are no more. Malformed records are logged and skipped.
+func (r *replayRecording) next() (PollRecord, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	for r.reader != nil {
+		line, err := r.reader.ReadBytes('\n')
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			rec := PollRecord{}
+			if jsonErr := jsoniter.ConfigFastest.Unmarshal(line, &rec); jsonErr != nil {
+				log.Warnf("skipping malformed poll record in '%s': %v", r.path, jsonErr)
+				continue
+			}
+			return rec, nil
+		}
+		// A partial last line was being written when recording stopped, and is ignored.
+		if err != nil && err != io.EOF {
+			log.Errorf("reading poll recording '%s': %v", r.path, err)
+		}
+		r.close()
+	}
+	return PollRecord{}, ErrRecordingEnded
+}
+
+// close closes the recording's file, after which it has no more polls. It must be called with the mutex held, or before the recording is shared.
+func (r *replayRecording) close() {
+	if r.file != nil {
+		r.file.Close()
+	}
+	if r.rotated != nil {
+		r.rotated.Close()
+	}
+	r.file, r.rotated, r.reader = nil, nil, nil
+}
+
+// offset returns the duration recorded times are moved by, which is from the first recorded poll to when the replay started. The replay starts when this is first called.
+func (g *ReplayPollGlobalCtx) offset() time.Duration {
+	g.m.Lock()
+	defer g.m.Unlock()
+	if g.start.IsZero() {
+		*g.start = time.Now()
+	}
+	return g.start.Sub(g.First)
+}
+
+func replayPoll(ctxI interface{}, url string, host string, pollID uint64) ([]byte, time.Time, time.Duration, error) {
+	ctx := (ctxI).(*HTTPPollCtx)
+	rec, err := ctx.Replay.recording.next()
+	if err != nil {
+		return nil, time.Now(), 0, err
+	}
+
+	gctx := ctx.Replay.Global
+	offset := gctx.offset()
+	due := gctx.First.Add(offset).Add(time.Duration(float64(rec.Time.Sub(gctx.First)) / gctx.Speed))
+	time.Sleep(time.Until(due))
+
+	ctx.Replay.UsingIPv4 = rec.IPv4
+	ctx.HTTPHeader = http.Header{}
+	if rec.ContentType != "" {
+		ctx.HTTPHeader.Set("Content-Type", rec.ContentType)
+	}
+	reqEnd := rec.Time.Add(offset)
+	if rec.Error != "" {
+		return nil, reqEnd, rec.RequestTime, errors.New(rec.Error)
+	}
+	return rec.Body, reqEnd, rec.RequestTime, nil
+}
+
+// firstRecordedPoll returns the time of the earliest poll in the given recording directory, which is the first poll in one of its recordings.
+func firstRecordedPoll(dir string) (time.Time, error) {
+	first := time.Time{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, RecordingFileExt) {
+			return nil
+		}
+		recording := openReplayRecording(path)
+		rec, err := recording.next()
+		recording.close()
+		if err != nil {
+			return nil
+		}
+		if first.IsZero() || rec.Time.Before(first) {
+			first = rec.Time
+		}
+		return nil
+	})
+	return first, err
+}
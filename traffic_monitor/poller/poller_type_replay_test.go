@@ -0,0 +1,162 @@
+package poller
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/traffic_monitor/config"
+)
+
+func TestRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	first := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	pollCtx := &HTTPPollCtx{}
+	recorded := []struct {
+		body        string
+		contentType string
+		err         error
+		ipv4        bool
+	}{
+		{body: `{"ats":{}}`, contentType: "text/json", ipv4: true},
+		{err: errors.New("connection refused")},
+		{body: "a,1\n", contentType: "text/csv", ipv4: true},
+	}
+	i := 0
+	pollFunc := recordingTestPollFunc(first, pollCtx, func() ([]byte, string, error) {
+		r := recorded[i]
+		i++
+		if r.err != nil {
+			return nil, "", r.err
+		}
+		return []byte(r.body), r.contentType, nil
+	})
+	recorder := NewPollRecorder(dir, 0)
+	info := CachePollInfo{ID: "cache0", PollConfig: PollConfig{URL: "http://192.0.2.1/_astats", URLv6: "http://[2001:db8::1]/_astats"}}
+	wrapped := recorder.wrap("health", info, pollFunc)
+	for j, r := range recorded {
+		url := info.URL
+		if !r.ipv4 {
+			url = info.URLv6
+		}
+		if _, _, _, err := wrapped(pollCtx, url, "", uint64(j)); (err != nil) != (r.err != nil) {
+			t.Fatalf("recorded poll %d: expected error %v, actual %v", j, r.err, err)
+		}
+	}
+	if _, err := os.Stat(RecordingPath(dir, "health", "cache0")); err != nil {
+		t.Fatalf("expected recording to exist, actual: %v", err)
+	}
+
+	cfg := config.DefaultConfig
+	cfg.PollReplayDir = dir
+	cfg.PollReplaySpeed = 1000
+	gctx := replayGlobalInit(cfg, config.StaticAppData{}).(*ReplayPollGlobalCtx)
+	if !gctx.First.Equal(first) {
+		t.Fatalf("expected first recorded poll %v, actual %v", first, gctx.First)
+	}
+	ctx := replayInit(PollerConfig{PollerID: "cache0", CachePoller: "health"}, gctx).(*HTTPPollCtx)
+
+	var lastEnd time.Time
+	for j, r := range recorded {
+		bts, reqEnd, reqTime, err := replayPoll(ctx, info.URL, "", uint64(j))
+		if (err != nil) != (r.err != nil) || (err != nil && err.Error() != r.err.Error()) {
+			t.Fatalf("replayed poll %d: expected error %v, actual %v", j, r.err, err)
+		}
+		if string(bts) != r.body {
+			t.Errorf("replayed poll %d: expected body '%s', actual '%s'", j, r.body, bts)
+		}
+		if err == nil && ctx.HTTPHeader.Get("Content-Type") != r.contentType {
+			t.Errorf("replayed poll %d: expected Content-Type '%s', actual '%s'", j, r.contentType, ctx.HTTPHeader.Get("Content-Type"))
+		}
+		if ctx.Replay.UsingIPv4 != r.ipv4 {
+			t.Errorf("replayed poll %d: expected IPv4 %t, actual %t", j, r.ipv4, ctx.Replay.UsingIPv4)
+		}
+		if reqTime != time.Duration(j+1)*time.Millisecond {
+			t.Errorf("replayed poll %d: expected request time %v, actual %v", j, time.Duration(j+1)*time.Millisecond, reqTime)
+		}
+		if j > 0 && reqEnd.Sub(lastEnd) != 6*time.Second {
+			t.Errorf("replayed poll %d: expected the recorded 6s since the last poll, actual %v", j, reqEnd.Sub(lastEnd))
+		}
+		lastEnd = reqEnd
+	}
+	if _, _, _, err := replayPoll(ctx, info.URL, "", 3); !errors.Is(err, ErrRecordingEnded) {
+		t.Errorf("expected recording to end, actual error: %v", err)
+	}
+
+	missing := replayInit(PollerConfig{PollerID: "cache1", CachePoller: "health"}, gctx)
+	if _, _, _, err := replayPoll(missing, info.URL, "", 4); !errors.Is(err, ErrRecordingEnded) {
+		t.Errorf("expected cache without a recording to end, actual error: %v", err)
+	}
+}
+
+// recordingTestPollFunc returns a PollerFunc returning the results of poll, 6 seconds apart starting at first, with request times of 1ms, 2ms, etc.
+func recordingTestPollFunc(first time.Time, pollCtx *HTTPPollCtx, poll func() ([]byte, string, error)) PollerFunc {
+	n := 0
+	return func(ctx interface{}, url string, host string, pollID uint64) ([]byte, time.Time, time.Duration, error) {
+		reqEnd := first.Add(time.Duration(n) * 6 * time.Second)
+		n++
+		bts, contentType, err := poll()
+		pollCtx.HTTPHeader = map[string][]string{"Content-Type": {contentType}}
+		return bts, reqEnd, time.Duration(n) * time.Millisecond, err
+	}
+}
+
+func TestPollRecorderRotation(t *testing.T) {
+	dir := t.TempDir()
+	first := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	bodies := []string{"a,1\n", "a,2\n", "a,3\n"}
+	i := 0
+	pollFunc := recordingTestPollFunc(first, &HTTPPollCtx{}, func() ([]byte, string, error) {
+		body := bodies[i]
+		i++
+		return []byte(body), "text/csv", nil
+	})
+	// Every record is larger than the max bytes, so each one rotates the recording, and only the last 2 are kept.
+	recorder := NewPollRecorder(dir, 1)
+	info := CachePollInfo{ID: "cache0", PollConfig: PollConfig{URL: "http://192.0.2.1/_astats"}}
+	wrapped := recorder.wrap("stat", info, pollFunc)
+	for j := range bodies {
+		if _, _, _, err := wrapped(&HTTPPollCtx{}, info.URL, "", uint64(j)); err != nil {
+			t.Fatalf("recorded poll %d: unexpected error %v", j, err)
+		}
+	}
+	recorder.Close()
+	if _, err := os.Stat(RotatedRecordingPath(RecordingPath(dir, "stat", "cache0"))); err != nil {
+		t.Fatalf("expected rotated recording to exist, actual: %v", err)
+	}
+
+	recording := openReplayRecording(RecordingPath(dir, "stat", "cache0"))
+	defer recording.close()
+	for _, expected := range bodies[1:] {
+		rec, err := recording.next()
+		if err != nil {
+			t.Fatalf("expected replayed poll '%s', actual error: %v", expected, err)
+		}
+		if string(rec.Body) != expected {
+			t.Errorf("expected the rotated recording to be replayed before the current recording, expected body '%s', actual '%s'", expected, rec.Body)
+		}
+	}
+	if _, err := recording.next(); !errors.Is(err, ErrRecordingEnded) {
+		t.Errorf("expected recording to end, actual error: %v", err)
+	}
+}
@@ -39,6 +39,9 @@ type PollerConfig struct {
 	Timeout     time.Duration
 	NoKeepAlive bool
 	PollerID    string
+	Probe       ProbeConfig
+	// CachePoller is the name of the CachePoller of the poller, e.g. "health" or "stat".
+	CachePoller string
 }
 
 // PollerGlobalInit performs global initialization, and returns a global context object.
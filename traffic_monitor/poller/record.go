@@ -0,0 +1,205 @@
+package poller
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+
+	"github.com/json-iterator/go"
+)
+
+// RecordingFileExt is the file extension of the poll recording of each cache server.
+const RecordingFileExt = ".jsonl"
+
+// PollRecord is the raw result of polling a cache server, as recorded for replay.
+type PollRecord struct {
+	// Time is when the poll finished.
+	Time time.Time `json:"time"`
+	// RequestTime is how long the poll took, in nanoseconds.
+	RequestTime time.Duration `json:"requestTime"`
+	// IPv4 is whether the cache server was polled over IPv4, rather than IPv6.
+	IPv4 bool `json:"ipv4"`
+	// ContentType is the Content-Type of the response, if the poll succeeded.
+	ContentType string `json:"contentType,omitempty"`
+	// Body is the response body, if the poll succeeded.
+	Body []byte `json:"body,omitempty"`
+	// Error is the error of the poll, if it failed.
+	Error string `json:"error,omitempty"`
+}
+
+// RecordingPath returns the path of the poll recording of the given cache server by the given CachePoller in the given recording directory.
+func RecordingPath(dir string, cachePoller string, id string) string {
+	return filepath.Join(dir, cachePoller, id+RecordingFileExt)
+}
+
+// RotatedRecordingPath returns the path the given poll recording is moved to when it's rotated, replacing the recording previously rotated there.
+func RotatedRecordingPath(path string) string {
+	return path + ".1"
+}
+
+// PollRecorder appends the results of polling cache servers to their recordings, one JSON object per line, oldest first.
+// When a recording would grow larger than the recorder's max bytes, it's rotated: moved to its RotatedRecordingPath, and started again, so each cache server's recordings use at most twice the max bytes.
+// It is safe for multiple goroutines.
+type PollRecorder struct {
+	dir        string
+	maxBytes   uint64
+	m          sync.Mutex
+	recordings map[string]*pollRecording
+}
+
+// pollRecording is the recording of a single cache server by a single CachePoller. Its mutex must be held to write to it, so writes to different recordings don't wait for each other.
+type pollRecording struct {
+	m    sync.Mutex
+	path string
+	file *os.File
+	size int64
+}
+
+// NewPollRecorder creates a PollRecorder which records to the given directory. A maxBytes of 0 means recordings are not limited in size.
+func NewPollRecorder(dir string, maxBytes uint64) *PollRecorder {
+	return &PollRecorder{dir: dir, maxBytes: maxBytes, recordings: map[string]*pollRecording{}}
+}
+
+// Record appends the given poll result to the recording of the given cache server by the given CachePoller, creating the recording if it doesn't exist, and rotating it if it would exceed the recorder's max bytes.
+func (r *PollRecorder) Record(cachePoller string, id string, rec PollRecord) error {
+	json := jsoniter.ConfigFastest
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshalling poll record: %v", err)
+	}
+	line = append(line, '\n')
+
+	path := RecordingPath(r.dir, cachePoller, id)
+	r.m.Lock()
+	recording, ok := r.recordings[path]
+	if !ok {
+		recording = &pollRecording{path: path}
+		r.recordings[path] = recording
+	}
+	r.m.Unlock()
+
+	recording.m.Lock()
+	defer recording.m.Unlock()
+	if recording.file == nil {
+		if err := recording.open(); err != nil {
+			return err
+		}
+	}
+	if r.maxBytes > 0 && recording.size > 0 && uint64(recording.size)+uint64(len(line)) > r.maxBytes {
+		if err := recording.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := recording.file.Write(line)
+	recording.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("writing poll recording '%s': %v", path, err)
+	}
+	return nil
+}
+
+// CloseRecording closes the recording of the given cache server by the given CachePoller, if it's open. Recording another poll of the cache server opens it again.
+func (r *PollRecorder) CloseRecording(cachePoller string, id string) {
+	path := RecordingPath(r.dir, cachePoller, id)
+	r.m.Lock()
+	recording, ok := r.recordings[path]
+	delete(r.recordings, path)
+	r.m.Unlock()
+	if ok {
+		recording.m.Lock()
+		recording.close()
+		recording.m.Unlock()
+	}
+}
+
+// Close closes all the open recordings.
+func (r *PollRecorder) Close() {
+	r.m.Lock()
+	recordings := r.recordings
+	r.recordings = map[string]*pollRecording{}
+	r.m.Unlock()
+	for _, recording := range recordings {
+		recording.m.Lock()
+		recording.close()
+		recording.m.Unlock()
+	}
+}
+
+// open opens the recording's file for appending, creating it and its directory if they don't exist. It must be called with the recording's mutex held.
+func (r *pollRecording) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("creating poll recording directory: %v", err)
+	}
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening poll recording: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("getting poll recording '%s' size: %v", r.path, err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// rotate moves the recording to its RotatedRecordingPath, and opens a new, empty recording. It must be called with the recording's mutex held.
+func (r *pollRecording) rotate() error {
+	r.close()
+	if err := os.Rename(r.path, RotatedRecordingPath(r.path)); err != nil {
+		return fmt.Errorf("rotating poll recording '%s': %v", r.path, err)
+	}
+	return r.open()
+}
+
+// close closes the recording's file, if it's open. It must be called with the recording's mutex held.
+func (r *pollRecording) close() {
+	if r.file == nil {
+		return
+	}
+	if err := r.file.Close(); err != nil {
+		log.Warnf("closing poll recording '%s': %v", r.path, err)
+	}
+	r.file = nil
+}
+
+// wrap returns a PollerFunc which records the results of the given PollerFunc polling the given cache server.
+func (r *PollRecorder) wrap(cachePoller string, info CachePollInfo, pollFunc PollerFunc) PollerFunc {
+	return func(ctx interface{}, url string, host string, pollID uint64) ([]byte, time.Time, time.Duration, error) {
+		bts, reqEnd, reqTime, err := pollFunc(ctx, url, host, pollID)
+		rec := PollRecord{Time: reqEnd, RequestTime: reqTime, IPv4: url == info.URL, Body: bts}
+		if err != nil {
+			rec.Error = err.Error()
+		} else if httpCtx, ok := ctx.(*HTTPPollCtx); ok && httpCtx.HTTPHeader != nil {
+			rec.ContentType = httpCtx.HTTPHeader.Get("Content-Type")
+		}
+		if recErr := r.Record(cachePoller, info.ID, rec); recErr != nil {
+			log.Errorf("recording poll of '%s': %v", info.ID, recErr)
+		}
+		return bts, reqEnd, reqTime, err
+	}
+}
@@ -0,0 +1,98 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package tmcheck
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	to "github.com/apache/trafficcontrol/traffic_ops/v3-client"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const TrafficMonitorCRConfigPath = "/publish/CrConfig"
+
+// CRConfigAgeMax is the default maximum time a Traffic Monitor's CRConfig may be behind the Traffic Ops CDN Snapshot.
+const CRConfigAgeMax = time.Duration(5) * time.Minute
+
+// GetCRConfig gets the CRConfig from the given Traffic Monitor.
+func GetCRConfig(uri string) (*tc.CRConfig, error) {
+	resp, err := getClient().Get(uri)
+	if err != nil {
+		return nil, fmt.Errorf("reading reply from %v: %v\n", uri, err)
+	}
+	defer resp.Body.Close()
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading reply from %v: %v\n", uri, err)
+	}
+
+	crConfig := tc.CRConfig{}
+	json := jsoniter.ConfigFastest
+	if err := json.Unmarshal(respBytes, &crConfig); err != nil {
+		return nil, fmt.Errorf("unmarshalling: %v", err)
+	}
+	return &crConfig, nil
+}
+
+// GetCRConfigAge returns how long the given Traffic Monitor's CRConfig has been behind the Traffic Ops CDN Snapshot of its CDN. It is 0 if the Traffic Monitor has the current Snapshot.
+func GetCRConfigAge(tmURI string, toClient *to.Session) (time.Duration, error) {
+	cdn, err := GetCDN(tmURI)
+	if err != nil {
+		return 0, fmt.Errorf("getting CDN from Traffic Monitor: %v", err)
+	}
+	toCRConfigBytes, _, err := toClient.GetCRConfig(cdn)
+	if err != nil {
+		return 0, fmt.Errorf("getting CRConfig from Traffic Ops: %v", err)
+	}
+	toCRConfig := tc.CRConfig{}
+	json := jsoniter.ConfigFastest
+	if err := json.Unmarshal(toCRConfigBytes, &toCRConfig); err != nil {
+		return 0, fmt.Errorf("unmarshalling Traffic Ops CRConfig JSON: %v", err)
+	}
+	tmCRConfig, err := GetCRConfig(tmURI + TrafficMonitorCRConfigPath)
+	if err != nil {
+		return 0, fmt.Errorf("getting CRConfig from Traffic Monitor: %v", err)
+	}
+	return CRConfigAge(tmCRConfig, &toCRConfig, time.Now())
+}
+
+// CRConfigAge returns how long, at the given time, the Traffic Monitor CRConfig tmCRConfig has been behind the Traffic Ops CRConfig toCRConfig, i.e. the time since the Traffic Ops Snapshot was taken, if the Traffic Monitor doesn't have it. It is 0 if the Traffic Monitor CRConfig is the same age or newer.
+// This isn't the difference between the dates of the Snapshots, which is how long apart they were taken, not how long the Traffic Monitor has lagged.
+func CRConfigAge(tmCRConfig *tc.CRConfig, toCRConfig *tc.CRConfig, now time.Time) (time.Duration, error) {
+	if toCRConfig.Stats.DateUnixSeconds == nil {
+		return 0, errors.New("Traffic Ops CRConfig has no date")
+	}
+	if tmCRConfig.Stats.DateUnixSeconds == nil {
+		return 0, errors.New("Traffic Monitor CRConfig has no date")
+	}
+	if *tmCRConfig.Stats.DateUnixSeconds >= *toCRConfig.Stats.DateUnixSeconds {
+		return 0, nil
+	}
+	age := now.Sub(time.Unix(*toCRConfig.Stats.DateUnixSeconds, 0))
+	if age < 0 {
+		return 0, nil // the clocks of Traffic Ops and this host may differ
+	}
+	return age, nil
+}
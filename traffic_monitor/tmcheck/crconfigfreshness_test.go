@@ -0,0 +1,60 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package tmcheck
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+func newTestCRConfig(date time.Time) *tc.CRConfig {
+	unix := date.Unix()
+	return &tc.CRConfig{Stats: tc.CRConfigStats{DateUnixSeconds: &unix}}
+}
+
+func TestCRConfigAge(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	tests := []struct {
+		name     string
+		tmDate   time.Time
+		toDate   time.Time
+		expected time.Duration
+	}{
+		{"current", now.Add(-time.Hour), now.Add(-time.Hour), 0},
+		{"newer", now.Add(-time.Minute), now.Add(-time.Hour), 0},
+		// a Snapshot taken long after the previous one is only as old as the time since it was taken.
+		{"behind new snapshot", now.Add(-time.Hour), now.Add(-10 * time.Second), 10 * time.Second},
+		{"behind old snapshot", now.Add(-time.Hour), now.Add(-10 * time.Minute), 10 * time.Minute},
+	}
+	for _, test := range tests {
+		age, err := CRConfigAge(newTestCRConfig(test.tmDate), newTestCRConfig(test.toDate), now)
+		if err != nil {
+			t.Errorf("%v: expected no error, actual %v", test.name, err)
+		} else if age != test.expected {
+			t.Errorf("%v: expected age %v, actual %v", test.name, test.expected, age)
+		}
+	}
+
+	if _, err := CRConfigAge(&tc.CRConfig{}, newTestCRConfig(now), now); err == nil {
+		t.Errorf("expected an error for a Traffic Monitor CRConfig without a date, actual nil")
+	}
+}
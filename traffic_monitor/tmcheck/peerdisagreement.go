@@ -0,0 +1,89 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package tmcheck
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/datareq"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const TrafficMonitorPeerStatesPath = "/publish/PeerStates"
+
+// TrafficMonitorRawCRStatesPath is the path of a Traffic Monitor's own CRStates, not combined with its peers'.
+const TrafficMonitorRawCRStatesPath = TrafficMonitorCRStatesPath + "?raw"
+
+// PeerDisagreementMax is the default maximum percentage of caches whose availability peers may disagree on.
+const PeerDisagreementMax = 10.0
+
+// GetPeerStates gets the PeerStates from the given Traffic Monitor.
+func GetPeerStates(uri string) (*datareq.APIPeerStates, error) {
+	resp, err := getClient().Get(uri)
+	if err != nil {
+		return nil, fmt.Errorf("reading reply from %v: %v\n", uri, err)
+	}
+	defer resp.Body.Close()
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading reply from %v: %v\n", uri, err)
+	}
+
+	peerStates := datareq.APIPeerStates{}
+	json := jsoniter.ConfigFastest
+	if err := json.Unmarshal(respBytes, &peerStates); err != nil {
+		return nil, fmt.Errorf("unmarshalling: %v", err)
+	}
+	return &peerStates, nil
+}
+
+// GetPeerDisagreement returns the percentage of caches the given Traffic Monitor polled whose availability at least one of its online peers disagrees with.
+func GetPeerDisagreement(tmURI string) (float64, error) {
+	crStates, err := GetCRStates(tmURI + TrafficMonitorRawCRStatesPath)
+	if err != nil {
+		return 0, fmt.Errorf("getting raw CRStates: %v", err)
+	}
+	peerStates, err := GetPeerStates(tmURI + TrafficMonitorPeerStatesPath)
+	if err != nil {
+		return 0, fmt.Errorf("getting PeerStates: %v", err)
+	}
+	return PeerDisagreement(crStates, peerStates), nil
+}
+
+// PeerDisagreement returns the percentage of caches in the Traffic Monitor's own CRStates whose availability at least one of its peers disagrees with. Caches a peer has no state for are not disagreements, and if there are no caches, the disagreement is 0.
+func PeerDisagreement(crStates *tc.CRStates, peerStates *datareq.APIPeerStates) float64 {
+	if len(crStates.Caches) == 0 {
+		return 0
+	}
+	disagreements := 0
+	for cacheName, available := range crStates.Caches {
+		for _, peerCaches := range peerStates.Peers {
+			peerStates := peerCaches[cacheName]
+			if len(peerStates) > 0 && peerStates[0].Value != available.IsAvailable {
+				disagreements++
+				break
+			}
+		}
+	}
+	return float64(disagreements) / float64(len(crStates.Caches)) * 100
+}
@@ -0,0 +1,55 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package tmcheck
+
+import (
+	"testing"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/datareq"
+)
+
+func TestPeerDisagreement(t *testing.T) {
+	crStates := tc.NewCRStates(4, 0)
+	crStates.Caches["agreed"] = tc.IsAvailable{IsAvailable: true}
+	crStates.Caches["disagreed-one"] = tc.IsAvailable{IsAvailable: true}
+	crStates.Caches["disagreed-both"] = tc.IsAvailable{IsAvailable: false}
+	crStates.Caches["unknown"] = tc.IsAvailable{IsAvailable: true}
+
+	peerStates := &datareq.APIPeerStates{Peers: map[tc.TrafficMonitorName]map[tc.CacheName][]datareq.CacheState{
+		"tm1": {
+			"agreed":         {{Value: true}},
+			"disagreed-one":  {{Value: false}},
+			"disagreed-both": {{Value: true}},
+		},
+		"tm2": {
+			"agreed":         {{Value: true}},
+			"disagreed-one":  {{Value: true}},
+			"disagreed-both": {{Value: true}},
+		},
+	}}
+
+	if actual := PeerDisagreement(&crStates, peerStates); actual != 50 {
+		t.Errorf("expected 50%% disagreement, actual %v%%", actual)
+	}
+	if actual := PeerDisagreement(&tc.CRStates{}, peerStates); actual != 0 {
+		t.Errorf("expected 0%% disagreement with no caches, actual %v%%", actual)
+	}
+}
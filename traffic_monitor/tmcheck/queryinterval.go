@@ -29,13 +29,21 @@ import (
 
 const QueryIntervalMax = time.Duration(10) * time.Second
 
+// GetQueryInterval gets the Query Interval 95th percentile of the given monitor.
+func GetQueryInterval(tmURI string) (time.Duration, error) {
+	stats, err := GetStats(tmURI + TrafficMonitorStatsPath)
+	if err != nil {
+		return 0, fmt.Errorf("getting Stats: %v", err)
+	}
+	return time.Duration(stats.QueryInterval95thPercentile) * time.Millisecond, nil
+}
+
 // ValidateQueryInterval validates the given monitor has an acceptable Query Interval 95th percentile.
 func ValidateQueryInterval(tmURI string, toClient *to.Session) error {
-	stats, err := GetStats(tmURI + TrafficMonitorStatsPath)
+	queryInterval, err := GetQueryInterval(tmURI)
 	if err != nil {
-		return fmt.Errorf("getting Stats: %v", err)
+		return err
 	}
-	queryInterval := time.Duration(stats.QueryInterval95thPercentile) * time.Millisecond
 
 	if queryInterval > QueryIntervalMax {
 		return fmt.Errorf("Query Interval 95th Percentile %v greater than max %v", queryInterval, QueryIntervalMax)
@@ -0,0 +1,67 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package tmcheck
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+// StalePollAgeMax is the default age after which a cache's last poll is stale.
+const StalePollAgeMax = time.Duration(30) * time.Second
+
+// StalePollsMax is the default maximum percentage of polled caches whose last poll may be stale.
+const StalePollsMax = 5.0
+
+// GetStalePolls returns the number of caches in the given Traffic Monitor's CRStates whose last poll is older than maxAge, and the number of caches it has polled.
+func GetStalePolls(tmURI string, maxAge time.Duration) (int, int, error) {
+	crStates, err := GetCRStates(tmURI + TrafficMonitorCRStatesPath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("getting CRStates: %v", err)
+	}
+	stale, polled := StalePolls(crStates, maxAge, time.Now())
+	return stale, polled, nil
+}
+
+// StalePolls returns the number of caches in the given CRStates whose last poll is older than maxAge at the given time, and the number of caches which have been polled.
+// Caches which have never been polled, such as those polled by another Traffic Monitor with distributed polling, are not counted.
+func StalePolls(crStates *tc.CRStates, maxAge time.Duration, now time.Time) (int, int) {
+	stale, polled := 0, 0
+	for _, available := range crStates.Caches {
+		if available.LastPoll.IsZero() {
+			continue
+		}
+		polled++
+		if now.Sub(available.LastPoll) > maxAge {
+			stale++
+		}
+	}
+	return stale, polled
+}
+
+// StalePollsPercent returns the percentage of polled caches which are stale, or 0 if no caches have been polled.
+func StalePollsPercent(stale int, polled int) float64 {
+	if polled == 0 {
+		return 0
+	}
+	return float64(stale) / float64(polled) * 100
+}
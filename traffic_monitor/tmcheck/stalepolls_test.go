@@ -0,0 +1,49 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package tmcheck
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+func TestStalePolls(t *testing.T) {
+	now := time.Now()
+	crStates := tc.NewCRStates(3, 0)
+	crStates.Caches["fresh"] = tc.IsAvailable{LastPoll: now.Add(-time.Second)}
+	crStates.Caches["stale"] = tc.IsAvailable{LastPoll: now.Add(-time.Minute)}
+	crStates.Caches["unpolled"] = tc.IsAvailable{}
+
+	stale, polled := StalePolls(&crStates, 30*time.Second, now)
+	if stale != 1 {
+		t.Errorf("expected 1 stale poll, actual %v", stale)
+	}
+	if polled != 2 {
+		t.Errorf("expected 2 polled caches, actual %v", polled)
+	}
+	if percent := StalePollsPercent(stale, polled); percent != 50 {
+		t.Errorf("expected 50%% stale polls, actual %v", percent)
+	}
+	if percent := StalePollsPercent(0, 0); percent != 0 {
+		t.Errorf("expected 0%% stale polls without polled caches, actual %v", percent)
+	}
+}
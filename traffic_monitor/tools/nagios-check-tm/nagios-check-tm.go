@@ -0,0 +1,296 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// nagios-check-tm is a Nagios and Icinga plugin which runs one of the tmcheck checks against a Traffic Monitor, or all the Traffic Monitors in Traffic Ops, and exits with the plugin status and perfdata of the check.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-nagios"
+	"github.com/apache/trafficcontrol/traffic_monitor/tmcheck"
+	to "github.com/apache/trafficcontrol/traffic_ops/v3-client"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const UserAgent = "tm-nagios-check/0.1"
+
+// checkOpts are the options of a check which aren't thresholds.
+type checkOpts struct {
+	maxPollAge time.Duration
+}
+
+// check is a subcommand. A check with a measure has warning and critical thresholds on the measured value; a check with only a validate fails or passes.
+type check struct {
+	description string
+	label       string
+	uom         string
+	warn        float64
+	crit        float64
+	needsTO     bool
+	measure     func(tmURI string, toClient *to.Session, opts checkOpts) (float64, string, error)
+	validate    func(tmURI string, toClient *to.Session) error
+}
+
+var checks = map[string]check{
+	"dsstats": {
+		description: "Delivery Services in the CRConfig with caches assigned are in DsStats",
+		needsTO:     true,
+		validate:    tmcheck.ValidateDSStats,
+	},
+	"offline": {
+		description: "OFFLINE and ADMIN_DOWN caches in the CRConfig are unavailable in CrStates",
+		needsTO:     true,
+		validate:    tmcheck.ValidateOfflineStates,
+	},
+	"peerpoller": {
+		description: "seconds since the oldest peer poll",
+		label:       "oldest_peer_poll",
+		uom:         "s",
+		warn:        (tmcheck.PeerPollMax / 2).Seconds(),
+		crit:        tmcheck.PeerPollMax.Seconds(),
+		measure: func(tmURI string, _ *to.Session, _ checkOpts) (float64, string, error) {
+			age, err := tmcheck.GetOldestPolledPeerTime(tmURI)
+			return age.Seconds(), fmt.Sprintf("oldest peer poll was %v ago", age), err
+		},
+	},
+	"queryinterval": {
+		description: "query interval 95th percentile, in milliseconds",
+		label:       "query_interval_95th",
+		uom:         "ms",
+		warn:        float64((tmcheck.QueryIntervalMax / 2).Milliseconds()),
+		crit:        float64(tmcheck.QueryIntervalMax.Milliseconds()),
+		measure: func(tmURI string, _ *to.Session, _ checkOpts) (float64, string, error) {
+			interval, err := tmcheck.GetQueryInterval(tmURI)
+			return float64(interval.Milliseconds()), fmt.Sprintf("query interval 95th percentile is %v", interval), err
+		},
+	},
+	"crconfig-freshness": {
+		description: "seconds the CRConfig is behind the Traffic Ops CDN Snapshot",
+		label:       "crconfig_age",
+		uom:         "s",
+		warn:        (tmcheck.CRConfigAgeMax / 5).Seconds(),
+		crit:        tmcheck.CRConfigAgeMax.Seconds(),
+		needsTO:     true,
+		measure: func(tmURI string, toClient *to.Session, _ checkOpts) (float64, string, error) {
+			age, err := tmcheck.GetCRConfigAge(tmURI, toClient)
+			return age.Seconds(), fmt.Sprintf("CRConfig is %v behind Traffic Ops", age), err
+		},
+	},
+	"stale-polls": {
+		description: "percent of polled caches last polled more than -maxpollage ago",
+		label:       "stale_polls",
+		uom:         "%",
+		warn:        tmcheck.StalePollsMax / 2,
+		crit:        tmcheck.StalePollsMax,
+		measure: func(tmURI string, _ *to.Session, opts checkOpts) (float64, string, error) {
+			stale, polled, err := tmcheck.GetStalePolls(tmURI, opts.maxPollAge)
+			return tmcheck.StalePollsPercent(stale, polled), fmt.Sprintf("%d of %d caches last polled more than %v ago", stale, polled, opts.maxPollAge), err
+		},
+	},
+	"peer-disagreement": {
+		description: "percent of caches whose availability a peer disagrees with",
+		label:       "peer_disagreement",
+		uom:         "%",
+		warn:        tmcheck.PeerDisagreementMax / 2,
+		crit:        tmcheck.PeerDisagreementMax,
+		measure: func(tmURI string, _ *to.Session, _ checkOpts) (float64, string, error) {
+			disagreement, err := tmcheck.GetPeerDisagreement(tmURI)
+			return disagreement, fmt.Sprintf("peers disagree on %.1f%% of caches", disagreement), err
+		},
+	},
+}
+
+// monitorResult is the result of a check of a single Traffic Monitor.
+type monitorResult struct {
+	Monitor  string           `json:"monitor"`
+	Status   string           `json:"status"`
+	Message  string           `json:"message"`
+	Perfdata *nagios.Perfdata `json:"perfdata,omitempty"`
+	status   nagios.Status
+}
+
+// checkResult is the result of a check of all the checked Traffic Monitors, and is the JSON output.
+type checkResult struct {
+	Check   string          `json:"check"`
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Results []monitorResult `json:"results"`
+}
+
+func usage(w *os.File) {
+	names := []string{}
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(w, "Usage: ./nagios-check-tm <check> [-tm http://traffic-monitor.example.net] [-to https://traffic-ops.example.net -touser bill -topass thelizard] [-warning n] [-critical n] [-json]\n\nChecks:\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-20s %s\n", name, checks[name].description)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 || strings.HasPrefix(os.Args[1], "-") {
+		usage(os.Stdout)
+		return
+	}
+	checkName := os.Args[1]
+	chk, ok := checks[checkName]
+	if !ok {
+		usage(os.Stdout)
+		nagios.ExitWithPerfdata(nagios.Unknown, fmt.Sprintf("no check '%s'", checkName))
+	}
+
+	flags := flag.NewFlagSet(checkName, flag.ExitOnError)
+	tmURI := flags.String("tm", "", "The Traffic Monitor URI to check. If empty, all Traffic Monitors in Traffic Ops are checked")
+	toURI := flags.String("to", "", "The Traffic Ops URI")
+	toUser := flags.String("touser", "", "The Traffic Ops user")
+	toPass := flags.String("topass", "", "The Traffic Ops password")
+	includeOffline := flags.Bool("includeOffline", false, "Whether to include Offline Monitors")
+	warn := flags.Float64("warning", chk.warn, "The value above which the check is WARNING")
+	crit := flags.Float64("critical", chk.crit, "The value above which the check is CRITICAL")
+	maxPollAge := flags.Duration("maxpollage", tmcheck.StalePollAgeMax, "The age after which a cache's last poll is stale, for the stale-polls check")
+	jsonOutput := flags.Bool("json", false, "Whether to write the result as JSON, rather than plugin output")
+	flags.Parse(os.Args[2:])
+
+	if *toURI == "" && (*tmURI == "" || chk.needsTO) {
+		usage(os.Stdout)
+		nagios.ExitWithPerfdata(nagios.Unknown, fmt.Sprintf("check '%s' requires -to", checkName))
+	}
+
+	var toClient *to.Session
+	if *toURI != "" {
+		session, _, err := to.LoginWithAgent(*toURI, *toUser, *toPass, true, UserAgent, false, tmcheck.RequestTimeout)
+		if err != nil {
+			nagios.ExitWithPerfdata(nagios.Unknown, fmt.Sprintf("logging in to Traffic Ops: %v", err))
+		}
+		toClient = session
+	}
+
+	monitors := map[string]string{}
+	if *tmURI != "" {
+		monitors[*tmURI] = *tmURI
+	} else {
+		servers, err := tmcheck.GetMonitors(toClient, *includeOffline)
+		if err != nil {
+			nagios.ExitWithPerfdata(nagios.Unknown, err.Error())
+		}
+		for _, server := range servers {
+			monitors[server.HostName] = fmt.Sprintf("http://%s.%s", server.HostName, server.DomainName)
+		}
+	}
+
+	result, status := runCheck(checkName, chk, monitors, toClient, *warn, *crit, checkOpts{maxPollAge: *maxPollAge})
+	if *jsonOutput {
+		json := jsoniter.ConfigFastest
+		bts, err := json.Marshal(result)
+		if err != nil {
+			nagios.ExitWithPerfdata(nagios.Unknown, fmt.Sprintf("marshalling result: %v", err))
+		}
+		nagios.Exit(status, string(bts))
+	}
+
+	perfdata := []nagios.Perfdata{}
+	longOutput := ""
+	for _, monitorResult := range result.Results {
+		if monitorResult.Perfdata != nil {
+			perfdata = append(perfdata, *monitorResult.Perfdata)
+		}
+		if len(result.Results) > 1 {
+			longOutput += fmt.Sprintf("\n%s: %s - %s", monitorResult.Monitor, monitorResult.Status, monitorResult.Message)
+		}
+	}
+	nagios.ExitWithPerfdata(status, result.Message+longOutput, perfdata...)
+}
+
+// runCheck runs the check against each of the given monitors, a map of names to URIs, and returns the result and its status, which is the worst of the monitors' statuses.
+func runCheck(checkName string, chk check, monitors map[string]string, toClient *to.Session, warn float64, crit float64, opts checkOpts) (checkResult, nagios.Status) {
+	names := make([]string, 0, len(monitors))
+	for name := range monitors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := checkResult{Check: checkName, Results: []monitorResult{}}
+	status := nagios.Ok
+	notOK := []string{}
+	for _, name := range names {
+		monitorResult := checkMonitor(chk, monitors[name], toClient, warn, crit, opts)
+		monitorResult.Monitor = name
+		if len(monitors) > 1 && monitorResult.Perfdata != nil {
+			monitorResult.Perfdata.Label = name + "_" + monitorResult.Perfdata.Label
+		}
+		if worse(monitorResult.status, status) {
+			status = monitorResult.status
+		}
+		if monitorResult.status != nagios.Ok {
+			notOK = append(notOK, name)
+		}
+		result.Results = append(result.Results, monitorResult)
+	}
+
+	switch {
+	case len(names) == 0:
+		status = nagios.Unknown
+		result.Message = "no Traffic Monitors to check"
+	case len(names) == 1:
+		result.Message = result.Results[0].Message
+	case len(notOK) == 0:
+		result.Message = fmt.Sprintf("%s OK on %d Traffic Monitors", checkName, len(names))
+	default:
+		result.Message = fmt.Sprintf("%s not OK on %d of %d Traffic Monitors: %s", checkName, len(notOK), len(names), strings.Join(notOK, ", "))
+	}
+	result.Status = status.String()
+	return result, status
+}
+
+// checkMonitor runs the check against a single monitor. Checks with thresholds are UNKNOWN if their value can't be measured, and checks without are CRITICAL if they fail for any reason.
+func checkMonitor(chk check, tmURI string, toClient *to.Session, warn float64, crit float64, opts checkOpts) monitorResult {
+	if chk.measure == nil {
+		if err := chk.validate(tmURI, toClient); err != nil {
+			return monitorResult{Status: nagios.Critical.String(), Message: strings.TrimSpace(err.Error()), status: nagios.Critical}
+		}
+		return monitorResult{Status: nagios.Ok.String(), Message: "valid", status: nagios.Ok}
+	}
+
+	val, msg, err := chk.measure(tmURI, toClient, opts)
+	if err != nil {
+		return monitorResult{Status: nagios.Unknown.String(), Message: strings.TrimSpace(err.Error()), status: nagios.Unknown}
+	}
+	status := nagios.ThresholdStatus(val, warn, crit)
+	return monitorResult{
+		Status:   status.String(),
+		Message:  msg,
+		Perfdata: &nagios.Perfdata{Label: chk.label, Value: val, UOM: chk.uom, Warn: &warn, Crit: &crit},
+		status:   status,
+	}
+}
+
+// worse returns whether the status a is worse than b. CRITICAL is worse than WARNING, which is worse than UNKNOWN, which is worse than OK.
+func worse(a nagios.Status, b nagios.Status) bool {
+	rank := map[nagios.Status]int{nagios.Ok: 0, nagios.Unknown: 1, nagios.Warning: 2, nagios.Critical: 3}
+	return rank[a] > rank[b]
+}
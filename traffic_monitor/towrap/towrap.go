@@ -30,6 +30,7 @@ import (
 	"net/http/cookiejar"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"time"
@@ -46,6 +47,12 @@ import (
 
 const localHostIP = "127.0.0.1"
 
+// RecordedCRConfigFile is the name of the file in a poll recording directory to which the CDN Snapshot is recorded.
+const RecordedCRConfigFile = "crconfig.json"
+
+// RecordedTMConfigFile is the name of the file in a poll recording directory to which the monitoring config is recorded.
+const RecordedTMConfigFile = "monitoring.json"
+
 // ErrNilSession is the error returned by operations performed on a nil session.
 var ErrNilSession = errors.New("nil session")
 
@@ -199,6 +206,10 @@ type TrafficOpsSessionThreadsafe struct {
 	crConfigHist       CRConfigHistoryThreadsafe
 	CRConfigBackupFile string
 	TMConfigBackupFile string
+	// recordDir is the poll recording directory to which the CDN Snapshot and monitoring config are also written, if polls are recorded.
+	recordDir string
+	// replayDir is the poll recording directory from which the CDN Snapshot and monitoring config are read instead of Traffic Ops, if polls are replayed.
+	replayDir string
 }
 
 // NewTrafficOpsSessionThreadsafe returns a new threadsafe
@@ -212,13 +223,16 @@ func NewTrafficOpsSessionThreadsafe(s *client.Session, ls *legacyClient.Session,
 		session:            &s,
 		legacySession:      &ls,
 		TMConfigBackupFile: cfg.TMConfigBackupFile,
+		recordDir:          cfg.PollRecordDir,
+		replayDir:          cfg.PollReplayDir,
 	}
 }
 
 // Initialized tells whether or not the TrafficOpsSessionThreadsafe has been
-// properly initialized with non-nil sessions.
+// properly initialized with non-nil sessions. When replaying a poll recording,
+// no sessions are needed, so it is always initialized.
 func (s TrafficOpsSessionThreadsafe) Initialized() bool {
-	return s.session != nil && *s.session != nil && s.legacySession != nil && *s.legacySession != nil
+	return s.replayDir != "" || s.session != nil && *s.session != nil && s.legacySession != nil && *s.legacySession != nil
 }
 
 // Update updates the TrafficOpsSessionThreadsafe's connection information with
@@ -236,6 +250,10 @@ func (s *TrafficOpsSessionThreadsafe) Update(
 	if s == nil {
 		return errors.New("cannot update nil session")
 	}
+	if s.replayDir != "" {
+		log.Infoln("replaying poll recording '" + s.replayDir + "', not logging in to Traffic Ops")
+		return nil
+	}
 	s.m.Lock()
 	defer s.m.Unlock()
 
@@ -372,8 +390,8 @@ func (s *TrafficOpsSessionThreadsafe) CRConfigValid(crc *tc.CRConfig, cdn string
 	return nil
 }
 
-// CRConfigRaw returns the CRConfig from the Traffic Ops. This is safe for
-// multiple goroutines.
+// CRConfigRaw returns the CRConfig from the Traffic Ops, or from the poll
+// recording being replayed. This is safe for multiple goroutines.
 func (s TrafficOpsSessionThreadsafe) CRConfigRaw(cdn string) ([]byte, error) {
 
 	var remoteAddr string
@@ -382,9 +400,55 @@ func (s TrafficOpsSessionThreadsafe) CRConfigRaw(cdn string) ([]byte, error) {
 	var configBytes []byte
 	json := jsoniter.ConfigFastest
 
+	if s.replayDir != "" {
+		if configBytes, err = ioutil.ReadFile(filepath.Join(s.replayDir, RecordedCRConfigFile)); err != nil {
+			return nil, fmt.Errorf("reading recorded CRConfig: %v", err)
+		}
+		remoteAddr = localHostIP
+	} else if crConfig, configBytes, remoteAddr, err = s.fetchCRConfig(cdn); err != nil {
+		return nil, err
+	}
+
+	hist := &CRConfigStat{
+		Err:     err,
+		ReqAddr: remoteAddr,
+		ReqTime: time.Now(),
+		Stats:   tc.CRConfigStats{},
+	}
+	defer s.crConfigHist.Add(hist)
+
+	if crConfig == nil {
+		crConfig = &tc.CRConfig{}
+		if err = json.Unmarshal(configBytes, crConfig); err != nil {
+			err = errors.New("invalid JSON: " + err.Error())
+			hist.Err = err
+			return configBytes, err
+		}
+	}
+	hist.Stats = crConfig.Stats
+
+	if err = s.CRConfigValid(crConfig, cdn); err != nil {
+		err = errors.New("invalid CRConfig: " + err.Error())
+		hist.Err = err
+		return configBytes, err
+	}
+
+	s.lastCRConfig.Set(cdn, configBytes, &crConfig.Stats)
+	return configBytes, nil
+}
+
+// fetchCRConfig returns the CRConfig from the Traffic Ops, or from the backup
+// file if it can't be fetched, along with its bytes and the address it was
+// fetched from. The returned CRConfig is nil if only its bytes were fetched.
+func (s TrafficOpsSessionThreadsafe) fetchCRConfig(cdn string) (*tc.CRConfig, []byte, string, error) {
+	var remoteAddr string
+	var crConfig *tc.CRConfig
+	var configBytes []byte
+	json := jsoniter.ConfigFastest
+
 	ss := s.get()
 	if ss == nil {
-		return nil, ErrNilSession
+		return nil, nil, "", ErrNilSession
 	}
 	response, reqInf, err := ss.GetCRConfig(cdn, client.RequestOptions{})
 	if reqInf.RemoteAddr != nil {
@@ -394,7 +458,7 @@ func (s TrafficOpsSessionThreadsafe) CRConfigRaw(cdn string) ([]byte, error) {
 		log.Warnln("getting CRConfig from Traffic Ops using up-to-date client: " + err.Error() + ". Retrying with legacy client")
 		ls := s.getLegacy()
 		if ls == nil {
-			return nil, ErrNilSession
+			return nil, nil, "", ErrNilSession
 		}
 		configBytes, reqInf, err = ls.GetCRConfig(cdn)
 		if reqInf.RemoteAddr != nil {
@@ -417,45 +481,21 @@ func (s TrafficOpsSessionThreadsafe) CRConfigRaw(cdn string) ([]byte, error) {
 		if wErr := ioutil.WriteFile(s.CRConfigBackupFile, configBytes, 0644); wErr != nil {
 			log.Errorf("failed to write CRConfig backup file: %v", wErr)
 		}
+		s.record(RecordedCRConfigFile, configBytes)
 	} else {
 		if s.BackupFileExists() {
 			log.Errorln("using backup file for CRConfig snapshot due to error fetching CRConfig snapshot from Traffic Ops: " + err.Error())
 			configBytes, err = ioutil.ReadFile(s.CRConfigBackupFile)
 			if err != nil {
-				return nil, fmt.Errorf("reading CRConfig backup file: %v", err)
+				return nil, nil, "", fmt.Errorf("reading CRConfig backup file: %v", err)
 			}
 			remoteAddr = localHostIP
 			err = nil
 		} else {
-			return nil, fmt.Errorf("failed to get CRConfig from Traffic Ops (%v), and there is no backup file", err)
-		}
-	}
-
-	hist := &CRConfigStat{
-		Err:     err,
-		ReqAddr: remoteAddr,
-		ReqTime: time.Now(),
-		Stats:   tc.CRConfigStats{},
-	}
-	defer s.crConfigHist.Add(hist)
-
-	if crConfig == nil {
-		if err = json.Unmarshal(configBytes, crConfig); err != nil {
-			err = errors.New("invalid JSON: " + err.Error())
-			hist.Err = err
-			return configBytes, err
+			return nil, nil, "", fmt.Errorf("failed to get CRConfig from Traffic Ops (%v), and there is no backup file", err)
 		}
 	}
-	hist.Stats = crConfig.Stats
-
-	if err = s.CRConfigValid(crConfig, cdn); err != nil {
-		err = errors.New("invalid CRConfig: " + err.Error())
-		hist.Err = err
-		return configBytes, err
-	}
-
-	s.lastCRConfig.Set(cdn, configBytes, &crConfig.Stats)
-	return configBytes, nil
+	return crConfig, configBytes, remoteAddr, nil
 }
 
 // LastCRConfig returns the last CRConfig requested from CRConfigRaw, and the
@@ -505,6 +545,14 @@ func (s TrafficOpsSessionThreadsafe) trafficMonitorConfigMapRaw(cdn string) (*tc
 	var configMap *tc.TrafficMonitorConfigMap
 	var err error
 
+	if s.replayDir != "" {
+		config, err := readTMConfigFile(filepath.Join(s.replayDir, RecordedTMConfigFile))
+		if err != nil {
+			return nil, fmt.Errorf("reading recorded monitoring config: %v", err)
+		}
+		return tc.TrafficMonitorTransformToMap(config)
+	}
+
 	config, err = s.fetchTMConfig(cdn)
 	if err != nil {
 		log.Warnln("getting Traffic Monitor config from Traffic Ops using up-to-date client: " + err.Error() + ". Retrying with legacy client")
@@ -529,17 +577,11 @@ func (s TrafficOpsSessionThreadsafe) trafficMonitorConfigMapRaw(cdn string) (*tc
 		}
 		log.Errorln("using backup file for monitoring config snapshot due to invalid monitoring config snapshot from Traffic Ops: " + err.Error())
 
-		b, err := ioutil.ReadFile(s.TMConfigBackupFile)
+		tmConfig, err := readTMConfigFile(s.TMConfigBackupFile)
 		if err != nil {
 			return nil, errors.New("reading TMConfigBackupFile: " + err.Error())
 		}
-
-		json := jsoniter.ConfigFastest
-		var tmConfig tc.TrafficMonitorConfig
-		if err := json.Unmarshal(b, &tmConfig); err != nil {
-			return nil, errors.New("unmarshalling backup file monitoring.json: " + err.Error())
-		}
-		return tc.TrafficMonitorTransformToMap(&tmConfig)
+		return tc.TrafficMonitorTransformToMap(tmConfig)
 	}
 
 	json := jsoniter.ConfigFastest
@@ -548,11 +590,44 @@ func (s TrafficOpsSessionThreadsafe) trafficMonitorConfigMapRaw(cdn string) (*tc
 		if wErr := ioutil.WriteFile(s.TMConfigBackupFile, data, 0644); wErr != nil {
 			log.Errorf("failed to write TM config backup file: %v", wErr)
 		}
+		s.record(RecordedTMConfigFile, data)
 	}
 
 	return configMap, err
 }
 
+// readTMConfigFile reads a monitoring config written by
+// trafficMonitorConfigMapRaw.
+func readTMConfigFile(path string) (*tc.TrafficMonitorConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	json := jsoniter.ConfigFastest
+	var tmConfig tc.TrafficMonitorConfig
+	if err := json.Unmarshal(b, &tmConfig); err != nil {
+		return nil, errors.New("unmarshalling monitoring config: " + err.Error())
+	}
+	return &tmConfig, nil
+}
+
+// record writes the given data fetched from Traffic Ops to the given file in
+// the poll recording directory, if polls are recorded, so the recording can be
+// replayed with the same configuration.
+func (s TrafficOpsSessionThreadsafe) record(fileName string, data []byte) {
+	if s.recordDir == "" {
+		return
+	}
+	if err := os.MkdirAll(s.recordDir, 0755); err != nil {
+		log.Errorf("failed to create poll recording directory: %v", err)
+		return
+	}
+	if err := ioutil.WriteFile(filepath.Join(s.recordDir, fileName), data, 0644); err != nil {
+		log.Errorf("failed to record %s: %v", fileName, err)
+	}
+}
+
 // TrafficMonitorConfigMap returns the Traffic Monitor config map from the
 // Traffic Ops. This is safe for multiple goroutines.
 func (s TrafficOpsSessionThreadsafe) TrafficMonitorConfigMap(cdn string) (*tc.TrafficMonitorConfigMap, error) {
@@ -651,11 +726,16 @@ func (s TrafficOpsSessionThreadsafe) fetchLegacyServerByHostname(hostName string
 }
 
 // MonitorCDN returns the name of the CDN of a Traffic Monitor with the given
-// hostName.
+// hostName. When replaying a poll recording, this is the CDN of the recorded
+// CDN Snapshot.
 func (s TrafficOpsSessionThreadsafe) MonitorCDN(hostName string) (string, error) {
 	var server tc.ServerV40
 	var err error
 
+	if s.replayDir != "" {
+		return recordedCDN(s.replayDir)
+	}
+
 	server, err = s.fetchServerByHostname(hostName)
 	if err != nil {
 		log.Warnln("getting server by hostname '" + hostName + "' using up-to-date client: " + err.Error() + ". Retrying with legacy client")
@@ -670,3 +750,20 @@ func (s TrafficOpsSessionThreadsafe) MonitorCDN(hostName string) (string, error)
 	// return an error in that case
 	return *server.CDNName, nil
 }
+
+// recordedCDN returns the name of the CDN of the CDN Snapshot in the given poll
+// recording directory.
+func recordedCDN(dir string) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, RecordedCRConfigFile))
+	if err != nil {
+		return "", fmt.Errorf("reading recorded CRConfig: %v", err)
+	}
+	crConfig := tc.CRConfig{}
+	if err := jsoniter.ConfigFastest.Unmarshal(b, &crConfig); err != nil {
+		return "", fmt.Errorf("unmarshalling recorded CRConfig: %v", err)
+	}
+	if crConfig.Stats.CDNName == nil {
+		return "", errors.New("recorded CRConfig.Stats.CDN missing")
+	}
+	return *crConfig.Stats.CDNName, nil
+}
@@ -28,6 +28,7 @@ import (
 	"time"
 
 	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/traffic_monitor/cache"
 	"github.com/apache/trafficcontrol/traffic_monitor/config"
 	"github.com/apache/trafficcontrol/traffic_monitor/manager"
 )
@@ -82,6 +83,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := cache.SetPrometheusStatNames(cfg.PrometheusStatNames); err != nil {
+		fmt.Printf("Error starting service: invalid prometheus_stat_names: %v\n", err)
+		os.Exit(1)
+	}
+
 	if cfg.ShortHostnameOverride != "" {
 		staticData.Hostname = cfg.ShortHostnameOverride
 	}
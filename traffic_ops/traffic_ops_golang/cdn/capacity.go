@@ -56,6 +56,27 @@ func getCapacity(tx *sql.Tx) (CapacityResp, error) {
 	return getMonitorsCapacity(tx, monitors)
 }
 
+// GetCDNCapacity returns the capacity data of the edge caches in the given
+// CDN, as reported by the first of the CDN's monitors that responds.
+func GetCDNCapacity(tx *sql.Tx, cdn tc.CDNName) (CapData, error) {
+	monitors, err := monitorhlp.GetURLs(tx)
+	if err != nil {
+		return CapData{}, errors.New("getting monitors: " + err.Error())
+	}
+	if len(monitors[cdn]) == 0 {
+		return CapData{}, errors.New("no monitors found for CDN '" + string(cdn) + "'")
+	}
+	client, err := monitorhlp.GetClient(tx)
+	if err != nil {
+		return CapData{}, errors.New("getting TM client: " + err.Error())
+	}
+	thresholds, err := getEdgeProfileHealthThresholdBandwidth(tx)
+	if err != nil {
+		return CapData{}, errors.New("getting profile thresholds: " + err.Error())
+	}
+	return getCapacityData(map[tc.CDNName][]string{cdn: monitors[cdn]}, thresholds, client, tx)
+}
+
 type CapacityResp struct {
 	AvailablePercent   float64 `json:"availablePercent"`
 	UnavailablePercent float64 `json:"unavailablePercent"`
@@ -200,7 +221,7 @@ func getStats(stats tc.ServerStats) (float64, float64, error) {
 
 func getEdgeProfileHealthThresholdBandwidth(tx *sql.Tx) (map[string]float64, error) {
 	rows, err := tx.Query(`
-SELECT pr.name as profile, pa.name, pa.config_file, pa.value
+SELECT pr.name as profile, pa.value
 FROM parameter as pa
 JOIN profile_parameter as pp ON pp.parameter = pa.id
 JOIN profile as pr ON pp.profile = pr.id
@@ -26,7 +26,7 @@ import (
 	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
 )
 
-func getCapacities(inf *api.APIInfo, ucdn string) (Capabilities, error) {
+func getCapacities(inf *api.APIInfo, ucdn string, adv *advertisedCDN) (Capabilities, error) {
 	capRows, err := inf.Tx.Tx.Query(CapabilityQuery, FciCapacityLimits, ucdn)
 	if err != nil {
 		return Capabilities{}, fmt.Errorf("querying capabilities: %w", err)
@@ -56,6 +56,9 @@ func getCapacities(inf *api.APIInfo, ucdn string) (Capabilities, error) {
 	for _, cap := range capabilities {
 		fciCap := Capability{}
 		fciCap.Footprints = footprintMap[cap.Id]
+		if adv != nil {
+			fciCap.Footprints = adv.Footprints
+		}
 		if fciCap.Footprints == nil {
 			fciCap.Footprints = []Footprint{}
 		}
@@ -81,6 +84,9 @@ func getCapacities(inf *api.APIInfo, ucdn string) (Capabilities, error) {
 
 			returnedLimits = append(returnedLimits, returnedTotalLimit)
 		}
+		if adv != nil && adv.EgressCapacity != nil {
+			applyEgressCapacity(returnedLimits, *adv.EgressCapacity)
+		}
 
 		fciCap.CapabilityType = FciCapacityLimits
 		fciCap.CapabilityValue = []CapacityCapabilityValue{
@@ -0,0 +1,420 @@
+package cdni
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/cdn"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// CountryCodeParameterName is the name of the Parameter which, when
+	// assigned to a Cache Group, gives the ISO 3166-1 alpha-2 code of the
+	// country the Cache Group serves.
+	CountryCodeParameterName = "cdni.country_code"
+
+	coverageZoneRequestTimeout = 10 * time.Second
+
+	// capacityCacheTTL is how long the capacity of the advertised CDN,
+	// obtained from Traffic Monitor, is used before it is obtained again.
+	capacityCacheTTL = time.Minute
+
+	edgeCachegroupsQuery = `
+SELECT DISTINCT cg.id, cg.name, co.latitude, co.longitude
+FROM cachegroup AS cg
+JOIN server AS s ON s.cachegroup = cg.id
+JOIN type AS t ON t.id = s.type
+JOIN cdn AS c ON c.id = s.cdn_id
+LEFT JOIN coordinate AS co ON co.id = cg.coordinate
+WHERE c.name = $1
+AND t.name LIKE '` + tc.EdgeTypePrefix + `%'`
+
+	cachegroupCountryCodesQuery = `
+SELECT DISTINCT p.value
+FROM parameter AS p
+JOIN cachegroup_parameter AS cgp ON cgp.parameter = p.id
+WHERE p.name = '` + CountryCodeParameterName + `'
+AND cgp.cachegroup = ANY($1)`
+
+	cachegroupASNsQuery = `SELECT DISTINCT asn FROM asn WHERE cachegroup = ANY($1)`
+
+	coverageZonePollingURLQuery = `
+SELECT p.value
+FROM parameter AS p
+JOIN profile_parameter AS pp ON pp.parameter = p.id
+JOIN profile AS pr ON pr.id = pp.profile
+JOIN cdn AS c ON c.id = pr.cdn
+WHERE p.name = '` + tc.CoverageZonePollingURL + `'
+AND p.config_file = 'CRConfig.json'
+AND c.name = $1
+LIMIT 1`
+)
+
+// advertisedCDN is the footprint and capacity data generated from the
+// topology of the CDN advertised to uCDNs.
+type advertisedCDN struct {
+	Footprints []Footprint
+	// EgressCapacity is the total egress capacity of the CDN in bits per
+	// second, or nil if it could not be obtained from Traffic Monitor.
+	EgressCapacity *int64
+}
+
+// getAdvertisedCDN generates the footprints and egress capacity of the CDN
+// configured to be advertised. If no CDN is configured to be advertised, this
+// returns nil, and footprints and limits are read from the database as-is.
+func getAdvertisedCDN(inf *api.APIInfo) (*advertisedCDN, error) {
+	if inf.Config.Cdni.AdvertisedCDN == "" {
+		return nil, nil
+	}
+	cdnName := inf.Config.Cdni.AdvertisedCDN
+	cachegroupIDs, cachegroups, err := getEdgeCachegroups(inf.Tx.Tx, cdnName)
+	if err != nil {
+		return nil, err
+	}
+	countryCodes, err := getCachegroupCountryCodes(inf.Tx.Tx, cachegroupIDs)
+	if err != nil {
+		return nil, err
+	}
+	asns, err := getCachegroupASNs(inf.Tx.Tx, cachegroupIDs)
+	if err != nil {
+		return nil, err
+	}
+	// The coverage zone file and Traffic Monitor are only used to refine the
+	// advertisement, so failing to reach them doesn't fail the request.
+	czf, err := getCoverageZoneFile(inf.Tx.Tx, cdnName)
+	if err != nil {
+		log.Warnf("generating CDNi footprints for CDN '%s': omitting coverage zone networks: %v", cdnName, err)
+	}
+	adv := advertisedCDN{Footprints: makeFootprints(countryCodes, asns, czf, cachegroups, inf.Config.Cdni.FootprintMaxDistanceKm)}
+
+	bps, err := getEgressCapacity(inf.Tx.Tx, cdnName)
+	if err != nil {
+		log.Warnf("generating CDNi egress limits for CDN '%s': using configured limits: %v", cdnName, err)
+	} else {
+		adv.EgressCapacity = &bps
+	}
+	return &adv, nil
+}
+
+// capacityCache holds the egress capacity of each CDN, or the error fetching
+// it, so that Traffic Monitor isn't polled on every advertisement request.
+var capacityCache = struct {
+	sync.Mutex
+	capacities map[string]int64
+	errs       map[string]error
+	fetched    map[string]time.Time
+	// fetching holds the fetch in progress of each CDN's capacity, which
+	// concurrent requests wait for rather than polling Traffic Monitor too.
+	fetching map[string]*capacityFetch
+}{
+	capacities: map[string]int64{},
+	errs:       map[string]error{},
+	fetched:    map[string]time.Time{},
+	fetching:   map[string]*capacityFetch{},
+}
+
+// capacityFetch is a fetch of a CDN's egress capacity. Its bps and err are set
+// before done is closed.
+type capacityFetch struct {
+	done chan struct{}
+	bps  int64
+	err  error
+}
+
+// fetchCDNCapacity fetches the capacity of a CDN from Traffic Monitor. It's a
+// variable so that tests can replace it.
+var fetchCDNCapacity = cdn.GetCDNCapacity
+
+// coverageZoneFileCache holds the Coverage Zone File of each CDN, so that it
+// isn't fetched on every request which uses it.
+var coverageZoneFileCache = struct {
+	sync.Mutex
+	files   map[string]tc.CoverageZoneFile
+	fetched map[string]time.Time
+}{
+	files:   map[string]tc.CoverageZoneFile{},
+	fetched: map[string]time.Time{},
+}
+
+// getEgressCapacity returns the total egress capacity in bits per second of
+// the given CDN's edge caches, polling Traffic Monitor if the cached capacity
+// is missing or stale. Traffic Monitor is polled without holding the cache
+// lock, so a slow Traffic Monitor only delays requests for its own CDN, and
+// failures are cached too, so they aren't retried on every request.
+func getEgressCapacity(tx *sql.Tx, cdnName string) (int64, error) {
+	capacityCache.Lock()
+	if time.Since(capacityCache.fetched[cdnName]) < capacityCacheTTL {
+		bps, err := capacityCache.capacities[cdnName], capacityCache.errs[cdnName]
+		capacityCache.Unlock()
+		return bps, err
+	}
+	if fetch, ok := capacityCache.fetching[cdnName]; ok {
+		capacityCache.Unlock()
+		<-fetch.done
+		return fetch.bps, fetch.err
+	}
+	fetch := &capacityFetch{done: make(chan struct{})}
+	capacityCache.fetching[cdnName] = fetch
+	capacityCache.Unlock()
+
+	if capData, err := fetchCDNCapacity(tx, tc.CDNName(cdnName)); err != nil {
+		fetch.err = err
+	} else {
+		fetch.bps = int64(capData.Capacity * 1000)
+	}
+
+	capacityCache.Lock()
+	capacityCache.capacities[cdnName] = fetch.bps
+	capacityCache.errs[cdnName] = fetch.err
+	capacityCache.fetched[cdnName] = time.Now()
+	delete(capacityCache.fetching, cdnName)
+	capacityCache.Unlock()
+	close(fetch.done)
+	return fetch.bps, fetch.err
+}
+
+// getEdgeCachegroups returns the IDs of the Cache Groups containing edge-tier
+// servers in the given CDN, and their coordinates by name. The coordinates of
+// Cache Groups without any are nil.
+func getEdgeCachegroups(tx *sql.Tx, cdnName string) ([]int64, map[string]*tc.CRConfigLatitudeLongitude, error) {
+	rows, err := tx.Query(edgeCachegroupsQuery, cdnName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("querying edge cachegroups: %w", err)
+	}
+	defer log.Close(rows, "closing edge cachegroups query")
+	ids := []int64{}
+	cachegroups := map[string]*tc.CRConfigLatitudeLongitude{}
+	for rows.Next() {
+		id := int64(0)
+		name := ""
+		lat := sql.NullFloat64{}
+		lon := sql.NullFloat64{}
+		if err := rows.Scan(&id, &name, &lat, &lon); err != nil {
+			return nil, nil, fmt.Errorf("scanning edge cachegroups: %w", err)
+		}
+		ids = append(ids, id)
+		cachegroups[name] = nil
+		if lat.Valid && lon.Valid {
+			cachegroups[name] = &tc.CRConfigLatitudeLongitude{Lat: lat.Float64, Lon: lon.Float64}
+		}
+	}
+	return ids, cachegroups, nil
+}
+
+func getCachegroupCountryCodes(tx *sql.Tx, cachegroupIDs []int64) ([]string, error) {
+	rows, err := tx.Query(cachegroupCountryCodesQuery, pq.Array(cachegroupIDs))
+	if err != nil {
+		return nil, fmt.Errorf("querying cachegroup country codes: %w", err)
+	}
+	defer log.Close(rows, "closing cachegroup country codes query")
+	countryCodes := []string{}
+	for rows.Next() {
+		code := ""
+		if err := rows.Scan(&code); err != nil {
+			return nil, fmt.Errorf("scanning cachegroup country codes: %w", err)
+		}
+		countryCodes = append(countryCodes, code)
+	}
+	return countryCodes, nil
+}
+
+func getCachegroupASNs(tx *sql.Tx, cachegroupIDs []int64) ([]int64, error) {
+	rows, err := tx.Query(cachegroupASNsQuery, pq.Array(cachegroupIDs))
+	if err != nil {
+		return nil, fmt.Errorf("querying cachegroup ASNs: %w", err)
+	}
+	defer log.Close(rows, "closing cachegroup ASNs query")
+	asns := []int64{}
+	for rows.Next() {
+		asn := int64(0)
+		if err := rows.Scan(&asn); err != nil {
+			return nil, fmt.Errorf("scanning cachegroup ASNs: %w", err)
+		}
+		asns = append(asns, asn)
+	}
+	return asns, nil
+}
+
+// getCoverageZoneFile returns the Coverage Zone File Traffic Router uses for
+// the given CDN, fetching it if the cached one is missing or stale.
+func getCoverageZoneFile(tx *sql.Tx, cdnName string) (tc.CoverageZoneFile, error) {
+	coverageZoneFileCache.Lock()
+	defer coverageZoneFileCache.Unlock()
+	if time.Since(coverageZoneFileCache.fetched[cdnName]) < coverageZoneCacheTTL {
+		return coverageZoneFileCache.files[cdnName], nil
+	}
+	czfURL := ""
+	if err := tx.QueryRow(coverageZonePollingURLQuery, cdnName).Scan(&czfURL); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return tc.CoverageZoneFile{}, errors.New("no " + tc.CoverageZonePollingURL + " parameter found")
+		}
+		return tc.CoverageZoneFile{}, fmt.Errorf("querying coverage zone polling URL: %w", err)
+	}
+	czf, err := fetchCoverageZoneFile(czfURL)
+	if err != nil {
+		return tc.CoverageZoneFile{}, err
+	}
+	coverageZoneFileCache.files[cdnName] = czf
+	coverageZoneFileCache.fetched[cdnName] = time.Now()
+	return czf, nil
+}
+
+// fetchCoverageZoneFile requests the Coverage Zone File at the given URL.
+func fetchCoverageZoneFile(czfURL string) (tc.CoverageZoneFile, error) {
+	client := http.Client{Timeout: coverageZoneRequestTimeout}
+	resp, err := client.Get(czfURL)
+	if err != nil {
+		return tc.CoverageZoneFile{}, fmt.Errorf("requesting coverage zone file '%s': %w", czfURL, err)
+	}
+	defer log.Close(resp.Body, "closing coverage zone file response body")
+	if resp.StatusCode != http.StatusOK {
+		return tc.CoverageZoneFile{}, fmt.Errorf("requesting coverage zone file '%s': got status %d", czfURL, resp.StatusCode)
+	}
+	czf := tc.CoverageZoneFile{}
+	if err := json.NewDecoder(resp.Body).Decode(&czf); err != nil {
+		return tc.CoverageZoneFile{}, fmt.Errorf("decoding coverage zone file '%s': %w", czfURL, err)
+	}
+	return czf, nil
+}
+
+// makeFootprints builds the RFC 8006 footprints of the given country codes,
+// ASNs, and the networks of the coverage zones of the given Cache Groups.
+// If maxDistanceKm is positive, the networks of other coverage zones whose
+// coordinates are within that distance of one of the Cache Groups are also
+// included, since Traffic Router routes their clients to the nearest Cache
+// Group.
+// Footprint types with no values are omitted, and values are de-duplicated
+// and sorted so that the advertisement is stable.
+func makeFootprints(countryCodes []string, asns []int64, czf tc.CoverageZoneFile, cachegroups map[string]*tc.CRConfigLatitudeLongitude, maxDistanceKm float64) []Footprint {
+	countryCodeSet := map[string]struct{}{}
+	for _, code := range countryCodes {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if len(code) != 2 {
+			log.Warnf("generating CDNi footprints: ignoring '%s' Parameter with invalid value '%s'", CountryCodeParameterName, code)
+			continue
+		}
+		countryCodeSet[code] = struct{}{}
+	}
+	asnSet := map[string]struct{}{}
+	for _, asn := range asns {
+		asnSet["as"+strconv.FormatInt(asn, 10)] = struct{}{}
+	}
+	ipv4Set := map[string]struct{}{}
+	ipv6Set := map[string]struct{}{}
+	for name, zone := range czf.CoverageZones {
+		if _, ok := cachegroups[name]; !ok && !nearCachegroup(zone.Coordinates, cachegroups, maxDistanceKm) {
+			continue
+		}
+		addCIDRs(ipv4Set, zone.Network, name)
+		addCIDRs(ipv6Set, zone.Network6, name)
+	}
+
+	footprints := []Footprint{}
+	for _, fp := range []struct {
+		Type   FootprintType
+		Values map[string]struct{}
+	}{
+		{CountryCode, countryCodeSet},
+		{Asn, asnSet},
+		{Ipv4Cidr, ipv4Set},
+		{Ipv6Cidr, ipv6Set},
+	} {
+		if len(fp.Values) == 0 {
+			continue
+		}
+		values := make([]string, 0, len(fp.Values))
+		for v := range fp.Values {
+			values = append(values, v)
+		}
+		sort.Strings(values)
+		footprints = append(footprints, Footprint{FootprintType: fp.Type, FootprintValue: values})
+	}
+	return footprints
+}
+
+// nearCachegroup returns whether the given coverage zone coordinates are
+// within maxDistanceKm of any of the given Cache Groups. Zones and Cache
+// Groups without valid coordinates are never near.
+func nearCachegroup(coordinates *tc.CoverageZoneCoordinates, cachegroups map[string]*tc.CRConfigLatitudeLongitude, maxDistanceKm float64) bool {
+	if maxDistanceKm <= 0 || coordinates == nil {
+		return false
+	}
+	lat, err := coordinates.Latitude.Float64()
+	if err != nil {
+		return false
+	}
+	lon, err := coordinates.Longitude.Float64()
+	if err != nil {
+		return false
+	}
+	zoneLocation := tc.CRConfigLatitudeLongitude{Lat: lat, Lon: lon}
+	for _, location := range cachegroups {
+		if location != nil && greatCircleDistance(zoneLocation, *location) <= maxDistanceKm {
+			return true
+		}
+	}
+	return false
+}
+
+// addCIDRs adds the given coverage zone networks to the set in their
+// canonical form, skipping any that aren't valid CIDRs.
+func addCIDRs(set map[string]struct{}, networks []string, zone string) {
+	for _, network := range networks {
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			log.Warnf("generating CDNi footprints: ignoring invalid network '%s' in coverage zone '%s'", network, zone)
+			continue
+		}
+		set[ipNet.String()] = struct{}{}
+	}
+}
+
+// applyEgressCapacity sets the hard maximum of every limit on the total
+// egress of the CDN to the given capacity. The soft maximum is scaled so that
+// it keeps the same proportion of the hard maximum as was configured.
+func applyEgressCapacity(limits []Limit, capacity int64) {
+	for i, l := range limits {
+		if l.LimitType != Egress || l.Scope != nil {
+			continue
+		}
+		if l.MaximumHard > 0 {
+			limits[i].MaximumSoft = int64(float64(capacity) * float64(l.MaximumSoft) / float64(l.MaximumHard))
+		} else {
+			limits[i].MaximumSoft = capacity
+		}
+		limits[i].MaximumHard = capacity
+	}
+}
@@ -0,0 +1,160 @@
+package cdni
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/cdn"
+)
+
+func TestMakeFootprints(t *testing.T) {
+	czf := tc.CoverageZoneFile{CoverageZones: map[string]tc.CoverageZoneLocation{
+		"cg1": {Network: []string{"192.0.2.0/24", "not-a-cidr"}, Network6: []string{"2001:db8::/32"}},
+		"cg2": {Network: []string{"198.51.100.7/24", "192.0.2.0/24"}},
+		"mid": {Network: []string{"203.0.113.0/24"}},
+	}}
+	cachegroups := map[string]*tc.CRConfigLatitudeLongitude{"cg1": nil, "cg2": nil}
+
+	actual := makeFootprints([]string{"US", "ca", "us", "usa"}, []int64{64497, 64496}, czf, cachegroups, 0)
+	expected := []Footprint{
+		{FootprintType: CountryCode, FootprintValue: []string{"ca", "us"}},
+		{FootprintType: Asn, FootprintValue: []string{"as64496", "as64497"}},
+		{FootprintType: Ipv4Cidr, FootprintValue: []string{"192.0.2.0/24", "198.51.100.0/24"}},
+		{FootprintType: Ipv6Cidr, FootprintValue: []string{"2001:db8::/32"}},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %+v, actual: %+v", expected, actual)
+	}
+
+	if actual := makeFootprints(nil, nil, tc.CoverageZoneFile{}, cachegroups, 0); len(actual) != 0 {
+		t.Errorf("expected no footprints without data, actual: %+v", actual)
+	}
+}
+
+func TestMakeFootprintsNearbyZones(t *testing.T) {
+	czf := tc.CoverageZoneFile{CoverageZones: map[string]tc.CoverageZoneLocation{
+		"cg1":     {Network: []string{"192.0.2.0/24"}},
+		"denver":  {Network: []string{"198.51.100.0/24"}, Coordinates: &tc.CoverageZoneCoordinates{Latitude: "39.7392", Longitude: "-104.9903"}},
+		"boulder": {Network: []string{"203.0.113.0/24"}, Coordinates: &tc.CoverageZoneCoordinates{Latitude: "40.0150", Longitude: "-105.2705"}},
+		"london":  {Network: []string{"198.18.0.0/15"}, Coordinates: &tc.CoverageZoneCoordinates{Latitude: "51.5072", Longitude: "-0.1276"}},
+		"invalid": {Network: []string{"100.64.0.0/10"}, Coordinates: &tc.CoverageZoneCoordinates{Latitude: "north", Longitude: "-105"}},
+	}}
+	// cg1 is in Denver; cg2 has no coordinates
+	cachegroups := map[string]*tc.CRConfigLatitudeLongitude{"cg1": {Lat: 39.7392, Lon: -104.9903}, "cg2": nil}
+
+	actual := makeFootprints(nil, nil, czf, cachegroups, 50)
+	expected := []Footprint{
+		{FootprintType: Ipv4Cidr, FootprintValue: []string{"192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24"}},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected zones within 50km to be included - expected: %+v, actual: %+v", expected, actual)
+	}
+
+	actual = makeFootprints(nil, nil, czf, cachegroups, 0)
+	expected = []Footprint{
+		{FootprintType: Ipv4Cidr, FootprintValue: []string{"192.0.2.0/24"}},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected only Cache Group zones without a maximum distance - expected: %+v, actual: %+v", expected, actual)
+	}
+}
+
+func TestApplyEgressCapacity(t *testing.T) {
+	scopeType := "hostname"
+	limits := []Limit{
+		{Id: "total_egress", LimitType: Egress, MaximumHard: 1000, MaximumSoft: 800},
+		{Id: "unset_egress", LimitType: Egress},
+		{Id: "host_egress", LimitType: Egress, MaximumHard: 10, MaximumSoft: 5, Scope: &LimitScope{ScopeType: &scopeType, ScopeValue: []string{"example.com"}}},
+		{Id: "total_requests", LimitType: Requests, MaximumHard: 20, MaximumSoft: 15},
+	}
+	applyEgressCapacity(limits, 5000)
+
+	expected := map[string][2]int64{
+		"total_egress":   {5000, 4000},
+		"unset_egress":   {5000, 5000},
+		"host_egress":    {10, 5},
+		"total_requests": {20, 15},
+	}
+	for _, l := range limits {
+		if actual := [2]int64{l.MaximumHard, l.MaximumSoft}; actual != expected[l.Id] {
+			t.Errorf("limit '%s' - expected hard and soft maximums: %v, actual: %v", l.Id, expected[l.Id], actual)
+		}
+	}
+}
+
+func TestGetEgressCapacity(t *testing.T) {
+	defer func(f func(*sql.Tx, tc.CDNName) (cdn.CapData, error)) { fetchCDNCapacity = f }(fetchCDNCapacity)
+	capacityCache.Lock()
+	delete(capacityCache.fetched, "slow")
+	capacityCache.Unlock()
+
+	fetches := int32(0)
+	release := make(chan struct{})
+	fetchCDNCapacity = func(tx *sql.Tx, cdnName tc.CDNName) (cdn.CapData, error) {
+		if cdnName == "other" {
+			return cdn.CapData{Capacity: 1}, nil
+		}
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		return cdn.CapData{}, errors.New("Traffic Monitor unreachable")
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := getEgressCapacity(nil, "slow"); err == nil {
+				t.Errorf("getting egress capacity of CDN with failing Traffic Monitor - expected: error, actual: nil")
+			}
+		}()
+	}
+
+	// a slow Traffic Monitor doesn't block requests for other CDNs.
+	done := make(chan struct{})
+	go func() {
+		if bps, err := getEgressCapacity(nil, "other"); err != nil || bps != 1000 {
+			t.Errorf("getting egress capacity of other CDN - expected: 1000 nil, actual: %v %v", bps, err)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("getting egress capacity of other CDN - expected: not blocked by slow CDN, actual: blocked")
+	}
+
+	close(release)
+	wg.Wait()
+	if _, err := getEgressCapacity(nil, "slow"); err == nil {
+		t.Errorf("getting egress capacity after failure - expected: cached error, actual: nil")
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("fetches of egress capacity - expected: 1, actual: %v", n)
+	}
+}
@@ -0,0 +1,151 @@
+package cdni
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"encoding/json"
+)
+
+// This file contains the metadata model of the CDNi Metadata Interface, as
+// defined by RFC 8006.
+
+// HostIndex is the top-level metadata object, listing the hostnames a uCDN
+// delegates to the dCDN.
+type HostIndex struct {
+	Hosts []HostMatch `json:"hosts"`
+}
+
+// HostMatch contains the metadata for requests to a single hostname.
+type HostMatch struct {
+	Host         string       `json:"host"`
+	HostMetadata HostMetadata `json:"host-metadata"`
+}
+
+// HostMetadata contains the metadata which applies to every request to a
+// hostname, and the paths which have their own metadata.
+type HostMetadata struct {
+	Metadata []GenericMetadata `json:"metadata"`
+	Paths    []PathMatch       `json:"paths,omitempty"`
+}
+
+// PathMatch contains the metadata for requests whose path matches a pattern.
+type PathMatch struct {
+	PathPattern  PatternMatch `json:"path-pattern"`
+	PathMetadata PathMetadata `json:"path-metadata"`
+}
+
+// PatternMatch is a pattern for matching strings, in which '*' matches any
+// sequence of characters, '?' matches any single character, and '\' escapes
+// the character that follows it.
+type PatternMatch struct {
+	Pattern       string `json:"pattern"`
+	CaseSensitive bool   `json:"case-sensitive"`
+}
+
+// PathMetadata contains the metadata which applies to the requests matched by
+// a PathMatch, and the sub-paths which have their own metadata.
+type PathMetadata struct {
+	Metadata []GenericMetadata `json:"metadata"`
+	Paths    []PathMatch       `json:"paths,omitempty"`
+}
+
+// SourceMetadata lists the sources from which the dCDN acquires content.
+type SourceMetadata struct {
+	Sources []Source `json:"sources"`
+}
+
+// Source is a set of equivalent endpoints from which content can be acquired
+// using the same protocol and authentication.
+type Source struct {
+	AcquisitionAuth *Auth    `json:"acquisition-auth,omitempty"`
+	Endpoints       []string `json:"endpoints"`
+	Protocol        Protocol `json:"protocol"`
+}
+
+// LocationACL restricts delivery based on the location of the client.
+type LocationACL struct {
+	Locations []LocationRule `json:"locations"`
+}
+
+// LocationRule allows or denies delivery to clients in the given footprints.
+type LocationRule struct {
+	Action     ACLAction   `json:"action"`
+	Footprints []Footprint `json:"footprints"`
+}
+
+// TimeWindowACL restricts delivery based on the time of the request.
+type TimeWindowACL struct {
+	Times []TimeWindowRule `json:"times"`
+}
+
+// TimeWindowRule allows or denies delivery during the given time windows.
+type TimeWindowRule struct {
+	Action  ACLAction    `json:"action"`
+	Windows []TimeWindow `json:"windows"`
+}
+
+// TimeWindow is a window of time between two Unix epoch timestamps, in
+// seconds.
+type TimeWindow struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// ProtocolACL restricts delivery based on the protocol of the request.
+type ProtocolACL struct {
+	ProtocolACL []ProtocolRule `json:"protocol-acl"`
+}
+
+// ProtocolRule allows or denies delivery over the given protocols.
+type ProtocolRule struct {
+	Action    ACLAction  `json:"action"`
+	Protocols []Protocol `json:"protocols"`
+}
+
+// Cache controls how query strings are treated in the cache key.
+type Cache struct {
+	ExcludeQueryString  bool     `json:"exclude-query-string"`
+	IncludeQueryStrings []string `json:"include-query-strings,omitempty"`
+}
+
+// Auth describes how requests are authenticated or authorized.
+type Auth struct {
+	AuthType  string          `json:"auth-type"`
+	AuthValue json.RawMessage `json:"auth-value,omitempty"`
+}
+
+// ACLAction is the action of an ACL rule.
+type ACLAction string
+
+const (
+	ACLAllow ACLAction = "allow"
+	ACLDeny  ACLAction = "deny"
+)
+
+// Protocol is a protocol from the CDNi Metadata Protocol Types registry.
+type Protocol string
+
+const (
+	ProtocolHTTP  Protocol = "http/1.1"
+	ProtocolHTTPS Protocol = "https/1.1"
+)
+
+// AuthTypeURISigning is the Auth type of requests signed with URI Signing.
+const AuthTypeURISigning = "MI.UriSigning"
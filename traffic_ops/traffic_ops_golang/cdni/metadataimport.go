@@ -0,0 +1,400 @@
+package cdni
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/lib/go-util"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/dbhelpers"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/deliveryservice"
+)
+
+const (
+	// importedXMLIDPrefix is the prefix of the XMLIDs of the Delivery Services
+	// created from CDNi metadata.
+	importedXMLIDPrefix = "cdni-"
+	maxXMLIDLength      = 48
+
+	insertRegexQuery         = `INSERT INTO regex (type, pattern) VALUES ((SELECT id FROM type WHERE name = $1), $2::text) RETURNING id`
+	insertDSRegexQuery       = `INSERT INTO deliveryservice_regex (deliveryservice, regex, set_number) VALUES ($1, $2, $3)`
+	insertSecondaryOriginSQL = `INSERT INTO origin (name, fqdn, protocol, is_primary, port, deliveryservice, tenant) VALUES ($1, $2, $3, FALSE, $4, $5, $6)`
+)
+
+var invalidXMLIDChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// importedDeliveryService is the Delivery Service that a HostMatch translates
+// into.
+type importedDeliveryService struct {
+	Host  string
+	XMLID string
+	// Origins are the origin URLs of the Delivery Service, of which the first
+	// is its primary origin.
+	Origins          []string
+	Protocol         int
+	QStringIgnore    int
+	SigningAlgorithm *string
+	// PathRegexes restrict the Delivery Service to the paths of the
+	// HostMatch, if it has any.
+	PathRegexes []string
+}
+
+// importHostIndex creates a Delivery Service, with its regexes and origins,
+// for every host in the given HostIndex, in the CDN configured to be
+// advertised to uCDNs. It returns the XMLIDs of the created Delivery Services.
+func importHostIndex(r *http.Request, inf *api.APIInfo, ucdn string, hostIndex HostIndex) ([]string, int, error, error) {
+	tx := inf.Tx.Tx
+	if inf.Config.Cdni == nil || inf.Config.Cdni.AdvertisedCDN == "" {
+		return nil, http.StatusInternalServerError, nil, errors.New("cdn.conf does not contain a CDNi advertised_cdn to import delivery services into")
+	}
+	cdnID, ok, err := dbhelpers.GetCDNIDFromName(tx, tc.CDNName(inf.Config.Cdni.AdvertisedCDN))
+	if err != nil {
+		return nil, http.StatusInternalServerError, nil, fmt.Errorf("getting CDNi advertised CDN ID: %w", err)
+	} else if !ok {
+		return nil, http.StatusInternalServerError, nil, fmt.Errorf("CDNi advertised CDN '%s' does not exist", inf.Config.Cdni.AdvertisedCDN)
+	}
+	typeID, ok, err := dbhelpers.GetTypeIDByName(string(tc.DSTypeHTTP), tx)
+	if err != nil {
+		return nil, http.StatusInternalServerError, nil, fmt.Errorf("getting HTTP type ID: %w", err)
+	} else if !ok {
+		return nil, http.StatusInternalServerError, nil, errors.New("no HTTP delivery service type found")
+	}
+
+	xmlIDs := []string{}
+	for _, hm := range hostIndex.Hosts {
+		imported, err := translateHostMatch(ucdn, hm)
+		if err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("host '%s': %w", hm.Host, err), nil
+		}
+		ds := tc.DeliveryServiceV4{}
+		ds.Active = util.BoolPtr(true)
+		ds.CDNID = &cdnID
+		ds.DisplayName = util.StrPtr(truncate(imported.Host, maxXMLIDLength))
+		ds.DSCP = util.IntPtr(0)
+		ds.GeoLimit = util.IntPtr(0)
+		ds.GeoProvider = util.IntPtr(0)
+		ds.InitialDispersion = util.IntPtr(1)
+		ds.IPV6RoutingEnabled = util.BoolPtr(true)
+		ds.LogsEnabled = util.BoolPtr(false)
+		ds.LongDesc = util.StrPtr("Imported from the CDNi metadata of uCDN '" + ucdn + "' for host '" + imported.Host + "'")
+		ds.MaxRequestHeaderBytes = util.IntPtr(tc.DefaultMaxRequestHeaderBytes)
+		ds.MissLat = util.FloatPtr(0)
+		ds.MissLong = util.FloatPtr(0)
+		ds.MultiSiteOrigin = util.BoolPtr(false)
+		ds.OrgServerFQDN = &imported.Origins[0]
+		ds.Protocol = &imported.Protocol
+		ds.QStringIgnore = &imported.QStringIgnore
+		ds.RangeRequestHandling = util.IntPtr(tc.RangeRequestHandlingDontCache)
+		ds.RegionalGeoBlocking = util.BoolPtr(false)
+		ds.RoutingName = util.StrPtr(tc.DefaultRoutingName)
+		ds.SigningAlgorithm = imported.SigningAlgorithm
+		ds.TenantID = &inf.User.TenantID
+		ds.TypeID = &typeID
+		ds.XMLID = &imported.XMLID
+		if inf.Config.Cdni.ImportTopology != "" {
+			ds.Topology = util.StrPtr(inf.Config.Cdni.ImportTopology)
+		}
+
+		created, errCode, userErr, sysErr := deliveryservice.CreateDeliveryService(r, inf, ds)
+		if userErr != nil || sysErr != nil {
+			if userErr != nil {
+				userErr = fmt.Errorf("creating delivery service for host '%s': %w", imported.Host, userErr)
+			}
+			return nil, errCode, userErr, sysErr
+		}
+		if err := createImportedRegexes(tx, *created.ID, imported); err != nil {
+			return nil, http.StatusInternalServerError, nil, fmt.Errorf("creating regexes for delivery service '%s': %w", imported.XMLID, err)
+		}
+		if err := createSecondaryOrigins(tx, *created.ID, inf.User.TenantID, imported); err != nil {
+			return nil, http.StatusInternalServerError, nil, fmt.Errorf("creating origins for delivery service '%s': %w", imported.XMLID, err)
+		}
+		xmlIDs = append(xmlIDs, imported.XMLID)
+	}
+	return xmlIDs, http.StatusOK, nil, nil
+}
+
+// createImportedRegexes creates a regex matching the host of the imported
+// Delivery Service and, if it has paths, a regex matching any of them, in set
+// 1. The default regex created with the Delivery Service is set 0. Traffic
+// Router requires every regex of a set to match, and any set, so the path
+// regex must be in the same set as the host to only match paths of the host.
+func createImportedRegexes(tx *sql.Tx, dsID int, imported importedDeliveryService) error {
+	const setNumber = 1
+	insert := func(regexType string, pattern string) error {
+		regexID := 0
+		if err := tx.QueryRow(insertRegexQuery, regexType, pattern).Scan(&regexID); err != nil {
+			return fmt.Errorf("inserting %s regex: %w", regexType, err)
+		}
+		if _, err := tx.Exec(insertDSRegexQuery, dsID, regexID, setNumber); err != nil {
+			return fmt.Errorf("inserting deliveryservice_regex: %w", err)
+		}
+		return nil
+	}
+	if err := insert("HOST_REGEXP", regexp.QuoteMeta(imported.Host)); err != nil {
+		return err
+	}
+	if len(imported.PathRegexes) == 0 {
+		return nil
+	}
+	return insert("PATH_REGEXP", anyRegex(imported.PathRegexes))
+}
+
+// anyRegex returns a regex matching any of the given regexes.
+func anyRegex(regexes []string) string {
+	if len(regexes) == 1 {
+		return regexes[0]
+	}
+	groups := make([]string, 0, len(regexes))
+	for _, re := range regexes {
+		groups = append(groups, "(?:"+re+")") // groups scope the flags of each, such as (?i)
+	}
+	return strings.Join(groups, "|")
+}
+
+// createSecondaryOrigins creates an origin for each of the imported Delivery
+// Service's origins after the first, which is created along with the
+// Delivery Service as its primary origin.
+func createSecondaryOrigins(tx *sql.Tx, dsID int, tenantID int, imported importedDeliveryService) error {
+	for i, origin := range imported.Origins[1:] {
+		protocol, endpoint := splitOriginURL(origin)
+		host, port, err := net.SplitHostPort(endpoint)
+		var portPtr *string
+		if err != nil {
+			host = endpoint
+		} else {
+			portPtr = &port
+		}
+		name := imported.XMLID + "-" + strconv.Itoa(i+1)
+		if _, err := tx.Exec(insertSecondaryOriginSQL, name, host, protocol, portPtr, dsID, tenantID); err != nil {
+			return fmt.Errorf("inserting origin '%s': %w", origin, err)
+		}
+	}
+	return nil
+}
+
+func splitOriginURL(origin string) (string, string) {
+	parts := strings.SplitN(origin, "://", 2)
+	return parts[0], parts[1]
+}
+
+// translateHostMatch translates a HostMatch into the Delivery Service that
+// implements its metadata, returning an error for any metadata that Traffic
+// Control cannot enforce.
+func translateHostMatch(ucdn string, hm HostMatch) (importedDeliveryService, error) {
+	if hm.Host == "" {
+		return importedDeliveryService{}, errors.New("host is required")
+	}
+	ds := importedDeliveryService{
+		Host:          strings.ToLower(hm.Host),
+		XMLID:         makeImportedXMLID(ucdn, hm.Host),
+		Protocol:      tc.DSProtocolHTTPAndHTTPS,
+		QStringIgnore: tc.QueryStringIgnoreUseInCacheKeyAndPassUp,
+	}
+	for _, md := range hm.HostMetadata.Metadata {
+		if err := applyMetadata(&ds, md); err != nil {
+			return importedDeliveryService{}, fmt.Errorf("%s: %w", md.Type, err)
+		}
+	}
+	if len(ds.Origins) == 0 {
+		return importedDeliveryService{}, errors.New("no " + string(MiSourceMetadata) + " with an endpoint given")
+	}
+	for _, pm := range hm.HostMetadata.Paths {
+		if len(pm.PathMetadata.Metadata) > 0 || len(pm.PathMetadata.Paths) > 0 {
+			return importedDeliveryService{}, fmt.Errorf("path '%s': path-specific metadata is not supported, because a delivery service's settings apply to all of its paths", pm.PathPattern.Pattern)
+		}
+		if pm.PathPattern.Pattern == "" {
+			return importedDeliveryService{}, errors.New("path pattern is required")
+		}
+		ds.PathRegexes = append(ds.PathRegexes, patternToRegex(pm.PathPattern))
+	}
+	return ds, nil
+}
+
+// applyMetadata applies a single generic metadata object to the Delivery
+// Service.
+func applyMetadata(ds *importedDeliveryService, md GenericMetadata) error {
+	switch md.Type {
+	case MiSourceMetadata:
+		sm := SourceMetadata{}
+		if err := json.Unmarshal(md.Value, &sm); err != nil {
+			return fmt.Errorf("unmarshalling: %w", err)
+		}
+		for _, src := range sm.Sources {
+			if src.AcquisitionAuth != nil {
+				return errors.New("acquisition-auth is not supported")
+			}
+			scheme := "http"
+			switch src.Protocol {
+			case ProtocolHTTP, "":
+			case ProtocolHTTPS:
+				scheme = "https"
+			default:
+				return fmt.Errorf("unsupported protocol '%s'", src.Protocol)
+			}
+			for _, endpoint := range src.Endpoints {
+				if endpoint == "" || strings.ContainsAny(endpoint, "/ ") {
+					return fmt.Errorf("invalid endpoint '%s'", endpoint)
+				}
+				ds.Origins = append(ds.Origins, scheme+"://"+endpoint)
+			}
+		}
+	case MiLocationACL:
+		acl := LocationACL{}
+		if err := json.Unmarshal(md.Value, &acl); err != nil {
+			return fmt.Errorf("unmarshalling: %w", err)
+		}
+		// Requests which match no rule are allowed, so allow rules alone don't restrict anything.
+		for _, rule := range acl.Locations {
+			if rule.Action != ACLAllow {
+				return errors.New("deny rules are not supported, since delivery services cannot deny delivery to a list of locations")
+			}
+		}
+	case MiTimeWindowACL:
+		acl := TimeWindowACL{}
+		if err := json.Unmarshal(md.Value, &acl); err != nil {
+			return fmt.Errorf("unmarshalling: %w", err)
+		}
+		// Requests which match no rule are allowed, so allow rules alone don't restrict anything.
+		for _, rule := range acl.Times {
+			if rule.Action != ACLAllow {
+				return errors.New("deny rules are not supported, since delivery services cannot restrict delivery by time")
+			}
+		}
+	case MiProtocolACL:
+		acl := ProtocolACL{}
+		if err := json.Unmarshal(md.Value, &acl); err != nil {
+			return fmt.Errorf("unmarshalling: %w", err)
+		}
+		protocol, err := protocolFromACL(acl)
+		if err != nil {
+			return err
+		}
+		ds.Protocol = protocol
+	case MiCache:
+		cache := Cache{}
+		if err := json.Unmarshal(md.Value, &cache); err != nil {
+			return fmt.Errorf("unmarshalling: %w", err)
+		}
+		if len(cache.IncludeQueryStrings) > 0 {
+			return errors.New("include-query-strings is not supported, the query string can only be included in or excluded from the cache key as a whole")
+		}
+		if cache.ExcludeQueryString {
+			ds.QStringIgnore = tc.QueryStringIgnoreIgnoreInCacheKeyAndPassUp
+		} else {
+			ds.QStringIgnore = tc.QueryStringIgnoreUseInCacheKeyAndPassUp
+		}
+	case MiAuth:
+		auth := Auth{}
+		if err := json.Unmarshal(md.Value, &auth); err != nil {
+			return fmt.Errorf("unmarshalling: %w", err)
+		}
+		if auth.AuthType != AuthTypeURISigning {
+			return fmt.Errorf("unsupported auth-type '%s', only '%s' is supported", auth.AuthType, AuthTypeURISigning)
+		}
+		ds.SigningAlgorithm = util.StrPtr(tc.SigningAlgorithmURISigning)
+	default:
+		return errors.New("unsupported metadata type")
+	}
+	return nil
+}
+
+// protocolFromACL returns the Delivery Service protocol which allows exactly
+// the protocols the ACL allows. Rules are evaluated in order, and protocols
+// which match no rule are allowed.
+func protocolFromACL(acl ProtocolACL) (int, error) {
+	allowed := map[Protocol]bool{}
+	for _, rule := range acl.ProtocolACL {
+		if rule.Action != ACLAllow && rule.Action != ACLDeny {
+			return 0, fmt.Errorf("unsupported action '%s'", rule.Action)
+		}
+		for _, p := range rule.Protocols {
+			if p != ProtocolHTTP && p != ProtocolHTTPS {
+				return 0, fmt.Errorf("unsupported protocol '%s'", p)
+			}
+			if _, ok := allowed[p]; !ok {
+				allowed[p] = rule.Action == ACLAllow
+			}
+		}
+	}
+	httpAllowed, ok := allowed[ProtocolHTTP]
+	httpAllowed = httpAllowed || !ok
+	httpsAllowed, ok := allowed[ProtocolHTTPS]
+	httpsAllowed = httpsAllowed || !ok
+	switch {
+	case httpAllowed && httpsAllowed:
+		return tc.DSProtocolHTTPAndHTTPS, nil
+	case httpsAllowed:
+		return tc.DSProtocolHTTPS, nil
+	case httpAllowed:
+		return tc.DSProtocolHTTP, nil
+	}
+	return 0, errors.New("denies every protocol")
+}
+
+// patternToRegex converts an RFC 8006 PatternMatch to an equivalent regular
+// expression.
+func patternToRegex(p PatternMatch) string {
+	sb := strings.Builder{}
+	if !p.CaseSensitive {
+		sb.WriteString("(?i)")
+	}
+	escaped := false
+	for _, c := range p.Pattern {
+		switch {
+		case escaped:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '*':
+			sb.WriteString(".*")
+		case c == '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+// makeImportedXMLID returns the XMLID of the Delivery Service imported for the
+// given uCDN and host.
+func makeImportedXMLID(ucdn string, host string) string {
+	xmlID := importedXMLIDPrefix + strings.ToLower(ucdn) + "-" + strings.ToLower(host)
+	xmlID = strings.Trim(invalidXMLIDChars.ReplaceAllString(xmlID, "-"), "-")
+	return strings.TrimRight(truncate(xmlID, maxXMLIDLength), "-")
+}
+
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	return s[:length]
+}
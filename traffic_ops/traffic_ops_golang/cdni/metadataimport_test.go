@@ -0,0 +1,199 @@
+package cdni
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+const testHostIndex = `{
+	"hosts": [
+		{
+			"host": "Video.Example.com",
+			"host-metadata": {
+				"metadata": [
+					{
+						"generic-metadata-type": "MI.SourceMetadata",
+						"generic-metadata-value": {
+							"sources": [
+								{"endpoints": ["origin.example.com", "origin2.example.com:8443"], "protocol": "https/1.1"}
+							]
+						}
+					},
+					{
+						"generic-metadata-type": "MI.LocationACL",
+						"generic-metadata-value": {
+							"locations": [
+								{"action": "allow", "footprints": [{"footprint-type": "countrycode", "footprint-value": ["us", "ca"]}]}
+							]
+						}
+					},
+					{
+						"generic-metadata-type": "MI.ProtocolACL",
+						"generic-metadata-value": {
+							"protocol-acl": [
+								{"action": "deny", "protocols": ["http/1.1"]}
+							]
+						}
+					},
+					{
+						"generic-metadata-type": "MI.Cache",
+						"generic-metadata-value": {"exclude-query-string": true}
+					},
+					{
+						"generic-metadata-type": "MI.Auth",
+						"generic-metadata-value": {"auth-type": "MI.UriSigning"}
+					}
+				],
+				"paths": [
+					{"path-pattern": {"pattern": "/live/*.m3u8", "case-sensitive": true}, "path-metadata": {"metadata": []}}
+				]
+			}
+		}
+	]
+}`
+
+func TestTranslateHostMatch(t *testing.T) {
+	hostIndex := HostIndex{}
+	if err := json.Unmarshal([]byte(testHostIndex), &hostIndex); err != nil {
+		t.Fatalf("unmarshalling host index: %v", err)
+	}
+	actual, err := translateHostMatch("ucdn1", hostIndex.Hosts[0])
+	if err != nil {
+		t.Fatalf("translating host match - expected: nil error, actual: %v", err)
+	}
+	signingAlgorithm := tc.SigningAlgorithmURISigning
+	expected := importedDeliveryService{
+		Host:             "video.example.com",
+		XMLID:            "cdni-ucdn1-video-example-com",
+		Origins:          []string{"https://origin.example.com", "https://origin2.example.com:8443"},
+		Protocol:         tc.DSProtocolHTTPS,
+		QStringIgnore:    tc.QueryStringIgnoreIgnoreInCacheKeyAndPassUp,
+		SigningAlgorithm: &signingAlgorithm,
+		PathRegexes:      []string{`/live/.*\.m3u8`},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("translating host match - expected: %+v, actual: %+v", expected, actual)
+	}
+}
+
+func TestTranslateHostMatchUnsupported(t *testing.T) {
+	source := GenericMetadata{Type: MiSourceMetadata, Value: json.RawMessage(`{"sources": [{"endpoints": ["origin.example.com"]}]}`)}
+	cases := map[string]HostMatch{
+		"no source": {Host: "a.example.com"},
+		"location deny rule": {Host: "a.example.com", HostMetadata: HostMetadata{Metadata: []GenericMetadata{source,
+			{Type: MiLocationACL, Value: json.RawMessage(`{"locations": [{"action": "deny", "footprints": [{"footprint-type": "countrycode", "footprint-value": ["us"]}]}]}`)},
+		}}},
+		"time window deny rule": {Host: "a.example.com", HostMetadata: HostMetadata{Metadata: []GenericMetadata{source,
+			{Type: MiTimeWindowACL, Value: json.RawMessage(`{"times": [{"action": "deny", "windows": [{"start": 0, "end": 1}]}]}`)},
+		}}},
+		"every protocol denied": {Host: "a.example.com", HostMetadata: HostMetadata{Metadata: []GenericMetadata{source,
+			{Type: MiProtocolACL, Value: json.RawMessage(`{"protocol-acl": [{"action": "deny", "protocols": ["http/1.1", "https/1.1"]}]}`)},
+		}}},
+		"path metadata": {Host: "a.example.com", HostMetadata: HostMetadata{Metadata: []GenericMetadata{source}, Paths: []PathMatch{
+			{PathPattern: PatternMatch{Pattern: "/a/*"}, PathMetadata: PathMetadata{Metadata: []GenericMetadata{source}}},
+		}}},
+		"unknown type": {Host: "a.example.com", HostMetadata: HostMetadata{Metadata: []GenericMetadata{source, {Type: "MI.Unknown"}}}},
+	}
+	for name, hm := range cases {
+		if _, err := translateHostMatch("ucdn1", hm); err == nil {
+			t.Errorf("translating host match with %s - expected: error, actual: nil", name)
+		}
+	}
+}
+
+func TestPatternToRegex(t *testing.T) {
+	cases := []struct {
+		Pattern  PatternMatch
+		Matches  []string
+		Excludes []string
+	}{
+		{PatternMatch{Pattern: "/video/*.ts", CaseSensitive: true}, []string{"/video/a/b.ts"}, []string{"/VIDEO/a.ts", "/video/a.tsx"}},
+		{PatternMatch{Pattern: "/img?.png"}, []string{"/img1.png", "/IMG2.PNG"}, []string{"/img10.png"}},
+		{PatternMatch{Pattern: `/literal\*`, CaseSensitive: true}, []string{"/literal*"}, []string{"/literalx"}},
+	}
+	for _, c := range cases {
+		re := regexp.MustCompile("^(?:" + patternToRegex(c.Pattern) + ")$")
+		for _, s := range c.Matches {
+			if !re.MatchString(s) {
+				t.Errorf("pattern '%s' - expected to match '%s'", c.Pattern.Pattern, s)
+			}
+		}
+		for _, s := range c.Excludes {
+			if re.MatchString(s) {
+				t.Errorf("pattern '%s' - expected not to match '%s'", c.Pattern.Pattern, s)
+			}
+		}
+	}
+}
+
+func TestMakeImportedXMLID(t *testing.T) {
+	if actual := makeImportedXMLID("UCDN_1", "www.example.com"); actual != "cdni-ucdn-1-www-example-com" {
+		t.Errorf("expected: cdni-ucdn-1-www-example-com, actual: %s", actual)
+	}
+	long := makeImportedXMLID("ucdn1", strings.Repeat("a", 40)+".example.com")
+	if len(long) > maxXMLIDLength || strings.HasSuffix(long, "-") {
+		t.Errorf("expected an XMLID of at most %d characters without a trailing '-', actual: %s", maxXMLIDLength, long)
+	}
+}
+
+func TestCreateImportedRegexes(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	imported := importedDeliveryService{
+		Host:        "video.example.com",
+		PathRegexes: []string{`/live/.*\.m3u8`, `(?i)/vod/.*`},
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO regex").WithArgs("HOST_REGEXP", `video\.example\.com`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	mock.ExpectExec("INSERT INTO deliveryservice_regex").WithArgs(1, 10, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO regex").WithArgs("PATH_REGEXP", `(?:/live/.*\.m3u8)|(?:(?i)/vod/.*)`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+	mock.ExpectExec("INSERT INTO deliveryservice_regex").WithArgs(1, 11, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tx, err := mockDB.Begin()
+	if err != nil {
+		t.Fatalf("beginning transaction: %v", err)
+	}
+	if err := createImportedRegexes(tx, 1, imported); err != nil {
+		t.Errorf("creating imported regexes - expected: nil error, actual: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expected the host and path regexes in set 1: %v", err)
+	}
+
+	pathRegex := regexp.MustCompile("^(?:" + anyRegex(imported.PathRegexes) + ")$")
+	for path, expected := range map[string]bool{"/live/a.m3u8": true, "/VOD/a.mp4": true, "/LIVE/a.m3u8": false, "/other": false} {
+		if actual := pathRegex.MatchString(path); actual != expected {
+			t.Errorf("path regex matching '%s' - expected: %v, actual: %v", path, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,462 @@
+package cdni
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/util/monitorhlp"
+)
+
+// coverageZoneCacheTTL is how long a fetched Coverage Zone File is used to
+// answer redirection requests and generate footprints before it is fetched
+// again.
+const coverageZoneCacheTTL = time.Minute
+
+// earthRadiusKm is the mean radius of the Earth, used to compute the distance
+// between Cache Groups.
+const earthRadiusKm = 6371.0
+
+// RedirectionRequest is a Redirection Request, as defined by RFC 7975. Only
+// one of its members is given in any single request.
+type RedirectionRequest struct {
+	DNS  *DNSRedirectionRequest  `json:"dns,omitempty"`
+	HTTP *HTTPRedirectionRequest `json:"http,omitempty"`
+}
+
+// DNSRedirectionRequest is the DNS Redirection Request of RFC 7975.
+type DNSRedirectionRequest struct {
+	ResolverIP   string `json:"resolver-ip"`
+	ClientSubnet string `json:"c-subnet,omitempty"`
+	QName        string `json:"qname"`
+	QType        string `json:"qtype"`
+	QClass       string `json:"qclass"`
+}
+
+// HTTPRedirectionRequest is the HTTP Redirection Request of RFC 7975. Request
+// headers, given as "cs-(<header name>)" members, are ignored except for the
+// Host header, which is used if the URI has no host.
+type HTTPRedirectionRequest struct {
+	ClientIP string `json:"c-ip"`
+	Host     string `json:"cs-(host)"`
+	Method   string `json:"cs-method"`
+	URI      string `json:"cs-uri"`
+	Version  string `json:"cs-version"`
+}
+
+// RedirectionResponse is a Redirection Response, as defined by RFC 7975.
+type RedirectionResponse struct {
+	HTTP *HTTPRedirectionResponse `json:"http,omitempty"`
+}
+
+// HTTPRedirectionResponse is the HTTP Redirection Response of RFC 7975.
+type HTTPRedirectionResponse struct {
+	Status   int    `json:"sc-status"`
+	Reason   string `json:"sc-reason"`
+	Location string `json:"sc-(location)"`
+}
+
+// coverageZoneNetwork is a network of a coverage zone.
+type coverageZoneNetwork struct {
+	Network *net.IPNet
+	Zone    string
+}
+
+// coverageZoneCache holds the parsed Coverage Zone File of each CDN, so that
+// it isn't fetched on every redirection request.
+var coverageZoneCache = struct {
+	sync.Mutex
+	networks map[string][]coverageZoneNetwork
+	fetched  map[string]time.Time
+}{
+	networks: map[string][]coverageZoneNetwork{},
+	fetched:  map[string]time.Time{},
+}
+
+// GetRedirection is the handler for the Request Routing Redirection interface
+// of RFC 7975. It responds to a uCDN's request for the URI of the edge cache
+// to which a client should be redirected.
+func GetRedirection(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, nil, nil)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	if inf.Config.Cdni == nil || inf.Config.Secrets[0] == "" || inf.Config.Cdni.DCdnId == "" {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("cdn.conf does not contain CDNi information"))
+		return
+	}
+	if inf.Config.Cdni.AdvertisedCDN == "" {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("cdn.conf does not contain a CDNi advertised CDN"))
+		return
+	}
+
+	bearerToken := getBearerToken(r)
+	if _, err := checkBearerToken(bearerToken, inf); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	var req RedirectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, fmt.Errorf("decoding redirection request: %w", err), nil)
+		return
+	}
+	if req.DNS != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, errors.New("DNS redirection is not supported"), nil)
+		return
+	}
+	if req.HTTP == nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, errors.New("redirection request must contain an 'http' object"), nil)
+		return
+	}
+	clientIP, uri, err := parseHTTPRedirectionRequest(*req.HTTP)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	cdnName := inf.Config.Cdni.AdvertisedCDN
+	crConfig, crStates, err := getMonitorState(inf.Tx.Tx, tc.CDNName(cdnName))
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusServiceUnavailable, nil, err)
+		return
+	}
+	zones, err := getCoverageZoneNetworks(inf.Tx.Tx, cdnName)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusServiceUnavailable, nil, err)
+		return
+	}
+
+	location, errCode, userErr := selectRedirectURL(crConfig, crStates, zones, clientIP, uri)
+	if userErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, nil)
+		return
+	}
+	api.WriteRespRaw(w, r, RedirectionResponse{HTTP: &HTTPRedirectionResponse{
+		Status:   http.StatusFound,
+		Reason:   http.StatusText(http.StatusFound),
+		Location: location,
+	}})
+}
+
+// parseHTTPRedirectionRequest returns the client IP address and the absolute
+// URI of the given request.
+func parseHTTPRedirectionRequest(req HTTPRedirectionRequest) (net.IP, *url.URL, error) {
+	clientIP := net.ParseIP(req.ClientIP)
+	if clientIP == nil {
+		return nil, nil, fmt.Errorf("invalid client IP address '%s'", req.ClientIP)
+	}
+	uri, err := url.Parse(req.URI)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid URI '%s': %w", req.URI, err)
+	}
+	if uri.Host == "" {
+		uri.Host = req.Host
+	}
+	if uri.Host == "" {
+		return nil, nil, errors.New("redirection request must contain either an absolute URI or a Host header")
+	}
+	if uri.Scheme == "" {
+		uri.Scheme = "http"
+	}
+	if uri.Scheme != "http" && uri.Scheme != "https" {
+		return nil, nil, fmt.Errorf("unsupported URI scheme '%s'", uri.Scheme)
+	}
+	return clientIP, uri, nil
+}
+
+// getMonitorState returns the CRConfig and CRStates of the given CDN from the
+// first of its Traffic Monitors which serves both.
+func getMonitorState(tx *sql.Tx, cdnName tc.CDNName) (tc.CRConfig, tc.CRStates, error) {
+	monitors, err := monitorhlp.GetURLs(tx)
+	if err != nil {
+		return tc.CRConfig{}, tc.CRStates{}, fmt.Errorf("getting monitors: %w", err)
+	}
+	if len(monitors[cdnName]) == 0 {
+		return tc.CRConfig{}, tc.CRStates{}, fmt.Errorf("no monitors found for CDN '%s'", cdnName)
+	}
+	client, err := monitorhlp.GetClient(tx)
+	if err != nil {
+		return tc.CRConfig{}, tc.CRStates{}, fmt.Errorf("getting TM client: %w", err)
+	}
+	for _, monitorFQDN := range monitors[cdnName] {
+		crStates, err := monitorhlp.GetCRStates(monitorFQDN, client)
+		if err != nil {
+			log.Warnf("CDNi redirection: getting CRStates from CDN '%s' monitor '%s', trying next monitor: %v", cdnName, monitorFQDN, err)
+			continue
+		}
+		crConfig, err := monitorhlp.GetCRConfig(monitorFQDN, client)
+		if err != nil {
+			log.Warnf("CDNi redirection: getting CRConfig from CDN '%s' monitor '%s', trying next monitor: %v", cdnName, monitorFQDN, err)
+			continue
+		}
+		return crConfig, crStates, nil
+	}
+	return tc.CRConfig{}, tc.CRStates{}, fmt.Errorf("no monitor of CDN '%s' returned its CRConfig and CRStates", cdnName)
+}
+
+// getCoverageZoneNetworks returns the coverage zone networks of the given CDN,
+// fetching its Coverage Zone File if the cached one is missing or stale.
+func getCoverageZoneNetworks(tx *sql.Tx, cdnName string) ([]coverageZoneNetwork, error) {
+	coverageZoneCache.Lock()
+	defer coverageZoneCache.Unlock()
+	if time.Since(coverageZoneCache.fetched[cdnName]) < coverageZoneCacheTTL {
+		return coverageZoneCache.networks[cdnName], nil
+	}
+	czf, err := getCoverageZoneFile(tx, cdnName)
+	if err != nil {
+		return nil, err
+	}
+	networks := makeCoverageZoneNetworks(czf)
+	coverageZoneCache.networks[cdnName] = networks
+	coverageZoneCache.fetched[cdnName] = time.Now()
+	return networks, nil
+}
+
+// makeCoverageZoneNetworks parses the networks of the given Coverage Zone
+// File, ordered from the most to the least specific, so that the first network
+// containing an address is its longest prefix match.
+func makeCoverageZoneNetworks(czf tc.CoverageZoneFile) []coverageZoneNetwork {
+	networks := []coverageZoneNetwork{}
+	for name, zone := range czf.CoverageZones {
+		for _, network := range append(append([]string{}, zone.Network...), zone.Network6...) {
+			_, ipNet, err := net.ParseCIDR(network)
+			if err != nil {
+				log.Warnf("CDNi redirection: ignoring invalid network '%s' in coverage zone '%s'", network, name)
+				continue
+			}
+			networks = append(networks, coverageZoneNetwork{Network: ipNet, Zone: name})
+		}
+	}
+	sort.Slice(networks, func(i, j int) bool {
+		iOnes, _ := networks[i].Network.Mask.Size()
+		jOnes, _ := networks[j].Network.Mask.Size()
+		if iOnes != jOnes {
+			return iOnes > jOnes
+		}
+		return networks[i].Zone < networks[j].Zone
+	})
+	return networks
+}
+
+// getCoverageZone returns the coverage zone containing the given address, or
+// false if it isn't in any coverage zone.
+func getCoverageZone(networks []coverageZoneNetwork, ip net.IP) (string, bool) {
+	for _, network := range networks {
+		if network.Network.Contains(ip) {
+			return network.Zone, true
+		}
+	}
+	return "", false
+}
+
+// selectRedirectURL returns the URL of the edge cache to which a client at the
+// given address should be redirected for the given URI. If the request can't
+// be redirected, the returned error is safe to show the user, and is returned
+// with the HTTP status code to respond with.
+func selectRedirectURL(crConfig tc.CRConfig, crStates tc.CRStates, zones []coverageZoneNetwork, clientIP net.IP, uri *url.URL) (string, int, error) {
+	host := strings.ToLower(uri.Hostname())
+	xmlID, ds, ok := findDeliveryService(crConfig, host)
+	if !ok {
+		return "", http.StatusNotFound, fmt.Errorf("no delivery service serves host '%s'", host)
+	}
+	if dsState, ok := crStates.DeliveryService[tc.DeliveryServiceName(xmlID)]; ok && !dsState.IsAvailable {
+		return "", http.StatusServiceUnavailable, fmt.Errorf("delivery service for host '%s' is unavailable", host)
+	}
+	if len(ds.Domains) == 0 {
+		return "", http.StatusNotFound, fmt.Errorf("delivery service for host '%s' has no domain", host)
+	}
+
+	scheme := uri.Scheme
+	if ds.Protocol != nil {
+		if scheme == "http" && ds.Protocol.RedirectOnHTTPS {
+			scheme = "https"
+		}
+		if scheme == "http" && ds.Protocol.AcceptHTTP != nil && !*ds.Protocol.AcceptHTTP {
+			return "", http.StatusBadRequest, fmt.Errorf("delivery service for host '%s' does not accept HTTP", host)
+		}
+		if scheme == "https" && !ds.Protocol.AcceptHTTPS {
+			return "", http.StatusBadRequest, fmt.Errorf("delivery service for host '%s' does not accept HTTPS", host)
+		}
+	}
+
+	zone, ok := getCoverageZone(zones, clientIP)
+	if !ok {
+		return "", http.StatusNotFound, fmt.Errorf("client IP address '%s' is not in any coverage zone", clientIP)
+	}
+	candidates := getAvailableCaches(crConfig, crStates, xmlID, ds)
+	cachegroup, ok := selectCachegroup(crConfig.EdgeLocations, candidates, zone)
+	if !ok {
+		return "", http.StatusServiceUnavailable, fmt.Errorf("no available cache serves host '%s' near client IP address '%s'", host, clientIP)
+	}
+	cache := selectCache(candidates[cachegroup], uri.Path)
+
+	location := url.URL{
+		Scheme:   scheme,
+		Host:     cache + "." + ds.Domains[0],
+		Path:     uri.Path,
+		RawPath:  uri.RawPath,
+		RawQuery: uri.RawQuery,
+	}
+	return location.String(), http.StatusOK, nil
+}
+
+// findDeliveryService returns the Delivery Service with a host regular
+// expression matching the whole of the given host.
+func findDeliveryService(crConfig tc.CRConfig, host string) (string, tc.CRConfigDeliveryService, bool) {
+	xmlIDs := make([]string, 0, len(crConfig.DeliveryServices))
+	for xmlID := range crConfig.DeliveryServices {
+		xmlIDs = append(xmlIDs, xmlID)
+	}
+	sort.Strings(xmlIDs)
+	for _, xmlID := range xmlIDs {
+		ds := crConfig.DeliveryServices[xmlID]
+		for _, matchSet := range ds.MatchSets {
+			if matchSet == nil || matchSet.Protocol != "HTTP" {
+				continue
+			}
+			for _, match := range matchSet.MatchList {
+				if match.MatchType != "HOST" {
+					continue
+				}
+				re, err := regexp.Compile(`^(?:` + match.Regex + `)$`)
+				if err != nil {
+					log.Warnf("CDNi redirection: ignoring invalid host regex '%s' of delivery service '%s': %v", match.Regex, xmlID, err)
+					continue
+				}
+				if re.MatchString(host) {
+					return xmlID, ds, true
+				}
+			}
+		}
+	}
+	return "", tc.CRConfigDeliveryService{}, false
+}
+
+// getAvailableCaches returns the names of the available edge caches assigned
+// to the given Delivery Service, by Cache Group. Cache Groups the Delivery
+// Service is disabled in are omitted.
+func getAvailableCaches(crConfig tc.CRConfig, crStates tc.CRStates, xmlID string, ds tc.CRConfigDeliveryService) map[string][]string {
+	topologyCachegroups := map[string]struct{}{}
+	if ds.Topology != nil {
+		for _, node := range crConfig.Topologies[*ds.Topology].Nodes {
+			topologyCachegroups[node] = struct{}{}
+		}
+	}
+	disabled := map[string]struct{}{}
+	for _, cg := range crStates.DeliveryService[tc.DeliveryServiceName(xmlID)].DisabledLocations {
+		disabled[string(cg)] = struct{}{}
+	}
+
+	caches := map[string][]string{}
+	for name, server := range crConfig.ContentServers {
+		if server.CacheGroup == nil || server.ServerType == nil || server.ServerStatus == nil {
+			continue
+		}
+		if !strings.HasPrefix(*server.ServerType, tc.EdgeTypePrefix) {
+			continue
+		}
+		if status := tc.CacheStatus(*server.ServerStatus); status != tc.CacheStatusReported && status != tc.CacheStatusOnline {
+			continue
+		}
+		if !crStates.Caches[tc.CacheName(name)].IsAvailable {
+			continue
+		}
+		if _, ok := disabled[*server.CacheGroup]; ok {
+			continue
+		}
+		// Topology-based Delivery Services are served by the caches in the
+		// Cache Groups of their Topology, rather than by assigned servers.
+		if ds.Topology != nil {
+			if _, ok := topologyCachegroups[*server.CacheGroup]; !ok {
+				continue
+			}
+		} else if _, ok := server.DeliveryServices[xmlID]; !ok {
+			continue
+		}
+		caches[*server.CacheGroup] = append(caches[*server.CacheGroup], name)
+	}
+	return caches
+}
+
+// selectCachegroup returns the client's Cache Group if it has available
+// caches, and otherwise the nearest Cache Group which does.
+func selectCachegroup(locations map[string]tc.CRConfigLatitudeLongitude, candidates map[string][]string, clientCachegroup string) (string, bool) {
+	if len(candidates[clientCachegroup]) > 0 {
+		return clientCachegroup, true
+	}
+	clientLocation, ok := locations[clientCachegroup]
+	if !ok {
+		return "", false
+	}
+	nearest := ""
+	nearestDistance := math.Inf(1)
+	for cachegroup := range candidates {
+		location, ok := locations[cachegroup]
+		if !ok {
+			continue
+		}
+		distance := greatCircleDistance(clientLocation, location)
+		if distance < nearestDistance || (distance == nearestDistance && cachegroup < nearest) {
+			nearest = cachegroup
+			nearestDistance = distance
+		}
+	}
+	return nearest, nearest != ""
+}
+
+// greatCircleDistance returns the distance in kilometres between two
+// locations, using the haversine formula.
+func greatCircleDistance(a, b tc.CRConfigLatitudeLongitude) float64 {
+	toRadians := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRadians(b.Lat - a.Lat)
+	dLon := toRadians(b.Lon - a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRadians(a.Lat))*math.Cos(toRadians(b.Lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// selectCache picks one of the given caches by the hash of the request path,
+// so that requests for the same content go to the same cache.
+func selectCache(caches []string, path string) string {
+	sorted := append([]string{}, caches...)
+	sort.Strings(sorted)
+	h := fnv.New32a()
+	h.Write([]byte(path))
+	return sorted[h.Sum32()%uint32(len(sorted))]
+}
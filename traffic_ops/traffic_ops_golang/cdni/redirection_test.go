@@ -0,0 +1,188 @@
+package cdni
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+func testRedirectionCRConfig() tc.CRConfig {
+	server := func(cachegroup, serverType, status string, dses ...string) tc.CRConfigTrafficOpsServer {
+		st := tc.CRConfigServerStatus(status)
+		s := tc.CRConfigTrafficOpsServer{CacheGroup: &cachegroup, ServerType: &serverType, ServerStatus: &st, DeliveryServices: map[string][]string{}}
+		for _, ds := range dses {
+			s.DeliveryServices[ds] = []string{}
+		}
+		return s
+	}
+	return tc.CRConfig{
+		ContentServers: map[string]tc.CRConfigTrafficOpsServer{
+			"edge-east-1": server("east", "EDGE", "REPORTED", "video"),
+			"edge-east-2": server("east", "EDGE", "ADMIN_DOWN", "video"),
+			"edge-west-1": server("west", "EDGE", "ONLINE", "video"),
+			"edge-west-2": server("west", "EDGE", "REPORTED", "video"),
+			"edge-far-1":  server("far", "EDGE", "REPORTED", "video"),
+			"mid-east-1":  server("east", "MID", "REPORTED", "video"),
+		},
+		DeliveryServices: map[string]tc.CRConfigDeliveryService{
+			"video": {
+				Domains:   []string{"video.cdn.example.com"},
+				MatchSets: []*tc.MatchSet{{Protocol: "HTTP", MatchList: []tc.MatchList{{MatchType: "HOST", Regex: `.*\.video\..*`}}}},
+			},
+		},
+		EdgeLocations: map[string]tc.CRConfigLatitudeLongitude{
+			"east": {Lat: 40.7, Lon: -74.0},
+			"west": {Lat: 37.8, Lon: -122.4},
+			"far":  {Lat: 51.5, Lon: -0.1},
+		},
+	}
+}
+
+func testRedirectionCRStates(unavailable ...string) tc.CRStates {
+	states := tc.CRStates{Caches: map[tc.CacheName]tc.IsAvailable{}}
+	for _, name := range []string{"edge-east-1", "edge-east-2", "edge-west-1", "edge-west-2", "edge-far-1", "mid-east-1"} {
+		states.Caches[tc.CacheName(name)] = tc.IsAvailable{IsAvailable: true}
+	}
+	for _, name := range unavailable {
+		states.Caches[tc.CacheName(name)] = tc.IsAvailable{IsAvailable: false}
+	}
+	return states
+}
+
+func TestGetCoverageZone(t *testing.T) {
+	networks := makeCoverageZoneNetworks(tc.CoverageZoneFile{CoverageZones: map[string]tc.CoverageZoneLocation{
+		"east":  {Network: []string{"192.0.2.0/24"}, Network6: []string{"2001:db8::/32"}},
+		"west":  {Network: []string{"192.0.2.128/25", "invalid"}},
+		"other": {Network: []string{"0.0.0.0/0"}},
+	}})
+	cases := map[string]string{
+		"192.0.2.1":     "east",
+		"192.0.2.200":   "west",
+		"203.0.113.1":   "other",
+		"2001:db8::1":   "east",
+		"2001:db9::1":   "",
+		"198.51.100.10": "other",
+	}
+	for ip, expected := range cases {
+		actual, ok := getCoverageZone(networks, net.ParseIP(ip))
+		if expected == "" && ok {
+			t.Errorf("coverage zone of '%s' - expected: none, actual: %s", ip, actual)
+		} else if actual != expected {
+			t.Errorf("coverage zone of '%s' - expected: %s, actual: %s", ip, expected, actual)
+		}
+	}
+}
+
+func TestSelectRedirectURL(t *testing.T) {
+	zones := makeCoverageZoneNetworks(tc.CoverageZoneFile{CoverageZones: map[string]tc.CoverageZoneLocation{
+		"east": {Network: []string{"192.0.2.0/24"}},
+		"west": {Network: []string{"198.51.100.0/24"}},
+	}})
+	uri, _ := url.Parse("http://www.video.example.com/movies/a.m3u8?token=1")
+
+	location, _, err := selectRedirectURL(testRedirectionCRConfig(), testRedirectionCRStates(), zones, net.ParseIP("192.0.2.1"), uri)
+	if err != nil {
+		t.Fatalf("expected: nil error, actual: %v", err)
+	}
+	if expected := "http://edge-east-1.video.cdn.example.com/movies/a.m3u8?token=1"; location != expected {
+		t.Errorf("expected: %s, actual: %s", expected, location)
+	}
+
+	// With no available edge in the client's cachegroup, the nearest is used.
+	location, _, err = selectRedirectURL(testRedirectionCRConfig(), testRedirectionCRStates("edge-east-1"), zones, net.ParseIP("192.0.2.1"), uri)
+	if err != nil {
+		t.Fatalf("expected: nil error, actual: %v", err)
+	}
+	if location != "http://edge-west-1.video.cdn.example.com/movies/a.m3u8?token=1" && location != "http://edge-west-2.video.cdn.example.com/movies/a.m3u8?token=1" {
+		t.Errorf("expected a redirect to an edge in 'west', actual: %s", location)
+	}
+
+	other, _ := url.Parse("http://www.other.example.com/")
+	if _, code, err := selectRedirectURL(testRedirectionCRConfig(), testRedirectionCRStates(), zones, net.ParseIP("192.0.2.1"), other); err == nil || code != http.StatusNotFound {
+		t.Errorf("unknown host - expected: %d error, actual: %d %v", http.StatusNotFound, code, err)
+	}
+	if _, code, err := selectRedirectURL(testRedirectionCRConfig(), testRedirectionCRStates(), zones, net.ParseIP("203.0.113.1"), uri); err == nil || code != http.StatusNotFound {
+		t.Errorf("client outside coverage zones - expected: %d error, actual: %d %v", http.StatusNotFound, code, err)
+	}
+}
+
+func TestSelectRedirectURLProtocol(t *testing.T) {
+	zones := makeCoverageZoneNetworks(tc.CoverageZoneFile{CoverageZones: map[string]tc.CoverageZoneLocation{
+		"east": {Network: []string{"192.0.2.0/24"}},
+	}})
+	crConfig := testRedirectionCRConfig()
+	ds := crConfig.DeliveryServices["video"]
+	ds.Protocol = &tc.CRConfigDeliveryServiceProtocol{AcceptHTTPS: true, RedirectOnHTTPS: true}
+	crConfig.DeliveryServices["video"] = ds
+
+	uri, _ := url.Parse("http://www.video.example.com/a")
+	location, _, err := selectRedirectURL(crConfig, testRedirectionCRStates(), zones, net.ParseIP("192.0.2.1"), uri)
+	if err != nil {
+		t.Fatalf("expected: nil error, actual: %v", err)
+	}
+	if expected := "https://edge-east-1.video.cdn.example.com/a"; location != expected {
+		t.Errorf("expected: %s, actual: %s", expected, location)
+	}
+
+	ds.Protocol = &tc.CRConfigDeliveryServiceProtocol{}
+	crConfig.DeliveryServices["video"] = ds
+	uri, _ = url.Parse("https://www.video.example.com/a")
+	if _, code, err := selectRedirectURL(crConfig, testRedirectionCRStates(), zones, net.ParseIP("192.0.2.1"), uri); err == nil || code != http.StatusBadRequest {
+		t.Errorf("HTTPS request to HTTP-only delivery service - expected: %d error, actual: %d %v", http.StatusBadRequest, code, err)
+	}
+}
+
+func TestSelectCache(t *testing.T) {
+	caches := []string{"c", "a", "b"}
+	first := selectCache(caches, "/some/path")
+	for i := 0; i < 10; i++ {
+		if actual := selectCache([]string{"b", "c", "a"}, "/some/path"); actual != first {
+			t.Fatalf("expected the same path to select the same cache regardless of order - expected: %s, actual: %s", first, actual)
+		}
+	}
+	if caches[0] != "c" {
+		t.Errorf("expected selecting a cache not to reorder the given caches")
+	}
+}
+
+func TestParseHTTPRedirectionRequest(t *testing.T) {
+	_, uri, err := parseHTTPRedirectionRequest(HTTPRedirectionRequest{ClientIP: "192.0.2.1", URI: "/a?b=c", Host: "www.example.com"})
+	if err != nil {
+		t.Fatalf("expected: nil error, actual: %v", err)
+	}
+	if expected := "http://www.example.com/a?b=c"; uri.String() != expected {
+		t.Errorf("expected: %s, actual: %s", expected, uri.String())
+	}
+	invalid := []HTTPRedirectionRequest{
+		{ClientIP: "not-an-ip", URI: "http://www.example.com/"},
+		{ClientIP: "192.0.2.1", URI: "/no-host"},
+		{ClientIP: "192.0.2.1", URI: "ftp://www.example.com/"},
+	}
+	for _, req := range invalid {
+		if _, _, err := parseHTTPRedirectionRequest(req); err == nil {
+			t.Errorf("parsing %+v - expected: error, actual: nil", req)
+		}
+	}
+}
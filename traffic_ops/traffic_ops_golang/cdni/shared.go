@@ -82,13 +82,19 @@ func GetCapabilities(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	capacities, err := getCapacities(inf, ucdn)
+	adv, err := getAdvertisedCDN(inf)
+	if err != nil {
+		api.HandleErr(w, r, nil, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	capacities, err := getCapacities(inf, ucdn, adv)
 	if err != nil {
 		api.HandleErr(w, r, nil, http.StatusInternalServerError, err, nil)
 		return
 	}
 
-	telemetries, err := getTelemetries(inf, ucdn)
+	telemetries, err := getTelemetries(inf, ucdn, adv)
 	if err != nil {
 		api.HandleErr(w, r, nil, http.StatusInternalServerError, err, nil)
 		return
@@ -401,8 +407,21 @@ func PutConfigurationResponse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	importedDSes := []string{}
 	for _, updatedData := range updatedDataList {
 		switch updatedData.Type {
+		case MiHostIndex:
+			var hostIndex HostIndex
+			if err = json.Unmarshal(updatedData.Value, &hostIndex); err != nil {
+				api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, fmt.Errorf("unmarshalling %s for configuration update: %w", MiHostIndex, err), nil)
+				return
+			}
+			xmlIDs, errCode, userErr, sysErr := importHostIndex(r, inf, ucdn, hostIndex)
+			if userErr != nil || sysErr != nil {
+				api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+				return
+			}
+			importedDSes = append(importedDSes, xmlIDs...)
 		case MiRequestedCapacityLimits:
 			var capacityRequestedLimits CapacityRequestedLimits
 			if err = json.Unmarshal(updatedData.Value, &capacityRequestedLimits); err != nil {
@@ -443,7 +462,11 @@ func PutConfigurationResponse(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if asyncErr := api.UpdateAsyncStatus(db, api.AsyncSucceeded, "Capacity requested update has been completed.", asyncId, true); asyncErr != nil {
+	asyncMsg := "Capacity requested update has been completed."
+	if len(importedDSes) > 0 {
+		asyncMsg = "Requested configuration update has been completed. Created delivery services: " + strings.Join(importedDSes, ", ")
+	}
+	if asyncErr := api.UpdateAsyncStatus(db, api.AsyncSucceeded, asyncMsg, asyncId, true); asyncErr != nil {
 		log.Errorf("updating async status for id %v: %v", asyncId, asyncErr)
 	}
 	status, err := deleteCapabilityRequest(reqId, inf.Tx.Tx)
@@ -751,11 +774,23 @@ type SupportedGenericMetadataType string
 
 const (
 	MiRequestedCapacityLimits SupportedGenericMetadataType = "MI.RequestedCapacityLimits"
+	// MiHostIndex carries an RFC 8006 HostIndex, whose hosts are imported as
+	// Delivery Services when the request is approved.
+	MiHostIndex SupportedGenericMetadataType = "MI.HostIndex"
+
+	MiSourceMetadata SupportedGenericMetadataType = "MI.SourceMetadata"
+	MiLocationACL    SupportedGenericMetadataType = "MI.LocationACL"
+	MiTimeWindowACL  SupportedGenericMetadataType = "MI.TimeWindowACL"
+	MiProtocolACL    SupportedGenericMetadataType = "MI.ProtocolACL"
+	MiCache          SupportedGenericMetadataType = "MI.Cache"
+	MiAuth           SupportedGenericMetadataType = "MI.Auth"
 )
 
+// isValid returns whether the metadata type can be given in a configuration
+// request. The other types are only valid within a HostIndex.
 func (s SupportedGenericMetadataType) isValid() bool {
 	switch s {
-	case MiRequestedCapacityLimits:
+	case MiRequestedCapacityLimits, MiHostIndex:
 		return true
 	}
 	return false
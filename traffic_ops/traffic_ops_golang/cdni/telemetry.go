@@ -26,7 +26,7 @@ import (
 	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
 )
 
-func getTelemetries(inf *api.APIInfo, ucdn string) (Capabilities, error) {
+func getTelemetries(inf *api.APIInfo, ucdn string, adv *advertisedCDN) (Capabilities, error) {
 	capRows, err := inf.Tx.Tx.Query(CapabilityQuery, FciTelemetry, ucdn)
 	if err != nil {
 		return Capabilities{}, fmt.Errorf("querying capabilities: %w", err)
@@ -61,6 +61,9 @@ func getTelemetries(inf *api.APIInfo, ucdn string) (Capabilities, error) {
 	for _, cap := range capabilities {
 		fciCap := Capability{}
 		fciCap.Footprints = footprintMap[cap.Id]
+		if adv != nil {
+			fciCap.Footprints = adv.Footprints
+		}
 		if fciCap.Footprints == nil {
 			fciCap.Footprints = []Footprint{}
 		}
@@ -141,6 +141,10 @@ type ConfigTrafficOpsGolang struct {
 	TLSConfig            *tls.Config     `json:"tls_config"`
 	TrafficVaultBackend  string          `json:"traffic_vault_backend"`
 	TrafficVaultConfig   json.RawMessage `json:"traffic_vault_config"`
+	// TrafficVaultMigrationBackend is the name of the Traffic Vault backend
+	// that records are copied to by the vault/migrate endpoint.
+	TrafficVaultMigrationBackend string          `json:"traffic_vault_migration_backend"`
+	TrafficVaultMigrationConfig  json.RawMessage `json:"traffic_vault_migration_config"`
 
 	// CRConfigUseRequestHost is whether to use the client request host header in the CRConfig. If false, uses the tm.url parameter.
 	// This defaults to false. Traffic Ops used to always use the host header, setting this true will resume that legacy behavior.
@@ -272,6 +276,17 @@ type ConfigInflux struct {
 
 type CdniConf struct {
 	DCdnId string `json:"dcdn_id"`
+	// AdvertisedCDN, if set, is the name of the CDN whose cachegroups,
+	// coverage zones and Traffic Monitor bandwidth are used to generate the
+	// footprints and egress limits of the FCI advertisement.
+	AdvertisedCDN string `json:"advertised_cdn"`
+	// FootprintMaxDistanceKm, if positive, is the distance from the advertised
+	// CDN's edge Cache Groups within which other coverage zones are included
+	// in its footprints, by their coordinates.
+	FootprintMaxDistanceKm float64 `json:"footprint_max_distance_km"`
+	// ImportTopology, if set, is the name of the Topology assigned to the
+	// Delivery Services created from approved CDNi metadata.
+	ImportTopology string `json:"import_topology"`
 }
 
 // NewFakeConfig returns a fake Config struct with just enough data to view Routes.
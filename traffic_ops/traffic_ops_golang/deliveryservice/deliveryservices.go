@@ -260,6 +260,13 @@ func recreateTLSVersions(versions []string, dsid int, tx *sql.Tx) error {
 	return nil
 }
 
+// CreateDeliveryService creates the given Delivery Service along with its
+// default regex, primary origin and parameters, exactly as the API 4.0
+// creation endpoint does, but without writing a response.
+func CreateDeliveryService(r *http.Request, inf *api.APIInfo, ds tc.DeliveryServiceV4) (*tc.DeliveryServiceV4, int, error, error) {
+	return createV40(nil, r, inf, ds, true)
+}
+
 // create creates the given ds in the database, and returns the DS with its id and other fields created on insert set. On error, the HTTP status code, user error, and system error are returned. The status code SHOULD NOT be used, if both errors are nil.
 func createV40(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, dsV40 tc.DeliveryServiceV40, omitExtraLongDescFields bool) (*tc.DeliveryServiceV40, int, error, error) {
 	user := inf.User
@@ -0,0 +1,170 @@
+package deliveryservice
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/lib/go-util"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/dbhelpers"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/tenant"
+)
+
+// GetSSLKeysVersions lists every version of a Delivery Service's SSL keys that
+// is stored in Traffic Vault, newest first.
+func GetSSLKeysVersions(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"xmlid"}, nil)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+	if !inf.Config.TrafficVaultEnabled {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("deliveryservice.GetSSLKeysVersions: Traffic Vault is not configured"))
+		return
+	}
+	xmlID := inf.Params["xmlid"]
+	if _, _, ok, err := getDSIDAndCDNIDFromName(inf.Tx.Tx, xmlID); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("deliveryservice.GetSSLKeysVersions: getting DS ID and CDN ID from name "+err.Error()))
+		return
+	} else if !ok {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, errors.New("no DS with name "+xmlID), nil)
+		return
+	}
+	if userErr, sysErr, errCode := tenant.Check(inf.User, xmlID, inf.Tx.Tx); userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+
+	versions, err := inf.Vault.GetDeliveryServiceSSLKeysVersions(xmlID, inf.Tx.Tx, r.Context())
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("getting SSL key versions from Traffic Vault for delivery service '"+xmlID+"': "+err.Error()))
+		return
+	}
+	sortSSLKeysVersions(versions)
+	api.WriteResp(w, r, versions)
+}
+
+// RollbackSSLKeys promotes a previously stored version of a Delivery Service's
+// SSL keys back to being its latest version, by storing them again as a new
+// version.
+func RollbackSSLKeys(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"xmlid", "version"}, []string{"version"})
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+	if !inf.Config.TrafficVaultEnabled {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("deliveryservice.RollbackSSLKeys: Traffic Vault is not configured"))
+		return
+	}
+	xmlID := inf.Params["xmlid"]
+	version := inf.IntParams["version"]
+	dsID, cdnID, ok, err := getDSIDAndCDNIDFromName(inf.Tx.Tx, xmlID)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("deliveryservice.RollbackSSLKeys: getting DS ID and CDN ID from name "+err.Error()))
+		return
+	} else if !ok {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, errors.New("no DS with name "+xmlID), nil)
+		return
+	}
+	userErr, sysErr, statusCode := dbhelpers.CheckIfCurrentUserCanModifyCDNWithID(inf.Tx.Tx, int64(cdnID), inf.User.UserName)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, statusCode, userErr, sysErr)
+		return
+	}
+	if userErr, sysErr, errCode := tenant.Check(inf.User, xmlID, inf.Tx.Tx); userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+
+	versionStr := strconv.Itoa(version)
+	keys, ok, err := inf.Vault.GetDeliveryServiceSSLKeys(xmlID, versionStr, inf.Tx.Tx, r.Context())
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("getting SSL keys from Traffic Vault for delivery service '"+xmlID+"': "+err.Error()))
+		return
+	} else if !ok {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, errors.New("no SSL keys version "+versionStr+" found for delivery service "+xmlID), nil)
+		return
+	}
+
+	versions, err := inf.Vault.GetDeliveryServiceSSLKeysVersions(xmlID, inf.Tx.Tx, r.Context())
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("getting SSL key versions from Traffic Vault for delivery service '"+xmlID+"': "+err.Error()))
+		return
+	}
+	currentVersion := int64(0)
+	if err := inf.Tx.Tx.QueryRow(`SELECT COALESCE(ssl_key_version, 0) FROM deliveryservice WHERE xml_id = $1`, xmlID).Scan(&currentVersion); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("getting SSL key version of delivery service '"+xmlID+"': "+err.Error()))
+		return
+	}
+
+	// The keys are stored again as a new version, rather than moving the version back, so that generating or renewing keys later never overwrites a stored version.
+	newVersion := nextSSLKeyVersion(versions, currentVersion)
+	dsSSLKeys := keys.DeliveryServiceSSLKeys
+	dsSSLKeys.Version = util.JSONIntStr(newVersion)
+	if err := inf.Vault.PutDeliveryServiceSSLKeys(dsSSLKeys, inf.Tx.Tx, r.Context()); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("putting SSL keys in Traffic Vault for delivery service '"+xmlID+"': "+err.Error()))
+		return
+	}
+	if err := updateSSLKeyVersion(xmlID, newVersion, inf.Tx.Tx); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("rolling back SSL keys of delivery service '"+xmlID+"': "+err.Error()))
+		return
+	}
+
+	newVersionStr := strconv.FormatInt(newVersion, 10)
+	api.CreateChangeLogRawTx(api.ApiChange, "DS: "+xmlID+", ID: "+strconv.Itoa(dsID)+", ACTION: Rolled back SSL keys to version "+versionStr+" as new version "+newVersionStr, inf.User, inf.Tx.Tx)
+	api.WriteResp(w, r, "Successfully rolled back ssl keys for "+xmlID+" to version "+versionStr+" as new version "+newVersionStr)
+}
+
+// nextSSLKeyVersion returns the version to store new SSL keys as: one more
+// than the greatest of the given stored versions and the Delivery Service's
+// current ssl_key_version.
+func nextSSLKeyVersion(versions []tc.DeliveryServiceSSLKeysVersion, currentVersion int64) int64 {
+	max := currentVersion
+	for _, v := range versions {
+		if version, err := strconv.ParseInt(v.Version, 10, 64); err == nil && version > max {
+			max = version
+		}
+	}
+	return max + 1
+}
+
+// sortSSLKeysVersions sorts the given versions newest first. Versions are
+// compared numerically where possible, since they are stored as strings.
+func sortSSLKeysVersions(versions []tc.DeliveryServiceSSLKeysVersion) {
+	sort.Slice(versions, func(i, j int) bool {
+		vi, errI := strconv.ParseInt(versions[i].Version, 10, 64)
+		vj, errJ := strconv.ParseInt(versions[j].Version, 10, 64)
+		if errI == nil && errJ == nil {
+			return vi > vj
+		}
+		if errI == nil || errJ == nil {
+			return errI == nil // numeric versions come before anything malformed
+		}
+		return versions[i].Version > versions[j].Version
+	})
+}
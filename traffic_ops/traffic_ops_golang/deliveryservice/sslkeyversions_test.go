@@ -0,0 +1,59 @@
+package deliveryservice
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"testing"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+func TestSortSSLKeysVersions(t *testing.T) {
+	versions := []tc.DeliveryServiceSSLKeysVersion{
+		{Version: "2"},
+		{Version: "bad"},
+		{Version: "10"},
+		{Version: "1", Latest: true},
+	}
+	sortSSLKeysVersions(versions)
+	expected := []string{"10", "2", "1", "bad"}
+	for i, v := range versions {
+		if v.Version != expected[i] {
+			t.Errorf("sorting SSL key versions - expected version %s at index %d, actual: %s", expected[i], i, v.Version)
+		}
+	}
+}
+
+func TestNextSSLKeyVersion(t *testing.T) {
+	versions := []tc.DeliveryServiceSSLKeysVersion{
+		{Version: "2"},
+		{Version: "bad"},
+		{Version: "10"},
+	}
+	if actual := nextSSLKeyVersion(versions, 3); actual != 11 {
+		t.Errorf("next SSL key version - expected: 11, actual: %d", actual)
+	}
+	if actual := nextSSLKeyVersion(versions, 12); actual != 13 {
+		t.Errorf("next SSL key version with greater current version - expected: 13, actual: %d", actual)
+	}
+	if actual := nextSSLKeyVersion(nil, 0); actual != 1 {
+		t.Errorf("next SSL key version with no versions - expected: 1, actual: %d", actual)
+	}
+}
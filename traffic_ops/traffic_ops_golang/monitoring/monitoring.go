@@ -23,6 +23,7 @@ package monitoring
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net"
 	"strconv"
@@ -130,13 +131,14 @@ type Router struct {
 }
 
 type DeliveryService struct {
-	XMLID              string   `json:"xmlId"`
-	TotalTPSThreshold  float64  `json:"totalTpsThreshold"`
-	Status             string   `json:"status"`
-	TotalKBPSThreshold float64  `json:"totalKbpsThreshold"`
-	Type               string   `json:"type"`
-	Topology           string   `json:"topology"`
-	HostRegexes        []string `json:"hostRegexes"`
+	XMLID              string                 `json:"xmlId"`
+	TotalTPSThreshold  float64                `json:"totalTpsThreshold"`
+	Status             string                 `json:"status"`
+	TotalKBPSThreshold float64                `json:"totalKbpsThreshold"`
+	Type               string                 `json:"type"`
+	Topology           string                 `json:"topology"`
+	HostRegexes        []string               `json:"hostRegexes"`
+	Parameters         map[string]interface{} `json:"parameters,omitempty"`
 }
 
 func GetMonitoringJSON(tx *sql.Tx, cdnName string) (*Monitoring, error) {
@@ -496,7 +498,14 @@ WHERE pr.config_file = $2;
 
 func getDeliveryServices(tx *sql.Tx, cdnName string) ([]DeliveryService, error) {
 	query := `
-	SELECT ds.xml_id, ds.global_max_tps, ds.global_max_mbps, t.name AS ds_type, ds.topology, ARRAY_AGG(r.pattern)
+	SELECT ds.xml_id, ds.global_max_tps, ds.global_max_mbps, t.name AS ds_type, ds.topology, ARRAY_AGG(r.pattern),
+	(
+		SELECT JSON_OBJECT_AGG(pr.name, pr.value)
+		FROM parameter pr
+		JOIN profile_parameter pp ON pp.parameter = pr.id
+		WHERE pp.profile = ds.profile
+		AND pr.config_file = $2
+	) AS parameters
 	FROM deliveryservice ds
 	JOIN type t ON ds.type = t.id
 	JOIN cdn ON cdn.id = ds.cdn_id
@@ -505,9 +514,9 @@ func getDeliveryServices(tx *sql.Tx, cdnName string) ([]DeliveryService, error)
 	WHERE ds.active = true
 	AND cdn.name=$1
 	AND r.type = (SELECT id FROM type WHERE name = 'HOST_REGEXP')
-	GROUP BY ds.xml_id, ds.global_max_tps, ds.xml_id, ds.global_max_mbps, t.name, ds.topology
+	GROUP BY ds.xml_id, ds.global_max_tps, ds.xml_id, ds.global_max_mbps, t.name, ds.topology, ds.profile
 	`
-	rows, err := tx.Query(query, cdnName)
+	rows, err := tx.Query(query, cdnName, CacheMonitorConfigFile)
 	if err != nil {
 		return nil, err
 	}
@@ -522,9 +531,14 @@ func getDeliveryServices(tx *sql.Tx, cdnName string) ([]DeliveryService, error)
 		var dsType string
 		var topology sql.NullString
 		var hostRegexes []string
-		if err := rows.Scan(&xmlid, &tps, &mbps, &dsType, &topology, pq.Array(&hostRegexes)); err != nil {
+		var paramsJSON []byte
+		if err := rows.Scan(&xmlid, &tps, &mbps, &dsType, &topology, pq.Array(&hostRegexes), &paramsJSON); err != nil {
 			return nil, err
 		}
+		params, err := deliveryServiceParameters(paramsJSON)
+		if err != nil {
+			return nil, fmt.Errorf("delivery service '%s' parameters: %v", xmlid.String, err)
+		}
 		dses = append(dses, DeliveryService{
 			XMLID:              xmlid.String,
 			TotalTPSThreshold:  tps.Float64,
@@ -533,11 +547,35 @@ func getDeliveryServices(tx *sql.Tx, cdnName string) ([]DeliveryService, error)
 			Type:               tc.GetDSTypeCategory(dsType),
 			Topology:           topology.String,
 			HostRegexes:        hostRegexes,
+			Parameters:         params,
 		})
 	}
 	return dses, nil
 }
 
+// deliveryServiceParameters returns the Parameters of a Delivery Service's
+// Profile from their JSON object of names to values, converting integer
+// values to numbers as cache server Profile Parameters are. It returns nil if
+// the Delivery Service has no Profile, or no Parameters.
+func deliveryServiceParameters(paramsJSON []byte) (map[string]interface{}, error) {
+	if len(paramsJSON) == 0 {
+		return nil, nil
+	}
+	strParams := map[string]string{}
+	if err := json.Unmarshal(paramsJSON, &strParams); err != nil {
+		return nil, err
+	}
+	params := make(map[string]interface{}, len(strParams))
+	for name, val := range strParams {
+		if valNum, err := strconv.Atoi(val); err == nil {
+			params[name] = valNum
+		} else {
+			params[name] = val
+		}
+	}
+	return params, nil
+}
+
 func getConfig(tx *sql.Tx, cdnName string) (map[string]interface{}, error) {
 	// TODO remove 'like' in query? Slow?
 	query := `
@@ -341,15 +341,27 @@ func TestGetDeliveryServices(t *testing.T) {
 		Type:               "HTTP",
 		Topology:           "foo",
 		HostRegexes:        []string{`.*\.example\..*`},
+		Parameters:         map[string]interface{}{"slo.cachegroup.available.min": 2, "slo.target": "0.999"},
+	}
+	noParamsDeliveryservice := DeliveryService{
+		XMLID:              "noParamsDsid",
+		TotalTPSThreshold:  1,
+		Status:             DeliveryServiceStatus,
+		TotalKBPSThreshold: 2,
+		Type:               "DNS",
+		HostRegexes:        []string{`.*\.noparams\..*`},
 	}
 
-	deliveryservices := []DeliveryService{deliveryservice}
+	deliveryservices := []DeliveryService{deliveryservice, noParamsDeliveryservice}
+	paramsJSON := map[string][]byte{
+		deliveryservice.XMLID: []byte(`{"slo.cachegroup.available.min" : "2", "slo.target" : "0.999"}`),
+	}
 
 	mock.ExpectBegin()
-	rows := sqlmock.NewRows([]string{"xml_id", "global_max_tps", "global_max_mbps", "ds_type", "topology", "host_regexes"})
+	rows := sqlmock.NewRows([]string{"xml_id", "global_max_tps", "global_max_mbps", "ds_type", "topology", "host_regexes", "parameters"})
 	for _, deliveryservice := range deliveryservices {
 		rows = rows.AddRow(deliveryservice.XMLID, deliveryservice.TotalTPSThreshold, deliveryservice.TotalKBPSThreshold/KilobitsPerMegabit,
-			deliveryservice.Type, deliveryservice.Topology, "{"+strings.Join(deliveryservice.HostRegexes, ",")+"}")
+			deliveryservice.Type, deliveryservice.Topology, "{"+strings.Join(deliveryservice.HostRegexes, ",")+"}", paramsJSON[deliveryservice.XMLID])
 	}
 
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
@@ -526,10 +538,10 @@ func TestGetMonitoringJSON(t *testing.T) {
 		deliveryservices := []DeliveryService{deliveryservice}
 		// routers := []Router{router}
 
-		rows := sqlmock.NewRows([]string{"xml_id", "global_max_tps", "global_max_mbps", "ds_type", "topology", "host_regexes"})
+		rows := sqlmock.NewRows([]string{"xml_id", "global_max_tps", "global_max_mbps", "ds_type", "topology", "host_regexes", "parameters"})
 		for _, deliveryservice := range deliveryservices {
 			rows = rows.AddRow(deliveryservice.XMLID, deliveryservice.TotalTPSThreshold, deliveryservice.TotalKBPSThreshold/KilobitsPerMegabit,
-				deliveryservice.Type, deliveryservice.Topology, "{"+strings.Join(deliveryservice.HostRegexes, ",")+"}")
+				deliveryservice.Type, deliveryservice.Topology, "{"+strings.Join(deliveryservice.HostRegexes, ",")+"}", nil)
 		}
 
 		mock.ExpectQuery("SELECT").WillReturnRows(rows)
@@ -21,5 +21,6 @@ package backends
  */
 
 import (
+	_ "github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/trafficvault/backends/filesystem"
 	_ "github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/trafficvault/backends/postgres"
 )
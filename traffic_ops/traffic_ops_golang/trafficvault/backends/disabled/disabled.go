@@ -45,6 +45,10 @@ func (d *Disabled) GetDeliveryServiceSSLKeys(xmlID string, version string, tx *s
 	return tc.DeliveryServiceSSLKeysV15{}, false, disabledErr
 }
 
+func (d *Disabled) GetDeliveryServiceSSLKeysVersions(xmlID string, tx *sql.Tx, ctx context.Context) ([]tc.DeliveryServiceSSLKeysVersion, error) {
+	return nil, disabledErr
+}
+
 func (d *Disabled) GetExpirationInformation(tx *sql.Tx, ctx context.Context, days int) ([]tc.SSLKeyExpirationInformation, error) {
 	return []tc.SSLKeyExpirationInformation{}, disabledErr
 }
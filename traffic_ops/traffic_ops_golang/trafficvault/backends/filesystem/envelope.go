@@ -0,0 +1,184 @@
+package filesystem
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"crypto/aes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-util"
+)
+
+const (
+	// recordFormatVersion is the version of the on-disk record format written
+	// by this backend.
+	recordFormatVersion = 1
+
+	// dataKeyLength is the length, in bytes, of the randomly generated AES-256
+	// data encryption key used for each record.
+	dataKeyLength = 32
+)
+
+// recordMetadata holds the unencrypted information about a record that is
+// needed to search and expire records without decrypting them.
+type recordMetadata struct {
+	CDN        string    `json:"cdn,omitempty"`
+	Provider   string    `json:"provider,omitempty"`
+	Version    string    `json:"version,omitempty"`
+	Expiration time.Time `json:"expiration,omitempty"`
+	Modified   time.Time `json:"modified"`
+}
+
+// record is the on-disk representation of a single secret. The secret itself
+// is encrypted with a random data key (Data), and the data key is encrypted
+// ("wrapped") with the key encryption key identified by KeyID.
+type record struct {
+	FormatVersion int            `json:"format_version"`
+	KeyID         string         `json:"key_id"`
+	WrappedKey    []byte         `json:"wrapped_key"`
+	Data          []byte         `json:"data"`
+	Metadata      recordMetadata `json:"metadata"`
+}
+
+// keyring holds the key encryption key used to wrap new data keys, along
+// with any previous key encryption keys that may still be needed to unwrap
+// the data keys of existing records.
+type keyring struct {
+	currentID string
+	keys      map[string][]byte
+}
+
+// keyID returns a short identifier for the given key encryption key, which
+// is stored in each record so that the right key can be found to unwrap it.
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// newKeyring builds a keyring from the given current key and any number of
+// previous keys.
+func newKeyring(current []byte, previous ...[]byte) keyring {
+	k := keyring{currentID: keyID(current), keys: make(map[string][]byte, len(previous)+1)}
+	for _, key := range previous {
+		k.keys[keyID(key)] = key
+	}
+	k.keys[k.currentID] = current
+	return k
+}
+
+// seal encrypts the given plaintext with a new data key, wraps the data key
+// with the current key encryption key, and returns the resulting record.
+func (k keyring) seal(plaintext []byte, meta recordMetadata) (record, error) {
+	dataKey := make([]byte, dataKeyLength)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return record{}, errors.New("generating data key: " + err.Error())
+	}
+	data, err := util.AESEncrypt(plaintext, dataKey)
+	if err != nil {
+		return record{}, errors.New("encrypting data: " + err.Error())
+	}
+	wrappedKey, err := util.AESEncrypt(dataKey, k.keys[k.currentID])
+	if err != nil {
+		return record{}, errors.New("wrapping data key: " + err.Error())
+	}
+	meta.Modified = time.Now()
+	return record{
+		FormatVersion: recordFormatVersion,
+		KeyID:         k.currentID,
+		WrappedKey:    wrappedKey,
+		Data:          data,
+		Metadata:      meta,
+	}, nil
+}
+
+// unwrap returns the plaintext data key of the given record.
+func (k keyring) unwrap(r record) ([]byte, error) {
+	if r.FormatVersion != recordFormatVersion {
+		return nil, fmt.Errorf("unsupported record format version %d", r.FormatVersion)
+	}
+	kek, ok := k.keys[r.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("record was encrypted with unknown key '%s'", r.KeyID)
+	}
+	dataKey, err := util.AESDecrypt(r.WrappedKey, kek)
+	if err != nil {
+		return nil, errors.New("unwrapping data key: " + err.Error())
+	}
+	return dataKey, nil
+}
+
+// open decrypts and returns the plaintext of the given record.
+func (k keyring) open(r record) ([]byte, error) {
+	dataKey, err := k.unwrap(r)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := util.AESDecrypt(r.Data, dataKey)
+	if err != nil {
+		return nil, errors.New("decrypting data: " + err.Error())
+	}
+	return plaintext, nil
+}
+
+// rewrap re-encrypts the data key of the given record with the current key
+// encryption key. The record data itself is left untouched. The returned
+// boolean is false if the record was already wrapped with the current key.
+func (k keyring) rewrap(r record) (record, bool, error) {
+	if r.KeyID == k.currentID {
+		return r, false, nil
+	}
+	dataKey, err := k.unwrap(r)
+	if err != nil {
+		return record{}, false, err
+	}
+	wrappedKey, err := util.AESEncrypt(dataKey, k.keys[k.currentID])
+	if err != nil {
+		return record{}, false, errors.New("wrapping data key: " + err.Error())
+	}
+	r.KeyID = k.currentID
+	r.WrappedKey = wrappedKey
+	return r, true, nil
+}
+
+// readKey reads a base64-encoded AES key from the file at the given location.
+func readKey(location string) ([]byte, error) {
+	keyBase64, err := ioutil.ReadFile(location)
+	if err != nil {
+		return nil, errors.New("reading file '" + location + "': " + err.Error())
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(keyBase64)))
+	if err != nil {
+		return nil, errors.New("AES key in '" + location + "' cannot be decoded from base64")
+	}
+	// verify the key works
+	if _, err := aes.NewCipher(key); err != nil {
+		return nil, errors.New("AES key in '" + location + "' is invalid: " + err.Error())
+	}
+	return key, nil
+}
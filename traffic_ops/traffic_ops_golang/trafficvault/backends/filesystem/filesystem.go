@@ -0,0 +1,591 @@
+// Package filesystem provides a TrafficVault implementation which stores
+// envelope-encrypted records as files under a local directory.
+package filesystem
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/lib/go-tc/tovalidate"
+	"github.com/apache/trafficcontrol/lib/go-util"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/deliveryservice"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/trafficvault"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	notImplementedErr = Error("this Traffic Vault functionality is not implemented for the filesystem backend")
+
+	filesystemBackendName = "filesystem"
+
+	latestVersion = "latest"
+)
+
+type Config struct {
+	BaseDirectory           string   `json:"base_directory"`
+	AesKeyLocation          string   `json:"aes_key_location"`
+	PreviousAesKeyLocations []string `json:"previous_aes_key_locations"`
+	RotateOnStartup         bool     `json:"rotate_on_startup"`
+}
+
+type Filesystem struct {
+	cfg     Config
+	keyring keyring
+	// mtx guards all reads and writes of the files under cfg.BaseDirectory.
+	mtx sync.RWMutex
+}
+
+// GetDeliveryServiceSSLKeys retrieves the SSL keys of the given version for
+// the delivery service identified by the given xmlID. If version is empty,
+// the implementation should return the latest version.
+func (f *Filesystem) GetDeliveryServiceSSLKeys(xmlID string, version string, tx *sql.Tx, ctx context.Context) (tc.DeliveryServiceSSLKeysV15, bool, error) {
+	if version == "" {
+		version = latestVersion
+	}
+	path, err := f.recordPath(sslKeyDir, xmlID, version)
+	if err != nil {
+		return tc.DeliveryServiceSSLKeysV15{}, false, err
+	}
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	r, ok, err := readRecord(path)
+	if err != nil || !ok {
+		return tc.DeliveryServiceSSLKeysV15{}, false, err
+	}
+	jsonKeys, err := f.keyring.open(r)
+	if err != nil {
+		return tc.DeliveryServiceSSLKeysV15{}, false, err
+	}
+	sslKey := tc.DeliveryServiceSSLKeysV15{}
+	if err := json.Unmarshal(jsonKeys, &sslKey); err != nil {
+		return tc.DeliveryServiceSSLKeysV15{}, false, errors.New("unmarshalling ssl keys: " + err.Error())
+	}
+	sslKey.Expiration = r.Metadata.Expiration
+	return sslKey, true, nil
+}
+
+// GetDeliveryServiceSSLKeysVersions retrieves information about every stored
+// version of the SSL keys for the delivery service identified by the given
+// xmlID, including which of them is the latest version.
+func (f *Filesystem) GetDeliveryServiceSSLKeysVersions(xmlID string, tx *sql.Tx, ctx context.Context) ([]tc.DeliveryServiceSSLKeysVersion, error) {
+	latestPath, err := f.recordPath(sslKeyDir, xmlID, latestVersion)
+	if err != nil {
+		return nil, err
+	}
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	latest, _, err := readRecord(latestPath)
+	if err != nil {
+		return nil, err
+	}
+	names, err := listDir(filepath.Dir(latestPath))
+	if err != nil {
+		return nil, err
+	}
+	versions := []tc.DeliveryServiceSSLKeysVersion{}
+	for _, name := range names {
+		version := strings.TrimSuffix(name, recordExt)
+		if version == latestVersion || version == name {
+			continue
+		}
+		r, ok, err := readRecord(filepath.Join(filepath.Dir(latestPath), name))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		versions = append(versions, tc.DeliveryServiceSSLKeysVersion{
+			Version:    version,
+			Latest:     version == latest.Metadata.Version,
+			Expiration: r.Metadata.Expiration,
+		})
+	}
+	return versions, nil
+}
+
+// GetExpirationInformation returns the expiration information for all SSL Keys.
+func (f *Filesystem) GetExpirationInformation(tx *sql.Tx, ctx context.Context, days int) ([]tc.SSLKeyExpirationInformation, error) {
+	fedMap := map[string]bool{}
+	fedRows, err := tx.Query("SELECT DISTINCT(ds.xml_id) FROM federation_deliveryservice AS fd JOIN deliveryservice AS ds ON ds.id = fd.deliveryservice")
+	if err != nil {
+		return []tc.SSLKeyExpirationInformation{}, err
+	}
+	defer fedRows.Close()
+	for fedRows.Next() {
+		var fedString string
+		if err = fedRows.Scan(&fedString); err != nil {
+			return []tc.SSLKeyExpirationInformation{}, err
+		}
+		fedMap[fedString] = true
+	}
+
+	iaRows, err := tx.Query("SELECT xml_id FROM deliveryservice WHERE NOT active")
+	if err != nil {
+		return []tc.SSLKeyExpirationInformation{}, err
+	}
+	defer iaRows.Close()
+	inactiveList := map[string]bool{}
+	for iaRows.Next() {
+		var inactiveXmlId string
+		if err = iaRows.Scan(&inactiveXmlId); err != nil {
+			return []tc.SSLKeyExpirationInformation{}, err
+		}
+		inactiveList[inactiveXmlId] = true
+	}
+
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	xmlIDs, err := listDir(filepath.Join(f.cfg.BaseDirectory, sslKeyDir))
+	if err != nil {
+		return []tc.SSLKeyExpirationInformation{}, err
+	}
+	cutoff := time.Now().AddDate(0, 0, days)
+	expirationInfos := []tc.SSLKeyExpirationInformation{}
+	for _, xmlID := range xmlIDs {
+		if inactiveList[xmlID] {
+			continue
+		}
+		path, err := f.recordPath(sslKeyDir, xmlID, latestVersion)
+		if err != nil {
+			return []tc.SSLKeyExpirationInformation{}, err
+		}
+		r, ok, err := readRecord(path)
+		if err != nil {
+			return []tc.SSLKeyExpirationInformation{}, err
+		}
+		if !ok || (days != 0 && r.Metadata.Expiration.After(cutoff)) {
+			continue
+		}
+		expirationInfos = append(expirationInfos, tc.SSLKeyExpirationInformation{
+			DeliveryService: xmlID,
+			CDN:             r.Metadata.CDN,
+			Provider:        r.Metadata.Provider,
+			Expiration:      r.Metadata.Expiration,
+			Federated:       fedMap[xmlID],
+		})
+	}
+	return expirationInfos, nil
+}
+
+// PutDeliveryServiceSSLKeys stores the given SSL keys for a delivery service.
+func (f *Filesystem) PutDeliveryServiceSSLKeys(key tc.DeliveryServiceSSLKeys, tx *sql.Tx, ctx context.Context) error {
+	version := strconv.FormatInt(int64(key.Version), 10)
+	versionPath, err := f.recordPath(sslKeyDir, key.DeliveryService, version)
+	if err != nil {
+		return err
+	}
+	latestPath, err := f.recordPath(sslKeyDir, key.DeliveryService, latestVersion)
+	if err != nil {
+		return err
+	}
+	keyJSON, err := json.Marshal(&key)
+	if err != nil {
+		return errors.New("marshalling keys: " + err.Error())
+	}
+
+	err = deliveryservice.Base64DecodeCertificate(&key.Certificate)
+	if err != nil {
+		return fmt.Errorf("decoding SSL keys, %w", err)
+	}
+	expiration, _, err := deliveryservice.ParseExpirationAndSansFromCert([]byte(key.Certificate.Crt), key.Hostname)
+	if err != nil {
+		return fmt.Errorf("parsing expiration from certificate: %w", err)
+	}
+
+	r, err := f.keyring.seal(keyJSON, recordMetadata{CDN: key.CDN, Provider: key.AuthType, Version: version, Expiration: expiration})
+	if err != nil {
+		return fmt.Errorf("encrypting keys: %w", err)
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if err := writeRecord(versionPath, r); err != nil {
+		return err
+	}
+	return writeRecord(latestPath, r)
+}
+
+// DeleteDeliveryServiceSSLKeys removes the SSL keys of the given version (or latest
+// if version is empty) for the delivery service identified by the given xmlID.
+func (f *Filesystem) DeleteDeliveryServiceSSLKeys(xmlID string, version string, tx *sql.Tx, ctx context.Context) error {
+	if version == "" {
+		version = latestVersion
+	}
+	path, err := f.recordPath(sslKeyDir, xmlID, version)
+	if err != nil {
+		return err
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if err := removeRecord(path); err != nil {
+		return err
+	}
+	f.removeEmptyDir(filepath.Dir(path))
+	return nil
+}
+
+// DeleteOldDeliveryServiceSSLKeys takes a set of existingXMLIDs as input and will remove
+// all SSL keys for delivery services in the CDN identified by the given cdnName that
+// do not contain an xmlID in the given set of existingXMLIDs. This method is called
+// during a snapshot operation in order to delete SSL keys for delivery services that
+// no longer exist.
+func (f *Filesystem) DeleteOldDeliveryServiceSSLKeys(existingXMLIDs map[string]struct{}, cdnName string, tx *sql.Tx, ctx context.Context) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	xmlIDs, err := listDir(filepath.Join(f.cfg.BaseDirectory, sslKeyDir))
+	if err != nil {
+		return err
+	}
+	for _, xmlID := range xmlIDs {
+		if _, ok := existingXMLIDs[xmlID]; ok {
+			continue
+		}
+		dir := filepath.Join(f.cfg.BaseDirectory, sslKeyDir, xmlID)
+		versions, err := listDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, version := range versions {
+			path := filepath.Join(dir, version)
+			r, ok, err := readRecord(path)
+			if err != nil {
+				return err
+			}
+			if !ok || r.Metadata.CDN != cdnName {
+				continue
+			}
+			if err := removeRecord(path); err != nil {
+				return err
+			}
+		}
+		f.removeEmptyDir(dir)
+	}
+	return nil
+}
+
+// GetCDNSSLKeys retrieves all the SSL keys for delivery services in the CDN identified
+// by the given cdnName.
+func (f *Filesystem) GetCDNSSLKeys(cdnName string, tx *sql.Tx, ctx context.Context) ([]tc.CDNSSLKey, error) {
+	keys := []tc.CDNSSLKey{}
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	xmlIDs, err := listDir(filepath.Join(f.cfg.BaseDirectory, sslKeyDir))
+	if err != nil {
+		return keys, err
+	}
+	for _, xmlID := range xmlIDs {
+		path, err := f.recordPath(sslKeyDir, xmlID, latestVersion)
+		if err != nil {
+			return keys, err
+		}
+		r, ok, err := readRecord(path)
+		if err != nil {
+			return keys, err
+		}
+		if !ok || r.Metadata.CDN != cdnName {
+			continue
+		}
+		jsonKey, err := f.keyring.open(r)
+		if err != nil {
+			log.Errorf("couldn't decrypt key for delivery service '%s': %v", xmlID, err)
+			continue
+		}
+		key := tc.CDNSSLKey{}
+		if err := json.Unmarshal(jsonKey, &key); err != nil {
+			log.Errorf("couldn't unmarshal json key for delivery service '%s': %v", xmlID, err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (f *Filesystem) GetDNSSECKeys(cdnName string, tx *sql.Tx, ctx context.Context) (tc.DNSSECKeysTrafficVault, bool, error) {
+	dnssecKeys := tc.DNSSECKeysTrafficVault{}
+	ok, err := f.getJSON(dnssecDir, cdnName, &dnssecKeys)
+	if err != nil || !ok {
+		return tc.DNSSECKeysTrafficVault{}, false, err
+	}
+	return dnssecKeys, true, nil
+}
+
+func (f *Filesystem) PutDNSSECKeys(cdnName string, keys tc.DNSSECKeysTrafficVault, tx *sql.Tx, ctx context.Context) error {
+	return f.putJSON(dnssecDir, cdnName, keys, recordMetadata{CDN: cdnName})
+}
+
+func (f *Filesystem) DeleteDNSSECKeys(cdnName string, tx *sql.Tx, ctx context.Context) error {
+	return f.delete(dnssecDir, cdnName)
+}
+
+func (f *Filesystem) GetURLSigKeys(xmlID string, tx *sql.Tx, ctx context.Context) (tc.URLSigKeys, bool, error) {
+	keys := tc.URLSigKeys{}
+	ok, err := f.getJSON(urlSigKeyDir, xmlID, &keys)
+	if err != nil || !ok {
+		return tc.URLSigKeys{}, false, err
+	}
+	return keys, true, nil
+}
+
+func (f *Filesystem) PutURLSigKeys(xmlID string, keys tc.URLSigKeys, tx *sql.Tx, ctx context.Context) error {
+	return f.putJSON(urlSigKeyDir, xmlID, keys, recordMetadata{})
+}
+
+func (f *Filesystem) DeleteURLSigKeys(xmlID string, tx *sql.Tx, ctx context.Context) error {
+	return f.delete(urlSigKeyDir, xmlID)
+}
+
+func (f *Filesystem) GetURISigningKeys(xmlID string, tx *sql.Tx, ctx context.Context) ([]byte, bool, error) {
+	path, err := f.recordPath(uriSigningKeyDir, xmlID)
+	if err != nil {
+		return []byte{}, false, err
+	}
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	r, ok, err := readRecord(path)
+	if err != nil || !ok {
+		return []byte{}, false, err
+	}
+	keysJSON, err := f.keyring.open(r)
+	if err != nil {
+		return []byte{}, false, err
+	}
+	return keysJSON, true, nil
+}
+
+func (f *Filesystem) PutURISigningKeys(xmlID string, keysJson []byte, tx *sql.Tx, ctx context.Context) error {
+	path, err := f.recordPath(uriSigningKeyDir, xmlID)
+	if err != nil {
+		return err
+	}
+	r, err := f.keyring.seal(keysJson, recordMetadata{})
+	if err != nil {
+		return errors.New("encrypting keys: " + err.Error())
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return writeRecord(path, r)
+}
+
+func (f *Filesystem) DeleteURISigningKeys(xmlID string, tx *sql.Tx, ctx context.Context) error {
+	return f.delete(uriSigningKeyDir, xmlID)
+}
+
+// Ping checks that the base directory exists and is writable.
+func (f *Filesystem) Ping(tx *sql.Tx, ctx context.Context) (tc.TrafficVaultPing, error) {
+	info, err := os.Stat(f.cfg.BaseDirectory)
+	if err != nil {
+		return tc.TrafficVaultPing{}, errors.New("Traffic Vault filesystem: checking base directory: " + err.Error())
+	}
+	if !info.IsDir() {
+		return tc.TrafficVaultPing{}, errors.New("Traffic Vault filesystem: base directory '" + f.cfg.BaseDirectory + "' is not a directory")
+	}
+	tmp, err := ioutil.TempFile(f.cfg.BaseDirectory, ".tmp-ping-")
+	if err != nil {
+		return tc.TrafficVaultPing{}, errors.New("Traffic Vault filesystem: base directory is not writable: " + err.Error())
+	}
+	tmp.Close()
+	if err := os.Remove(tmp.Name()); err != nil {
+		return tc.TrafficVaultPing{}, errors.New("Traffic Vault filesystem: removing ping file: " + err.Error())
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	return tc.TrafficVaultPing{Status: "OK", Server: hostname + ":" + f.cfg.BaseDirectory}, nil
+}
+
+func (f *Filesystem) GetBucketKey(bucket string, key string, tx *sql.Tx) ([]byte, bool, error) {
+	return nil, false, notImplementedErr
+}
+
+// RotateKeys re-wraps the data key of every record that was not encrypted
+// with the current key encryption key, so that previous keys may be retired.
+// It returns the number of records that were re-wrapped.
+func (f *Filesystem) RotateKeys() (int, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	rotated := 0
+	err := filepath.Walk(f.cfg.BaseDirectory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".tmp-") || filepath.Ext(path) != recordExt {
+			return nil
+		}
+		r, ok, err := readRecord(path)
+		if err != nil || !ok {
+			return err
+		}
+		r, changed, err := f.keyring.rewrap(r)
+		if err != nil {
+			return errors.New("rotating '" + path + "': " + err.Error())
+		}
+		if !changed {
+			return nil
+		}
+		if err := writeRecord(path, r); err != nil {
+			return err
+		}
+		rotated++
+		return nil
+	})
+	return rotated, err
+}
+
+// getJSON reads and decrypts the named record in the given directory, and
+// unmarshals it into v. The returned boolean is false if no such record
+// exists.
+func (f *Filesystem) getJSON(dir string, name string, v interface{}) (bool, error) {
+	path, err := f.recordPath(dir, name)
+	if err != nil {
+		return false, err
+	}
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	r, ok, err := readRecord(path)
+	if err != nil || !ok {
+		return false, err
+	}
+	b, err := f.keyring.open(r)
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return false, errors.New("unmarshalling keys: " + err.Error())
+	}
+	return true, nil
+}
+
+// putJSON marshals, encrypts and stores v as the named record in the given
+// directory, replacing any existing record.
+func (f *Filesystem) putJSON(dir string, name string, v interface{}, meta recordMetadata) error {
+	path, err := f.recordPath(dir, name)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return errors.New("marshalling keys: " + err.Error())
+	}
+	r, err := f.keyring.seal(b, meta)
+	if err != nil {
+		return errors.New("encrypting keys: " + err.Error())
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return writeRecord(path, r)
+}
+
+// delete removes the named record in the given directory, if it exists.
+func (f *Filesystem) delete(dir string, name string) error {
+	path, err := f.recordPath(dir, name)
+	if err != nil {
+		return err
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return removeRecord(path)
+}
+
+// removeEmptyDir removes the given directory if it is empty. Failures are
+// ignored, since a non-empty directory is expected to fail.
+func (f *Filesystem) removeEmptyDir(dir string) {
+	os.Remove(dir)
+}
+
+func init() {
+	trafficvault.AddBackend(filesystemBackendName, filesystemLoad)
+}
+
+func filesystemLoad(b json.RawMessage) (trafficvault.TrafficVault, error) {
+	cfg := Config{}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, errors.New("unmarshalling filesystem config: " + err.Error())
+	}
+	if err := validateConfig(cfg); err != nil {
+		return nil, errors.New("validating filesystem config: " + err.Error())
+	}
+	return newFilesystem(cfg)
+}
+
+func newFilesystem(cfg Config) (*Filesystem, error) {
+	if err := os.MkdirAll(cfg.BaseDirectory, dirPerm); err != nil {
+		return nil, errors.New("creating base directory: " + err.Error())
+	}
+	current, err := readKey(cfg.AesKeyLocation)
+	if err != nil {
+		return nil, err
+	}
+	previous := make([][]byte, 0, len(cfg.PreviousAesKeyLocations))
+	for _, location := range cfg.PreviousAesKeyLocations {
+		key, err := readKey(location)
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, key)
+	}
+	f := &Filesystem{cfg: cfg, keyring: newKeyring(current, previous...)}
+	if cfg.RotateOnStartup {
+		rotated, err := f.RotateKeys()
+		if err != nil {
+			return nil, errors.New("rotating keys: " + err.Error())
+		}
+		log.Infof("Traffic Vault filesystem: re-wrapped %d records with the current key", rotated)
+	}
+	return f, nil
+}
+
+func validateConfig(cfg Config) error {
+	errs := tovalidate.ToErrors(validation.Errors{
+		"base_directory":   validation.Validate(cfg.BaseDirectory, validation.Required),
+		"aes_key_location": validation.Validate(cfg.AesKeyLocation, validation.Required),
+	})
+	for _, location := range cfg.PreviousAesKeyLocations {
+		if location == "" {
+			errs = append(errs, errors.New("previous_aes_key_locations: cannot contain empty locations"))
+			break
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return util.JoinErrs(errs)
+}
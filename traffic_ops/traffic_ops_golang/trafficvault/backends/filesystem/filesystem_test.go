@@ -0,0 +1,256 @@
+package filesystem
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/lib/go-util"
+)
+
+func writeTestKey(t *testing.T, dir string, name string) string {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)), 0600); err != nil {
+		t.Fatalf("writing key: %v", err)
+	}
+	return path
+}
+
+// makeTestSSLKeys returns SSL keys for the given delivery service and version,
+// with a base64-encoded self-signed certificate that expires at notAfter.
+func makeTestSSLKeys(t *testing.T, xmlID string, version int64, notAfter time.Time) tc.DeliveryServiceSSLKeys {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating private key: %v", err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(version),
+		Subject:      pkix.Name{CommonName: xmlID + ".example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+	crt := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return tc.DeliveryServiceSSLKeys{
+		CDN:             "cdn1",
+		DeliveryService: xmlID,
+		Hostname:        xmlID + ".example.com",
+		Version:         util.JSONIntStr(version),
+		Certificate:     tc.DeliveryServiceSSLKeysCertificate{Crt: base64.StdEncoding.EncodeToString(crt)},
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	if err := validateConfig(Config{BaseDirectory: "/var/lib/tv", AesKeyLocation: "/etc/tv.key"}); err != nil {
+		t.Errorf("validating good config - expected: nil error, actual: %v", err)
+	}
+	badConfigs := map[string]Config{
+		"missing base_directory":   {AesKeyLocation: "/etc/tv.key"},
+		"missing aes_key_location": {BaseDirectory: "/var/lib/tv"},
+		"empty previous key":       {BaseDirectory: "/var/lib/tv", AesKeyLocation: "/etc/tv.key", PreviousAesKeyLocations: []string{""}},
+	}
+	for reason, cfg := range badConfigs {
+		if err := validateConfig(cfg); err == nil {
+			t.Errorf("validating bad config (%s) - expected: error, actual: nil", reason)
+		}
+	}
+}
+
+func TestRecordPathRejectsTraversal(t *testing.T) {
+	f := &Filesystem{cfg: Config{BaseDirectory: "/var/lib/tv"}}
+	for _, name := range []string{"", ".", "..", "../etc", "a/b", `a\b`} {
+		if _, err := f.recordPath(urlSigKeyDir, name); err == nil {
+			t.Errorf("expected error for name '%s', actual: nil", name)
+		}
+	}
+}
+
+func TestURLSigKeysRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	f, err := newFilesystem(Config{BaseDirectory: filepath.Join(dir, "vault"), AesKeyLocation: writeTestKey(t, dir, "current.key")})
+	if err != nil {
+		t.Fatalf("creating filesystem backend: %v", err)
+	}
+	ctx := context.Background()
+	keys := tc.URLSigKeys{"key0": "foo", "key1": "bar"}
+	if err := f.PutURLSigKeys("ds1", keys, nil, ctx); err != nil {
+		t.Fatalf("putting URL sig keys: %v", err)
+	}
+	raw, err := ioutil.ReadFile(filepath.Join(dir, "vault", urlSigKeyDir, "ds1"+recordExt))
+	if err != nil {
+		t.Fatalf("reading record: %v", err)
+	}
+	if bytes.Contains(raw, []byte("foo")) {
+		t.Error("expected stored record to be encrypted, but it contains plaintext")
+	}
+	actual, ok, err := f.GetURLSigKeys("ds1", nil, ctx)
+	if err != nil || !ok {
+		t.Fatalf("getting URL sig keys - expected: ok, actual: %t %v", ok, err)
+	}
+	if len(actual) != 2 || actual["key0"] != "foo" || actual["key1"] != "bar" {
+		t.Errorf("getting URL sig keys - expected: %v, actual: %v", keys, actual)
+	}
+	if err := f.DeleteURLSigKeys("ds1", nil, ctx); err != nil {
+		t.Fatalf("deleting URL sig keys: %v", err)
+	}
+	if _, ok, err := f.GetURLSigKeys("ds1", nil, ctx); err != nil || ok {
+		t.Errorf("getting deleted URL sig keys - expected: not found, actual: %t %v", ok, err)
+	}
+}
+
+func TestSSLKeysVersions(t *testing.T) {
+	dir := t.TempDir()
+	f, err := newFilesystem(Config{BaseDirectory: filepath.Join(dir, "vault"), AesKeyLocation: writeTestKey(t, dir, "current.key")})
+	if err != nil {
+		t.Fatalf("creating filesystem backend: %v", err)
+	}
+	ctx := context.Background()
+	exp1 := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	exp2 := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	if err := f.PutDeliveryServiceSSLKeys(makeTestSSLKeys(t, "ds1", 1, exp1), nil, ctx); err != nil {
+		t.Fatalf("putting SSL keys version 1: %v", err)
+	}
+	if err := f.PutDeliveryServiceSSLKeys(makeTestSSLKeys(t, "ds1", 2, exp2), nil, ctx); err != nil {
+		t.Fatalf("putting SSL keys version 2: %v", err)
+	}
+
+	latest, ok, err := f.GetDeliveryServiceSSLKeys("ds1", "", nil, ctx)
+	if err != nil || !ok {
+		t.Fatalf("getting latest SSL keys - expected: ok, actual: %t %v", ok, err)
+	}
+	if latest.Version != 2 || !latest.Expiration.Equal(exp2) {
+		t.Errorf("getting latest SSL keys - expected: version 2 expiring %v, actual: version %d expiring %v", exp2, latest.Version, latest.Expiration)
+	}
+
+	versions, err := f.GetDeliveryServiceSSLKeysVersions("ds1", nil, ctx)
+	if err != nil {
+		t.Fatalf("getting SSL key versions: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("getting SSL key versions - expected: 2 versions, actual: %+v", versions)
+	}
+	for _, v := range versions {
+		switch v.Version {
+		case "1":
+			if v.Latest || !v.Expiration.Equal(exp1) {
+				t.Errorf("expected version 1 to not be latest and expire at %v, actual: %+v", exp1, v)
+			}
+		case "2":
+			if !v.Latest || !v.Expiration.Equal(exp2) {
+				t.Errorf("expected version 2 to be latest and expire at %v, actual: %+v", exp2, v)
+			}
+		default:
+			t.Errorf("unexpected version: %+v", v)
+		}
+	}
+
+	if err := f.DeleteOldDeliveryServiceSSLKeys(map[string]struct{}{}, "cdn1", nil, ctx); err != nil {
+		t.Fatalf("deleting old SSL keys: %v", err)
+	}
+	if versions, err := f.GetDeliveryServiceSSLKeysVersions("ds1", nil, ctx); err != nil || len(versions) != 0 {
+		t.Errorf("getting SSL key versions after deleting old keys - expected: none, actual: %+v %v", versions, err)
+	}
+}
+
+func TestRotateKeys(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "vault")
+	oldKey := writeTestKey(t, dir, "old.key")
+	newKey := writeTestKey(t, dir, "new.key")
+	ctx := context.Background()
+
+	f, err := newFilesystem(Config{BaseDirectory: base, AesKeyLocation: oldKey})
+	if err != nil {
+		t.Fatalf("creating filesystem backend: %v", err)
+	}
+	if err := f.PutURISigningKeys("ds1", []byte(`{"keys":[]}`), nil, ctx); err != nil {
+		t.Fatalf("putting URI signing keys: %v", err)
+	}
+	if err := f.PutDNSSECKeys("cdn1", tc.DNSSECKeysTrafficVault{}, nil, ctx); err != nil {
+		t.Fatalf("putting DNSSEC keys: %v", err)
+	}
+
+	if _, err := newFilesystem(Config{BaseDirectory: base, AesKeyLocation: newKey, RotateOnStartup: true}); err == nil {
+		t.Error("rotating without the previous key - expected: error, actual: nil")
+	}
+
+	f, err = newFilesystem(Config{BaseDirectory: base, AesKeyLocation: newKey, PreviousAesKeyLocations: []string{oldKey}})
+	if err != nil {
+		t.Fatalf("creating filesystem backend: %v", err)
+	}
+	if rotated, err := f.RotateKeys(); err != nil || rotated != 2 {
+		t.Fatalf("rotating keys - expected: 2 records, actual: %d %v", rotated, err)
+	}
+	if rotated, err := f.RotateKeys(); err != nil || rotated != 0 {
+		t.Errorf("rotating keys again - expected: 0 records, actual: %d %v", rotated, err)
+	}
+
+	f, err = newFilesystem(Config{BaseDirectory: base, AesKeyLocation: newKey})
+	if err != nil {
+		t.Fatalf("creating filesystem backend: %v", err)
+	}
+	keys, ok, err := f.GetURISigningKeys("ds1", nil, ctx)
+	if err != nil || !ok {
+		t.Fatalf("getting URI signing keys after rotation - expected: ok, actual: %t %v", ok, err)
+	}
+	if string(keys) != `{"keys":[]}` {
+		t.Errorf("getting URI signing keys after rotation - expected: %s, actual: %s", `{"keys":[]}`, keys)
+	}
+}
+
+func TestPing(t *testing.T) {
+	dir := t.TempDir()
+	f, err := newFilesystem(Config{BaseDirectory: filepath.Join(dir, "vault"), AesKeyLocation: writeTestKey(t, dir, "current.key")})
+	if err != nil {
+		t.Fatalf("creating filesystem backend: %v", err)
+	}
+	resp, err := f.Ping(nil, context.Background())
+	if err != nil {
+		t.Fatalf("pinging - expected: nil error, actual: %v", err)
+	}
+	if resp.Status != "OK" {
+		t.Errorf("pinging - expected status: OK, actual: %s", resp.Status)
+	}
+	f.cfg.BaseDirectory = filepath.Join(dir, "nonexistent")
+	if _, err := f.Ping(nil, context.Background()); err == nil {
+		t.Error("pinging a nonexistent directory - expected: error, actual: nil")
+	}
+}
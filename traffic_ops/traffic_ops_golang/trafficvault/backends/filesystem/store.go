@@ -0,0 +1,146 @@
+package filesystem
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	sslKeyDir        = "sslkey"
+	dnssecDir        = "dnssec"
+	urlSigKeyDir     = "url_sig_key"
+	uriSigningKeyDir = "uri_signing_key"
+
+	recordExt = ".json"
+
+	dirPerm  = 0700
+	filePerm = 0600
+)
+
+// validateName makes sure that the given name (an XMLID, CDN name, or key
+// version) can be safely used as a single path element.
+func validateName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\\x00") {
+		return errors.New("invalid name '" + name + "'")
+	}
+	return nil
+}
+
+// recordPath returns the path of the record file identified by the given
+// path elements, which are validated before use.
+func (f *Filesystem) recordPath(dir string, names ...string) (string, error) {
+	elems := []string{f.cfg.BaseDirectory, dir}
+	for _, name := range names {
+		if err := validateName(name); err != nil {
+			return "", err
+		}
+		elems = append(elems, name)
+	}
+	return filepath.Join(elems...) + recordExt, nil
+}
+
+// readRecord reads the record at the given path. The returned boolean is
+// false if no record exists at the path.
+func readRecord(path string) (record, bool, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return record{}, false, nil
+		}
+		return record{}, false, errors.New("reading record: " + err.Error())
+	}
+	r := record{}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return record{}, false, errors.New("unmarshalling record '" + path + "': " + err.Error())
+	}
+	return r, true, nil
+}
+
+// writeRecord atomically writes the given record to the given path, creating
+// any parent directories as necessary.
+func writeRecord(path string, r record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return errors.New("marshalling record: " + err.Error())
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return errors.New("creating directory: " + err.Error())
+	}
+	tmp, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return errors.New("creating temporary file: " + err.Error())
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+	if err := tmp.Chmod(filePerm); err != nil {
+		tmp.Close()
+		return errors.New("setting temporary file permissions: " + err.Error())
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return errors.New("writing temporary file: " + err.Error())
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.New("syncing temporary file: " + err.Error())
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.New("closing temporary file: " + err.Error())
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return errors.New("renaming temporary file: " + err.Error())
+	}
+	return nil
+}
+
+// removeRecord removes the record at the given path, if it exists.
+func removeRecord(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.New("removing record: " + err.Error())
+	}
+	return nil
+}
+
+// listDir returns the names of the entries in the given directory, ignoring
+// temporary files. A directory that doesn't exist is treated as empty.
+func listDir(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.New("reading directory: " + err.Error())
+	}
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), ".tmp-") {
+			continue
+		}
+		names = append(names, info.Name())
+	}
+	return names, nil
+}
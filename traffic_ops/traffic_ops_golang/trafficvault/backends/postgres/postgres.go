@@ -157,6 +157,42 @@ func (p *Postgres) GetDeliveryServiceSSLKeys(xmlID string, version string, tx *s
 	return sslKey, true, nil
 }
 
+// GetDeliveryServiceSSLKeysVersions retrieves information about every stored
+// version of the SSL keys for the delivery service identified by the given
+// xmlID, including which of them is the latest version.
+func (p *Postgres) GetDeliveryServiceSSLKeysVersions(xmlID string, tx *sql.Tx, ctx context.Context) ([]tc.DeliveryServiceSSLKeysVersion, error) {
+	tvTx, dbCtx, cancelFunc, err := p.beginTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.commitTransaction(tvTx, dbCtx, cancelFunc)
+
+	// the latest version is stored as a copy of the data of its numbered version
+	query := `
+SELECT s.version, s.expiration, COALESCE(s.data = l.data, FALSE)
+FROM sslkey AS s
+LEFT JOIN sslkey AS l ON l.deliveryservice = s.deliveryservice AND l.version = $2
+WHERE s.deliveryservice = $1 AND s.version <> $2
+`
+	rows, err := tvTx.Query(query, xmlID, latestVersion)
+	if err != nil {
+		e := checkErrWithContext("Traffic Vault PostgreSQL: executing SELECT SSL Key versions query", err, ctx.Err())
+		return nil, e
+	}
+	defer rows.Close()
+
+	versions := []tc.DeliveryServiceSSLKeysVersion{}
+	for rows.Next() {
+		v := tc.DeliveryServiceSSLKeysVersion{}
+		if err := rows.Scan(&v.Version, &v.Expiration, &v.Latest); err != nil {
+			e := checkErrWithContext("Traffic Vault PostgreSQL: scanning SSL Key versions", err, ctx.Err())
+			return nil, e
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
 // GetExpirationInformation returns the expiration information for all SSL Keys.
 func (p *Postgres) GetExpirationInformation(tx *sql.Tx, ctx context.Context, days int) ([]tc.SSLKeyExpirationInformation, error) {
 	tvTx, dbCtx, cancelFunc, err := p.beginTransaction(ctx)
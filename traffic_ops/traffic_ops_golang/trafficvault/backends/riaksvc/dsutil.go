@@ -28,6 +28,7 @@ import (
 	"github.com/apache/trafficcontrol/lib/go-log"
 	"github.com/apache/trafficcontrol/lib/go-rfc"
 	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/deliveryservice"
 
 	"github.com/basho/riak-go-client"
 )
@@ -73,6 +74,64 @@ func getDeliveryServiceSSLKeysObjV15(xmlID string, version string, tx *sql.Tx, a
 	return key, found, nil
 }
 
+func getDeliveryServiceSSLKeysVersions(xmlID string, tx *sql.Tx, authOpts *riak.AuthOptions, riakPort *uint) ([]tc.DeliveryServiceSSLKeysVersion, error) {
+	versions := []tc.DeliveryServiceSSLKeysVersion{}
+	err := withCluster(tx, authOpts, riakPort, func(cluster StorageCluster) error {
+		query := `deliveryservice:` + xmlID
+		fields := []string{"_yz_rk", "hostname", "certificate.crt"} // '_yz_rk' is the magic Riak field that populates the key. Without this, doc.Key would be empty.
+		searchDocs, err := search(cluster, sslKeysIndex, query, "", cdnSSLKeysLimit, fields)
+		if err != nil {
+			return errors.New("riak search error: " + err.Error())
+		}
+		versions = searchDocsToSSLKeysVersions(xmlID, searchDocs)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("with cluster error: " + err.Error())
+	}
+	return versions, nil
+}
+
+// searchDocsToSSLKeysVersions converts the SearchDoc array returned by Riak for the SSL keys of the given delivery service into a slice of versions. Riak doesn't store which version is the latest, so the version whose certificate matches the certificate of the 'latest' key is considered the latest.
+func searchDocsToSSLKeysVersions(xmlID string, docs []*riak.SearchDoc) []tc.DeliveryServiceSSLKeysVersion {
+	latestCrt := ""
+	crts := map[string]string{}
+	hostnames := map[string]string{}
+	for _, doc := range docs {
+		if !strings.HasPrefix(doc.Key, xmlID+"-") {
+			continue // the search may also match keys of delivery services whose XMLID has this XMLID as a prefix
+		}
+		version := strings.TrimPrefix(doc.Key, xmlID+"-")
+		crt := ""
+		if docCrts := doc.Fields["certificate.crt"]; len(docCrts) > 0 {
+			crt = docCrts[0]
+		}
+		if version == dsSSLKeyVersionLatest {
+			latestCrt = crt
+			continue
+		}
+		crts[version] = crt
+		if docHosts := doc.Fields["hostname"]; len(docHosts) > 0 {
+			hostnames[version] = docHosts[0]
+		}
+	}
+
+	versions := make([]tc.DeliveryServiceSSLKeysVersion, 0, len(crts))
+	for version, crt := range crts {
+		v := tc.DeliveryServiceSSLKeysVersion{Version: version, Latest: crt != "" && crt == latestCrt}
+		cert := tc.DeliveryServiceSSLKeysCertificate{Crt: crt}
+		if err := deliveryservice.Base64DecodeCertificate(&cert); err != nil {
+			log.Warnln("decoding Riak SSL key certificate for delivery service '" + xmlID + "' version '" + version + "': " + err.Error())
+		} else if exp, _, err := deliveryservice.ParseExpirationAndSansFromCert([]byte(cert.Crt), hostnames[version]); err != nil {
+			log.Warnln("parsing Riak SSL key expiration for delivery service '" + xmlID + "' version '" + version + "': " + err.Error())
+		} else {
+			v.Expiration = exp
+		}
+		versions = append(versions, v)
+	}
+	return versions
+}
+
 func putDeliveryServiceSSLKeysObj(key tc.DeliveryServiceSSLKeys, tx *sql.Tx, authOpts *riak.AuthOptions, riakPort *uint) error {
 	keyJSON, err := json.Marshal(&key)
 	if err != nil {
@@ -44,6 +44,10 @@ func (r *Riak) GetDeliveryServiceSSLKeys(xmlID string, version string, tx *sql.T
 	return getDeliveryServiceSSLKeysObjV15(xmlID, version, tx, &r.cfg.AuthOptions, &r.cfg.Port)
 }
 
+func (r *Riak) GetDeliveryServiceSSLKeysVersions(xmlID string, tx *sql.Tx, ctx context.Context) ([]tc.DeliveryServiceSSLKeysVersion, error) {
+	return getDeliveryServiceSSLKeysVersions(xmlID, tx, &r.cfg.AuthOptions, &r.cfg.Port)
+}
+
 func (r *Riak) GetExpirationInformation(tx *sql.Tx, ctx context.Context, days int) ([]tc.SSLKeyExpirationInformation, error) {
 	return []tc.SSLKeyExpirationInformation{}, errors.New("Not implemented for this Traffic Vault backend.")
 }
@@ -42,6 +42,10 @@ type TrafficVault interface {
 	// the delivery service identified by the given xmlID. If version is empty,
 	// the implementation should return the latest version.
 	GetDeliveryServiceSSLKeys(xmlID string, version string, tx *sql.Tx, ctx context.Context) (tc.DeliveryServiceSSLKeysV15, bool, error)
+	// GetDeliveryServiceSSLKeysVersions retrieves information about every stored
+	// version of the SSL keys for the delivery service identified by the given
+	// xmlID, including which of them is the latest version.
+	GetDeliveryServiceSSLKeysVersions(xmlID string, tx *sql.Tx, ctx context.Context) ([]tc.DeliveryServiceSSLKeysVersion, error)
 	// GetExpirationInformation retrieves the SSL key expiration information for all delivery services.
 	GetExpirationInformation(tx *sql.Tx, ctx context.Context, days int) ([]tc.SSLKeyExpirationInformation, error)
 	// PutDeliveryServiceSSLKeys stores the given SSL keys for a delivery service.
@@ -0,0 +1,456 @@
+package vault
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-rfc"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/auth"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/config"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/trafficvault"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// maxReportedFailures is the maximum number of failed records listed by name
+// in the async status message of a migration.
+const maxReportedFailures = 10
+
+// inMigration is whether the server is currently executing a Traffic Vault
+// migration. This is used to only perform 1 migration at a time.
+// This MUST NOT be changed outside of atomic operations.
+var inMigration = uint64(0)
+
+// setInMigration attempts to set whether the server is currently executing a Traffic Vault migration.
+// Returns false if a migration is already executing.
+// If this returns true, the caller MUST call unsetInMigration().
+func setInMigration() bool { return atomic.CompareAndSwapUint64(&inMigration, 0, 1) }
+
+// unsetInMigration sets the flag indicating that the server is currently executing a Traffic Vault migration to false.
+// This MUST NOT be called, unless setInMigration() was previously called and returned true.
+func unsetInMigration() { atomic.StoreUint64(&inMigration, 0) }
+
+// migrationTarget is the Traffic Vault backend that records are migrated to.
+// It is loaded on first use, so that its connections are only opened if a
+// migration is actually requested.
+var migrationTarget struct {
+	sync.Mutex
+	tv trafficvault.TrafficVault
+}
+
+func getMigrationTarget(cfg *config.Config) (trafficvault.TrafficVault, error) {
+	migrationTarget.Lock()
+	defer migrationTarget.Unlock()
+	if migrationTarget.tv != nil {
+		return migrationTarget.tv, nil
+	}
+	tv, err := trafficvault.GetBackend(cfg.TrafficVaultMigrationBackend, cfg.TrafficVaultMigrationConfig)
+	if err != nil {
+		return nil, err
+	}
+	migrationTarget.tv = tv
+	return tv, nil
+}
+
+// MigrateTrafficVault starts copying every record in the configured Traffic
+// Vault backend to the configured migration target backend in the background.
+// If the dryRun query parameter is true, nothing is written to the target, and
+// the resulting report only describes what would be copied.
+func MigrateTrafficVault(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, nil, nil)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	if inf.User.RoleName != tc.AdminRoleName {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusForbidden, errors.New("only the '"+tc.AdminRoleName+"' Role may migrate Traffic Vault"), nil)
+		return
+	}
+	if !inf.Config.TrafficVaultEnabled {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("migrating Traffic Vault: Traffic Vault is not configured"))
+		return
+	}
+	if inf.Config.TrafficVaultMigrationBackend == "" {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, errors.New("no Traffic Vault migration backend is configured"), nil)
+		return
+	}
+	dryRun := false
+	if dryRunStr, ok := inf.Params["dryRun"]; ok {
+		var err error
+		if dryRun, err = strconv.ParseBool(dryRunStr); err != nil {
+			api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, errors.New("dryRun must be a boolean"), nil)
+			return
+		}
+	}
+
+	target, err := getMigrationTarget(inf.Config)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("loading Traffic Vault migration backend: "+err.Error()))
+		return
+	}
+
+	if !setInMigration() {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusConflict, errors.New("the server is already executing a Traffic Vault migration"), nil)
+		return
+	}
+	db, err := api.GetDB(r.Context())
+	if err != nil {
+		unsetInMigration()
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("migrating Traffic Vault: getting db from context: "+err.Error()))
+		return
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		unsetInMigration()
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("migrating Traffic Vault: beginning tx: "+err.Error()))
+		return
+	}
+	startMsg := "Traffic Vault migration started."
+	if dryRun {
+		startMsg = "Traffic Vault migration dry run started."
+	}
+	asyncTx, err := db.Begin()
+	if err != nil {
+		tx.Rollback()
+		unsetInMigration()
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("migrating Traffic Vault: beginning asyncTx: "+err.Error()))
+		return
+	}
+	jobID, errCode, userErr, sysErr := api.InsertAsyncStatus(asyncTx, startMsg)
+	if userErr != nil || sysErr != nil {
+		tx.Rollback()
+		unsetInMigration()
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+
+	go doMigration(tx, db, inf.Vault, target, dryRun, jobID, inf.User) // doMigration takes ownership of tx and MUST close it.
+
+	changeLogMsg := "TRAFFIC VAULT: started migration to backend " + inf.Config.TrafficVaultMigrationBackend
+	if dryRun {
+		changeLogMsg += " (dry run)"
+	}
+	api.CreateChangeLogRawTx(api.ApiChange, changeLogMsg, inf.User, inf.Tx.Tx)
+
+	msg := startMsg + " Status updates can be found here: " + api.CurrentAsyncEndpoint + strconv.Itoa(jobID)
+	w.Header().Add(rfc.Location, api.CurrentAsyncEndpoint+strconv.Itoa(jobID))
+	api.WriteAlerts(w, r, http.StatusAccepted, tc.CreateAlerts(tc.SuccessLevel, msg))
+}
+
+// doMigration migrates all records and records the outcome in the async
+// status identified by jobID.
+// This takes ownership of tx, and MUST call `tx.Commit()`.
+// This MUST call unsetInMigration() before returning.
+func doMigration(tx *sql.Tx, asyncDB *sqlx.DB, source trafficvault.TrafficVault, target trafficvault.TrafficVault, dryRun bool, jobID int, user *auth.CurrentUser) {
+	defer unsetInMigration()
+	defer tx.Commit()
+
+	m := migrator{source: source, target: target, dryRun: dryRun, tx: tx}
+	dses, cdns, err := getMigrationObjects(tx)
+	if err != nil {
+		log.Errorln("migrating Traffic Vault: " + err.Error())
+		if asyncErr := api.UpdateAsyncStatus(asyncDB, api.AsyncFailed, "Traffic Vault migration failed.", jobID, true); asyncErr != nil {
+			log.Errorf("updating async status for id %d: %v", jobID, asyncErr)
+		}
+		return
+	}
+
+	for _, cdn := range cdns {
+		m.migrateDNSSECKeys(cdn)
+	}
+	for i, ds := range dses {
+		m.migrateSSLKeys(ds)
+		m.migrateURLSigKeys(ds)
+		m.migrateURISigningKeys(ds)
+		if (i+1)%100 == 0 {
+			msg := fmt.Sprintf("Traffic Vault migration in progress. %d of %d delivery services processed.", i+1, len(dses))
+			if asyncErr := api.UpdateAsyncStatus(asyncDB, api.AsyncPending, msg, jobID, false); asyncErr != nil {
+				log.Errorf("updating async status for id %d: %v", jobID, asyncErr)
+			}
+		}
+	}
+
+	status := api.AsyncSucceeded
+	if len(m.report.failed) > 0 {
+		status = api.AsyncFailed
+	}
+	msg := m.report.String()
+	log.Infoln(msg)
+	if asyncErr := api.UpdateAsyncStatus(asyncDB, status, msg, jobID, true); asyncErr != nil {
+		log.Errorf("updating async status for id %d: %v", jobID, asyncErr)
+	}
+	if !dryRun {
+		api.CreateChangeLogRawTx(api.ApiChange, "TRAFFIC VAULT: "+msg, user, tx)
+	}
+}
+
+// getMigrationObjects returns the XMLIDs of all delivery services and the
+// names of all CDNs, which identify every record stored in Traffic Vault.
+func getMigrationObjects(tx *sql.Tx) ([]string, []string, error) {
+	dses := []string{}
+	rows, err := tx.Query(`SELECT xml_id FROM deliveryservice ORDER BY xml_id`)
+	if err != nil {
+		return nil, nil, errors.New("querying delivery services: " + err.Error())
+	}
+	defer rows.Close()
+	for rows.Next() {
+		ds := ""
+		if err := rows.Scan(&ds); err != nil {
+			return nil, nil, errors.New("scanning delivery services: " + err.Error())
+		}
+		dses = append(dses, ds)
+	}
+
+	cdns := []string{}
+	cdnRows, err := tx.Query(`SELECT name FROM cdn ORDER BY name`)
+	if err != nil {
+		return nil, nil, errors.New("querying cdns: " + err.Error())
+	}
+	defer cdnRows.Close()
+	for cdnRows.Next() {
+		cdn := ""
+		if err := cdnRows.Scan(&cdn); err != nil {
+			return nil, nil, errors.New("scanning cdns: " + err.Error())
+		}
+		cdns = append(cdns, cdn)
+	}
+	return dses, cdns, nil
+}
+
+// migrationReport counts the outcome of migrating each record.
+type migrationReport struct {
+	dryRun bool
+	// copied is the number of records that were copied to the target and
+	// verified, or, in a dry run, that would have been copied.
+	copied int
+	// identical is the number of records that already had the same checksum
+	// in the target, and so were not copied.
+	identical int
+	// failed is the names of the records that could not be migrated.
+	failed []string
+}
+
+func (r migrationReport) String() string {
+	msg := ""
+	if r.dryRun {
+		msg = fmt.Sprintf("Traffic Vault migration dry run complete. %d records would be copied, %d records are already identical in the target, %d records failed.", r.copied, r.identical, len(r.failed))
+	} else {
+		msg = fmt.Sprintf("Traffic Vault migration complete. %d records copied and verified, %d records were already identical in the target, %d records failed.", r.copied, r.identical, len(r.failed))
+	}
+	if len(r.failed) == 0 {
+		return msg
+	}
+	failed := r.failed
+	if len(failed) > maxReportedFailures {
+		failed = failed[:maxReportedFailures]
+		failed = append(failed, "...")
+	}
+	return msg + " Failed records: " + strings.Join(failed, ", ") + " (see the error log for details)"
+}
+
+// migrator copies records from a source Traffic Vault backend to a target,
+// verifying the checksum of each copied record.
+type migrator struct {
+	source trafficvault.TrafficVault
+	target trafficvault.TrafficVault
+	dryRun bool
+	tx     *sql.Tx
+	report migrationReport
+}
+
+// checksum returns a checksum of the JSON representation of the given value.
+func checksum(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", errors.New("marshalling: " + err.Error())
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// migrateRecord migrates a single record, named name for reporting purposes.
+// get must return the checksum of the record in the given backend, and
+// whether the record exists there; put must copy the record from the source
+// to the target.
+func (m *migrator) migrateRecord(name string, get func(tv trafficvault.TrafficVault) (string, bool, error), put func() error) {
+	m.report.dryRun = m.dryRun
+	fail := func(err error) {
+		log.Errorln("migrating Traffic Vault record '" + name + "': " + err.Error())
+		m.report.failed = append(m.report.failed, name)
+	}
+	sourceSum, ok, err := get(m.source)
+	if err != nil {
+		fail(errors.New("getting from source: " + err.Error()))
+		return
+	}
+	if !ok {
+		return
+	}
+	targetSum, ok, err := get(m.target)
+	if err != nil {
+		fail(errors.New("getting from target: " + err.Error()))
+		return
+	}
+	if ok && targetSum == sourceSum {
+		m.report.identical++
+		return
+	}
+	if m.dryRun {
+		m.report.copied++
+		return
+	}
+	if err := put(); err != nil {
+		fail(errors.New("putting into target: " + err.Error()))
+		return
+	}
+	targetSum, ok, err = get(m.target)
+	if err != nil {
+		fail(errors.New("verifying target: " + err.Error()))
+		return
+	}
+	if !ok || targetSum != sourceSum {
+		fail(errors.New("verifying target: checksum mismatch after copy"))
+		return
+	}
+	m.report.copied++
+}
+
+func (m *migrator) migrateSSLKeys(xmlID string) {
+	ctx := context.Background()
+	versions, err := m.source.GetDeliveryServiceSSLKeysVersions(xmlID, m.tx, ctx)
+	if err != nil {
+		log.Errorln("migrating Traffic Vault SSL keys for delivery service '" + xmlID + "': listing versions: " + err.Error())
+		m.report.failed = append(m.report.failed, "sslkeys:"+xmlID)
+		return
+	}
+	// copy older versions first, since storing any version also makes it the latest
+	sort.Slice(versions, func(i, j int) bool {
+		vi, _ := strconv.ParseInt(versions[i].Version, 10, 64)
+		vj, _ := strconv.ParseInt(versions[j].Version, 10, 64)
+		return vi < vj
+	})
+	for _, v := range versions {
+		m.migrateSSLKeysVersion(xmlID, v.Version)
+	}
+	m.migrateSSLKeysVersion(xmlID, "")
+}
+
+// migrateSSLKeysVersion migrates the given version of a delivery service's
+// SSL keys. If version is empty, the latest version is migrated.
+func (m *migrator) migrateSSLKeysVersion(xmlID string, version string) {
+	name := "sslkeys:" + xmlID + ":" + version
+	if version == "" {
+		name = "sslkeys:" + xmlID + ":latest"
+	}
+	ctx := context.Background()
+	keys := tc.DeliveryServiceSSLKeys{}
+	m.migrateRecord(name, func(tv trafficvault.TrafficVault) (string, bool, error) {
+		k, ok, err := tv.GetDeliveryServiceSSLKeys(xmlID, version, m.tx, ctx)
+		if err != nil || !ok {
+			return "", ok, err
+		}
+		if tv == m.source {
+			keys = k.DeliveryServiceSSLKeys
+		}
+		// backends differ in whether they return the expiration, so it isn't part of the checksum
+		sum, err := checksum(k.DeliveryServiceSSLKeys)
+		return sum, true, err
+	}, func() error {
+		return m.target.PutDeliveryServiceSSLKeys(keys, m.tx, ctx)
+	})
+}
+
+func (m *migrator) migrateDNSSECKeys(cdn string) {
+	ctx := context.Background()
+	keys := tc.DNSSECKeysTrafficVault{}
+	m.migrateRecord("dnssec:"+cdn, func(tv trafficvault.TrafficVault) (string, bool, error) {
+		k, ok, err := tv.GetDNSSECKeys(cdn, m.tx, ctx)
+		if err != nil || !ok {
+			return "", ok, err
+		}
+		if tv == m.source {
+			keys = k
+		}
+		sum, err := checksum(k)
+		return sum, true, err
+	}, func() error {
+		return m.target.PutDNSSECKeys(cdn, keys, m.tx, ctx)
+	})
+}
+
+func (m *migrator) migrateURLSigKeys(xmlID string) {
+	ctx := context.Background()
+	keys := tc.URLSigKeys{}
+	m.migrateRecord("urlsigkeys:"+xmlID, func(tv trafficvault.TrafficVault) (string, bool, error) {
+		k, ok, err := tv.GetURLSigKeys(xmlID, m.tx, ctx)
+		if err != nil || !ok {
+			return "", ok, err
+		}
+		if tv == m.source {
+			keys = k
+		}
+		sum, err := checksum(k)
+		return sum, true, err
+	}, func() error {
+		return m.target.PutURLSigKeys(xmlID, keys, m.tx, ctx)
+	})
+}
+
+func (m *migrator) migrateURISigningKeys(xmlID string) {
+	ctx := context.Background()
+	keys := []byte{}
+	m.migrateRecord("urisigningkeys:"+xmlID, func(tv trafficvault.TrafficVault) (string, bool, error) {
+		k, ok, err := tv.GetURISigningKeys(xmlID, m.tx, ctx)
+		if err != nil || !ok {
+			return "", ok, err
+		}
+		if tv == m.source {
+			keys = k
+		}
+		// the keys are stored as raw JSON, so whitespace differences are ignored
+		compacted := bytes.Buffer{}
+		if err := json.Compact(&compacted, k); err != nil {
+			return "", true, errors.New("compacting URI signing keys: " + err.Error())
+		}
+		sum := sha256.Sum256(compacted.Bytes())
+		return hex.EncodeToString(sum[:]), true, nil
+	}, func() error {
+		return m.target.PutURISigningKeys(xmlID, keys, m.tx, ctx)
+	})
+}
@@ -0,0 +1,162 @@
+package vault
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/lib/go-util"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/trafficvault"
+	_ "github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/trafficvault/backends/filesystem"
+)
+
+func newTestBackend(t *testing.T, dir string) trafficvault.TrafficVault {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	keyPath := filepath.Join(dir, "aes.key")
+	if err := ioutil.WriteFile(keyPath, []byte(base64.StdEncoding.EncodeToString(key)), 0600); err != nil {
+		t.Fatalf("writing key: %v", err)
+	}
+	cfg, err := json.Marshal(map[string]string{"base_directory": filepath.Join(dir, "vault"), "aes_key_location": keyPath})
+	if err != nil {
+		t.Fatalf("marshalling config: %v", err)
+	}
+	tv, err := trafficvault.GetBackend("filesystem", cfg)
+	if err != nil {
+		t.Fatalf("loading filesystem backend: %v", err)
+	}
+	return tv
+}
+
+func makeTestSSLKeys(t *testing.T, xmlID string, version int64) tc.DeliveryServiceSSLKeys {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating private key: %v", err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(version),
+		Subject:      pkix.Name{CommonName: xmlID + ".example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+	crt := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return tc.DeliveryServiceSSLKeys{
+		CDN:             "cdn1",
+		DeliveryService: xmlID,
+		Hostname:        xmlID + ".example.com",
+		Version:         util.JSONIntStr(version),
+		Certificate:     tc.DeliveryServiceSSLKeysCertificate{Crt: base64.StdEncoding.EncodeToString(crt)},
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	source := newTestBackend(t, t.TempDir())
+	target := newTestBackend(t, t.TempDir())
+
+	if err := source.PutDeliveryServiceSSLKeys(makeTestSSLKeys(t, "ds1", 1), nil, ctx); err != nil {
+		t.Fatalf("putting SSL keys version 1: %v", err)
+	}
+	if err := source.PutDeliveryServiceSSLKeys(makeTestSSLKeys(t, "ds1", 2), nil, ctx); err != nil {
+		t.Fatalf("putting SSL keys version 2: %v", err)
+	}
+	if err := source.PutURLSigKeys("ds1", tc.URLSigKeys{"key0": "foo"}, nil, ctx); err != nil {
+		t.Fatalf("putting URL sig keys: %v", err)
+	}
+	if err := source.PutURISigningKeys("ds1", []byte(`{"keys": []}`), nil, ctx); err != nil {
+		t.Fatalf("putting URI signing keys: %v", err)
+	}
+	if err := source.PutDNSSECKeys("cdn1", tc.DNSSECKeysTrafficVault{}, nil, ctx); err != nil {
+		t.Fatalf("putting DNSSEC keys: %v", err)
+	}
+	// already identical in the target, so it must not be counted as copied
+	if err := target.PutURLSigKeys("ds1", tc.URLSigKeys{"key0": "foo"}, nil, ctx); err != nil {
+		t.Fatalf("putting URL sig keys in target: %v", err)
+	}
+
+	migrate := func(dryRun bool) migrationReport {
+		m := migrator{source: source, target: target, dryRun: dryRun}
+		m.migrateDNSSECKeys("cdn1")
+		for _, ds := range []string{"ds1", "ds2"} {
+			m.migrateSSLKeys(ds)
+			m.migrateURLSigKeys(ds)
+			m.migrateURISigningKeys(ds)
+		}
+		return m.report
+	}
+
+	// SSL versions 1 and 2, SSL latest, URI signing keys and DNSSEC keys
+	report := migrate(true)
+	if report.copied != 5 || report.identical != 1 || len(report.failed) != 0 {
+		t.Errorf("dry run - expected: 5 copied, 1 identical, 0 failed, actual: %+v", report)
+	}
+	if _, ok, err := target.GetDNSSECKeys("cdn1", nil, ctx); err != nil || ok {
+		t.Errorf("dry run - expected: nothing written to the target, actual: %t %v", ok, err)
+	}
+
+	// putting version 2 also writes latest, so latest is identical by the time it is migrated
+	report = migrate(false)
+	if report.copied != 4 || report.identical != 2 || len(report.failed) != 0 {
+		t.Errorf("migration - expected: 4 copied, 2 identical, 0 failed, actual: %+v", report)
+	}
+	latest, ok, err := target.GetDeliveryServiceSSLKeys("ds1", "", nil, ctx)
+	if err != nil || !ok {
+		t.Fatalf("getting latest SSL keys from target - expected: ok, actual: %t %v", ok, err)
+	}
+	if latest.Version != 2 {
+		t.Errorf("getting latest SSL keys from target - expected: version 2, actual: version %d", latest.Version)
+	}
+
+	report = migrate(false)
+	if report.copied != 0 || report.identical != 6 || len(report.failed) != 0 {
+		t.Errorf("repeated migration - expected: 0 copied, 6 identical, 0 failed, actual: %+v", report)
+	}
+}
+
+func TestMigrationReportString(t *testing.T) {
+	r := migrationReport{copied: 3, identical: 1}
+	for i := 0; i < maxReportedFailures+2; i++ {
+		r.failed = append(r.failed, "urlsigkeys:ds")
+	}
+	expected := "Traffic Vault migration complete. 3 records copied and verified, 1 records were already identical in the target, 12 records failed. Failed records: urlsigkeys:ds, urlsigkeys:ds, urlsigkeys:ds, urlsigkeys:ds, urlsigkeys:ds, urlsigkeys:ds, urlsigkeys:ds, urlsigkeys:ds, urlsigkeys:ds, urlsigkeys:ds, ... (see the error log for details)"
+	if actual := r.String(); actual != expected {
+		t.Errorf("expected: %s, actual: %s", expected, actual)
+	}
+}
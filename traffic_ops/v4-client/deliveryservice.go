@@ -72,6 +72,18 @@ const (
 	// of the Delivery Service of interest).
 	apiAPIDeliveryServiceXMLIDSSLKeys = apiDeliveryServices + "/xmlId/%s/sslkeys"
 
+	// apiDeliveryServiceXMLIDSSLKeysVersions is the API path on which Traffic Ops serves information
+	// about the stored versions of the SSL keys used by a Delivery Service identified by its XMLID. It is
+	// intended to be used with fmt.Sprintf to insert its required path parameter (namely the XMLID
+	// of the Delivery Service of interest).
+	apiDeliveryServiceXMLIDSSLKeysVersions = apiAPIDeliveryServiceXMLIDSSLKeys + "/versions"
+
+	// apiDeliveryServiceXMLIDSSLKeysRollback is the API path on which Traffic Ops will make a stored
+	// version of the SSL keys used by a Delivery Service identified by its XMLID the latest version. It
+	// is intended to be used with fmt.Sprintf to insert its required path parameters (namely the XMLID
+	// of the Delivery Service of interest and the version to roll back to).
+	apiDeliveryServiceXMLIDSSLKeysRollback = apiDeliveryServiceXMLIDSSLKeysVersions + "/%d/rollback"
+
 	// apiDeliveryServiceGenerateSSLKeys is the API path on which Traffic Ops will generate new SSL keys.
 	apiDeliveryServiceGenerateSSLKeys = apiDeliveryServices + "/sslkeys/generate"
 
@@ -280,6 +292,22 @@ func (to *Session) GetDeliveryServiceSSLKeys(xmlid string, opts RequestOptions)
 	return data, reqInf, err
 }
 
+// GetDeliveryServiceSSLKeysVersions retrieves information about every stored
+// version of the SSL keys of the Delivery Service with the given XMLID.
+func (to *Session) GetDeliveryServiceSSLKeysVersions(xmlid string, opts RequestOptions) (tc.DeliveryServiceSSLKeysVersionsResponse, toclientlib.ReqInf, error) {
+	var data tc.DeliveryServiceSSLKeysVersionsResponse
+	reqInf, err := to.get(fmt.Sprintf(apiDeliveryServiceXMLIDSSLKeysVersions, url.QueryEscape(xmlid)), opts, &data)
+	return data, reqInf, err
+}
+
+// RollbackDeliveryServiceSSLKeys makes the given stored version of the SSL
+// keys of the Delivery Service with the given XMLID its latest version.
+func (to *Session) RollbackDeliveryServiceSSLKeys(xmlid string, version int, opts RequestOptions) (tc.DeliveryServiceSSLKeysGenerationResponse, toclientlib.ReqInf, error) {
+	var resp tc.DeliveryServiceSSLKeysGenerationResponse
+	reqInf, err := to.post(fmt.Sprintf(apiDeliveryServiceXMLIDSSLKeysRollback, url.QueryEscape(xmlid), version), opts, nil, &resp)
+	return resp, reqInf, err
+}
+
 // GetDeliveryServicesEligible returns the servers eligible for assignment to the Delivery
 // Service identified by the integral, unique identifier 'dsID'.
 func (to *Session) GetDeliveryServicesEligible(dsID int, opts RequestOptions) (tc.DSServerResponseV4, toclientlib.ReqInf, error) {
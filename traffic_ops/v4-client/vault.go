@@ -22,6 +22,8 @@ import (
 const (
 	// apiVaultPing is the partial path (excluding the /api/<version> prefix) to the /vault/ping API endpoint.
 	apiVaultPing = "/vault/ping"
+	// apiVaultMigrate is the partial path (excluding the /api/<version> prefix) to the /vault/migrate API endpoint.
+	apiVaultMigrate = "/vault/migrate"
 )
 
 // TrafficVaultPing returns a response indicating whether or not Traffic Vault is responsive.
@@ -30,3 +32,12 @@ func (to *Session) TrafficVaultPing(opts RequestOptions) (tc.TrafficVaultPingRes
 	reqInf, err := to.get(apiVaultPing, opts, &data)
 	return data, reqInf, err
 }
+
+// MigrateTrafficVault asynchronously copies every record in Traffic Vault to
+// the migration backend configured in Traffic Ops. Set the "dryRun" query
+// parameter in opts to only report what would be copied.
+func (to *Session) MigrateTrafficVault(opts RequestOptions) (tc.Alerts, toclientlib.ReqInf, error) {
+	var alerts tc.Alerts
+	reqInf, err := to.post(apiVaultMigrate, opts, nil, &alerts)
+	return alerts, reqInf, err
+}